@@ -0,0 +1,87 @@
+package feeds
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testFeed() Feed {
+	published := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	return Feed{
+		Title:   "Tom & Jerry's <forum>",
+		Link:    "http://localhost:8000/",
+		Self:    "http://localhost:8080/feeds/posts.atom",
+		Updated: published.Add(time.Hour),
+		Entries: []Entry{{
+			ID:         "http://localhost:8000/post/1",
+			Title:      "Generics <T> & you",
+			Link:       "http://localhost:8000/post/1",
+			Author:     "alice",
+			Content:    `<p>Use <code>any</code> &amp; friends</p>`,
+			Categories: []string{"go"},
+			Published:  published,
+			Updated:    published.Add(time.Hour),
+		}},
+	}
+}
+
+func TestAtom(t *testing.T) {
+	body, err := Atom(testFeed())
+	if err != nil {
+		t.Fatalf("Atom failed: %v", err)
+	}
+	if !strings.HasPrefix(string(body), xml.Header) {
+		t.Error("Expected an XML declaration")
+	}
+
+	var feed atomFeed
+	if err := xml.Unmarshal(body, &feed); err != nil {
+		t.Fatalf("Expected well-formed XML: %v", err)
+	}
+	if feed.Title != "Tom & Jerry's <forum>" || feed.Updated != "2024-05-01T10:00:00Z" {
+		t.Errorf("Unexpected feed: %+v", feed)
+	}
+	if len(feed.Entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(feed.Entries))
+	}
+	entry := feed.Entries[0]
+	if entry.Title != "Generics <T> & you" || entry.Published != "2024-05-01T09:00:00Z" || entry.Updated != "2024-05-01T10:00:00Z" {
+		t.Errorf("Unexpected entry: %+v", entry)
+	}
+	if entry.Content.Type != "html" || entry.Content.Body != `<p>Use <code>any</code> &amp; friends</p>` {
+		t.Errorf("Expected the HTML content to round-trip, got %+v", entry.Content)
+	}
+	if strings.Contains(string(body), "<p>") {
+		t.Error("Expected the HTML content to be escaped")
+	}
+}
+
+func TestRSS(t *testing.T) {
+	body, err := RSS(testFeed())
+	if err != nil {
+		t.Fatalf("RSS failed: %v", err)
+	}
+
+	var feed rssFeed
+	if err := xml.Unmarshal(body, &feed); err != nil {
+		t.Fatalf("Expected well-formed XML: %v", err)
+	}
+	if feed.Version != "2.0" || feed.Channel.Description != "Tom & Jerry's <forum>" {
+		t.Errorf("Unexpected channel: %+v", feed.Channel)
+	}
+	if feed.Channel.LastBuildDate != "Wed, 01 May 2024 10:00:00 +0000" {
+		t.Errorf("Unexpected lastBuildDate %q", feed.Channel.LastBuildDate)
+	}
+	if len(feed.Channel.Items) != 1 {
+		t.Fatalf("Expected 1 item, got %d", len(feed.Channel.Items))
+	}
+	item := feed.Channel.Items[0]
+	if item.Title != "Generics <T> & you" || !item.GUID.IsPermaLink || item.Creator != "alice" {
+		t.Errorf("Unexpected item: %+v", item)
+	}
+	if item.PubDate != "Wed, 01 May 2024 09:00:00 +0000" {
+		t.Errorf("Unexpected pubDate %q", item.PubDate)
+	}
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"io/fs"
+	"log"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"forum/sqlite"
+	"forum/utils"
+)
+
+// uploadDir is where avatars and post images are stored
+var uploadDir = "static"
+
+const (
+	// defaultSiteStatsDays is the range of /api/admin/stats when none is given
+	defaultSiteStatsDays = 30
+	// topCategoryCount is how many categories /api/admin/stats lists
+	topCategoryCount = 5
+)
+
+// GetSiteStats returns registrations, active users, posts, comments and
+// reactions per ?period=day|week over ?from=&to=, site totals, storage sizes
+// and the top categories (admin only)
+func GetSiteStats(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	period := query.Get("period")
+	if period == "" {
+		period = sqlite.PeriodDay
+	}
+	if period != sqlite.PeriodDay && period != sqlite.PeriodWeek {
+		utils.SendJSONError(w, "period must be day or week", http.StatusBadRequest)
+		return
+	}
+
+	var err error
+	to := time.Now().UTC()
+	if raw := query.Get("to"); raw != "" {
+		if to, err = time.Parse("2006-01-02", raw); err != nil {
+			utils.SendJSONError(w, "to must be a date like 2024-01-31", http.StatusBadRequest)
+			return
+		}
+	}
+	from := to.AddDate(0, 0, -(defaultSiteStatsDays - 1))
+	if raw := query.Get("from"); raw != "" {
+		if from, err = time.Parse("2006-01-02", raw); err != nil {
+			utils.SendJSONError(w, "from must be a date like 2024-01-01", http.StatusBadRequest)
+			return
+		}
+	}
+	if from.After(to) || to.Sub(from) >= maxStatsDays*24*time.Hour {
+		utils.SendJSONError(w, "from must not be after to, and the range can be at most a year", http.StatusBadRequest)
+		return
+	}
+
+	stats, err := sqlite.GetSiteStats(db, period, from, to, topCategoryCount)
+	if err != nil {
+		utils.SendJSONError(w, "Failed to fetch stats", http.StatusInternalServerError)
+		return
+	}
+	if stats.UploadBytes, err = dirSize(uploadDir); err != nil {
+		log.Printf("Warning: Failed to measure uploads: %v", err)
+	}
+	utils.SendJSONResponse(w, stats, http.StatusOK)
+}
+
+// dirSize returns the total size of the files under dir, 0 if it does not
+// exist
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	return size, err
+}
+
+// ListUsers returns a page of users matching ?q= by username or email, in
+// ?sort=username|created_at|last_seen|posts|comments order, ?order=asc|desc
+// (admin only)
+func ListUsers(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	sort := query.Get("sort")
+	if sort == "" {
+		sort = "created_at"
+	}
+	order := query.Get("order")
+	if order != "" && order != "asc" && order != "desc" {
+		utils.SendJSONError(w, "order must be asc or desc", http.StatusBadRequest)
+		return
+	}
+	// Newest accounts first unless asked otherwise
+	desc := order == "desc" || (order == "" && sort == "created_at")
+
+	page, limit := utils.GetPaginationParams(r)
+	if limit > 100 {
+		limit = 100
+	}
+	users, err := sqlite.ListUsers(db, query.Get("q"), sort, desc, page, limit)
+	if errors.Is(err, sqlite.ErrUnknownSort) {
+		utils.SendJSONError(w, "sort must be username, created_at, last_seen, posts or comments", http.StatusBadRequest)
+		return
+	} else if err != nil {
+		utils.SendJSONError(w, "Failed to fetch users", http.StatusInternalServerError)
+		return
+	}
+	utils.SendJSONResponse(w, map[string]any{"users": users, "page": page, "limit": limit}, http.StatusOK)
+}
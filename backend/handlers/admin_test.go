@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"forum/middleware"
+	"forum/models"
+	"forum/sqlite"
+	"forum/utils"
+)
+
+func TestAdminDashboard(t *testing.T) {
+	db := setupSchemaTestDB(t)
+	defer db.Close()
+
+	adminID, adminHeader := createTestUser(t, db, "admin")
+	_, userHeader := createTestUser(t, db, "user")
+	if err := sqlite.SetUserRole(db, "admin", sqlite.RoleAdmin); err != nil {
+		t.Fatalf("SetUserRole failed: %v", err)
+	}
+	hash, err := utils.HashPassword("secret123")
+	if err != nil {
+		t.Fatalf("HashPassword failed: %v", err)
+	}
+	if err := sqlite.CreateUser(db, "troll", "troll@example.com", hash, ""); err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	troll, _ := sqlite.GetUserByUsername(db, "troll")
+
+	uploadDir = t.TempDir()
+	mux := http.NewServeMux()
+	mux.Handle("/api/admin/stats", middleware.AdminMiddleware(db, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { GetSiteStats(db, w, r) })))
+	mux.Handle("/api/admin/users", middleware.AdminMiddleware(db, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { ListUsers(db, w, r) })))
+	mux.Handle("/api/admin/users/{id}/ban", middleware.AdminMiddleware(db, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { BanUser(db, w, r) })))
+	mux.HandleFunc("/api/login", func(w http.ResponseWriter, r *http.Request) { LoginUser(db, w, r) })
+
+	t.Run("only admins", func(t *testing.T) {
+		for _, path := range []string{"/api/admin/stats", "/api/admin/users"} {
+			if rr := testRequest(mux, http.MethodGet, path, userHeader, ""); rr.Code != http.StatusForbidden {
+				t.Errorf("Expected 403 for %s, got %d", path, rr.Code)
+			}
+		}
+	})
+
+	t.Run("stats", func(t *testing.T) {
+		rr := testRequest(mux, http.MethodGet, "/api/admin/stats?period=week", adminHeader, "")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var stats models.SiteStats
+		json.NewDecoder(rr.Body).Decode(&stats)
+		if stats.Period != "week" || stats.Totals.Users != 3 || stats.Totals.ActiveNow != 2 || len(stats.Series) < 5 {
+			t.Errorf("Unexpected stats: %+v", stats)
+		}
+		if rr := testRequest(mux, http.MethodGet, "/api/admin/stats?period=month", adminHeader, ""); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for an unknown period, got %d", rr.Code)
+		}
+	})
+
+	t.Run("list users", func(t *testing.T) {
+		rr := testRequest(mux, http.MethodGet, "/api/admin/users?q=troll", adminHeader, "")
+		var page struct {
+			Users []models.AdminUser `json:"users"`
+		}
+		json.NewDecoder(rr.Body).Decode(&page)
+		if len(page.Users) != 1 || page.Users[0].Email != "troll@example.com" {
+			t.Errorf("Unexpected users: %+v", page.Users)
+		}
+		if rr := testRequest(mux, http.MethodGet, "/api/admin/users?sort=email", adminHeader, ""); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for an unknown sort, got %d", rr.Code)
+		}
+	})
+
+	t.Run("ban and unban", func(t *testing.T) {
+		login := `{"username":"troll","password":"secret123"}`
+		if rr := testRequest(mux, http.MethodPost, "/api/login", http.Header{}, login); rr.Code != http.StatusOK {
+			t.Fatalf("Expected login to work before the ban, got %d: %s", rr.Code, rr.Body.String())
+		}
+
+		if rr := testRequest(mux, http.MethodPost, "/api/admin/users/"+troll.ID+"/ban", userHeader, `{"reason":"Spam"}`); rr.Code != http.StatusForbidden {
+			t.Errorf("Expected 403 for a regular user, got %d", rr.Code)
+		}
+		if rr := testRequest(mux, http.MethodPost, "/api/admin/users/"+troll.ID+"/ban", adminHeader, `{"reason":"Spam"}`); rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		if rr := testRequest(mux, http.MethodPost, "/api/login", http.Header{}, login); rr.Code != http.StatusForbidden {
+			t.Errorf("Expected banned login to return 403, got %d", rr.Code)
+		}
+
+		rr := testRequest(mux, http.MethodGet, "/api/admin/users?q=troll", adminHeader, "")
+		var page struct {
+			Users []models.AdminUser `json:"users"`
+		}
+		json.NewDecoder(rr.Body).Decode(&page)
+		if len(page.Users) != 1 || page.Users[0].Ban == nil || page.Users[0].Ban.Reason != "Spam" {
+			t.Errorf("Expected the ban in the user list, got %+v", page.Users)
+		}
+
+		if rr := testRequest(mux, http.MethodDelete, "/api/admin/users/"+troll.ID+"/ban", adminHeader, ""); rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", rr.Code)
+		}
+		if rr := testRequest(mux, http.MethodPost, "/api/login", http.Header{}, login); rr.Code != http.StatusOK {
+			t.Errorf("Expected login to work after the unban, got %d", rr.Code)
+		}
+
+		if rr := testRequest(mux, http.MethodPost, "/api/admin/users/"+adminID+"/ban", adminHeader, `{"reason":"Oops"}`); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 when banning yourself, got %d", rr.Code)
+		}
+		if rr := testRequest(mux, http.MethodPost, "/api/admin/users/nobody/ban", adminHeader, `{"reason":"Spam"}`); rr.Code != http.StatusNotFound {
+			t.Errorf("Expected 404, got %d", rr.Code)
+		}
+	})
+}
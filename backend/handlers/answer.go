@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"forum/sqlite"
+	"forum/utils"
+)
+
+// AcceptAnswer marks {"comment_id": ...} as the accepted answer of the
+// question in the path (POST), or clears it (DELETE). Only the question's
+// author and moderators may do so (protected).
+func AcceptAnswer(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := utils.GetUserIDFromSession(db, r)
+	if err != nil || userID == "" {
+		utils.SendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	postID, err := utils.ValidateID(r.PathValue("id"), "post ID")
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var commentID int
+	if r.Method == http.MethodPost {
+		var request struct {
+			CommentID int `json:"comment_id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.CommentID <= 0 {
+			utils.SendJSONError(w, "comment_id is required", http.StatusBadRequest)
+			return
+		}
+		commentID = request.CommentID
+	}
+
+	authorID, err := sqlite.GetPostAuthorID(db, postID)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.SendJSONError(w, "Post not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.SendJSONError(w, "Failed to fetch post", http.StatusInternalServerError)
+		return
+	}
+	if authorID != userID {
+		role, err := sqlite.GetUserRole(db, userID)
+		if err != nil || !sqlite.CanModerate(role) {
+			utils.SendJSONError(w, "Only the author or a moderator can accept an answer", http.StatusForbidden)
+			return
+		}
+	}
+
+	err = sqlite.AcceptAnswer(db, postID, commentID)
+	if errors.Is(err, sqlite.ErrNotAQuestion) || errors.Is(err, sqlite.ErrNotAnAnswer) {
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err != nil {
+		utils.SendJSONError(w, "Failed to accept answer", http.StatusInternalServerError)
+		return
+	}
+
+	var accepted *int
+	if commentID != 0 {
+		accepted = &commentID
+	}
+	utils.SendJSONResponse(w, map[string]*int{"accepted_comment_id": accepted}, http.StatusOK)
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"forum/models"
+	"forum/sqlite"
+)
+
+func TestAcceptAnswer(t *testing.T) {
+	db := setupSchemaTestDB(t)
+	defer db.Close()
+	alice, aliceHeader := createTestUser(t, db, "alice")
+	bob, bobHeader := createTestUser(t, db, "bob")
+	_, modHeader := createTestUser(t, db, "mod")
+	if err := sqlite.SetUserRole(db, "mod", sqlite.RoleModerator); err != nil {
+		t.Fatalf("SetUserRole failed: %v", err)
+	}
+
+	question, _ := sqlite.CreatePost(db, alice, nil, "How?", "Help", "")
+	sqlite.SetPostType(db, question.ID, "question")
+	answer, _ := sqlite.CreateComment(db, bob, question.ID, "Like this")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/posts/{id}/accept", func(w http.ResponseWriter, r *http.Request) { AcceptAnswer(db, w, r) })
+	mux.HandleFunc("/api/posts", func(w http.ResponseWriter, r *http.Request) { GetPosts(db, w, r) })
+
+	acceptPath := "/api/posts/" + strconv.Itoa(question.ID) + "/accept"
+	body := `{"comment_id":` + strconv.Itoa(answer.ID) + `}`
+
+	if rr := testRequest(mux, http.MethodPost, acceptPath, bobHeader, body); rr.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for another user, got %d", rr.Code)
+	}
+	if rr := testRequest(mux, http.MethodPost, acceptPath, aliceHeader, `{}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without a comment, got %d", rr.Code)
+	}
+	if rr := testRequest(mux, http.MethodPost, acceptPath, modHeader, body); rr.Code != http.StatusOK {
+		t.Errorf("Expected a moderator to accept, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var posts []models.Post
+	json.NewDecoder(testRequest(mux, http.MethodGet, "/api/posts?answered=true", http.Header{}, "").Body).Decode(&posts)
+	if len(posts) != 1 || posts[0].AcceptedCommentID == nil || *posts[0].AcceptedCommentID != answer.ID {
+		t.Errorf("Expected the answered question, got %+v", posts)
+	}
+	if rr := testRequest(mux, http.MethodGet, "/api/posts?answered=maybe", http.Header{}, ""); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a bad filter, got %d", rr.Code)
+	}
+
+	if rr := testRequest(mux, http.MethodDelete, acceptPath, aliceHeader, ""); rr.Code != http.StatusOK {
+		t.Errorf("Expected the author to clear the answer, got %d", rr.Code)
+	}
+	json.NewDecoder(testRequest(mux, http.MethodGet, "/api/posts?answered=false", http.Header{}, "").Body).Decode(&posts)
+	if len(posts) != 1 || posts[0].ID != question.ID {
+		t.Errorf("Expected the unanswered question, got %+v", posts)
+	}
+}
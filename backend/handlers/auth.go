@@ -14,6 +14,7 @@ import (
 	"forum/models"
 	"forum/sqlite"
 	"forum/utils"
+	"forum/webhooks"
 )
 
 func RegisterUser(db *sql.DB, w http.ResponseWriter, r *http.Request) {
@@ -149,6 +150,15 @@ func RegisterUser(db *sql.DB, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Tell webhooks about the new user, leaving out private details like the email
+	if user, err := sqlite.GetUserByUsername(db, sanitizedUsername); err != nil {
+		log.Printf("Warning: failed to look up new user %s for webhooks: %v", sanitizedUsername, err)
+	} else {
+		enqueueWebhook(db, webhooks.UserRegistered, map[string]any{
+			"id": user.ID, "username": user.Username, "avatar_url": user.AvatarURL, "created_at": user.CreatedAt,
+		})
+	}
+
 	utils.SendJSONResponse(w, map[string]string{"message": "User registered successfully"}, http.StatusCreated)
 }
 
@@ -239,6 +249,15 @@ func LoginUser(db *sql.DB, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Banned and suspended users keep their account but cannot sign in
+	if ban, err := sqlite.GetActiveBan(db, user.ID); err != nil {
+		utils.SendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	} else if ban != nil {
+		utils.SendBanError(w, ban)
+		return
+	}
+
 	// Delete all existing sessions for this user (single session policy)
 	// This ensures only the most recent login session persists
 	err = sqlite.DeleteAllUserSessions(db, user.ID)
@@ -290,6 +309,10 @@ func GetUser(db *sql.DB, w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
+	applyPresence(db, user, true)
+	if user.Role, err = sqlite.GetUserRole(db, userID); err != nil {
+		log.Printf("Warning: Failed to load role of %s: %v", userID, err)
+	}
 
 	utils.SendJSONResponse(w, user, http.StatusOK)
 }
@@ -337,6 +360,7 @@ func RequireAuth(db *sql.DB, w http.ResponseWriter, r *http.Request) (string, bo
 	return userID, true
 }
 
+// GetOwner returns the public profile of a user by ID, e.g. a post's author
 func GetOwner(db *sql.DB, w http.ResponseWriter, r *http.Request) {
 	userId := r.URL.Query().Get("user_id")
 
@@ -346,10 +370,11 @@ func GetOwner(db *sql.DB, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := sqlite.GetUserByID(db, userId)
+	profile, err := sqlite.GetProfileByID(db, userId)
 	if err != nil {
 		utils.SendJSONError(w, "User not found", http.StatusNotFound)
 		return
 	}
-	utils.SendJSONResponse(w, user, http.StatusOK)
+	profile.Online, profile.LastSeenAt = presenceOf(db, profile.ID, false)
+	utils.SendJSONResponse(w, profile, http.StatusOK)
 }
@@ -38,6 +38,9 @@ func setupTestDB(t *testing.T) *sql.DB {
 		email TEXT UNIQUE NOT NULL,
 		password_hash TEXT NOT NULL,
 		avatar_url TEXT DEFAULT '/static/default-avatar.png',
+		banned_at DATETIME,
+		banned_until DATETIME,
+		ban_reason TEXT NOT NULL DEFAULT '',
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 	);
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+
+	"forum/models"
+	"forum/sqlite"
+	"forum/utils"
+)
+
+// BlockUser blocks (POST) or unblocks (DELETE) the user named in the path.
+// Blocking also removes follows between the two users.
+func BlockUser(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	updateRelation(db, w, r, "blocked", sqlite.BlockUser, sqlite.UnblockUser)
+}
+
+// MuteUser mutes (POST) or unmutes (DELETE) the user named in the path
+func MuteUser(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	updateRelation(db, w, r, "muted", sqlite.MuteUser, sqlite.UnmuteUser)
+}
+
+func updateRelation(db *sql.DB, w http.ResponseWriter, r *http.Request, field string, add, remove func(*sql.DB, string, string) error) {
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := utils.GetUserIDFromSession(db, r)
+	if err != nil || userID == "" {
+		utils.SendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	other, err := sqlite.GetUserByUsername(db, r.PathValue("username"))
+	if err != nil {
+		utils.SendJSONError(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if other.ID == userID {
+		utils.SendJSONError(w, "You cannot do this to yourself", http.StatusBadRequest)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		err = add(db, userID, other.ID)
+	} else {
+		err = remove(db, userID, other.ID)
+	}
+	if err != nil {
+		utils.SendJSONError(w, "Failed to update user", http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendJSONResponse(w, map[string]bool{field: r.Method == http.MethodPost}, http.StatusOK)
+}
+
+// GetBlockedUsers lists the users the current user blocked
+func GetBlockedUsers(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	listRelation(db, w, r, sqlite.GetBlockedUsers)
+}
+
+// GetMutedUsers lists the users the current user muted
+func GetMutedUsers(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	listRelation(db, w, r, sqlite.GetMutedUsers)
+}
+
+func listRelation(db *sql.DB, w http.ResponseWriter, r *http.Request, list func(*sql.DB, string) ([]models.UserSummary, error)) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := utils.GetUserIDFromSession(db, r)
+	if err != nil || userID == "" {
+		utils.SendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	users, err := list(db, userID)
+	if err != nil {
+		utils.SendJSONError(w, "Failed to fetch users", http.StatusInternalServerError)
+		return
+	}
+	utils.SendJSONResponse(w, users, http.StatusOK)
+}
+
+// canInteract checks that there is no block between userID and the author of
+// the content they are replying or reacting to. It writes the error response
+// and returns false otherwise.
+func canInteract(db *sql.DB, w http.ResponseWriter, userID, authorID string) bool {
+	blocked, err := sqlite.IsBlocked(db, userID, authorID)
+	if err != nil {
+		log.Printf("Warning: Failed to check blocks between %s and %s: %v", userID, authorID, err)
+		utils.SendJSONError(w, "Failed to check permissions", http.StatusInternalServerError)
+		return false
+	}
+	if blocked {
+		utils.SendJSONError(w, "You cannot interact with this user", http.StatusForbidden)
+		return false
+	}
+	return true
+}
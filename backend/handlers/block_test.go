@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"forum/sqlite"
+)
+
+func TestBlockedInteractions(t *testing.T) {
+	db := setupSchemaTestDB(t)
+	defer db.Close()
+
+	alice, _ := createTestUser(t, db, "alice")
+	bob, bobHeader := createTestUser(t, db, "bob")
+	post, err := sqlite.CreatePost(db, alice, nil, "Hello", "World", "")
+	if err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+	comment, err := sqlite.CreateComment(db, alice, post.ID, "first")
+	if err != nil {
+		t.Fatalf("Failed to create comment: %v", err)
+	}
+	if err := sqlite.BlockUser(db, alice, bob); err != nil {
+		t.Fatalf("Failed to block: %v", err)
+	}
+
+	postID := strconv.Itoa(post.ID)
+	commentID := strconv.Itoa(comment.ID)
+	for _, tc := range []struct {
+		name    string
+		handler func(w http.ResponseWriter, r *http.Request)
+		body    string
+	}{
+		{"comment", func(w http.ResponseWriter, r *http.Request) { CreateComment(db, w, r) }, `{"post_id":` + postID + `,"content":"hi"}`},
+		{"reply", func(w http.ResponseWriter, r *http.Request) { CreateReplComment(db, w, r) }, `{"parent_comment_id":` + commentID + `,"content":"hi"}`},
+		{"reaction", func(w http.ResponseWriter, r *http.Request) { ToggleLike(db, w, r) }, `{"post_id":` + postID + `,"type":"like"}`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body))
+			req.Header = bobHeader.Clone()
+			rr := httptest.NewRecorder()
+			tc.handler(rr, req)
+			if rr.Code != http.StatusForbidden {
+				t.Errorf("Expected 403, got %d: %s", rr.Code, rr.Body.String())
+			}
+		})
+	}
+
+	var rows int
+	if err := db.QueryRow(`
+		SELECT (SELECT COUNT(*) FROM comments) + (SELECT COUNT(*) FROM replycomments) + (SELECT COUNT(*) FROM likes)
+	`).Scan(&rows); err != nil || rows != 1 {
+		t.Errorf("Expected only alice's comment to exist, got %d rows (%v)", rows, err)
+	}
+}
@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"forum/models"
+	"forum/sqlite"
+	"forum/utils"
+)
+
+const (
+	maxBookmarkNoteLength = 500
+	maxFolderNameLength   = 50
+)
+
+// Bookmarks lists (GET) or saves (POST) the current user's bookmarks. GET
+// takes ?folder_id= (a folder ID, or "none" for unfiled bookmarks) and the
+// usual page and limit.
+func Bookmarks(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := utils.GetUserIDFromSession(db, r)
+	if err != nil || userID == "" {
+		utils.SendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		saveBookmark(db, w, r, userID)
+		return
+	}
+
+	var filter sqlite.BookmarkFilter
+	switch folder := r.URL.Query().Get("folder_id"); folder {
+	case "":
+	case "none":
+		filter.Unfiled = true
+	default:
+		folderID, err := utils.ValidateID(folder, "folder_id")
+		if err != nil {
+			utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		filter.FolderID = &folderID
+	}
+
+	page, limit := utils.GetPaginationParams(r)
+	if limit > 100 {
+		limit = 100
+	}
+	bookmarks, err := sqlite.GetBookmarks(db, userID, filter, page, limit)
+	if err != nil {
+		utils.SendJSONError(w, "Failed to fetch bookmarks", http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendJSONResponse(w, map[string]any{"bookmarks": bookmarks, "page": page, "limit": limit}, http.StatusOK)
+}
+
+func saveBookmark(db *sql.DB, w http.ResponseWriter, r *http.Request, userID string) {
+	var request struct {
+		PostID    *int   `json:"post_id"`
+		CommentID *int   `json:"comment_id"`
+		FolderID  *int   `json:"folder_id"`
+		Note      string `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request data", http.StatusBadRequest)
+		return
+	}
+	if (request.PostID == nil) == (request.CommentID == nil) {
+		utils.SendJSONError(w, "Provide either post_id or comment_id", http.StatusBadRequest)
+		return
+	}
+	note, err := bookmarkNote(request.Note)
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if request.PostID != nil {
+		_, err = sqlite.GetPostAuthorID(db, *request.PostID)
+	} else {
+		_, err = sqlite.GetCommentPostID(db, *request.CommentID)
+	}
+	if err != nil {
+		utils.SendJSONError(w, "Post or comment not found", http.StatusNotFound)
+		return
+	}
+
+	id, err := sqlite.SaveBookmark(db, userID, request.PostID, request.CommentID, request.FolderID, note)
+	if errors.Is(err, sqlite.ErrFolderNotFound) {
+		utils.SendJSONError(w, "Folder not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.SendJSONError(w, "Failed to save bookmark", http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendJSONResponse(w, map[string]any{"id": id}, http.StatusCreated)
+}
+
+// Bookmark moves a bookmark to another folder and replaces its note (PUT),
+// or removes it (DELETE)
+func Bookmark(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut && r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := utils.GetUserIDFromSession(db, r)
+	if err != nil || userID == "" {
+		utils.SendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	bookmarkID, err := utils.ValidateID(r.PathValue("id"), "bookmark ID")
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		err = sqlite.DeleteBookmark(db, userID, bookmarkID)
+	} else {
+		var request struct {
+			FolderID *int   `json:"folder_id"`
+			Note     string `json:"note"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request data", http.StatusBadRequest)
+			return
+		}
+		var note string
+		if note, err = bookmarkNote(request.Note); err != nil {
+			utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		err = sqlite.UpdateBookmark(db, userID, bookmarkID, request.FolderID, note)
+	}
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		utils.SendJSONError(w, "Bookmark not found", http.StatusNotFound)
+	case errors.Is(err, sqlite.ErrFolderNotFound):
+		utils.SendJSONError(w, "Folder not found", http.StatusNotFound)
+	case err != nil:
+		utils.SendJSONError(w, "Failed to update bookmark", http.StatusInternalServerError)
+	default:
+		utils.SendJSONResponse(w, map[string]string{"message": "Bookmark updated"}, http.StatusOK)
+	}
+}
+
+// bookmarkNote validates an optional note
+func bookmarkNote(note string) (string, error) {
+	if strings.TrimSpace(note) == "" {
+		return "", nil
+	}
+	return utils.ValidateAndSanitizeString(note, maxBookmarkNoteLength, "Note")
+}
+
+// BookmarkFolders lists (GET) or creates (POST) the current user's folders
+func BookmarkFolders(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := utils.GetUserIDFromSession(db, r)
+	if err != nil || userID == "" {
+		utils.SendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		folders, err := sqlite.GetBookmarkFolders(db, userID)
+		if err != nil {
+			utils.SendJSONError(w, "Failed to fetch folders", http.StatusInternalServerError)
+			return
+		}
+		utils.SendJSONResponse(w, folders, http.StatusOK)
+		return
+	}
+
+	name, ok := decodeFolderName(w, r)
+	if !ok {
+		return
+	}
+	folder, err := sqlite.CreateBookmarkFolder(db, userID, name)
+	if sqlite.IsUniqueConstraintError(err) {
+		utils.SendJSONError(w, "You already have a folder with this name", http.StatusConflict)
+		return
+	} else if err != nil {
+		utils.SendJSONError(w, "Failed to create folder", http.StatusInternalServerError)
+		return
+	}
+	utils.SendJSONResponse(w, folder, http.StatusCreated)
+}
+
+// BookmarkFolder renames (PUT) or deletes (DELETE) a folder. Deleting a
+// folder keeps its bookmarks, unfiled.
+func BookmarkFolder(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut && r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := utils.GetUserIDFromSession(db, r)
+	if err != nil || userID == "" {
+		utils.SendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	folderID, err := utils.ValidateID(r.PathValue("id"), "folder ID")
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		err = sqlite.DeleteBookmarkFolder(db, userID, folderID)
+	} else {
+		name, ok := decodeFolderName(w, r)
+		if !ok {
+			return
+		}
+		err = sqlite.RenameBookmarkFolder(db, userID, folderID, name)
+	}
+	switch {
+	case errors.Is(err, sqlite.ErrFolderNotFound):
+		utils.SendJSONError(w, "Folder not found", http.StatusNotFound)
+	case sqlite.IsUniqueConstraintError(err):
+		utils.SendJSONError(w, "You already have a folder with this name", http.StatusConflict)
+	case err != nil:
+		utils.SendJSONError(w, "Failed to update folder", http.StatusInternalServerError)
+	default:
+		utils.SendJSONResponse(w, map[string]string{"message": "Folder updated"}, http.StatusOK)
+	}
+}
+
+// decodeFolderName reads {"name": ...} from the request body, writing the
+// error response if it is missing or invalid
+func decodeFolderName(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var request struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request data", http.StatusBadRequest)
+		return "", false
+	}
+	name, err := utils.ValidateAndSanitizeString(request.Name, maxFolderNameLength, "Folder name")
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+		return "", false
+	}
+	return name, true
+}
+
+// markBookmarked flags the posts the viewer has bookmarked, if signed in
+func markBookmarked(db *sql.DB, r *http.Request, posts []models.Post) {
+	viewerID, _ := utils.GetUserIDFromSession(db, r)
+	if err := sqlite.MarkBookmarked(db, viewerID, posts); err != nil {
+		log.Printf("Warning: Failed to load bookmarks: %v", err)
+	}
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"forum/models"
+	"forum/sqlite"
+)
+
+func TestBookmarks(t *testing.T) {
+	db := setupSchemaTestDB(t)
+	defer db.Close()
+
+	alice, aliceHeader := createTestUser(t, db, "alice")
+	_, bobHeader := createTestUser(t, db, "bob")
+	var postIDs []int
+	for _, title := range []string{"First", "Second", "Third"} {
+		post, err := sqlite.CreatePost(db, alice, nil, title, "Body", "")
+		if err != nil {
+			t.Fatalf("CreatePost failed: %v", err)
+		}
+		postIDs = append(postIDs, post.ID)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/bookmarks", func(w http.ResponseWriter, r *http.Request) { Bookmarks(db, w, r) })
+	mux.HandleFunc("/api/bookmarks/{id}", func(w http.ResponseWriter, r *http.Request) { Bookmark(db, w, r) })
+	mux.HandleFunc("/api/bookmarks/folders", func(w http.ResponseWriter, r *http.Request) { BookmarkFolders(db, w, r) })
+	mux.HandleFunc("/api/bookmarks/folders/{id}", func(w http.ResponseWriter, r *http.Request) { BookmarkFolder(db, w, r) })
+
+	type page struct {
+		Bookmarks []models.Bookmark `json:"bookmarks"`
+		Limit     int               `json:"limit"`
+	}
+	list := func(query string, header http.Header) page {
+		t.Helper()
+		rr := testRequest(mux, http.MethodGet, "/api/bookmarks"+query, header, "")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var p page
+		json.NewDecoder(rr.Body).Decode(&p)
+		return p
+	}
+
+	t.Run("requires a session", func(t *testing.T) {
+		for _, c := range []struct{ method, path, body string }{
+			{http.MethodGet, "/api/bookmarks", ""},
+			{http.MethodPost, "/api/bookmarks", `{"post_id":1}`},
+			{http.MethodDelete, "/api/bookmarks/1", ""},
+			{http.MethodGet, "/api/bookmarks/folders", ""},
+			{http.MethodPost, "/api/bookmarks/folders", `{"name":"Reading"}`},
+			{http.MethodDelete, "/api/bookmarks/folders/1", ""},
+		} {
+			if rr := testRequest(mux, c.method, c.path, nil, c.body); rr.Code != http.StatusUnauthorized {
+				t.Errorf("Expected 401 for %s %s, got %d", c.method, c.path, rr.Code)
+			}
+		}
+	})
+
+	rr := testRequest(mux, http.MethodPost, "/api/bookmarks/folders", aliceHeader, `{"name":"Reading"}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var folder models.BookmarkFolder
+	json.NewDecoder(rr.Body).Decode(&folder)
+	folderPath := "/api/bookmarks/folders/" + strconv.Itoa(folder.ID)
+
+	var bookmarkIDs []int
+	for _, postID := range postIDs {
+		body := `{"post_id":` + strconv.Itoa(postID) + `,"folder_id":` + strconv.Itoa(folder.ID) + `}`
+		rr := testRequest(mux, http.MethodPost, "/api/bookmarks", aliceHeader, body)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("Expected 201, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var created struct {
+			ID int `json:"id"`
+		}
+		json.NewDecoder(rr.Body).Decode(&created)
+		bookmarkIDs = append(bookmarkIDs, created.ID)
+	}
+	bookmarkPath := "/api/bookmarks/" + strconv.Itoa(bookmarkIDs[0])
+
+	t.Run("pagination", func(t *testing.T) {
+		first := list("?limit=2", aliceHeader)
+		if len(first.Bookmarks) != 2 || first.Limit != 2 {
+			t.Fatalf("Unexpected first page: %+v", first)
+		}
+		second := list("?limit=2&page=2", aliceHeader)
+		if len(second.Bookmarks) != 1 || second.Bookmarks[0].ID == first.Bookmarks[0].ID {
+			t.Errorf("Unexpected second page: %+v", second)
+		}
+		if p := list("?limit=1000", aliceHeader); p.Limit != 100 || len(p.Bookmarks) != 3 {
+			t.Errorf("Expected the limit to be capped at 100, got %d with %d bookmarks", p.Limit, len(p.Bookmarks))
+		}
+		if rr := testRequest(mux, http.MethodGet, "/api/bookmarks?folder_id=abc", aliceHeader, ""); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for an invalid folder_id, got %d", rr.Code)
+		}
+	})
+
+	t.Run("other users' bookmarks and folders are not found", func(t *testing.T) {
+		if p := list("", bobHeader); len(p.Bookmarks) != 0 {
+			t.Errorf("Expected bob to see none of alice's bookmarks, got %+v", p.Bookmarks)
+		}
+		if p := list("?folder_id="+strconv.Itoa(folder.ID), bobHeader); len(p.Bookmarks) != 0 {
+			t.Errorf("Expected bob to see nothing in alice's folder, got %+v", p.Bookmarks)
+		}
+		for _, c := range []struct{ method, path, body string }{
+			{http.MethodPut, bookmarkPath, `{"note":"mine now"}`},
+			{http.MethodDelete, bookmarkPath, ""},
+			{http.MethodPut, folderPath, `{"name":"Taken"}`},
+			{http.MethodDelete, folderPath, ""},
+			{http.MethodPost, "/api/bookmarks", `{"post_id":` + strconv.Itoa(postIDs[0]) + `,"folder_id":` + strconv.Itoa(folder.ID) + `}`},
+		} {
+			if rr := testRequest(mux, c.method, c.path, bobHeader, c.body); rr.Code != http.StatusNotFound {
+				t.Errorf("Expected 404 for bob's %s %s, got %d", c.method, c.path, rr.Code)
+			}
+		}
+		if p := list("?folder_id="+strconv.Itoa(folder.ID), aliceHeader); len(p.Bookmarks) != 3 || p.Bookmarks[len(p.Bookmarks)-1].Note != "" {
+			t.Errorf("Expected alice's bookmarks to be untouched, got %+v", p.Bookmarks)
+		}
+	})
+
+	t.Run("owner updates and deletes", func(t *testing.T) {
+		if rr := testRequest(mux, http.MethodPut, bookmarkPath, aliceHeader, `{"note":"read later"}`); rr.Code != http.StatusOK {
+			t.Errorf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		if rr := testRequest(mux, http.MethodPut, bookmarkPath, aliceHeader, `{"folder_id":999}`); rr.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 for an unknown folder, got %d", rr.Code)
+		}
+		if rr := testRequest(mux, http.MethodDelete, folderPath, aliceHeader, ""); rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		if p := list("?folder_id=none", aliceHeader); len(p.Bookmarks) != 3 {
+			t.Errorf("Expected the folder's bookmarks to become unfiled, got %+v", p.Bookmarks)
+		}
+		if rr := testRequest(mux, http.MethodDelete, bookmarkPath, aliceHeader, ""); rr.Code != http.StatusOK {
+			t.Errorf("Expected 200, got %d", rr.Code)
+		}
+		if rr := testRequest(mux, http.MethodDelete, bookmarkPath, aliceHeader, ""); rr.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 for a deleted bookmark, got %d", rr.Code)
+		}
+	})
+}
@@ -3,53 +3,204 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"forum/models"
 	"forum/sqlite"
 	"forum/utils"
 )
 
+var (
+	slugPattern  = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+	colorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+)
+
+// userCategoriesAllowed reports whether posts may create categories by
+// naming ones that do not exist yet. Set ALLOW_USER_CATEGORIES=true to allow
+// it; by default only admins create categories.
+func userCategoriesAllowed() bool {
+	allowed, _ := strconv.ParseBool(os.Getenv("ALLOW_USER_CATEGORIES"))
+	return allowed
+}
+
+// GetCategories lists the categories in display order. Archived ones are
+// included with ?include_archived=true.
+func GetCategories(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	includeArchived, _ := strconv.ParseBool(r.URL.Query().Get("include_archived"))
+	categories, err := sqlite.GetCategories(db, includeArchived)
+	if err != nil {
+		utils.SendJSONError(w, "Failed to fetch categories", http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendJSONResponse(w, categories, http.StatusOK)
+}
+
+// CreateCategory creates a category (admin only)
 func CreateCategory(db *sql.DB, w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	var category models.Category
-	err := json.NewDecoder(r.Body).Decode(&category)
+	category, err := decodeCategory(r)
 	if err != nil {
-		http.Error(w, "Invalid category data", http.StatusBadRequest)
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	// Validate and sanitize category name
-	sanitizedName, err := utils.ValidateAndSanitizeString(category.Name, 50, "category name")
+	created, err := sqlite.CreateCategory(db, category)
+	if err != nil {
+		sendCategoryError(w, err)
+		return
+	}
+	utils.SendJSONResponse(w, created, http.StatusCreated)
+}
+
+// Category updates (PUT) or deletes (DELETE) the category in the path (admin
+// only). PUT replaces every editable field. Deleting a category keeps its
+// posts and moves its subcategories up to its parent.
+func Category(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut && r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	categoryID, err := utils.ValidateID(r.PathValue("id"), "category ID")
 	if err != nil {
 		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	err = sqlite.CreateCategory(db, sanitizedName)
+	if r.Method == http.MethodDelete {
+		if err := sqlite.DeleteCategory(db, categoryID); err != nil {
+			sendCategoryError(w, err)
+			return
+		}
+		utils.SendJSONResponse(w, map[string]string{"message": "Category deleted"}, http.StatusOK)
+		return
+	}
+
+	category, err := decodeCategory(r)
 	if err != nil {
-		utils.SendJSONError(w, "Failed to create category", http.StatusInternalServerError)
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	category.ID = categoryID
 
-	utils.SendJSONResponse(w, category, http.StatusCreated)
+	updated, err := sqlite.UpdateCategory(db, category)
+	if err != nil {
+		sendCategoryError(w, err)
+		return
+	}
+	utils.SendJSONResponse(w, updated, http.StatusOK)
 }
 
-func GetCategories(db *sql.DB, w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
+// MergeCategory moves the posts, followers and subcategories of the category
+// in the path into {"into_id": ...} and deletes it (admin only)
+func MergeCategory(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	categories, err := sqlite.GetCategories(db)
+	sourceID, err := utils.ValidateID(r.PathValue("id"), "category ID")
 	if err != nil {
-		utils.SendJSONError(w, "Failed to fetch categories", http.StatusInternalServerError)
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	utils.SendJSONResponse(w, categories, http.StatusOK)
+	var request struct {
+		IntoID int `json:"into_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request data", http.StatusBadRequest)
+		return
+	}
+	if request.IntoID <= 0 || request.IntoID == sourceID {
+		utils.SendJSONError(w, "into_id must be another category", http.StatusBadRequest)
+		return
+	}
+
+	if err := sqlite.MergeCategories(db, sourceID, request.IntoID); err != nil {
+		sendCategoryError(w, err)
+		return
+	}
+
+	target, err := sqlite.GetCategory(db, request.IntoID)
+	if err != nil {
+		utils.SendJSONError(w, "Failed to fetch category", http.StatusInternalServerError)
+		return
+	}
+	utils.SendJSONResponse(w, target, http.StatusOK)
+}
+
+// decodeCategory reads and validates the category fields of a request body
+func decodeCategory(r *http.Request) (models.Category, error) {
+	var request struct {
+		Name        string `json:"name"`
+		Slug        string `json:"slug"`
+		Description string `json:"description"`
+		Color       string `json:"color"`
+		ParentID    *int   `json:"parent_id"`
+		SortOrder   int    `json:"sort_order"`
+		Archived    bool   `json:"archived"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return models.Category{}, errors.New("invalid category data")
+	}
+
+	name, err := utils.ValidateAndSanitizeString(request.Name, 50, "category name")
+	if err != nil {
+		return models.Category{}, err
+	}
+	category := models.Category{
+		Name:      name,
+		Slug:      strings.TrimSpace(request.Slug),
+		Color:     strings.TrimSpace(request.Color),
+		ParentID:  request.ParentID,
+		SortOrder: request.SortOrder,
+		Archived:  request.Archived,
+	}
+
+	if category.Slug != "" && (len(category.Slug) > 60 || !slugPattern.MatchString(category.Slug)) {
+		return category, errors.New("slug must be up to 60 lower case letters, digits and single hyphens")
+	}
+	if category.Color != "" && !colorPattern.MatchString(category.Color) {
+		return category, errors.New(`color must look like "#1a2b3c"`)
+	}
+	if category.ParentID != nil && *category.ParentID <= 0 {
+		return category, fmt.Errorf("parent_id must be a positive integer")
+	}
+	if strings.TrimSpace(request.Description) != "" {
+		if category.Description, err = utils.ValidateAndSanitizeString(request.Description, 500, "description"); err != nil {
+			return category, err
+		}
+	}
+	return category, nil
+}
+
+// sendCategoryError maps errors from the category queries to responses
+func sendCategoryError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		utils.SendJSONError(w, "Category not found", http.StatusNotFound)
+	case errors.Is(err, sqlite.ErrCategoryCycle):
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+	case sqlite.IsUniqueConstraintError(err):
+		utils.SendJSONError(w, "A category with this name or slug already exists", http.StatusConflict)
+	default:
+		utils.SendJSONError(w, "Failed to update category", http.StatusInternalServerError)
+	}
 }
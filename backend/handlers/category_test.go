@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"forum/middleware"
+	"forum/models"
+	"forum/sqlite"
+)
+
+func TestAdminCategories(t *testing.T) {
+	db := setupSchemaTestDB(t)
+	defer db.Close()
+
+	_, adminHeader := createTestUser(t, db, "admin")
+	_, userHeader := createTestUser(t, db, "user")
+	if err := sqlite.SetUserRole(db, "admin", "admin"); err != nil {
+		t.Fatalf("SetUserRole failed: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/api/admin/categories", middleware.AdminMiddleware(db, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { CreateCategory(db, w, r) })))
+	mux.Handle("/api/admin/categories/{id}", middleware.AdminMiddleware(db, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { Category(db, w, r) })))
+	mux.Handle("/api/admin/categories/{id}/merge", middleware.AdminMiddleware(db, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { MergeCategory(db, w, r) })))
+
+	t.Run("only admins", func(t *testing.T) {
+		if rr := testRequest(mux, http.MethodPost, "/api/admin/categories", userHeader, `{"name":"Nope"}`); rr.Code != http.StatusForbidden {
+			t.Errorf("Expected 403, got %d", rr.Code)
+		}
+	})
+
+	var parent models.Category
+	t.Run("create and validate", func(t *testing.T) {
+		rr := testRequest(mux, http.MethodPost, "/api/admin/categories", adminHeader, `{"name":"Gophers","description":"All things Go","color":"#00ADD8"}`)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("Expected 201, got %d: %s", rr.Code, rr.Body.String())
+		}
+		json.NewDecoder(rr.Body).Decode(&parent)
+		if parent.Slug != "gophers" || parent.Description != "All things Go" {
+			t.Errorf("Unexpected category: %+v", parent)
+		}
+
+		for _, body := range []string{`{"name":""}`, `{"name":"X","color":"red"}`, `{"name":"X","slug":"Not A Slug"}`} {
+			if rr := testRequest(mux, http.MethodPost, "/api/admin/categories", adminHeader, body); rr.Code != http.StatusBadRequest {
+				t.Errorf("Expected 400 for %s, got %d", body, rr.Code)
+			}
+		}
+		if rr := testRequest(mux, http.MethodPost, "/api/admin/categories", adminHeader, `{"name":"Gophers"}`); rr.Code != http.StatusConflict {
+			t.Errorf("Expected 409 for a duplicate name, got %d", rr.Code)
+		}
+	})
+
+	t.Run("update, merge and delete", func(t *testing.T) {
+		rr := testRequest(mux, http.MethodPost, "/api/admin/categories", adminHeader, `{"name":"Child","parent_id":`+strconv.Itoa(parent.ID)+`}`)
+		var child models.Category
+		json.NewDecoder(rr.Body).Decode(&child)
+
+		path := "/api/admin/categories/" + strconv.Itoa(parent.ID)
+		if rr := testRequest(mux, http.MethodPut, path, adminHeader, `{"name":"Gophers","parent_id":`+strconv.Itoa(child.ID)+`}`); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected a cycle to be rejected, got %d", rr.Code)
+		}
+		if rr := testRequest(mux, http.MethodPost, path+"/merge", adminHeader, `{"into_id":`+strconv.Itoa(child.ID)+`}`); rr.Code != http.StatusOK {
+			t.Errorf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		if rr := testRequest(mux, http.MethodDelete, path, adminHeader, ""); rr.Code != http.StatusNotFound {
+			t.Errorf("Expected the merged category to be gone, got %d", rr.Code)
+		}
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"forum/models"
+	"forum/screening"
 	"forum/sqlite"
 	"forum/utils"
 )
@@ -30,7 +31,7 @@ func CreateComment(db *sql.DB, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sanitizedContent, err := utils.ValidateAndSanitizeString(comment.Content, 2000, "comment")
+	sanitizedContent, err := utils.ValidateContent(comment.Content, 2000, "comment")
 	if err != nil {
 		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
 		return
@@ -50,8 +51,31 @@ func CreateComment(db *sql.DB, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Blocked users cannot comment on each other's posts
+	authorID, err := sqlite.GetPostAuthorID(db, comment.PostID)
+	if err != nil {
+		utils.SendJSONError(w, "Post not found", http.StatusNotFound)
+		return
+	}
+	if !canInteract(db, w, userID, authorID) || !threadOpen(db, w, comment.PostID) {
+		return
+	}
+
+	submission := screening.Content{Kind: sqlite.ContentComment, UserID: userID, Content: sanitizedContent}
+	verdict, ok := screenContent(db, w, &submission)
+	if !ok {
+		return
+	}
+	if verdict.Action == screening.Flag {
+		holdContent(db, w, models.QueueItem{
+			Kind: sqlite.ContentComment, UserID: userID, TargetID: &comment.PostID,
+			Content: submission.Content, Reasons: verdict.Reasons,
+		})
+		return
+	}
+
 	// Create top-level comment
-	comm, err := sqlite.CreateComment(db, comment.UserID, comment.PostID, sanitizedContent)
+	comm, err := publishComment(db, comment.UserID, comment.PostID, submission.Content)
 	if err != nil {
 		utils.SendJSONError(w, "Failed to create comment", http.StatusInternalServerError)
 		return
@@ -79,7 +103,7 @@ func CreateReplComment(db *sql.DB, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sanitizedReplyContent, err := utils.ValidateAndSanitizeString(reply.Content, 2000, "reply")
+	sanitizedReplyContent, err := utils.ValidateContent(reply.Content, 2000, "reply")
 	if err != nil {
 		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
 		return
@@ -105,8 +129,39 @@ func CreateReplComment(db *sql.DB, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Blocked users cannot reply to each other
+	authorID, err := sqlite.GetCommentAuthorID(db, reply.ParentCommentID)
+	if err != nil {
+		utils.SendJSONError(w, "Comment not found", http.StatusNotFound)
+		return
+	}
+	if !canInteract(db, w, userID, authorID) {
+		return
+	}
+	postID, err := sqlite.GetCommentPostID(db, reply.ParentCommentID)
+	if err != nil {
+		utils.SendJSONError(w, "Comment not found", http.StatusNotFound)
+		return
+	}
+	if !threadOpen(db, w, postID) {
+		return
+	}
+
+	submission := screening.Content{Kind: sqlite.ContentReply, UserID: userID, Content: sanitizedReplyContent}
+	verdict, ok := screenContent(db, w, &submission)
+	if !ok {
+		return
+	}
+	if verdict.Action == screening.Flag {
+		holdContent(db, w, models.QueueItem{
+			Kind: sqlite.ContentReply, UserID: userID, TargetID: &reply.ParentCommentID,
+			Content: submission.Content, Reasons: verdict.Reasons,
+		})
+		return
+	}
+
 	// Create the reply
-	createdReply, err := sqlite.CreateReplyComment(db, reply.UserID, reply.ParentCommentID, sanitizedReplyContent)
+	createdReply, err := publishReply(db, reply.UserID, reply.ParentCommentID, submission.Content)
 	if err != nil {
 		utils.SendJSONError(w, "Failed to create reply", http.StatusInternalServerError)
 		return
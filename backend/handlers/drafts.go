@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"forum/realtime"
+	"forum/sqlite"
+	"forum/utils"
+	"forum/webhooks"
+)
+
+// maxScheduleAhead is how far in the future a post can be scheduled
+const maxScheduleAhead = 365 * 24 * time.Hour
+
+// parsePostStatus reads the status and publish_at (RFC 3339) fields of a new
+// post. No status publishes the post now, unless publish_at is given, which
+// schedules it.
+func parsePostStatus(status, publishAt string) (string, *time.Time, error) {
+	if status == "" {
+		status = sqlite.PostPublished
+		if publishAt != "" {
+			status = sqlite.PostScheduled
+		}
+	}
+	switch status {
+	case sqlite.PostPublished, sqlite.PostDraft:
+		if publishAt != "" {
+			return "", nil, errors.New("publish_at is only allowed for scheduled posts")
+		}
+		return status, nil, nil
+	case sqlite.PostScheduled:
+		at, err := parsePublishAt(publishAt)
+		return status, at, err
+	default:
+		return "", nil, errors.New(`status must be "draft", "scheduled" or "published"`)
+	}
+}
+
+// parsePublishAt reads when a post should be published, which must be in
+// the coming year
+func parsePublishAt(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, errors.New("publish_at is required for scheduled posts")
+	}
+	at, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, errors.New("publish_at must be an RFC 3339 time, like 2024-06-01T09:00:00Z")
+	}
+	if !at.After(time.Now()) {
+		return nil, errors.New("publish_at must be in the future")
+	}
+	if at.After(time.Now().Add(maxScheduleAhead)) {
+		return nil, fmt.Errorf("publish_at must be within %d days", int(maxScheduleAhead/(24*time.Hour)))
+	}
+	at = at.UTC()
+	return &at, nil
+}
+
+// GetDrafts returns a page of the current user's drafts and scheduled posts
+// (protected)
+func GetDrafts(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := utils.GetUserIDFromSession(db, r)
+	if err != nil || userID == "" {
+		utils.SendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	page, limit := utils.GetPaginationParams(r)
+	if limit > 100 {
+		limit = 100
+	}
+	posts, err := sqlite.GetDrafts(db, userID, page, limit)
+	if err != nil {
+		utils.SendJSONError(w, "Failed to fetch drafts", http.StatusInternalServerError)
+		return
+	}
+	utils.SendJSONResponse(w, posts, http.StatusOK)
+}
+
+// PublishDraft publishes the draft or scheduled post in the path now, or
+// schedules it with {"publish_at": ...}. Only its author may do so
+// (protected).
+func PublishDraft(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := utils.GetUserIDFromSession(db, r)
+	if err != nil || userID == "" {
+		utils.SendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	postID, err := utils.ValidateID(r.PathValue("id"), "post ID")
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The body is optional: without publish_at the post goes out now
+	var request struct {
+		PublishAt string `json:"publish_at"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && !errors.Is(err, io.EOF) {
+		utils.SendJSONError(w, "Invalid request data", http.StatusBadRequest)
+		return
+	}
+
+	post, err := sqlite.GetPost(db, postID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && post.UserID != userID && post.Status != sqlite.PostPublished) {
+		utils.SendJSONError(w, "Post not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.SendJSONError(w, "Failed to fetch post", http.StatusInternalServerError)
+		return
+	}
+	if post.UserID != userID {
+		utils.SendJSONError(w, "Only the author can publish a post", http.StatusForbidden)
+		return
+	}
+
+	if request.PublishAt != "" {
+		publishAt, err := parsePublishAt(request.PublishAt)
+		if err != nil {
+			utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		err = sqlite.SchedulePost(db, postID, *publishAt)
+		if errors.Is(err, sqlite.ErrPostPublished) {
+			utils.SendJSONError(w, err.Error(), http.StatusConflict)
+			return
+		} else if err != nil {
+			utils.SendJSONError(w, "Failed to schedule post", http.StatusInternalServerError)
+			return
+		}
+		post.Status, post.PublishAt = sqlite.PostScheduled, publishAt
+		utils.SendJSONResponse(w, post, http.StatusOK)
+		return
+	}
+
+	post, err = sqlite.PublishPost(db, postID)
+	if errors.Is(err, sqlite.ErrPostPublished) {
+		utils.SendJSONError(w, err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		log.Printf("Error publishing post %d: %v", postID, err)
+		utils.SendJSONError(w, "Failed to publish post", http.StatusInternalServerError)
+		return
+	}
+	realtime.Publish(realtime.Event{Type: realtime.EventPostCreated, Data: post})
+	enqueueWebhook(db, webhooks.PostCreated, post)
+	utils.SendJSONResponse(w, post, http.StatusOK)
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"forum/models"
+	"forum/sqlite"
+)
+
+func TestDrafts(t *testing.T) {
+	db := setupSchemaTestDB(t)
+	defer db.Close()
+
+	_, aliceHeader := createTestUser(t, db, "alice")
+	_, bobHeader := createTestUser(t, db, "bob")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/posts/create", func(w http.ResponseWriter, r *http.Request) { CreatePost(db, w, r) })
+	mux.HandleFunc("/api/posts/drafts", func(w http.ResponseWriter, r *http.Request) { GetDrafts(db, w, r) })
+	mux.HandleFunc("/api/posts/{id}", func(w http.ResponseWriter, r *http.Request) { GetPost(db, w, r) })
+	mux.HandleFunc("/api/posts/{id}/publish", func(w http.ResponseWriter, r *http.Request) { PublishDraft(db, w, r) })
+
+	createPost := func(fields map[string]string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		form.WriteField("title", "Plans")
+		form.WriteField("content", "What we will do next quarter")
+		for name, value := range fields {
+			form.WriteField(name, value)
+		}
+		form.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/api/posts/create", &body)
+		req.Header = aliceHeader.Clone()
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+	decodePost := func(rr *httptest.ResponseRecorder, code int) models.Post {
+		t.Helper()
+		if rr.Code != code {
+			t.Fatalf("Expected %d, got %d: %s", code, rr.Code, rr.Body.String())
+		}
+		var post models.Post
+		json.NewDecoder(rr.Body).Decode(&post)
+		return post
+	}
+
+	t.Run("invalid status and times", func(t *testing.T) {
+		for _, fields := range []map[string]string{
+			{"status": "hidden"},
+			{"status": "scheduled"},
+			{"status": "draft", "publish_at": time.Now().Add(time.Hour).Format(time.RFC3339)},
+			{"publish_at": "tomorrow"},
+			{"publish_at": time.Now().Add(-time.Hour).Format(time.RFC3339)},
+		} {
+			if rr := createPost(fields); rr.Code != http.StatusBadRequest {
+				t.Errorf("Expected 400 for %v, got %d", fields, rr.Code)
+			}
+		}
+	})
+
+	draft := decodePost(createPost(map[string]string{"status": "draft"}), http.StatusCreated)
+	if draft.Status != sqlite.PostDraft {
+		t.Fatalf("Expected a draft, got %q", draft.Status)
+	}
+	scheduled := decodePost(createPost(map[string]string{"publish_at": time.Now().Add(time.Hour).Format(time.RFC3339)}), http.StatusCreated)
+	if scheduled.Status != sqlite.PostScheduled || scheduled.PublishAt == nil {
+		t.Fatalf("Expected a scheduled post, got %+v", scheduled)
+	}
+	draftPath := "/api/posts/" + strconv.Itoa(draft.ID)
+
+	t.Run("only the author sees drafts", func(t *testing.T) {
+		if rr := testRequest(mux, http.MethodGet, draftPath, bobHeader, ""); rr.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 for someone else's draft, got %d", rr.Code)
+		}
+		if rr := testRequest(mux, http.MethodGet, draftPath, http.Header{}, ""); rr.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 for a visitor, got %d", rr.Code)
+		}
+		decodePost(testRequest(mux, http.MethodGet, draftPath, aliceHeader, ""), http.StatusOK)
+
+		rr := testRequest(mux, http.MethodGet, "/api/posts/drafts", aliceHeader, "")
+		var drafts []models.Post
+		json.NewDecoder(rr.Body).Decode(&drafts)
+		if rr.Code != http.StatusOK || len(drafts) != 2 {
+			t.Errorf("Expected 2 drafts, got %d (%d)", len(drafts), rr.Code)
+		}
+		if rr := testRequest(mux, http.MethodGet, "/api/posts/drafts", http.Header{}, ""); rr.Code != http.StatusUnauthorized {
+			t.Errorf("Expected 401 when signed out, got %d", rr.Code)
+		}
+	})
+
+	t.Run("publish", func(t *testing.T) {
+		if rr := testRequest(mux, http.MethodPost, draftPath+"/publish", bobHeader, ""); rr.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 publishing someone else's draft, got %d", rr.Code)
+		}
+		if rr := testRequest(mux, http.MethodPost, draftPath+"/publish", aliceHeader, `{"publish_at":"soon"}`); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for a bad publish_at, got %d", rr.Code)
+		}
+
+		at := time.Now().Add(2 * time.Hour).UTC().Truncate(time.Second)
+		post := decodePost(testRequest(mux, http.MethodPost, draftPath+"/publish", aliceHeader, `{"publish_at":"`+at.Format(time.RFC3339)+`"}`), http.StatusOK)
+		if post.Status != sqlite.PostScheduled || post.PublishAt == nil || !post.PublishAt.Equal(at) {
+			t.Errorf("Expected the draft to be scheduled for %v, got %+v", at, post)
+		}
+
+		post = decodePost(testRequest(mux, http.MethodPost, draftPath+"/publish", aliceHeader, ""), http.StatusOK)
+		if post.Status != sqlite.PostPublished {
+			t.Errorf("Expected the post to be published, got %q", post.Status)
+		}
+		if rr := testRequest(mux, http.MethodPost, draftPath+"/publish", aliceHeader, ""); rr.Code != http.StatusConflict {
+			t.Errorf("Expected 409 publishing twice, got %d", rr.Code)
+		}
+		decodePost(testRequest(mux, http.MethodGet, draftPath, bobHeader, ""), http.StatusOK)
+	})
+}
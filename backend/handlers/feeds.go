@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"html"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"forum/feeds"
+	"forum/middleware"
+	"forum/sqlite"
+	"forum/utils"
+)
+
+// feedLength is how many of the newest posts a feed lists
+const feedLength = 20
+
+// feedFormats are the file names feeds are served as, with their writer and
+// content type
+var feedFormats = map[string]struct {
+	write       func(feeds.Feed) ([]byte, error)
+	contentType string
+}{
+	"posts.atom": {feeds.Atom, "application/atom+xml; charset=utf-8"},
+	"posts.rss":  {feeds.RSS, "application/rss+xml; charset=utf-8"},
+}
+
+// GetPostsFeed serves the newest posts as /feeds/posts.atom or
+// /feeds/posts.rss (public)
+func GetPostsFeed(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	serveFeed(db, w, r, feeds.Feed{
+		Title: "Forum: latest posts",
+		Link:  middleware.FrontendOrigin() + "/",
+	}, sqlite.PostFilter{})
+}
+
+// GetCategoryFeed serves the newest posts of the category named in the path
+// by its name or slug (public)
+func GetCategoryFeed(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	category, err := sqlite.GetCategoryByName(db, r.PathValue("category"))
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.SendJSONError(w, "Category not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.SendJSONError(w, "Failed to fetch category", http.StatusInternalServerError)
+		return
+	}
+	serveFeed(db, w, r, feeds.Feed{
+		Title:       "Forum: " + category.Name,
+		Description: category.Description,
+		Link:        middleware.FrontendOrigin() + "/category/" + strconv.Itoa(category.ID),
+	}, sqlite.PostFilter{Category: category.ID})
+}
+
+// GetUserFeed serves the newest posts of the user named in the path, unless
+// they keep their posts private (public)
+func GetUserFeed(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	profile, err := sqlite.GetProfileByUsername(db, r.PathValue("username"))
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.SendJSONError(w, "User not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.SendJSONError(w, "Failed to fetch profile", http.StatusInternalServerError)
+		return
+	}
+	settings, err := sqlite.GetPrivacySettings(db, profile.ID)
+	if err != nil {
+		utils.SendJSONError(w, "Failed to fetch privacy settings", http.StatusInternalServerError)
+		return
+	}
+	if !settings.ShowPosts {
+		utils.SendJSONError(w, "This user keeps this list private", http.StatusForbidden)
+		return
+	}
+	serveFeed(db, w, r, feeds.Feed{
+		Title:       "Forum: posts by " + profile.Username,
+		Description: profile.Bio,
+		Link:        middleware.FrontendOrigin() + "/users/" + url.PathEscape(profile.Username),
+	}, sqlite.PostFilter{AuthorID: profile.ID})
+}
+
+// serveFeed fills feed with the newest posts matching filter and writes it
+// in the format named by the file in the path. The ETag and Last-Modified
+// headers let feed readers poll with conditional requests and get 304 Not
+// Modified until something changes.
+func serveFeed(db *sql.DB, w http.ResponseWriter, r *http.Request, feed feeds.Feed, filter sqlite.PostFilter) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	format, ok := feedFormats[r.PathValue("file")]
+	if !ok {
+		utils.SendJSONError(w, "Feed not found; use posts.atom or posts.rss", http.StatusNotFound)
+		return
+	}
+
+	// Feeds are read anonymously, so they show what a visitor sees
+	posts, err := sqlite.GetPosts(db, "", filter, 1, feedLength)
+	if err != nil {
+		utils.SendJSONError(w, "Failed to fetch posts", http.StatusInternalServerError)
+		return
+	}
+
+	feed.Self = middleware.BackendOrigin() + r.URL.Path
+
+	var modified time.Time
+	for _, post := range posts {
+		updated := post.UpdatedAt
+		if post.CreatedAt.After(updated) {
+			updated = post.CreatedAt
+		}
+		if updated.After(modified) {
+			modified = updated
+		}
+		link := middleware.FrontendOrigin() + "/post/" + strconv.Itoa(post.ID)
+		feed.Entries = append(feed.Entries, feeds.Entry{
+			ID:         link,
+			Title:      html.UnescapeString(post.Title), // titles are stored HTML-escaped
+			Link:       link,
+			Author:     post.Username,
+			Content:    post.ContentHTML,
+			Categories: post.CategoryNames,
+			Published:  post.CreatedAt,
+			Updated:    updated,
+		})
+	}
+	// An empty feed is dated at the epoch rather than now, so that its body,
+	// and with it its ETag, stays the same between requests
+	feed.Updated = modified
+	if modified.IsZero() {
+		feed.Updated = time.Unix(0, 0)
+	}
+
+	body, err := format.write(feed)
+	if err != nil {
+		log.Printf("Error writing feed %s: %v", r.URL.Path, err)
+		utils.SendJSONError(w, "Failed to write feed", http.StatusInternalServerError)
+		return
+	}
+
+	// ServeContent answers If-None-Match and If-Modified-Since for us; an
+	// empty feed has no modification time to offer
+	sum := sha256.Sum256(body)
+	w.Header().Set("Content-Type", format.contentType)
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	http.ServeContent(w, r, "", modified, bytes.NewReader(body))
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"forum/models"
+	"forum/sqlite"
+)
+
+func TestFeeds(t *testing.T) {
+	db := setupSchemaTestDB(t)
+	defer db.Close()
+
+	aliceID, _ := createTestUser(t, db, "alice")
+	bobID, _ := createTestUser(t, db, "bob")
+	category, err := sqlite.CreateCategory(db, models.Category{Name: "Go Lang", Description: "All things Go"})
+	if err != nil {
+		t.Fatalf("CreateCategory failed: %v", err)
+	}
+	if _, err := sqlite.CreatePost(db, aliceID, []int{category.ID}, "Tom &amp; Jerry", "Cats **and** mice", ""); err != nil {
+		t.Fatalf("CreatePost failed: %v", err)
+	}
+	if _, err := sqlite.CreatePost(db, bobID, nil, "Off topic", "Something else", ""); err != nil {
+		t.Fatalf("CreatePost failed: %v", err)
+	}
+	if _, err := sqlite.CreateDraft(db, aliceID, []int{category.ID}, "Secret draft", "Not yet", "", nil); err != nil {
+		t.Fatalf("CreateDraft failed: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/feeds/{file}", func(w http.ResponseWriter, r *http.Request) { GetPostsFeed(db, w, r) })
+	mux.HandleFunc("/feeds/categories/{category}/{file}", func(w http.ResponseWriter, r *http.Request) { GetCategoryFeed(db, w, r) })
+	mux.HandleFunc("/feeds/users/{username}/{file}", func(w http.ResponseWriter, r *http.Request) { GetUserFeed(db, w, r) })
+
+	t.Run("site feeds", func(t *testing.T) {
+		rr := testRequest(mux, http.MethodGet, "/feeds/posts.atom", nil, "")
+		if rr.Code != http.StatusOK || !strings.HasPrefix(rr.Header().Get("Content-Type"), "application/atom+xml") {
+			t.Fatalf("Expected an Atom feed, got %d %q", rr.Code, rr.Header().Get("Content-Type"))
+		}
+		body := rr.Body.String()
+		if !strings.Contains(body, "<title>Tom &amp; Jerry</title>") || !strings.Contains(body, "Off topic") {
+			t.Errorf("Expected both posts with unescaped titles, got %s", body)
+		}
+		if !strings.Contains(body, "&lt;strong&gt;and&lt;/strong&gt;") {
+			t.Errorf("Expected rendered, escaped content, got %s", body)
+		}
+		if strings.Contains(body, "Secret draft") {
+			t.Error("Expected drafts to stay out of feeds")
+		}
+
+		rr = testRequest(mux, http.MethodGet, "/feeds/posts.rss", nil, "")
+		if rr.Code != http.StatusOK || !strings.HasPrefix(rr.Header().Get("Content-Type"), "application/rss+xml") {
+			t.Errorf("Expected an RSS feed, got %d %q", rr.Code, rr.Header().Get("Content-Type"))
+		}
+		if rr := testRequest(mux, http.MethodGet, "/feeds/posts.json", nil, ""); rr.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 for an unknown format, got %d", rr.Code)
+		}
+	})
+
+	t.Run("conditional requests", func(t *testing.T) {
+		rr := testRequest(mux, http.MethodGet, "/feeds/posts.atom", nil, "")
+		etag, lastModified := rr.Header().Get("ETag"), rr.Header().Get("Last-Modified")
+		if etag == "" || lastModified == "" {
+			t.Fatalf("Expected ETag and Last-Modified, got %q and %q", etag, lastModified)
+		}
+		if rr := testRequest(mux, http.MethodGet, "/feeds/posts.atom", http.Header{"If-None-Match": {etag}}, ""); rr.Code != http.StatusNotModified {
+			t.Errorf("Expected 304 for a matching ETag, got %d", rr.Code)
+		}
+		if rr := testRequest(mux, http.MethodGet, "/feeds/posts.atom", http.Header{"If-Modified-Since": {lastModified}}, ""); rr.Code != http.StatusNotModified {
+			t.Errorf("Expected 304 when not modified since, got %d", rr.Code)
+		}
+		if rr := testRequest(mux, http.MethodGet, "/feeds/posts.atom", http.Header{"If-None-Match": {`"stale"`}}, ""); rr.Code != http.StatusOK {
+			t.Errorf("Expected 200 for a stale ETag, got %d", rr.Code)
+		}
+	})
+
+	t.Run("category feeds", func(t *testing.T) {
+		for _, name := range []string{"go-lang", "Go%20Lang"} {
+			rr := testRequest(mux, http.MethodGet, "/feeds/categories/"+name+"/posts.atom", nil, "")
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected 200 for %s, got %d", name, rr.Code)
+			}
+			if body := rr.Body.String(); !strings.Contains(body, "Tom &amp; Jerry") || strings.Contains(body, "Off topic") {
+				t.Errorf("Expected only the category's post for %s, got %s", name, body)
+			}
+			if body := rr.Body.String(); !strings.Contains(body, `<category term="Go Lang">`) {
+				t.Errorf("Expected entries to list their categories for %s, got %s", name, body)
+			}
+		}
+		if rr := testRequest(mux, http.MethodGet, "/feeds/categories/nope/posts.atom", nil, ""); rr.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 for an unknown category, got %d", rr.Code)
+		}
+	})
+
+	t.Run("user feeds", func(t *testing.T) {
+		rr := testRequest(mux, http.MethodGet, "/feeds/users/bob/posts.rss", nil, "")
+		if body := rr.Body.String(); rr.Code != http.StatusOK || !strings.Contains(body, "Off topic") || strings.Contains(body, "Jerry") {
+			t.Errorf("Expected only bob's post, got %d %s", rr.Code, body)
+		}
+		if body := rr.Body.String(); !strings.Contains(body, "/users/bob</link>") {
+			t.Errorf("Expected the feed to link to bob's profile, got %s", body)
+		}
+		if err := sqlite.UpdatePrivacySettings(db, bobID, models.PrivacySettings{ShowOnlineStatus: true}); err != nil {
+			t.Fatalf("UpdatePrivacySettings failed: %v", err)
+		}
+		if rr := testRequest(mux, http.MethodGet, "/feeds/users/bob/posts.rss", nil, ""); rr.Code != http.StatusForbidden {
+			t.Errorf("Expected 403 for private posts, got %d", rr.Code)
+		}
+		if rr := testRequest(mux, http.MethodGet, "/feeds/users/nobody/posts.rss", nil, ""); rr.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 for an unknown user, got %d", rr.Code)
+		}
+	})
+
+	t.Run("empty feeds and self links", func(t *testing.T) {
+		t.Setenv("BACKEND_ORIGIN", "https://api.forum.example")
+		if _, err := sqlite.CreateCategory(db, models.Category{Name: "Empty"}); err != nil {
+			t.Fatalf("CreateCategory failed: %v", err)
+		}
+
+		header := http.Header{"X-Forwarded-Proto": {"http"}}
+		first := testRequest(mux, http.MethodGet, "/feeds/categories/empty/posts.atom", header, "")
+		if first.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", first.Code)
+		}
+		body := first.Body.String()
+		if !strings.Contains(body, "<updated>1970-01-01T00:00:00Z</updated>") {
+			t.Errorf("Expected an empty feed to be dated at the epoch, got %s", body)
+		}
+		if !strings.Contains(body, `href="https://api.forum.example/feeds/categories/empty/posts.atom"`) || strings.Contains(body, "example.com") {
+			t.Errorf("Expected the self link to use BACKEND_ORIGIN, got %s", body)
+		}
+		if second := testRequest(mux, http.MethodGet, "/feeds/categories/empty/posts.atom", header, ""); second.Header().Get("ETag") != first.Header().Get("ETag") {
+			t.Errorf("Expected the ETag of an empty feed to stay the same, got %q and %q", first.Header().Get("ETag"), second.Header().Get("ETag"))
+		}
+	})
+}
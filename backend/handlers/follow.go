@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"forum/models"
+	"forum/sqlite"
+	"forum/utils"
+)
+
+// FollowUser follows (POST) or unfollows (DELETE) the user named in the path
+func FollowUser(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := utils.GetUserIDFromSession(db, r)
+	if err != nil || userID == "" {
+		utils.SendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	followee, err := sqlite.GetUserByUsername(db, r.PathValue("username"))
+	if err != nil {
+		utils.SendJSONError(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if followee.ID == userID {
+		utils.SendJSONError(w, "You cannot follow yourself", http.StatusBadRequest)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		blocked, err := sqlite.IsBlocked(db, userID, followee.ID)
+		if err != nil {
+			utils.SendJSONError(w, "Failed to follow user", http.StatusInternalServerError)
+			return
+		}
+		if blocked {
+			utils.SendJSONError(w, "You cannot follow this user", http.StatusForbidden)
+			return
+		}
+		err = sqlite.FollowUser(db, userID, followee.ID)
+	} else {
+		err = sqlite.UnfollowUser(db, userID, followee.ID)
+	}
+	if err != nil {
+		utils.SendJSONError(w, "Failed to update follow", http.StatusInternalServerError)
+		return
+	}
+
+	profile, err := sqlite.GetProfileByID(db, followee.ID)
+	if err != nil {
+		utils.SendJSONError(w, "Failed to fetch profile", http.StatusInternalServerError)
+		return
+	}
+	utils.SendJSONResponse(w, map[string]any{
+		"following":      r.Method == http.MethodPost,
+		"follower_count": profile.FollowerCount,
+	}, http.StatusOK)
+}
+
+// GetProfileFollowers lists the users following the user named in the path
+func GetProfileFollowers(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	listFollows(db, w, r, sqlite.GetFollowers)
+}
+
+// GetProfileFollowing lists the users the user named in the path follows
+func GetProfileFollowing(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	listFollows(db, w, r, sqlite.GetFollowing)
+}
+
+func listFollows(db *sql.DB, w http.ResponseWriter, r *http.Request, list func(*sql.DB, string, int, int) ([]models.UserSummary, error)) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := sqlite.GetUserByUsername(db, r.PathValue("username"))
+	if err != nil {
+		utils.SendJSONError(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	page, limit := utils.GetPaginationParams(r)
+	if limit > 100 {
+		limit = 100
+	}
+	users, err := list(db, user.ID, page, limit)
+	if err != nil {
+		utils.SendJSONError(w, "Failed to fetch follows", http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendJSONResponse(w, map[string]any{"users": users, "page": page, "limit": limit}, http.StatusOK)
+}
+
+// FollowCategory follows (POST) or unfollows (DELETE) the category given by
+// ?category_id=
+func FollowCategory(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := utils.GetUserIDFromSession(db, r)
+	if err != nil || userID == "" {
+		utils.SendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	categoryID, err := utils.ValidateID(r.URL.Query().Get("category_id"), "category_id")
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		err = sqlite.FollowCategory(db, userID, categoryID)
+	} else {
+		err = sqlite.UnfollowCategory(db, userID, categoryID)
+	}
+	if err == sql.ErrNoRows {
+		utils.SendJSONError(w, "Category not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.SendJSONError(w, "Failed to update follow", http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendJSONResponse(w, map[string]any{"following": r.Method == http.MethodPost}, http.StatusOK)
+}
+
+// GetFollowedCategories lists the categories the current user follows
+func GetFollowedCategories(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := utils.GetUserIDFromSession(db, r)
+	if err != nil || userID == "" {
+		utils.SendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	categories, err := sqlite.GetFollowedCategories(db, userID)
+	if err != nil {
+		utils.SendJSONError(w, "Failed to fetch categories", http.StatusInternalServerError)
+		return
+	}
+	utils.SendJSONResponse(w, categories, http.StatusOK)
+}
+
+// GetFeed returns the current user's home feed: posts by followed users and
+// in followed categories, newest first. Pass the returned next_cursor as
+// cursor to load the next page; it is empty on the last page.
+func GetFeed(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := utils.GetUserIDFromSession(db, r)
+	if err != nil || userID == "" {
+		utils.SendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	cursor, err := parseFeedCursor(r.URL.Query().Get("cursor"))
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	_, limit := utils.GetPaginationParams(r)
+	if limit > 100 {
+		limit = 100
+	}
+
+	posts, err := sqlite.GetFeed(db, userID, cursor, limit)
+	if err != nil {
+		utils.SendJSONError(w, "Failed to fetch feed", http.StatusInternalServerError)
+		return
+	}
+	avatars := make(map[string]string)
+	for i := range posts {
+		avatar, ok := avatars[posts[i].UserID]
+		if !ok {
+			if author, err := sqlite.GetUserByID(db, posts[i].UserID); err == nil {
+				avatar = author.AvatarURL
+			}
+			avatars[posts[i].UserID] = avatar
+		}
+		posts[i].ProfileAvatar = avatar
+	}
+	markBookmarked(db, r, posts)
+	markUnread(db, r, posts)
+
+	nextCursor := ""
+	if len(posts) == limit {
+		last := posts[len(posts)-1]
+		nextCursor = fmt.Sprintf("%d-%d", last.CreatedAt.Unix(), last.ID)
+	}
+	utils.SendJSONResponse(w, map[string]any{
+		"posts":       posts,
+		"next_cursor": nextCursor,
+	}, http.StatusOK)
+}
+
+// parseFeedCursor reads a "<unix seconds>-<post id>" cursor; an empty cursor
+// starts at the newest post
+func parseFeedCursor(value string) (sqlite.FeedCursor, error) {
+	if value == "" {
+		return sqlite.FeedCursor{}, nil
+	}
+	seconds, id, ok := strings.Cut(value, "-")
+	unix, err1 := strconv.ParseInt(seconds, 10, 64)
+	postID, err2 := strconv.Atoi(id)
+	if !ok || err1 != nil || err2 != nil || postID <= 0 {
+		return sqlite.FeedCursor{}, fmt.Errorf("invalid cursor")
+	}
+	return sqlite.FeedCursor{CreatedAt: time.Unix(unix, 0), ID: postID}, nil
+}
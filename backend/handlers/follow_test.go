@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"forum/sqlite"
+)
+
+func TestFollowAndFeed(t *testing.T) {
+	db := setupSchemaTestDB(t)
+	defer db.Close()
+
+	_, aliceHeader := createTestUser(t, db, "alice")
+	bob, _ := createTestUser(t, db, "bob")
+	for _, title := range []string{"First", "Second"} {
+		if _, err := sqlite.CreatePost(db, bob, nil, title, "Body", ""); err != nil {
+			t.Fatalf("Failed to create post: %v", err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/users/{username}/follow", func(w http.ResponseWriter, r *http.Request) { FollowUser(db, w, r) })
+	mux.HandleFunc("/api/feed", func(w http.ResponseWriter, r *http.Request) { GetFeed(db, w, r) })
+
+	if rr := testRequest(mux, http.MethodPost, "/api/users/alice/follow", aliceHeader, ""); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected following yourself to fail, got %d", rr.Code)
+	}
+	if rr := testRequest(mux, http.MethodPost, "/api/users/bob/follow", aliceHeader, ""); rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var page struct {
+		Posts []struct {
+			Title string `json:"title"`
+		} `json:"posts"`
+		NextCursor string `json:"next_cursor"`
+	}
+	rr := testRequest(mux, http.MethodGet, "/api/feed?limit=1", aliceHeader, "")
+	json.NewDecoder(rr.Body).Decode(&page)
+	if rr.Code != http.StatusOK || len(page.Posts) != 1 || page.Posts[0].Title != "Second" || page.NextCursor == "" {
+		t.Fatalf("Unexpected first page %d: %+v", rr.Code, page)
+	}
+
+	rr = testRequest(mux, http.MethodGet, "/api/feed?limit=1&cursor="+page.NextCursor, aliceHeader, "")
+	page.Posts = nil
+	json.NewDecoder(rr.Body).Decode(&page)
+	if len(page.Posts) != 1 || page.Posts[0].Title != "First" {
+		t.Errorf("Unexpected second page: %+v", page)
+	}
+
+	if rr := testRequest(mux, http.MethodGet, "/api/feed?cursor=bogus", aliceHeader, ""); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected an invalid cursor to be rejected, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"forum/sqlite"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// setupSchemaTestDB creates an in-memory database from the real schema.sql
+func setupSchemaTestDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	db.SetMaxOpenConns(1) // each :memory: connection is its own database
+
+	if _, err := db.Exec("PRAGMA foreign_keys = ON"); err != nil {
+		t.Fatalf("Failed to enable foreign keys: %v", err)
+	}
+	schemaSQL, err := os.ReadFile("../schema.sql")
+	if err != nil {
+		t.Fatalf("Failed to read schema.sql: %v", err)
+	}
+	if _, err := db.Exec(string(schemaSQL)); err != nil {
+		t.Fatalf("Failed to apply schema.sql: %v", err)
+	}
+	return db
+}
+
+// createTestUser creates a user with a session and returns its ID and
+// cookie header
+func createTestUser(t *testing.T, db *sql.DB, username string) (string, http.Header) {
+	if err := sqlite.CreateUser(db, username, username+"@example.com", "password", ""); err != nil {
+		t.Fatalf("Failed to create user %s: %v", username, err)
+	}
+	user, err := sqlite.GetUserByUsername(db, username)
+	if err != nil {
+		t.Fatalf("Failed to get user %s: %v", username, err)
+	}
+	sessionID, err := sqlite.CreateSession(db, user.ID)
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	header := http.Header{}
+	header.Set("Cookie", "session_id="+sessionID)
+	return user.ID, header
+}
+
+// testRequest sends a request with the given headers and body through
+// handler and returns the recorded response
+func testRequest(handler http.Handler, method, path string, header http.Header, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	for key, values := range header.Clone() {
+		req.Header[key] = values
+	}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
@@ -3,14 +3,19 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 
+	"forum/models"
+	"forum/realtime"
 	"forum/sqlite"
 	"forum/utils"
+	"forum/webhooks"
 )
 
-// ToggleLike handles liking/disliking a post or comment
+// ToggleLike adds or removes the current user's reaction on a post or comment
 func ToggleLike(db *sql.DB, w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -21,7 +26,7 @@ func ToggleLike(db *sql.DB, w http.ResponseWriter, r *http.Request) {
 	var request struct {
 		PostID    *int   `json:"post_id,omitempty"`
 		CommentID *int   `json:"comment_id,omitempty"`
-		Type      string `json:"type"` // like or dislike
+		Type      string `json:"type"` // a reaction type name, e.g. like or dislike
 	}
 
 	// Decode request body
@@ -43,9 +48,9 @@ func ToggleLike(db *sql.DB, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Validate type
-	if request.Type != "like" && request.Type != "dislike" {
-		http.Error(w, "Invalid type. Must be 'like' or 'dislike'", http.StatusBadRequest)
+	// Validate type; the database knows which reaction types exist
+	if request.Type == "" {
+		http.Error(w, "Missing reaction type", http.StatusBadRequest)
 		return
 	}
 
@@ -60,60 +65,206 @@ func ToggleLike(db *sql.DB, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Call the updated toggle function with type
-	err := sqlite.ToggleLike(db, userID, request.PostID, request.CommentID, request.Type)
+	// Blocked users cannot react to each other's content
+	var authorID string
+	var err error
+	if request.PostID != nil {
+		authorID, err = sqlite.GetPostAuthorID(db, *request.PostID)
+	} else {
+		authorID, err = sqlite.GetCommentAuthorID(db, *request.CommentID)
+	}
 	if err != nil {
+		utils.SendJSONError(w, "Post or comment not found", http.StatusNotFound)
+		return
+	}
+	if !canInteract(db, w, userID, authorID) {
+		return
+	}
+
+	// Call the updated toggle function with type
+	active, err := sqlite.ToggleLike(db, userID, request.PostID, request.CommentID, request.Type)
+	if errors.Is(err, sqlite.ErrUnknownReaction) {
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err != nil {
 		utils.SendJSONError(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
 		return
 	}
 
+	publishReaction(db, userID, request.Type, active, request.PostID, request.CommentID)
+
 	utils.SendJSONResponse(w, map[string]string{"message": "Reaction toggled successfully"}, http.StatusOK)
 }
 
-// GetReactions returns the total number of likes and dislikes for a post or comment
+// publishReaction sends the new reaction totals to live streams and the
+// toggle itself to webhooks. Post counts are public; comment counts go to
+// streams watching the post. Webhooks get the toggle even when the totals or
+// the post cannot be looked up.
+func publishReaction(db *sql.DB, userID, reaction string, active bool, postID, commentID *int) {
+	data := map[string]any{}
+	event := realtime.Event{Type: realtime.EventReactionUpdated, Data: data}
+	if postID != nil {
+		data["post_id"] = *postID
+	} else {
+		data["comment_id"] = *commentID
+		var err error
+		if event.PostID, err = sqlite.GetCommentPostID(db, *commentID); err != nil {
+			log.Printf("Warning: Failed to find post for comment %d: %v", *commentID, err)
+		} else {
+			data["post_id"] = event.PostID
+		}
+	}
+
+	summary, err := sqlite.GetReactionSummary(db, postID, commentID, userID)
+	if err != nil {
+		log.Printf("Warning: Failed to count reactions for stream: %v", err)
+	} else {
+		data["likes"], data["dislikes"], data["counts"] = summary.Likes, summary.Dislikes, summary.Counts
+		if _, ok := data["post_id"]; ok {
+			realtime.Publish(event)
+		}
+	}
+
+	toggled := map[string]any{"user_id": userID, "type": reaction, "active": active}
+	for key, value := range data {
+		toggled[key] = value
+	}
+	enqueueWebhook(db, webhooks.ReactionToggled, toggled)
+}
+
+// GetReactions returns the reaction counts for a post or comment, with the
+// current user's own reactions (public)
 func GetReactions(db *sql.DB, w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	query := r.URL.Query()
-	postIDStr := query.Get("post_id")
-	commentIDStr := query.Get("comment_id")
+	postID, commentID, ok := reactionTargetParams(w, r)
+	if !ok {
+		return
+	}
+
+	viewerID, _ := utils.GetUserIDFromSession(db, r)
+	summary, err := sqlite.GetReactionSummary(db, postID, commentID, viewerID)
+	if err != nil {
+		utils.SendJSONError(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendJSONResponse(w, summary, http.StatusOK)
+}
 
-	var (
-		postID    *int
-		commentID *int
-		err       error
-	)
+// GetReactors returns a page of the users who left the ?type= reaction on a
+// post or comment (public)
+func GetReactors(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
 
-	if postIDStr != "" {
+	postID, commentID, ok := reactionTargetParams(w, r)
+	if !ok {
+		return
+	}
+	reactionType := r.URL.Query().Get("type")
+	if reactionType == "" {
+		http.Error(w, "Missing reaction type", http.StatusBadRequest)
+		return
+	}
+
+	page, limit := utils.GetPaginationParams(r)
+	if limit > 100 {
+		limit = 100
+	}
+	users, err := sqlite.GetReactors(db, postID, commentID, reactionType, page, limit)
+	if err != nil {
+		utils.SendJSONError(w, "Failed to fetch reactions", http.StatusInternalServerError)
+		return
+	}
+	utils.SendJSONResponse(w, users, http.StatusOK)
+}
+
+// reactionTargetParams reads the post_id or comment_id query parameter. It
+// writes the error response and returns false if neither is valid.
+func reactionTargetParams(w http.ResponseWriter, r *http.Request) (postID, commentID *int, ok bool) {
+	query := r.URL.Query()
+	if postIDStr := query.Get("post_id"); postIDStr != "" {
 		id, err := utils.ValidateID(postIDStr, "post_id")
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
+			return nil, nil, false
 		}
-		postID = &id
-	} else if commentIDStr != "" {
+		return &id, nil, true
+	}
+	if commentIDStr := query.Get("comment_id"); commentIDStr != "" {
 		id, err := utils.ValidateID(commentIDStr, "comment_id")
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
+			return nil, nil, false
 		}
-		commentID = &id
-	} else {
-		http.Error(w, "Must provide post_id or comment_id", http.StatusBadRequest)
+		return nil, &id, true
+	}
+	http.Error(w, "Must provide post_id or comment_id", http.StatusBadRequest)
+	return nil, nil, false
+}
+
+// GetReactionTypes returns the enabled reaction types in display order (public)
+func GetReactionTypes(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	likes, dislikes, err := sqlite.CountLikesAndDislikes(db, postID, commentID)
+	types, err := sqlite.GetReactionTypes(db, false)
 	if err != nil {
-		utils.SendJSONError(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		utils.SendJSONError(w, "Failed to fetch reaction types", http.StatusInternalServerError)
 		return
 	}
+	utils.SendJSONResponse(w, types, http.StatusOK)
+}
 
-	utils.SendJSONResponse(w, map[string]int{
-		"likes":    likes,
-		"dislikes": dislikes,
-	}, http.StatusOK)
+// AdminReactionTypes lists every reaction type, disabled ones included (GET),
+// or creates or updates one (POST) (admin only)
+func AdminReactionTypes(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		types, err := sqlite.GetReactionTypes(db, true)
+		if err != nil {
+			utils.SendJSONError(w, "Failed to fetch reaction types", http.StatusInternalServerError)
+			return
+		}
+		utils.SendJSONResponse(w, types, http.StatusOK)
+
+	case http.MethodPost:
+		var request models.ReactionType
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request data", http.StatusBadRequest)
+			return
+		}
+		emoji, err := utils.ValidateAndSanitizeString(request.Emoji, 16, "emoji")
+		if err != nil {
+			utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		label, err := utils.ValidateAndSanitizeString(request.Label, 30, "label")
+		if err != nil {
+			utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		request.Emoji, request.Label = emoji, label
+
+		saved, err := sqlite.SaveReactionType(db, request)
+		if errors.Is(err, sqlite.ErrInvalidReaction) || errors.Is(err, sqlite.ErrBuiltInReaction) {
+			utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		} else if err != nil {
+			utils.SendJSONError(w, "Failed to save reaction type", http.StatusInternalServerError)
+			return
+		}
+		utils.SendJSONResponse(w, saved, http.StatusOK)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
 }
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"forum/middleware"
+	"forum/models"
+	"forum/sqlite"
+)
+
+func TestReactions(t *testing.T) {
+	db := setupSchemaTestDB(t)
+	defer db.Close()
+
+	authorID, _ := createTestUser(t, db, "author")
+	_, adminHeader := createTestUser(t, db, "admin")
+	_, bobHeader := createTestUser(t, db, "bob")
+	if err := sqlite.SetUserRole(db, "admin", "admin"); err != nil {
+		t.Fatalf("SetUserRole failed: %v", err)
+	}
+	post, err := sqlite.CreatePost(db, authorID, nil, "Hello", "World", "")
+	if err != nil {
+		t.Fatalf("CreatePost failed: %v", err)
+	}
+	postID := strconv.Itoa(post.ID)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/likes/toggle", func(w http.ResponseWriter, r *http.Request) { ToggleLike(db, w, r) })
+	mux.HandleFunc("/api/likes/reactions", func(w http.ResponseWriter, r *http.Request) { GetReactions(db, w, r) })
+	mux.HandleFunc("/api/likes/reactors", func(w http.ResponseWriter, r *http.Request) { GetReactors(db, w, r) })
+	mux.HandleFunc("/api/reactions", func(w http.ResponseWriter, r *http.Request) { GetReactionTypes(db, w, r) })
+	mux.Handle("/api/admin/reactions", middleware.AdminMiddleware(db, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { AdminReactionTypes(db, w, r) })))
+
+	t.Run("toggle and count", func(t *testing.T) {
+		for _, reaction := range []string{"like", "celebrate"} {
+			rr := testRequest(mux, http.MethodPost, "/api/likes/toggle", bobHeader, `{"post_id":`+postID+`,"type":"`+reaction+`"}`)
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected 200 for %s, got %d: %s", reaction, rr.Code, rr.Body.String())
+			}
+		}
+		if rr := testRequest(mux, http.MethodPost, "/api/likes/toggle", bobHeader, `{"post_id":`+postID+`,"type":"shrug"}`); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for an unknown type, got %d", rr.Code)
+		}
+
+		rr := testRequest(mux, http.MethodGet, "/api/likes/reactions?post_id="+postID, bobHeader, "")
+		var summary models.ReactionSummary
+		json.NewDecoder(rr.Body).Decode(&summary)
+		if summary.Likes != 1 || summary.Dislikes != 0 || summary.Counts["celebrate"] != 1 || len(summary.ViewerReactions) != 2 {
+			t.Errorf("Unexpected summary: %+v", summary)
+		}
+	})
+
+	t.Run("who reacted", func(t *testing.T) {
+		rr := testRequest(mux, http.MethodGet, "/api/likes/reactors?post_id="+postID+"&type=celebrate", http.Header{}, "")
+		var users []models.UserSummary
+		json.NewDecoder(rr.Body).Decode(&users)
+		if rr.Code != http.StatusOK || len(users) != 1 || users[0].Username != "bob" {
+			t.Errorf("Unexpected reactors (%d): %+v", rr.Code, users)
+		}
+		if rr := testRequest(mux, http.MethodGet, "/api/likes/reactors?post_id="+postID, http.Header{}, ""); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 without a type, got %d", rr.Code)
+		}
+	})
+
+	t.Run("manage types", func(t *testing.T) {
+		body := `{"name":"rocket","emoji":"🚀","label":"Rocket","sort_order":9,"enabled":true}`
+		if rr := testRequest(mux, http.MethodPost, "/api/admin/reactions", bobHeader, body); rr.Code != http.StatusForbidden {
+			t.Errorf("Expected 403 for a regular user, got %d", rr.Code)
+		}
+		if rr := testRequest(mux, http.MethodPost, "/api/admin/reactions", adminHeader, body); rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		if rr := testRequest(mux, http.MethodPost, "/api/admin/reactions", adminHeader, `{"name":"dislike","emoji":"👎","label":"Dislike","enabled":false}`); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 when disabling a built-in type, got %d", rr.Code)
+		}
+
+		rr := testRequest(mux, http.MethodGet, "/api/reactions", http.Header{}, "")
+		var types []models.ReactionType
+		json.NewDecoder(rr.Body).Decode(&types)
+		if len(types) != 7 || types[len(types)-1].Name != "rocket" {
+			t.Errorf("Unexpected reaction types: %+v", types)
+		}
+	})
+}
@@ -0,0 +1,341 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"forum/middleware"
+	"forum/models"
+	"forum/realtime"
+	"forum/sqlite"
+	"forum/utils"
+	"forum/websocket"
+)
+
+const (
+	chatPingInterval   = 30 * time.Second
+	chatIdleTimeout    = 2 * chatPingInterval
+	maxMessageLength   = 2000
+	maxChatCommandSize = 16 << 10
+)
+
+// chatCommand is a message sent by the client over the chat WebSocket:
+//
+//	{"type": "message", "recipient_id": "...", "content": "hi", "client_id": "c1"}
+//	{"type": "message", "conversation_id": 3, "content": "hi"}
+//	{"type": "typing", "conversation_id": 3}
+//	{"type": "read", "conversation_id": 3, "message_id": 42}
+type chatCommand struct {
+	Type           string `json:"type"`
+	ConversationID int    `json:"conversation_id"`
+	RecipientID    string `json:"recipient_id"`
+	Content        string `json:"content"`
+	MessageID      int    `json:"message_id"`
+	ClientID       string `json:"client_id,omitempty"` // echoed back with the result
+}
+
+// chatError is sent to the client when a command fails
+type chatError struct {
+	Type     string `json:"type"`
+	Error    string `json:"error"`
+	ClientID string `json:"client_id,omitempty"`
+}
+
+// errChat is a command failure that is safe to show to the user
+type errChat string
+
+func (e errChat) Error() string { return string(e) }
+
+// Chat upgrades to a WebSocket for direct messages, authenticated by the
+// session cookie. Every open connection of a user receives their private
+// events (messages, typing indicators, read receipts and notifications)
+// through the realtime hub, so all of their tabs and devices stay in sync.
+func Chat(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromSession(db, r)
+	if err != nil || userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	// Browsers send cookies on cross-site WebSocket handshakes
+	if !middleware.AllowedOrigin(r.Header.Get("Origin")) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	conn, err := websocket.Upgrade(w, r)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	conn.SetReadLimit(maxChatCommandSize)
+	conn.SetIdleTimeout(chatIdleTimeout)
+
+	sub := realtime.Default.Subscribe(userID, nil)
+	defer realtime.Default.Unsubscribe(sub)
+	if err := conn.WriteJSON(map[string]string{"type": "ready", "user_id": userID}); err != nil {
+		return
+	}
+
+	readDone := make(chan struct{})
+	go func() {
+		defer close(readDone)
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+
+			var cmd chatCommand
+			if err := json.Unmarshal(data, &cmd); err != nil {
+				conn.WriteJSON(chatError{Type: "error", Error: "Invalid command"})
+				continue
+			}
+			if err := handleChatCommand(db, userID, cmd); err != nil {
+				message := "Failed to process command"
+				var chatErr errChat
+				if errors.As(err, &chatErr) {
+					message = chatErr.Error()
+				} else {
+					log.Printf("Chat command %q failed: %v", cmd.Type, err)
+				}
+				conn.WriteJSON(chatError{Type: "error", Error: message, ClientID: cmd.ClientID})
+			}
+		}
+	}()
+
+	ping := time.NewTicker(chatPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-readDone:
+			return
+
+		case e, open := <-sub.Events():
+			if !open {
+				conn.CloseWithStatus(websocket.CloseGoingAway, "")
+				return
+			}
+			// Public activity is left to the event stream
+			if e.UserID == "" {
+				continue
+			}
+			if err := conn.WriteJSON(e); err != nil {
+				return
+			}
+
+		case <-ping.C:
+			// Close the socket once the session behind it is gone (logout)
+			if current, err := utils.GetUserIDFromSession(db, r); err != nil || current != userID {
+				conn.CloseWithStatus(websocket.ClosePolicyViolation, "Session expired")
+				return
+			}
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// handleChatCommand carries out one client command and publishes the result
+// to the participants
+func handleChatCommand(db *sql.DB, userID string, cmd chatCommand) error {
+	switch cmd.Type {
+	case "message":
+		content, err := utils.ValidateContent(cmd.Content, maxMessageLength, "message")
+		if err != nil {
+			return errChat(err.Error())
+		}
+		message, peerID, err := sendDirectMessage(db, userID, cmd.ConversationID, cmd.RecipientID, content)
+		if err != nil {
+			return err
+		}
+		// The sender's other sessions get it too; client_id lets the
+		// sending tab match it to what it displayed optimistically
+		data := map[string]any{"message": message, "client_id": cmd.ClientID}
+		realtime.Publish(realtime.Event{Type: realtime.EventMessageCreated, UserID: peerID, Data: data})
+		realtime.Publish(realtime.Event{Type: realtime.EventMessageCreated, UserID: userID, Data: data})
+		return nil
+
+	case "typing":
+		peerID, err := conversationPeer(db, cmd.ConversationID, userID)
+		if err != nil {
+			return err
+		}
+		if err := checkNotBlocked(db, userID, peerID); err != nil {
+			return err
+		}
+		realtime.Publish(realtime.Event{
+			Type:   realtime.EventMessageTyping,
+			UserID: peerID,
+			Data:   map[string]any{"conversation_id": cmd.ConversationID, "user_id": userID},
+		})
+		return nil
+
+	case "read":
+		peerID, err := conversationPeer(db, cmd.ConversationID, userID)
+		if err != nil {
+			return err
+		}
+		lastRead, err := sqlite.MarkConversationRead(db, cmd.ConversationID, userID, cmd.MessageID)
+		if err != nil {
+			return err
+		}
+		data := map[string]any{"conversation_id": cmd.ConversationID, "user_id": userID, "message_id": lastRead}
+		realtime.Publish(realtime.Event{Type: realtime.EventMessageRead, UserID: peerID, Data: data})
+		realtime.Publish(realtime.Event{Type: realtime.EventMessageRead, UserID: userID, Data: data})
+		return nil
+	}
+
+	return errChat("Unknown command type")
+}
+
+// sendDirectMessage stores a message addressed either to an existing
+// conversation or to a recipient, starting a conversation on first contact.
+// It returns the message and the recipient's ID.
+func sendDirectMessage(db *sql.DB, senderID string, conversationID int, recipientID, content string) (models.Message, string, error) {
+	var err error
+	switch {
+	case conversationID != 0:
+		recipientID, err = conversationPeer(db, conversationID, senderID)
+		if err != nil {
+			return models.Message{}, "", err
+		}
+	case recipientID != "":
+		if recipientID == senderID {
+			return models.Message{}, "", errChat("You cannot message yourself")
+		}
+		if _, err := sqlite.GetUserByID(db, recipientID); err != nil {
+			return models.Message{}, "", errChat("Recipient not found")
+		}
+	default:
+		return models.Message{}, "", errChat("Missing conversation_id or recipient_id")
+	}
+
+	if err := checkNotBlocked(db, senderID, recipientID); err != nil {
+		return models.Message{}, "", err
+	}
+
+	if conversationID == 0 {
+		conversationID, err = sqlite.GetOrCreateConversation(db, senderID, recipientID)
+		if err != nil {
+			return models.Message{}, "", err
+		}
+	}
+
+	message, err := sqlite.CreateMessage(db, conversationID, senderID, content)
+	return message, recipientID, err
+}
+
+// conversationPeer returns the other participant, or an error the client
+// can show when userID is not part of the conversation
+func conversationPeer(db *sql.DB, conversationID int, userID string) (string, error) {
+	peerID, err := sqlite.GetConversationPeer(db, conversationID, userID)
+	if err == sql.ErrNoRows {
+		return "", errChat("Conversation not found")
+	}
+	return peerID, err
+}
+
+// checkNotBlocked fails when either user has blocked the other
+func checkNotBlocked(db *sql.DB, userID, otherID string) error {
+	blocked, err := sqlite.IsBlocked(db, userID, otherID)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return errChat("You cannot message this user")
+	}
+	return nil
+}
+
+// GetConversations lists the current user's conversations, most recently
+// active first
+func GetConversations(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := utils.GetUserIDFromSession(db, r)
+	if err != nil || userID == "" {
+		utils.SendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	page, limit := utils.GetPaginationParams(r)
+	conversations, err := sqlite.GetConversations(db, userID, page, limit)
+	if err != nil {
+		utils.SendJSONError(w, "Failed to fetch conversations", http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendJSONResponse(w, map[string]any{
+		"conversations": conversations,
+		"page":          page,
+		"limit":         limit,
+	}, http.StatusOK)
+}
+
+// GetMessages returns a conversation's history, newest first. Pass the
+// returned next_before as before to load older messages.
+func GetMessages(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := utils.GetUserIDFromSession(db, r)
+	if err != nil || userID == "" {
+		utils.SendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	conversationID, err := utils.ValidateID(query.Get("conversation_id"), "conversation_id")
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	beforeID := 0
+	if before := query.Get("before"); before != "" {
+		if beforeID, err = utils.ValidateID(before, "before"); err != nil {
+			utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 50
+	}
+
+	if _, err := sqlite.GetConversationPeer(db, conversationID, userID); err != nil {
+		if err == sql.ErrNoRows {
+			utils.SendJSONError(w, "Conversation not found", http.StatusNotFound)
+			return
+		}
+		utils.SendJSONError(w, "Failed to fetch messages", http.StatusInternalServerError)
+		return
+	}
+
+	messages, err := sqlite.GetMessages(db, conversationID, beforeID, limit)
+	if err != nil {
+		utils.SendJSONError(w, "Failed to fetch messages", http.StatusInternalServerError)
+		return
+	}
+
+	var nextBefore *int
+	if len(messages) == limit {
+		nextBefore = &messages[len(messages)-1].ID
+	}
+
+	utils.SendJSONResponse(w, map[string]any{
+		"messages":    messages,
+		"next_before": nextBefore,
+	}, http.StatusOK)
+}
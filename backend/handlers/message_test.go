@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"forum/websocket"
+)
+
+// chatFrame is what the server sends: a hub event or an error
+type chatFrame struct {
+	Type  string          `json:"type"`
+	Data  json.RawMessage `json:"data"`
+	Error string          `json:"error"`
+}
+
+// readFrame reads the next frame of the given type, skipping others
+func readFrame(t *testing.T, conn *websocket.Conn, frameType string) chatFrame {
+	t.Helper()
+	conn.SetIdleTimeout(5 * time.Second)
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("Waiting for %s: %v", frameType, err)
+		}
+		var frame chatFrame
+		if err := json.Unmarshal(data, &frame); err != nil {
+			t.Fatalf("Invalid frame %s: %v", data, err)
+		}
+		if frame.Type == frameType {
+			return frame
+		}
+	}
+}
+
+func TestChat(t *testing.T) {
+	db := setupSchemaTestDB(t)
+	defer db.Close()
+
+	alice, aliceHeader := createTestUser(t, db, "alice")
+	bob, bobHeader := createTestUser(t, db, "bob")
+	carol, carolHeader := createTestUser(t, db, "carol")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Chat(db, w, r)
+	}))
+	defer server.Close()
+
+	dial := func(header http.Header) *websocket.Conn {
+		conn, err := websocket.Dial(server.URL, header)
+		if err != nil {
+			t.Fatalf("Dial failed: %v", err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		readFrame(t, conn, "ready") // subscribed from here on
+		return conn
+	}
+
+	t.Run("requires a session", func(t *testing.T) {
+		if _, err := websocket.Dial(server.URL, nil); err == nil {
+			t.Error("Expected the handshake to fail without a session")
+		}
+	})
+
+	t.Run("rejects foreign origins", func(t *testing.T) {
+		header := aliceHeader.Clone()
+		header.Set("Origin", "http://evil.example")
+		if _, err := websocket.Dial(server.URL, header); err == nil {
+			t.Error("Expected the handshake to fail for a foreign origin")
+		}
+	})
+
+	aliceTab1 := dial(aliceHeader)
+	aliceTab2 := dial(aliceHeader)
+	bobConn := dial(bobHeader)
+
+	var conversationID int
+	t.Run("message reaches every session of both users", func(t *testing.T) {
+		err := aliceTab1.WriteJSON(map[string]any{"type": "message", "recipient_id": bob, "content": "hi *bob*", "client_id": "c1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, conn := range []*websocket.Conn{bobConn, aliceTab1, aliceTab2} {
+			frame := readFrame(t, conn, "message.created")
+			var data struct {
+				Message struct {
+					ConversationID int    `json:"conversation_id"`
+					SenderID       string `json:"sender_id"`
+					ContentHTML    string `json:"content_html"`
+				} `json:"message"`
+				ClientID string `json:"client_id"`
+			}
+			if err := json.Unmarshal(frame.Data, &data); err != nil {
+				t.Fatal(err)
+			}
+			if data.Message.SenderID != alice || data.ClientID != "c1" || data.Message.ContentHTML != "<p>hi <em>bob</em></p>" {
+				t.Errorf("Unexpected message event: %s", frame.Data)
+			}
+			conversationID = data.Message.ConversationID
+		}
+	})
+
+	t.Run("typing and read receipts", func(t *testing.T) {
+		if err := bobConn.WriteJSON(map[string]any{"type": "typing", "conversation_id": conversationID}); err != nil {
+			t.Fatal(err)
+		}
+		frame := readFrame(t, aliceTab2, "message.typing")
+		var typing struct {
+			UserID string `json:"user_id"`
+		}
+		json.Unmarshal(frame.Data, &typing)
+		if typing.UserID != bob {
+			t.Errorf("Expected bob typing, got %s", frame.Data)
+		}
+
+		if err := bobConn.WriteJSON(map[string]any{"type": "read", "conversation_id": conversationID}); err != nil {
+			t.Fatal(err)
+		}
+		frame = readFrame(t, aliceTab1, "message.read")
+		var read struct {
+			UserID    string `json:"user_id"`
+			MessageID int    `json:"message_id"`
+		}
+		json.Unmarshal(frame.Data, &read)
+		if read.UserID != bob || read.MessageID == 0 {
+			t.Errorf("Unexpected read receipt: %s", frame.Data)
+		}
+	})
+
+	t.Run("outsiders cannot use the conversation", func(t *testing.T) {
+		carolConn := dial(carolHeader)
+		if err := carolConn.WriteJSON(map[string]any{"type": "message", "conversation_id": conversationID, "content": "hey"}); err != nil {
+			t.Fatal(err)
+		}
+		if frame := readFrame(t, carolConn, "error"); frame.Error != "Conversation not found" {
+			t.Errorf("Unexpected error %q", frame.Error)
+		}
+	})
+
+	t.Run("blocked users cannot message each other", func(t *testing.T) {
+		if _, err := db.Exec(`INSERT INTO user_blocks (blocker_id, blocked_id) VALUES (?, ?)`, carol, alice); err != nil {
+			t.Fatal(err)
+		}
+		if err := aliceTab1.WriteJSON(map[string]any{"type": "message", "recipient_id": carol, "content": "hello", "client_id": "c2"}); err != nil {
+			t.Fatal(err)
+		}
+		frame := readFrame(t, aliceTab1, "error")
+		if frame.Error != "You cannot message this user" {
+			t.Errorf("Unexpected error %q", frame.Error)
+		}
+		var count int
+		db.QueryRow(`SELECT COUNT(*) FROM messages`).Scan(&count)
+		if count != 1 {
+			t.Errorf("Expected only the first message to be stored, got %d", count)
+		}
+	})
+
+	t.Run("history over REST", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/conversations/messages?conversation_id="+strconv.Itoa(conversationID), nil)
+		req.Header = bobHeader
+		rr := httptest.NewRecorder()
+		GetMessages(db, rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var body struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+			NextBefore *int `json:"next_before"`
+		}
+		json.Unmarshal(rr.Body.Bytes(), &body)
+		if len(body.Messages) != 1 || body.Messages[0].Content != "hi *bob*" || body.NextBefore != nil {
+			t.Errorf("Unexpected history: %s", rr.Body.String())
+		}
+
+		req = httptest.NewRequest("GET", "/api/conversations/messages?conversation_id="+strconv.Itoa(conversationID), nil)
+		req.Header = carolHeader
+		rr = httptest.NewRecorder()
+		GetMessages(db, rr, req)
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("Expected status 404 for a non-member, got %d", rr.Code)
+		}
+	})
+}
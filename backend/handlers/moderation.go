@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"forum/middleware"
+	"forum/sqlite"
+	"forum/utils"
+)
+
+const (
+	// maxBanReasonLength caps the reason shown to banned users
+	maxBanReasonLength = 500
+	// maxSuspensionHours is the longest suspension; longer ones should be bans
+	maxSuspensionHours = 365 * 24
+)
+
+// BanUser bans or suspends the user in the path (POST) or lifts their ban
+// early (DELETE). The POST body is {"reason": "...", "hours": 72}; leaving
+// out hours bans them for good. Banned users are signed out and shown the
+// reason when they try to sign in again (moderators and admins).
+func BanUser(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	moderatorID, _ := middleware.GetUserID(r)
+	userID := r.PathValue("id")
+	if moderatorID == userID {
+		utils.SendJSONError(w, "You cannot ban yourself", http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		Reason string `json:"reason"`
+		Hours  *int   `json:"hours"` // nil for a permanent ban
+	}
+	// The body is optional when lifting a ban
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && !errors.Is(err, io.EOF) {
+		utils.SendJSONError(w, "Invalid request data", http.StatusBadRequest)
+		return
+	}
+
+	// A reason is required to ban and optional when lifting a ban
+	var reason string
+	var err error
+	if request.Reason != "" || r.Method == http.MethodPost {
+		if reason, err = utils.ValidateAndSanitizeString(request.Reason, maxBanReasonLength, "reason"); err != nil {
+			utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if r.Method == http.MethodDelete {
+		err = sqlite.LiftBan(db, moderatorID, userID, reason)
+	} else {
+		var until *time.Time
+		if request.Hours != nil {
+			if *request.Hours < 1 || *request.Hours > maxSuspensionHours {
+				utils.SendJSONError(w, "hours must be between 1 and 8760, or left out for a permanent ban", http.StatusBadRequest)
+				return
+			}
+			end := time.Now().Add(time.Duration(*request.Hours) * time.Hour)
+			until = &end
+		}
+		err = sqlite.BanUser(db, moderatorID, userID, reason, until)
+	}
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		utils.SendJSONError(w, "User not found", http.StatusNotFound)
+		return
+	case errors.Is(err, sqlite.ErrBanAdmin), errors.Is(err, sqlite.ErrBanModerator):
+		utils.SendJSONError(w, err.Error(), http.StatusForbidden)
+		return
+	case errors.Is(err, sqlite.ErrNotBanned):
+		utils.SendJSONError(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		utils.SendJSONError(w, "Failed to update user", http.StatusInternalServerError)
+		return
+	}
+
+	ban, err := sqlite.GetActiveBan(db, userID)
+	if err != nil {
+		utils.SendJSONError(w, "Failed to fetch ban", http.StatusInternalServerError)
+		return
+	}
+	utils.SendJSONResponse(w, map[string]any{"ban": ban}, http.StatusOK)
+}
+
+// GetModerationLog returns a page of bans, suspensions and lifted bans,
+// newest first, optionally for one ?user_id= (moderators and admins)
+func GetModerationLog(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	page, limit := utils.GetPaginationParams(r)
+	if limit > 100 {
+		limit = 100
+	}
+	actions, err := sqlite.GetModerationLog(db, r.URL.Query().Get("user_id"), page, limit)
+	if err != nil {
+		utils.SendJSONError(w, "Failed to fetch moderation log", http.StatusInternalServerError)
+		return
+	}
+	utils.SendJSONResponse(w, map[string]any{"actions": actions, "page": page, "limit": limit}, http.StatusOK)
+}
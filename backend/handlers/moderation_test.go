@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"forum/middleware"
+	"forum/models"
+	"forum/sqlite"
+	"forum/utils"
+)
+
+func TestBanUser(t *testing.T) {
+	db := setupSchemaTestDB(t)
+	defer db.Close()
+
+	modID, modHeader := createTestUser(t, db, "mod")
+	_, userHeader := createTestUser(t, db, "user")
+	if err := sqlite.SetUserRole(db, "mod", sqlite.RoleModerator); err != nil {
+		t.Fatalf("SetUserRole failed: %v", err)
+	}
+	hash, err := utils.HashPassword("secret123")
+	if err != nil {
+		t.Fatalf("HashPassword failed: %v", err)
+	}
+	if err := sqlite.CreateUser(db, "troll", "troll@example.com", hash, ""); err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	troll, _ := sqlite.GetUserByUsername(db, "troll")
+
+	mux := http.NewServeMux()
+	mux.Handle("/api/moderation/users/{id}/ban", middleware.ModeratorMiddleware(db, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { BanUser(db, w, r) })))
+	mux.Handle("/api/moderation/log", middleware.ModeratorMiddleware(db, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { GetModerationLog(db, w, r) })))
+	mux.Handle("/api/protected", middleware.AuthMiddleware(db, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	mux.HandleFunc("/api/login", func(w http.ResponseWriter, r *http.Request) { LoginUser(db, w, r) })
+
+	banPath := "/api/moderation/users/" + troll.ID + "/ban"
+	login := `{"username":"troll","password":"secret123"}`
+
+	t.Run("only moderators", func(t *testing.T) {
+		if rr := testRequest(mux, http.MethodPost, banPath, userHeader, `{"reason":"No"}`); rr.Code != http.StatusForbidden {
+			t.Errorf("Expected 403, got %d", rr.Code)
+		}
+		if rr := testRequest(mux, http.MethodPost, banPath, modHeader, `{"hours":24}`); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 without a reason, got %d", rr.Code)
+		}
+		for _, hours := range []string{"0", "-1", "8761"} {
+			if rr := testRequest(mux, http.MethodPost, banPath, modHeader, `{"reason":"Spam","hours":`+hours+`}`); rr.Code != http.StatusBadRequest {
+				t.Errorf("Expected 400 for %s hours, got %d", hours, rr.Code)
+			}
+		}
+		if rr := testRequest(mux, http.MethodPost, "/api/moderation/users/"+modID+"/ban", modHeader, `{"reason":"Oops"}`); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 when banning yourself, got %d", rr.Code)
+		}
+	})
+
+	t.Run("suspended users are turned away", func(t *testing.T) {
+		rr := testRequest(mux, http.MethodPost, banPath, modHeader, `{"reason":"Spam","hours":24}`)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var response struct {
+			Ban models.Ban `json:"ban"`
+		}
+		json.NewDecoder(rr.Body).Decode(&response)
+		if response.Ban.Reason != "Spam" || response.Ban.Until == nil {
+			t.Errorf("Unexpected ban: %+v", response.Ban)
+		}
+
+		rr = testRequest(mux, http.MethodPost, "/api/login", http.Header{}, login)
+		if rr.Code != http.StatusForbidden {
+			t.Fatalf("Expected login to return 403, got %d", rr.Code)
+		}
+		var refusal struct {
+			Error  string  `json:"error"`
+			Reason string  `json:"reason"`
+			Until  *string `json:"until"`
+		}
+		json.NewDecoder(rr.Body).Decode(&refusal)
+		if refusal.Reason != "Spam" || refusal.Until == nil || !strings.Contains(refusal.Error, "suspended") {
+			t.Errorf("Unexpected refusal: %+v", refusal)
+		}
+
+		// A session that outlived the ban is rejected and removed
+		sessionID, err := sqlite.CreateSession(db, troll.ID)
+		if err != nil {
+			t.Fatalf("CreateSession failed: %v", err)
+		}
+		header := http.Header{}
+		header.Set("Cookie", "session_id="+sessionID)
+		if rr := testRequest(mux, http.MethodGet, "/api/protected", header, ""); rr.Code != http.StatusForbidden {
+			t.Errorf("Expected 403 for a leftover session, got %d", rr.Code)
+		}
+		if rr := testRequest(mux, http.MethodGet, "/api/protected", header, ""); rr.Code != http.StatusUnauthorized {
+			t.Errorf("Expected the leftover session to be gone, got %d", rr.Code)
+		}
+	})
+
+	t.Run("lifting", func(t *testing.T) {
+		if rr := testRequest(mux, http.MethodDelete, banPath, modHeader, ""); rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		if rr := testRequest(mux, http.MethodDelete, banPath, modHeader, ""); rr.Code != http.StatusConflict {
+			t.Errorf("Expected 409 for a user who is not banned, got %d", rr.Code)
+		}
+		if rr := testRequest(mux, http.MethodPost, "/api/login", http.Header{}, login); rr.Code != http.StatusOK {
+			t.Errorf("Expected login to work again, got %d", rr.Code)
+		}
+	})
+
+	t.Run("moderation log", func(t *testing.T) {
+		rr := testRequest(mux, http.MethodGet, "/api/moderation/log?user_id="+troll.ID, modHeader, "")
+		var page struct {
+			Actions []models.ModerationAction `json:"actions"`
+		}
+		json.NewDecoder(rr.Body).Decode(&page)
+		if len(page.Actions) != 2 || page.Actions[0].Action != sqlite.ActionLift || page.Actions[1].Moderator != "mod" {
+			t.Errorf("Unexpected log: %+v", page.Actions)
+		}
+	})
+}
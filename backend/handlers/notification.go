@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"forum/sqlite"
+	"forum/utils"
+)
+
+// GetNotifications lists the current user's notifications. Pass unread=true
+// to only get unread ones.
+func GetNotifications(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := utils.GetUserIDFromSession(db, r)
+	if err != nil || userID == "" {
+		utils.SendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	page, limit := utils.GetPaginationParams(r)
+	if limit > 100 {
+		limit = 100
+	}
+	unreadOnly := r.URL.Query().Get("unread") == "true"
+
+	notifications, unread, err := sqlite.GetNotifications(db, userID, unreadOnly, page, limit)
+	if err != nil {
+		utils.SendJSONError(w, "Failed to fetch notifications", http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendJSONResponse(w, map[string]any{
+		"notifications": notifications,
+		"unread_count":  unread,
+		"page":          page,
+		"limit":         limit,
+	}, http.StatusOK)
+}
+
+// MarkNotificationsRead marks the listed notifications as read. It changes
+// nothing if any of them belongs to someone else.
+func MarkNotificationsRead(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request struct {
+		IDs []int `json:"ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || len(request.IDs) == 0 {
+		http.Error(w, "Invalid request data", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := utils.GetUserIDFromSession(db, r)
+	if err != nil || userID == "" {
+		utils.SendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	updated, err := sqlite.MarkNotificationsRead(db, userID, request.IDs)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.SendJSONError(w, "Notification not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.SendJSONError(w, "Failed to update notifications", http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendJSONResponse(w, map[string]int64{"updated": updated}, http.StatusOK)
+}
+
+// MarkAllNotificationsRead marks every notification of the current user as read
+func MarkAllNotificationsRead(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := utils.GetUserIDFromSession(db, r)
+	if err != nil || userID == "" {
+		utils.SendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	updated, err := sqlite.MarkAllNotificationsRead(db, userID)
+	if err != nil {
+		utils.SendJSONError(w, "Failed to update notifications", http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendJSONResponse(w, map[string]int64{"updated": updated}, http.StatusOK)
+}
+
+// NotificationPreferences reads (GET) or updates (PUT) which notification
+// types the current user receives
+func NotificationPreferences(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := utils.GetUserIDFromSession(db, r)
+	if err != nil || userID == "" {
+		utils.SendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if r.Method == http.MethodPut {
+		var prefs map[string]bool
+		if err := json.NewDecoder(r.Body).Decode(&prefs); err != nil {
+			http.Error(w, "Invalid preferences data", http.StatusBadRequest)
+			return
+		}
+		for t := range prefs {
+			if !isNotificationType(t) {
+				utils.SendJSONError(w, "Unknown notification type: "+t, http.StatusBadRequest)
+				return
+			}
+		}
+		if err := sqlite.SetNotificationPreferences(db, userID, prefs); err != nil {
+			utils.SendJSONError(w, "Failed to save preferences", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	prefs, err := sqlite.GetNotificationPreferences(db, userID)
+	if err != nil {
+		utils.SendJSONError(w, "Failed to fetch preferences", http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendJSONResponse(w, prefs, http.StatusOK)
+}
+
+// isNotificationType reports whether t is a known notification type
+func isNotificationType(t string) bool {
+	for _, known := range sqlite.NotificationTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"forum/models"
+	"forum/sqlite"
+)
+
+func TestNotifications(t *testing.T) {
+	db := setupSchemaTestDB(t)
+	defer db.Close()
+
+	alice, aliceHeader := createTestUser(t, db, "alice")
+	bob, bobHeader := createTestUser(t, db, "bob")
+	for _, title := range []string{"First", "Second", "Third"} {
+		post, err := sqlite.CreatePost(db, alice, nil, title, "Body", "")
+		if err != nil {
+			t.Fatalf("CreatePost failed: %v", err)
+		}
+		if _, err := sqlite.CreateComment(db, bob, post.ID, "nice"); err != nil {
+			t.Fatalf("CreateComment failed: %v", err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/notifications", func(w http.ResponseWriter, r *http.Request) { GetNotifications(db, w, r) })
+	mux.HandleFunc("/api/notifications/read", func(w http.ResponseWriter, r *http.Request) { MarkNotificationsRead(db, w, r) })
+	mux.HandleFunc("/api/notifications/read-all", func(w http.ResponseWriter, r *http.Request) { MarkAllNotificationsRead(db, w, r) })
+	mux.HandleFunc("/api/notifications/preferences", func(w http.ResponseWriter, r *http.Request) { NotificationPreferences(db, w, r) })
+
+	type page struct {
+		Notifications []models.Notification `json:"notifications"`
+		UnreadCount   int                   `json:"unread_count"`
+		Limit         int                   `json:"limit"`
+	}
+	list := func(query string, header http.Header) page {
+		t.Helper()
+		rr := testRequest(mux, http.MethodGet, "/api/notifications"+query, header, "")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var p page
+		json.NewDecoder(rr.Body).Decode(&p)
+		return p
+	}
+
+	t.Run("requires a session", func(t *testing.T) {
+		for _, c := range []struct{ method, path, body string }{
+			{http.MethodGet, "/api/notifications", ""},
+			{http.MethodPost, "/api/notifications/read", `{"ids":[1]}`},
+			{http.MethodPost, "/api/notifications/read-all", ""},
+			{http.MethodGet, "/api/notifications/preferences", ""},
+		} {
+			if rr := testRequest(mux, c.method, c.path, nil, c.body); rr.Code != http.StatusUnauthorized {
+				t.Errorf("Expected 401 for %s %s, got %d", c.method, c.path, rr.Code)
+			}
+		}
+	})
+
+	t.Run("pagination", func(t *testing.T) {
+		first := list("?limit=2", aliceHeader)
+		if len(first.Notifications) != 2 || first.UnreadCount != 3 || first.Limit != 2 {
+			t.Fatalf("Unexpected first page: %+v", first)
+		}
+		second := list("?limit=2&page=2", aliceHeader)
+		if len(second.Notifications) != 1 || second.Notifications[0].ID == first.Notifications[0].ID {
+			t.Errorf("Unexpected second page: %+v", second)
+		}
+		if p := list("?limit=1000", aliceHeader); p.Limit != 100 || len(p.Notifications) != 3 {
+			t.Errorf("Expected the limit to be capped at 100, got %d with %d notifications", p.Limit, len(p.Notifications))
+		}
+		if p := list("", bobHeader); len(p.Notifications) != 0 {
+			t.Errorf("Expected bob to see none of alice's notifications, got %+v", p.Notifications)
+		}
+	})
+
+	t.Run("only the owner marks them read", func(t *testing.T) {
+		notifications := list("", aliceHeader).Notifications
+		body := `{"ids":[` + strconv.Itoa(notifications[0].ID) + `]}`
+
+		if rr := testRequest(mux, http.MethodPost, "/api/notifications/read", bobHeader, body); rr.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 for another user's notification, got %d", rr.Code)
+		}
+		mixed := `{"ids":[` + strconv.Itoa(notifications[0].ID) + `,999]}`
+		if rr := testRequest(mux, http.MethodPost, "/api/notifications/read", aliceHeader, mixed); rr.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 when one of the notifications is unknown, got %d", rr.Code)
+		}
+		if p := list("", aliceHeader); p.UnreadCount != 3 {
+			t.Fatalf("Expected refused requests to mark nothing read, got %d unread", p.UnreadCount)
+		}
+
+		rr := testRequest(mux, http.MethodPost, "/api/notifications/read", aliceHeader, body)
+		var response struct {
+			Updated int `json:"updated"`
+		}
+		json.NewDecoder(rr.Body).Decode(&response)
+		if rr.Code != http.StatusOK || response.Updated != 1 {
+			t.Errorf("Expected 1 notification marked read, got %d: %s", rr.Code, rr.Body.String())
+		}
+		if p := list("?unread=true", aliceHeader); len(p.Notifications) != 2 || p.UnreadCount != 2 {
+			t.Errorf("Expected 2 unread notifications, got %+v", p)
+		}
+
+		if rr := testRequest(mux, http.MethodPost, "/api/notifications/read-all", bobHeader, ""); rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", rr.Code)
+		}
+		if p := list("", aliceHeader); p.UnreadCount != 2 {
+			t.Errorf("Expected bob's read-all to leave alice's notifications alone, got %d unread", p.UnreadCount)
+		}
+	})
+
+	t.Run("preferences", func(t *testing.T) {
+		if rr := testRequest(mux, http.MethodPut, "/api/notifications/preferences", aliceHeader, `{"shout":true}`); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for an unknown type, got %d", rr.Code)
+		}
+		if rr := testRequest(mux, http.MethodPut, "/api/notifications/preferences", aliceHeader, `{"reaction":false}`); rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var prefs map[string]bool
+		json.NewDecoder(testRequest(mux, http.MethodGet, "/api/notifications/preferences", bobHeader, "").Body).Decode(&prefs)
+		if !prefs["reaction"] {
+			t.Errorf("Expected alice's preferences not to change bob's, got %v", prefs)
+		}
+	})
+}
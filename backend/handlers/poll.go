@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"forum/models"
+	"forum/sqlite"
+	"forum/utils"
+)
+
+const (
+	minPollOptions = 2
+	maxPollOptions = 10
+)
+
+// decodePoll reads and validates the poll sent with a new post as a JSON
+// string. It returns nil when the post has no poll.
+func decodePoll(raw string) (*models.Poll, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var request struct {
+		Question       string     `json:"question"`
+		Options        []string   `json:"options"`
+		MultipleChoice bool       `json:"multiple_choice"`
+		HideResults    bool       `json:"hide_results"`
+		ClosesAt       *time.Time `json:"closes_at"`
+	}
+	if err := json.Unmarshal([]byte(raw), &request); err != nil {
+		return nil, errors.New("invalid poll data")
+	}
+
+	question, err := utils.ValidateAndSanitizeString(request.Question, 300, "poll question")
+	if err != nil {
+		return nil, err
+	}
+	if len(request.Options) < minPollOptions || len(request.Options) > maxPollOptions {
+		return nil, fmt.Errorf("a poll needs %d to %d options", minPollOptions, maxPollOptions)
+	}
+	if request.ClosesAt != nil && !request.ClosesAt.After(time.Now()) {
+		return nil, errors.New("closes_at must be in the future")
+	}
+
+	poll := &models.Poll{
+		Question:       question,
+		MultipleChoice: request.MultipleChoice,
+		HideResults:    request.HideResults,
+		ClosesAt:       request.ClosesAt,
+	}
+	seen := make(map[string]bool)
+	for _, text := range request.Options {
+		option, err := utils.ValidateAndSanitizeString(text, 100, "poll option")
+		if err != nil {
+			return nil, err
+		}
+		if seen[strings.ToLower(option)] {
+			return nil, fmt.Errorf("duplicate poll option %q", text)
+		}
+		seen[strings.ToLower(option)] = true
+		poll.Options = append(poll.Options, models.PollOption{Text: option})
+	}
+	return poll, nil
+}
+
+// VotePoll records the current user's vote {"option_ids": [...]} on the poll
+// in the path and returns the updated poll (protected)
+func VotePoll(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := utils.GetUserIDFromSession(db, r)
+	if err != nil || userID == "" {
+		utils.SendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	pollID, err := utils.ValidateID(r.PathValue("id"), "poll ID")
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		OptionIDs []int `json:"option_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request data", http.StatusBadRequest)
+		return
+	}
+
+	poll, err := sqlite.GetPoll(db, pollID, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.SendJSONError(w, "Poll not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.SendJSONError(w, "Failed to fetch poll", http.StatusInternalServerError)
+		return
+	}
+
+	// Blocked users cannot vote on each other's polls
+	authorID, err := sqlite.GetPostAuthorID(db, poll.PostID)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.SendJSONError(w, "Poll not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.SendJSONError(w, "Failed to fetch poll", http.StatusInternalServerError)
+		return
+	}
+	if !canInteract(db, w, userID, authorID) {
+		return
+	}
+
+	err = sqlite.Vote(db, pollID, userID, request.OptionIDs)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		utils.SendJSONError(w, "Poll not found", http.StatusNotFound)
+		return
+	case errors.Is(err, sqlite.ErrPollClosed), errors.Is(err, sqlite.ErrPostLocked):
+		utils.SendJSONError(w, err.Error(), http.StatusForbidden)
+		return
+	case errors.Is(err, sqlite.ErrAlreadyVoted):
+		utils.SendJSONError(w, err.Error(), http.StatusConflict)
+		return
+	case errors.Is(err, sqlite.ErrInvalidVote):
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		utils.SendJSONError(w, "Failed to record vote", http.StatusInternalServerError)
+		return
+	}
+
+	poll, err = sqlite.GetPoll(db, pollID, userID)
+	if err != nil {
+		utils.SendJSONError(w, "Failed to fetch poll", http.StatusInternalServerError)
+		return
+	}
+	utils.SendJSONResponse(w, poll, http.StatusOK)
+}
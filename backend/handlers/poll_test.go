@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"forum/models"
+	"forum/sqlite"
+)
+
+func TestPolls(t *testing.T) {
+	db := setupSchemaTestDB(t)
+	defer db.Close()
+	aliceID, aliceHeader := createTestUser(t, db, "alice")
+	_, bobHeader := createTestUser(t, db, "bob")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/posts/create", func(w http.ResponseWriter, r *http.Request) { CreatePost(db, w, r) })
+	mux.HandleFunc("/api/posts/{id}", func(w http.ResponseWriter, r *http.Request) { GetPost(db, w, r) })
+	mux.HandleFunc("/api/polls/{id}/vote", func(w http.ResponseWriter, r *http.Request) { VotePoll(db, w, r) })
+	mux.HandleFunc("/api/admin/posts/{id}/lock", func(w http.ResponseWriter, r *http.Request) { LockPost(db, w, r) })
+	mux.HandleFunc("/api/comments/create", func(w http.ResponseWriter, r *http.Request) { CreateComment(db, w, r) })
+
+	createPost := func(poll string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		form.WriteField("title", "Poll")
+		form.WriteField("content", "Please vote")
+		form.WriteField("poll", poll)
+		form.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/api/posts/create", &body)
+		req.Header = aliceHeader.Clone()
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("poll validation", func(t *testing.T) {
+		for _, poll := range []string{
+			`{"question":"One option?","options":["Only"]}`,
+			`{"question":"","options":["A","B"]}`,
+			`{"question":"Dupes?","options":["A","a"]}`,
+			`{"question":"Past?","options":["A","B"],"closes_at":"2001-01-01T00:00:00Z"}`,
+		} {
+			if rr := createPost(poll); rr.Code != http.StatusBadRequest {
+				t.Errorf("Expected 400 for %s, got %d", poll, rr.Code)
+			}
+		}
+	})
+
+	rr := createPost(`{"question":"Tabs or spaces?","options":["Tabs","Spaces"],"hide_results":true}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var post models.Post
+	json.NewDecoder(rr.Body).Decode(&post)
+	if post.Poll == nil || len(post.Poll.Options) != 2 {
+		t.Fatalf("Expected the post to carry its poll, got %+v", post.Poll)
+	}
+	postPath := "/api/posts/" + strconv.Itoa(post.ID)
+	votePath := "/api/polls/" + strconv.Itoa(post.Poll.ID) + "/vote"
+	spaces := post.Poll.Options[1].ID
+
+	t.Run("voting reveals hidden results", func(t *testing.T) {
+		var before models.Post
+		json.NewDecoder(testRequest(mux, http.MethodGet, postPath, bobHeader, "").Body).Decode(&before)
+		if before.Poll == nil || before.Poll.Options[0].VoteCount != nil {
+			t.Errorf("Expected hidden results before voting, got %+v", before.Poll)
+		}
+
+		rr := testRequest(mux, http.MethodPost, votePath, bobHeader, `{"option_ids":[`+strconv.Itoa(spaces)+`]}`)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var poll models.Poll
+		json.NewDecoder(rr.Body).Decode(&poll)
+		if poll.Options[1].VoteCount == nil || *poll.Options[1].VoteCount != 1 || len(poll.ViewerVote) != 1 {
+			t.Errorf("Expected bob's vote to be counted, got %+v", poll)
+		}
+
+		if rr := testRequest(mux, http.MethodPost, votePath, bobHeader, `{"option_ids":[`+strconv.Itoa(spaces)+`]}`); rr.Code != http.StatusConflict {
+			t.Errorf("Expected 409 for a second vote, got %d", rr.Code)
+		}
+		if rr := testRequest(mux, http.MethodPost, votePath, aliceHeader, `{"option_ids":[9999]}`); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for an unknown option, got %d", rr.Code)
+		}
+	})
+
+	t.Run("locked threads reject votes and comments", func(t *testing.T) {
+		if rr := testRequest(mux, http.MethodPost, "/api/admin/posts/"+strconv.Itoa(post.ID)+"/lock", aliceHeader, ""); rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", rr.Code)
+		}
+		if rr := testRequest(mux, http.MethodPost, votePath, aliceHeader, `{"option_ids":[`+strconv.Itoa(spaces)+`]}`); rr.Code != http.StatusForbidden {
+			t.Errorf("Expected 403 for a vote, got %d", rr.Code)
+		}
+		if rr := testRequest(mux, http.MethodPost, "/api/comments/create", bobHeader, `{"post_id":`+strconv.Itoa(post.ID)+`,"content":"hi"}`); rr.Code != http.StatusForbidden {
+			t.Errorf("Expected 403 for a comment, got %d", rr.Code)
+		}
+		if rr := testRequest(mux, http.MethodGet, "/api/posts/9999", bobHeader, ""); rr.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 for a missing post, got %d", rr.Code)
+		}
+	})
+
+	t.Run("polls of unpublished posts are not found", func(t *testing.T) {
+		draft, err := sqlite.CreatePostWithDetails(db, aliceID, "Draft", "Soon", models.PostDetails{
+			Status: sqlite.PostDraft,
+			Poll:   &models.Poll{Question: "Ready?", Options: []models.PollOption{{Text: "Yes"}, {Text: "No"}}},
+		})
+		if err != nil {
+			t.Fatalf("CreatePostWithDetails failed: %v", err)
+		}
+		path := "/api/polls/" + strconv.Itoa(draft.Poll.ID) + "/vote"
+		body := `{"option_ids":[` + strconv.Itoa(draft.Poll.Options[0].ID) + `]}`
+		if rr := testRequest(mux, http.MethodPost, path, bobHeader, body); rr.Code != http.StatusNotFound {
+			t.Errorf("Expected 404, got %d", rr.Code)
+		}
+	})
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -14,6 +15,7 @@ import (
 	"bytes"
 	
 	"forum/models"
+	"forum/screening"
 	"forum/sqlite"
 	"forum/utils"
 )
@@ -48,7 +50,8 @@ func CreatePost(db *sql.DB, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sanitizedContent, err := utils.ValidateAndSanitizeString(content, 10000, "content")
+	// Content is Markdown: stored raw and rendered to safe HTML when read
+	sanitizedContent, err := utils.ValidateContent(content, 10000, "content")
 	if err != nil {
 		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
 		return
@@ -75,6 +78,44 @@ func CreatePost(db *sql.DB, w http.ResponseWriter, r *http.Request) {
 
 	log.Printf("DEBUG: Final categoryNames = %v", categoryNames)
 
+	// Tags come as a JSON string or a form array, like category names
+	var rawTags []string
+	if tagsJSON := r.FormValue("tags"); tagsJSON != "" {
+		if err := json.Unmarshal([]byte(tagsJSON), &rawTags); err != nil {
+			utils.SendJSONError(w, "Invalid tags", http.StatusBadRequest)
+			return
+		}
+	} else {
+		rawTags = r.Form["tags[]"]
+	}
+	tags, err := sqlite.NormalizeTags(rawTags)
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	postType := r.FormValue("post_type")
+	if postType == "" {
+		postType = "discussion"
+	} else if postType != "discussion" && postType != "question" {
+		utils.SendJSONError(w, `post_type must be "discussion" or "question"`, http.StatusBadRequest)
+		return
+	}
+
+	// An optional poll comes as a JSON string
+	poll, err := decodePoll(r.FormValue("poll"))
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Posts can be saved as drafts or scheduled instead of published now
+	status, publishAt, err := parsePostStatus(r.FormValue("status"), r.FormValue("publish_at"))
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Validate user session
 	userID, ok := RequireAuth(db, w, r)
 	if !ok || userID == "" {
@@ -82,6 +123,14 @@ func CreatePost(db *sql.DB, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Screen the post before saving anything for it
+	submission := screening.Content{Kind: sqlite.ContentPost, UserID: userID, Title: sanitizedTitle, Content: sanitizedContent, Tags: tags, Poll: poll}
+	verdict, ok := screenContent(db, w, &submission)
+	if !ok {
+		return
+	}
+	sanitizedTitle, sanitizedContent, tags = submission.Title, submission.Content, submission.Tags
+
 	// Handle optional image upload
 	var imageURL string
 	file, header, err := r.FormFile("image")
@@ -118,14 +167,29 @@ func CreatePost(db *sql.DB, w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get category IDs by resolving category names
-	categoryIDs, err := sqlite.GetOrCreateCategoryIDs(db, categoryNames)
-	if err != nil {
+	categoryIDs, err := sqlite.GetOrCreateCategoryIDs(db, categoryNames, userCategoriesAllowed())
+	if errors.Is(err, sqlite.ErrUnknownCategory) || errors.Is(err, sqlite.ErrArchivedCategory) {
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err != nil {
 		http.Error(w, "Failed to resolve categories", http.StatusInternalServerError)
 		return
 	}
 
-	// Create the post with categories
-	post, err := sqlite.CreatePost(db, userID, categoryIDs, sanitizedTitle, sanitizedContent, imageURL)
+	details := models.PostDetails{
+		CategoryIDs: categoryIDs, Tags: tags, PostType: postType, ImageURL: imageURL, Poll: poll,
+		Status: status, PublishAt: publishAt,
+	}
+	if verdict.Action == screening.Flag {
+		holdContent(db, w, models.QueueItem{
+			Kind: sqlite.ContentPost, UserID: userID, Title: sanitizedTitle, Content: sanitizedContent,
+			Post: &details, Reasons: verdict.Reasons,
+		})
+		return
+	}
+
+	// Create the post with categories, tags, type and poll
+	post, err := publishPost(db, userID, sanitizedTitle, sanitizedContent, details)
 	if err != nil {
 		log.Println("Error creating post:", err)
 		utils.SendJSONError(w, "Failed to create post", http.StatusInternalServerError)
@@ -136,6 +200,46 @@ func CreatePost(db *sql.DB, w http.ResponseWriter, r *http.Request) {
 	utils.SendJSONResponse(w, post, http.StatusCreated)
 }
 
+// GetPost returns a single post with its poll, as seen by the viewer (public)
+func GetPost(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	postID, err := utils.ValidateID(r.PathValue("id"), "post ID")
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Drafts and scheduled posts are only visible to their author
+	viewerID, _ := utils.GetUserIDFromSession(db, r)
+	post, err := sqlite.GetPost(db, postID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && post.Status != sqlite.PostPublished && post.UserID != viewerID) {
+		utils.SendJSONError(w, "Post not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.SendJSONError(w, "Failed to fetch post", http.StatusInternalServerError)
+		return
+	}
+	if author, err := sqlite.GetUserByID(db, post.UserID); err == nil {
+		post.ProfileAvatar = author.AvatarURL
+	}
+
+	if post.Poll, err = sqlite.GetPollByPostID(db, postID, viewerID); err != nil {
+		utils.SendJSONError(w, "Failed to fetch poll", http.StatusInternalServerError)
+		return
+	}
+	readPost(db, r, viewerID, &post)
+	posts := []models.Post{post}
+	if err := sqlite.MarkBookmarked(db, viewerID, posts); err != nil {
+		log.Printf("Warning: Failed to load bookmarks: %v", err)
+	}
+
+	utils.SendJSONResponse(w, posts[0], http.StatusOK)
+}
+
 // GetPosts fetches posts (with optional filters)
 func GetPosts(db *sql.DB, w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -146,8 +250,36 @@ func GetPosts(db *sql.DB, w http.ResponseWriter, r *http.Request) {
 	// Extract pagination parameters from the URL query
 	page, limit := utils.GetPaginationParams(r)
 
-	// Fetch posts with pagination
-	posts, err := sqlite.GetPosts(db, page, limit)
+	// ?tag=go&tag=web lists posts having all of the given tags
+	tags, err := sqlite.NormalizeTags(r.URL.Query()["tag"])
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter := sqlite.PostFilter{Tags: tags}
+
+	// ?type=question lists questions; ?answered=true|false also narrows them
+	// to questions with or without an accepted answer
+	switch postType := r.URL.Query().Get("type"); postType {
+	case "", "discussion", "question":
+		filter.PostType = postType
+	default:
+		utils.SendJSONError(w, `type must be "discussion" or "question"`, http.StatusBadRequest)
+		return
+	}
+	if answered := r.URL.Query().Get("answered"); answered != "" {
+		value, err := strconv.ParseBool(answered)
+		if err != nil {
+			utils.SendJSONError(w, "answered must be true or false", http.StatusBadRequest)
+			return
+		}
+		filter.Answered = &value
+	}
+
+	// Fetch posts with pagination, hiding authors the viewer muted or blocked
+	viewerID, _ := utils.GetUserIDFromSession(db, r)
+	posts, err := sqlite.GetPosts(db, viewerID, filter, page, limit)
 	if err != nil {
 		fmt.Println("THE ERROR IS HERE")
 		utils.SendJSONError(w, "Failed to fetch posts", http.StatusInternalServerError)
@@ -165,6 +297,12 @@ func GetPosts(db *sql.DB, w http.ResponseWriter, r *http.Request) {
 		post.ProfileAvatar = userInfo.AvatarURL
 		fullPosts = append(fullPosts, post)
 	}
+	if err := sqlite.MarkBookmarked(db, viewerID, fullPosts); err != nil {
+		log.Printf("Warning: Failed to load bookmarks: %v", err)
+	}
+	if err := sqlite.MarkUnread(db, viewerID, fullPosts); err != nil {
+		log.Printf("Warning: Failed to load read state: %v", err)
+	}
 
 	utils.SendJSONResponse(w, fullPosts, http.StatusOK)
 }
@@ -204,6 +342,8 @@ func GetLikedPosts(db *sql.DB, w http.ResponseWriter, r *http.Request) {
 		post.ProfileAvatar = userInfo.AvatarURL
 		fullPosts = append(fullPosts, post)
 	}
+	markBookmarked(db, r, fullPosts)
+	markUnread(db, r, fullPosts)
 
 	utils.SendJSONResponse(w, fullPosts, http.StatusOK)
 }
@@ -241,13 +381,50 @@ func UpdatePost(db *sql.DB, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = sqlite.UpdatePost(db, post.ID, post.Title, post.Content)
+	sanitizedTitle, err := utils.ValidateAndSanitizeString(post.Title, 200, "title")
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	content, err := utils.ValidateContent(post.Content, 10000, "content")
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Tags are only replaced when the request includes them
+	var tags []string
+	if post.Tags != nil {
+		if tags, err = sqlite.NormalizeTags(post.Tags); err != nil {
+			utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Edits are screened like new posts; the post keeps its current text
+	// while a held edit waits for review
+	submission := screening.Content{Kind: sqlite.ContentPost, UserID: userID, PostID: post.ID, Title: sanitizedTitle, Content: content, Tags: tags}
+	verdict, ok := screenContent(db, w, &submission)
+	if !ok {
+		return
+	}
+	sanitizedTitle, content, tags = submission.Title, submission.Content, submission.Tags
+	if verdict.Action == screening.Flag {
+		holdContent(db, w, models.QueueItem{
+			Kind: sqlite.ContentPost, UserID: userID, TargetID: &post.ID, Title: sanitizedTitle, Content: content,
+			Post: &models.PostDetails{Tags: tags}, Reasons: verdict.Reasons,
+		})
+		return
+	}
+
+	updatedPost, err := editPost(db, post.ID, sanitizedTitle, content, tags)
 	if err != nil {
 		utils.SendJSONError(w, "Failed to update post", http.StatusInternalServerError)
 		return
 	}
 
-	utils.SendJSONResponse(w, post, http.StatusOK)
+	utils.SendJSONResponse(w, updatedPost, http.StatusOK)
 }
 
 func DeletePost(db *sql.DB, w http.ResponseWriter, r *http.Request) {
@@ -292,6 +469,50 @@ func DeletePost(db *sql.DB, w http.ResponseWriter, r *http.Request) {
 	utils.SendJSONResponse(w, map[string]string{"message": "Post deleted"}, http.StatusOK)
 }
 
+// LockPost locks (POST) or unlocks (DELETE) the post in the path, stopping
+// new comments and poll votes (admin only)
+func LockPost(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	postID, err := utils.ValidateID(r.PathValue("id"), "post ID")
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	locked := r.Method == http.MethodPost
+	err = sqlite.SetPostLocked(db, postID, locked)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.SendJSONError(w, "Post not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.SendJSONError(w, "Failed to update post", http.StatusInternalServerError)
+		return
+	}
+	utils.SendJSONResponse(w, map[string]bool{"locked": locked}, http.StatusOK)
+}
+
+// threadOpen checks that the post is not locked. It writes the error
+// response and returns false otherwise.
+func threadOpen(db *sql.DB, w http.ResponseWriter, postID int) bool {
+	locked, err := sqlite.IsPostLocked(db, postID)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.SendJSONError(w, "Post not found", http.StatusNotFound)
+		return false
+	} else if err != nil {
+		utils.SendJSONError(w, "Failed to fetch post", http.StatusInternalServerError)
+		return false
+	}
+	if locked {
+		utils.SendJSONError(w, sqlite.ErrPostLocked.Error(), http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
 func GetPostComments(db *sql.DB, w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -308,7 +529,8 @@ func GetPostComments(db *sql.DB, w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid post_id parameter", http.StatusBadRequest)
 		return
 	}
-	comments, err := sqlite.GetPostComments(db, postID)
+	viewerID, _ := utils.GetUserIDFromSession(db, r)
+	comments, err := sqlite.GetPostComments(db, postID, viewerID)
 	if err != nil {
 		utils.SendJSONError(w, "Failed to fetch comments", http.StatusInternalServerError)
 		return
@@ -49,6 +49,14 @@ func setupPostTestDB(t *testing.T) *sql.DB {
 		title TEXT NOT NULL,
 		content TEXT NOT NULL,
 		image_url TEXT,
+		locked INTEGER NOT NULL DEFAULT 0,
+		post_type TEXT NOT NULL DEFAULT 'discussion',
+		accepted_comment_id INTEGER,
+		like_count INTEGER NOT NULL DEFAULT 0,
+		dislike_count INTEGER NOT NULL DEFAULT 0,
+		view_count INTEGER NOT NULL DEFAULT 0,
+		status TEXT NOT NULL DEFAULT 'published',
+		publish_at DATETIME,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		FOREIGN KEY (user_id) REFERENCES users(id)
@@ -62,6 +70,16 @@ func setupPostTestDB(t *testing.T) *sql.DB {
 		FOREIGN KEY (category_id) REFERENCES categories(id)
 	);
 
+	CREATE TABLE reaction_types (
+		name TEXT PRIMARY KEY,
+		emoji TEXT NOT NULL,
+		label TEXT NOT NULL,
+		sort_order INTEGER NOT NULL DEFAULT 0,
+		built_in INTEGER NOT NULL DEFAULT 0,
+		enabled INTEGER NOT NULL DEFAULT 1
+	);
+	INSERT INTO reaction_types (name, emoji, label, built_in) VALUES ('like', '👍', 'Like', 1), ('dislike', '👎', 'Dislike', 1);
+
 	CREATE TABLE likes (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		user_id TEXT NOT NULL,
@@ -113,7 +131,7 @@ func TestGetLikedPosts(t *testing.T) {
 	}
 
 	// Create a like for the post using ToggleLike
-	err = sqlite.ToggleLike(db, userID, &post.ID, nil, "like")
+	_, err = sqlite.ToggleLike(db, userID, &post.ID, nil, "like")
 	if err != nil {
 		t.Fatalf("Failed to create like: %v", err)
 	}
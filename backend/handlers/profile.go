@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"forum/models"
+	"forum/sqlite"
+	"forum/utils"
+)
+
+// maxBioLength is the longest profile bio accepted
+const maxBioLength = 500
+
+// GetProfile returns the public profile of the user named in the path
+func GetProfile(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	profile, err := sqlite.GetProfileByUsername(db, r.PathValue("username"))
+	if err == sql.ErrNoRows {
+		utils.SendJSONError(w, "User not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.SendJSONError(w, "Failed to fetch profile", http.StatusInternalServerError)
+		return
+	}
+
+	viewerID, _ := utils.GetUserIDFromSession(db, r)
+	profile.Online, profile.LastSeenAt = presenceOf(db, profile.ID, viewerID == profile.ID)
+	if viewerID != "" && viewerID != profile.ID {
+		if profile.IsFollowing, err = sqlite.IsFollowing(db, viewerID, profile.ID); err != nil {
+			log.Printf("Warning: Failed to check follow of %s: %v", profile.ID, err)
+		}
+	}
+	utils.SendJSONResponse(w, profile, http.StatusOK)
+}
+
+// GetProfilePosts lists the posts of the user named in the path
+func GetProfilePosts(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	profile, page, limit, ok := profileActivity(db, w, r, func(s models.PrivacySettings) bool { return s.ShowPosts })
+	if !ok {
+		return
+	}
+
+	posts, err := sqlite.GetPostsByUser(db, profile.ID, page, limit)
+	if err != nil {
+		utils.SendJSONError(w, "Failed to fetch posts", http.StatusInternalServerError)
+		return
+	}
+	for i := range posts {
+		posts[i].ProfileAvatar = profile.AvatarURL
+	}
+	markBookmarked(db, r, posts)
+	markUnread(db, r, posts)
+
+	utils.SendJSONResponse(w, map[string]any{"posts": posts, "page": page, "limit": limit}, http.StatusOK)
+}
+
+// GetProfileComments lists the comments and replies of the user named in the
+// path
+func GetProfileComments(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	profile, page, limit, ok := profileActivity(db, w, r, func(s models.PrivacySettings) bool { return s.ShowComments })
+	if !ok {
+		return
+	}
+
+	comments, err := sqlite.GetCommentsByUser(db, profile.ID, page, limit)
+	if err != nil {
+		utils.SendJSONError(w, "Failed to fetch comments", http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendJSONResponse(w, map[string]any{"comments": comments, "page": page, "limit": limit}, http.StatusOK)
+}
+
+// GetProfileLiked lists the posts liked by the user named in the path. Liked
+// posts are private unless the user opts in.
+func GetProfileLiked(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	profile, page, limit, ok := profileActivity(db, w, r, func(s models.PrivacySettings) bool { return s.ShowLikedPosts })
+	if !ok {
+		return
+	}
+
+	posts, err := sqlite.GetPostsLikedByUser(db, profile.ID, page, limit)
+	if err != nil {
+		utils.SendJSONError(w, "Failed to fetch liked posts", http.StatusInternalServerError)
+		return
+	}
+	for i := range posts {
+		author, err := sqlite.GetUserByID(db, posts[i].UserID)
+		if err != nil {
+			log.Printf("Warning: Failed to fetch author of post %d: %v", posts[i].ID, err)
+			continue
+		}
+		posts[i].ProfileAvatar = author.AvatarURL
+	}
+	markBookmarked(db, r, posts)
+	markUnread(db, r, posts)
+
+	utils.SendJSONResponse(w, map[string]any{"posts": posts, "page": page, "limit": limit}, http.StatusOK)
+}
+
+// profileActivity resolves the user named in the path and the requested page
+// for one of the activity lists. It writes the error response and returns
+// false if the user does not exist or hides the list from the viewer; users
+// can always see their own activity.
+func profileActivity(db *sql.DB, w http.ResponseWriter, r *http.Request, visible func(models.PrivacySettings) bool) (models.Profile, int, int, bool) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return models.Profile{}, 0, 0, false
+	}
+
+	profile, err := sqlite.GetProfileByUsername(db, r.PathValue("username"))
+	if err == sql.ErrNoRows {
+		utils.SendJSONError(w, "User not found", http.StatusNotFound)
+		return profile, 0, 0, false
+	} else if err != nil {
+		utils.SendJSONError(w, "Failed to fetch profile", http.StatusInternalServerError)
+		return profile, 0, 0, false
+	}
+
+	viewerID, _ := utils.GetUserIDFromSession(db, r)
+	if viewerID != profile.ID {
+		settings, err := sqlite.GetPrivacySettings(db, profile.ID)
+		if err != nil {
+			utils.SendJSONError(w, "Failed to fetch privacy settings", http.StatusInternalServerError)
+			return profile, 0, 0, false
+		}
+		if !visible(settings) {
+			utils.SendJSONError(w, "This user keeps this list private", http.StatusForbidden)
+			return profile, 0, 0, false
+		}
+	}
+
+	page, limit := utils.GetPaginationParams(r)
+	if limit > 100 {
+		limit = 100
+	}
+	return profile, page, limit, true
+}
+
+// UpdateProfile sets the current user's bio. An empty bio clears it.
+func UpdateProfile(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := utils.GetUserIDFromSession(db, r)
+	if err != nil || userID == "" {
+		utils.SendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var request struct {
+		Bio string `json:"bio"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request data", http.StatusBadRequest)
+		return
+	}
+
+	bio := ""
+	if strings.TrimSpace(request.Bio) != "" {
+		bio, err = utils.ValidateAndSanitizeString(request.Bio, maxBioLength, "Bio")
+		if err != nil {
+			utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := sqlite.UpdateBio(db, userID, bio); err != nil {
+		utils.SendJSONError(w, "Failed to update profile", http.StatusInternalServerError)
+		return
+	}
+
+	profile, err := sqlite.GetProfileByID(db, userID)
+	if err != nil {
+		utils.SendJSONError(w, "Failed to fetch profile", http.StatusInternalServerError)
+		return
+	}
+	profile.Online, profile.LastSeenAt = presenceOf(db, userID, true)
+	utils.SendJSONResponse(w, profile, http.StatusOK)
+}
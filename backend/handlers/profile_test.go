@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"forum/sqlite"
+)
+
+func TestProfileRoutes(t *testing.T) {
+	db := setupSchemaTestDB(t)
+	defer db.Close()
+
+	alice, aliceHeader := createTestUser(t, db, "alice")
+	_, bobHeader := createTestUser(t, db, "bob")
+	if _, err := sqlite.CreatePost(db, alice, nil, "Hello", "World", ""); err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	for pattern, handler := range map[string]func(*sql.DB, http.ResponseWriter, *http.Request){
+		"/api/users/{username}":          GetProfile,
+		"/api/users/{username}/posts":    GetProfilePosts,
+		"/api/users/{username}/comments": GetProfileComments,
+		"/api/users/{username}/liked":    GetProfileLiked,
+		"/api/user/profile":              UpdateProfile,
+	} {
+		handler := handler
+		mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) { handler(db, w, r) })
+	}
+
+	t.Run("profile leaves out the email", func(t *testing.T) {
+		rr := testRequest(mux, http.MethodGet, "/api/users/alice", bobHeader, "")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		if strings.Contains(rr.Body.String(), "@example.com") {
+			t.Errorf("Expected no email in %s", rr.Body.String())
+		}
+		var profile struct {
+			Username  string `json:"username"`
+			PostCount int    `json:"post_count"`
+		}
+		json.NewDecoder(rr.Body).Decode(&profile)
+		if profile.Username != "alice" || profile.PostCount != 1 {
+			t.Errorf("Unexpected profile: %+v", profile)
+		}
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		if rr := testRequest(mux, http.MethodGet, "/api/users/nobody/posts", nil, ""); rr.Code != http.StatusNotFound {
+			t.Errorf("Expected 404, got %d", rr.Code)
+		}
+	})
+
+	t.Run("privacy settings hide lists from others", func(t *testing.T) {
+		if rr := testRequest(mux, http.MethodGet, "/api/users/alice/posts", nil, ""); rr.Code != http.StatusOK {
+			t.Errorf("Expected posts to be public by default, got %d", rr.Code)
+		}
+		if rr := testRequest(mux, http.MethodGet, "/api/users/alice/liked", bobHeader, ""); rr.Code != http.StatusForbidden {
+			t.Errorf("Expected liked posts to be private by default, got %d", rr.Code)
+		}
+		if rr := testRequest(mux, http.MethodGet, "/api/users/alice/liked", aliceHeader, ""); rr.Code != http.StatusOK {
+			t.Errorf("Expected users to see their own liked posts, got %d", rr.Code)
+		}
+	})
+
+	t.Run("update bio", func(t *testing.T) {
+		rr := testRequest(mux, http.MethodPut, "/api/user/profile", aliceHeader, `{"bio":"Hi <there>"}`)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		if rr := testRequest(mux, http.MethodPut, "/api/user/profile", aliceHeader, `{"bio":"`+strings.Repeat("a", maxBioLength+1)+`"}`); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected an overlong bio to be rejected, got %d", rr.Code)
+		}
+		profile, err := sqlite.GetProfileByID(db, alice)
+		if err != nil || profile.Bio != "Hi &lt;there&gt;" {
+			t.Errorf("Expected the escaped bio to be stored, got %q (%v)", profile.Bio, err)
+		}
+	})
+}
@@ -0,0 +1,346 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"forum/middleware"
+	"forum/models"
+	"forum/realtime"
+	"forum/screening"
+	"forum/sqlite"
+	"forum/utils"
+	"forum/webhooks"
+)
+
+// screenContent runs new content through the screening pipeline, which may
+// rewrite it. It replies and returns false if the content was blocked or
+// could not be screened.
+func screenContent(db *sql.DB, w http.ResponseWriter, c *screening.Content) (screening.Verdict, bool) {
+	verdict, err := screening.Default.Screen(db, c)
+	if err != nil {
+		log.Printf("Error screening %s: %v", c.Kind, err)
+		utils.SendJSONError(w, "Failed to check content", http.StatusInternalServerError)
+		return verdict, false
+	}
+	if verdict.Action == screening.Block {
+		utils.SendJSONError(w, "Your "+c.Kind+" was not published: it "+verdict.Reasons[0], http.StatusBadRequest)
+		return verdict, false
+	}
+	return verdict, true
+}
+
+// holdContent puts flagged content in the moderation queue and tells the
+// author it is waiting for review
+func holdContent(db *sql.DB, w http.ResponseWriter, item models.QueueItem) {
+	id, err := sqlite.QueueContent(db, item)
+	if err != nil {
+		log.Printf("Error queueing %s: %v", item.Kind, err)
+		utils.SendJSONError(w, "Failed to save "+item.Kind, http.StatusInternalServerError)
+		return
+	}
+	utils.SendJSONResponse(w, map[string]any{
+		"queued":   true,
+		"queue_id": id,
+		"message":  "Your " + item.Kind + " will be published once a moderator has reviewed it",
+	}, http.StatusAccepted)
+}
+
+// publishPost creates a post with its tags, type and poll, and announces it.
+// Drafts and scheduled posts are only saved; they are announced when they
+// are published.
+func publishPost(db *sql.DB, userID, title, content string, details models.PostDetails) (models.Post, error) {
+	post, err := sqlite.CreatePostWithDetails(db, userID, title, content, details)
+	if err != nil {
+		return post, err
+	}
+
+	if post.Status == sqlite.PostPublished {
+		realtime.Publish(realtime.Event{Type: realtime.EventPostCreated, Data: post})
+		enqueueWebhook(db, webhooks.PostCreated, post)
+	}
+	return post, nil
+}
+
+// editPost saves an edit of a post and returns the updated post. Its tags
+// are only replaced when tags is not nil.
+func editPost(db *sql.DB, postID int, title, content string, tags []string) (models.Post, error) {
+	if err := sqlite.UpdatePost(db, postID, title, content); err != nil {
+		return models.Post{}, err
+	}
+	if tags != nil {
+		if err := sqlite.SetPostTags(db, postID, tags); err != nil {
+			return models.Post{}, err
+		}
+	}
+	return sqlite.GetPost(db, postID)
+}
+
+// publishComment creates a top-level comment and announces it
+func publishComment(db *sql.DB, userID string, postID int, content string) (models.Comment, error) {
+	comment, err := sqlite.CreateComment(db, userID, postID, content)
+	if err != nil {
+		return comment, err
+	}
+	realtime.Publish(realtime.Event{Type: realtime.EventCommentCreated, PostID: comment.PostID, Data: comment})
+	enqueueWebhook(db, webhooks.CommentCreated, comment)
+	return comment, nil
+}
+
+// publishReply creates a reply to a comment and announces it
+func publishReply(db *sql.DB, userID string, parentCommentID int, content string) (models.ReplyComment, error) {
+	reply, err := sqlite.CreateReplyComment(db, userID, parentCommentID, content)
+	if err != nil {
+		return reply, err
+	}
+	realtime.Publish(realtime.Event{Type: realtime.EventReplyCreated, PostID: reply.PostID, Data: reply})
+	enqueueWebhook(db, webhooks.CommentCreated, reply)
+	return reply, nil
+}
+
+// GetModerationQueue returns a page of held content with ?status=pending
+// (the default), approved or rejected, oldest first (moderators and admins)
+func GetModerationQueue(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "":
+		status = sqlite.QueuePending
+	case sqlite.QueuePending, sqlite.QueueApproved, sqlite.QueueRejected:
+	default:
+		utils.SendJSONError(w, "status must be pending, approved or rejected", http.StatusBadRequest)
+		return
+	}
+
+	page, limit := utils.GetPaginationParams(r)
+	if limit > 100 {
+		limit = 100
+	}
+	items, err := sqlite.GetQueue(db, status, page, limit)
+	if err != nil {
+		utils.SendJSONError(w, "Failed to fetch moderation queue", http.StatusInternalServerError)
+		return
+	}
+	utils.SendJSONResponse(w, map[string]any{"items": items, "page": page, "limit": limit}, http.StatusOK)
+}
+
+// ApproveQueueItem publishes held content (moderators and admins)
+func ApproveQueueItem(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	reviewQueueItem(db, w, r, sqlite.QueueApproved)
+}
+
+// RejectQueueItem discards held content (moderators and admins)
+func RejectQueueItem(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	reviewQueueItem(db, w, r, sqlite.QueueRejected)
+}
+
+// reviewQueueItem approves or rejects the queue item in the path. Approved
+// content is published as if it had just been written; if that fails the
+// item goes back in the queue.
+func reviewQueueItem(db *sql.DB, w http.ResponseWriter, r *http.Request, status string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		utils.SendJSONError(w, "Invalid queue item ID", http.StatusBadRequest)
+		return
+	}
+	reviewerID, _ := middleware.GetUserID(r)
+
+	item, err := sqlite.ClaimQueueItem(db, id, reviewerID, status)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		utils.SendJSONError(w, "Queue item not found", http.StatusNotFound)
+		return
+	case errors.Is(err, sqlite.ErrAlreadyReviewed):
+		utils.SendJSONError(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		utils.SendJSONError(w, "Failed to review queue item", http.StatusInternalServerError)
+		return
+	}
+	if status == sqlite.QueueRejected {
+		removeHeldImage(item)
+		utils.SendJSONResponse(w, item, http.StatusOK)
+		return
+	}
+
+	// Things may have changed while the item waited in the queue
+	if reason, err := stillPublishable(db, item); err != nil || reason != "" {
+		if err := sqlite.ReopenQueueItem(db, id); err != nil {
+			log.Printf("Warning: Failed to reopen queue item %d: %v", id, err)
+		}
+		if err != nil {
+			log.Printf("Error checking queue item %d: %v", id, err)
+			utils.SendJSONError(w, "Failed to check queue item", http.StatusInternalServerError)
+			return
+		}
+		utils.SendJSONError(w, "Cannot publish: "+reason, http.StatusConflict)
+		return
+	}
+
+	var publishedID int
+	var published any
+	switch item.Kind {
+	case sqlite.ContentPost:
+		var post models.Post
+		if item.TargetID != nil {
+			post, err = editPost(db, *item.TargetID, item.Title, item.Content, item.Post.Tags)
+		} else {
+			post, err = publishPost(db, item.UserID, item.Title, item.Content, *item.Post)
+		}
+		publishedID, published = post.ID, post
+	case sqlite.ContentComment:
+		var comment models.Comment
+		comment, err = publishComment(db, item.UserID, *item.TargetID, item.Content)
+		publishedID, published = comment.ID, comment
+	case sqlite.ContentReply:
+		var reply models.ReplyComment
+		reply, err = publishReply(db, item.UserID, *item.TargetID, item.Content)
+		publishedID, published = reply.ID, reply
+	}
+	if err != nil {
+		log.Printf("Error publishing queue item %d: %v", id, err)
+		if err := sqlite.ReopenQueueItem(db, id); err != nil {
+			log.Printf("Warning: Failed to reopen queue item %d: %v", id, err)
+		}
+		utils.SendJSONError(w, "Failed to publish; the post or comment it answers may have been deleted", http.StatusConflict)
+		return
+	}
+	if err := sqlite.SetQueuePublished(db, id, publishedID); err != nil {
+		log.Printf("Warning: Failed to record what queue item %d was published as: %v", id, err)
+	}
+	item.PublishedID = &publishedID
+	utils.SendJSONResponse(w, map[string]any{"item": item, "published": published}, http.StatusOK)
+}
+
+// removeHeldImage deletes the image uploaded with a rejected post, which
+// CreatePost saved before the post was held
+func removeHeldImage(item models.QueueItem) {
+	if item.Post == nil || item.Post.ImageURL == "" {
+		return
+	}
+	path := filepath.Clean(strings.TrimPrefix(item.Post.ImageURL, "/"))
+	if filepath.Dir(path) != filepath.Join("static", "pictures") {
+		return
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		log.Printf("Warning: Failed to remove image of queue item %d: %v", item.ID, err)
+	}
+}
+
+// stillPublishable repeats the checks made when held content was written: its
+// author must not be banned, an edited post must still exist, and a comment
+// or reply must not be in a locked thread or answer someone who blocked its
+// author, or was blocked by them. It
+// returns why the item cannot be published, or "" if it can.
+func stillPublishable(db *sql.DB, item models.QueueItem) (string, error) {
+	if ban, err := sqlite.GetActiveBan(db, item.UserID); err != nil {
+		return "", err
+	} else if ban != nil {
+		return "the author is banned", nil
+	}
+	if item.Kind == sqlite.ContentPost {
+		if item.TargetID == nil {
+			return "", nil
+		}
+		if _, err := sqlite.GetPost(db, *item.TargetID); errors.Is(err, sql.ErrNoRows) {
+			return "the post it edits was deleted", nil
+		} else if err != nil {
+			return "", err
+		}
+		return "", nil
+	}
+
+	var authorID string
+	var postID int
+	var err error
+	if item.Kind == sqlite.ContentComment {
+		postID = *item.TargetID
+		authorID, err = sqlite.GetPostAuthorID(db, postID)
+	} else {
+		authorID, err = sqlite.GetCommentAuthorID(db, *item.TargetID)
+		if err == nil {
+			postID, err = sqlite.GetCommentPostID(db, *item.TargetID)
+		}
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return "the post or comment it answers was deleted", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	if blocked, err := sqlite.IsBlocked(db, item.UserID, authorID); err != nil {
+		return "", err
+	} else if blocked {
+		return "the author and the person it answers have blocked each other", nil
+	}
+	if locked, err := sqlite.IsPostLocked(db, postID); err != nil {
+		return "", err
+	} else if locked {
+		return sqlite.ErrPostLocked.Error(), nil
+	}
+	return "", nil
+}
+
+// AdminBannedWords lists the banned words (GET), adds or changes one (POST
+// {"word", "action", "replacement"}) or removes ?word= (DELETE) (admin only)
+func AdminBannedWords(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		words, err := sqlite.GetBannedWords(db)
+		if err != nil {
+			utils.SendJSONError(w, "Failed to fetch banned words", http.StatusInternalServerError)
+			return
+		}
+		utils.SendJSONResponse(w, words, http.StatusOK)
+
+	case http.MethodPost:
+		var request models.BannedWord
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request data", http.StatusBadRequest)
+			return
+		}
+		if len(request.Replacement) > 50 {
+			utils.SendJSONError(w, "replacement exceeds maximum length of 50 characters", http.StatusBadRequest)
+			return
+		}
+		saved, err := sqlite.SaveBannedWord(db, request)
+		if errors.Is(err, sqlite.ErrInvalidWord) {
+			utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		} else if err != nil {
+			utils.SendJSONError(w, "Failed to save banned word", http.StatusInternalServerError)
+			return
+		}
+		utils.SendJSONResponse(w, saved, http.StatusOK)
+
+	case http.MethodDelete:
+		err := sqlite.DeleteBannedWord(db, r.URL.Query().Get("word"))
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.SendJSONError(w, "Word not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			utils.SendJSONError(w, "Failed to delete banned word", http.StatusInternalServerError)
+			return
+		}
+		utils.SuccessResponse(w, "Word removed")
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
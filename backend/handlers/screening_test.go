@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"forum/middleware"
+	"forum/models"
+	"forum/sqlite"
+)
+
+func TestContentScreening(t *testing.T) {
+	db := setupSchemaTestDB(t)
+	defer db.Close()
+
+	adminID, adminHeader := createTestUser(t, db, "admin")
+	_, modHeader := createTestUser(t, db, "mod")
+	_, aliceHeader := createTestUser(t, db, "alice")
+	if err := sqlite.SetUserRole(db, "admin", sqlite.RoleAdmin); err != nil {
+		t.Fatalf("SetUserRole failed: %v", err)
+	}
+	if err := sqlite.SetUserRole(db, "mod", sqlite.RoleModerator); err != nil {
+		t.Fatalf("SetUserRole failed: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/posts/create", func(w http.ResponseWriter, r *http.Request) { CreatePost(db, w, r) })
+	mux.HandleFunc("/api/posts/update", func(w http.ResponseWriter, r *http.Request) { UpdatePost(db, w, r) })
+	mux.HandleFunc("/api/comments/create", func(w http.ResponseWriter, r *http.Request) { CreateComment(db, w, r) })
+	mux.Handle("/api/moderation/queue", middleware.ModeratorMiddleware(db, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { GetModerationQueue(db, w, r) })))
+	mux.Handle("/api/moderation/queue/{id}/approve", middleware.ModeratorMiddleware(db, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { ApproveQueueItem(db, w, r) })))
+	mux.Handle("/api/moderation/queue/{id}/reject", middleware.ModeratorMiddleware(db, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { RejectQueueItem(db, w, r) })))
+	mux.Handle("/api/admin/words", middleware.AdminMiddleware(db, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { AdminBannedWords(db, w, r) })))
+
+	createPost := func(title, content string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		form.WriteField("title", title)
+		form.WriteField("content", content)
+		form.WriteField("tags", `["go"]`)
+		form.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/api/posts/create", &body)
+		req.Header = aliceHeader.Clone()
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+	queued := func(rr *httptest.ResponseRecorder) int {
+		t.Helper()
+		if rr.Code != http.StatusAccepted {
+			t.Fatalf("Expected 202, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var response struct {
+			QueueID int `json:"queue_id"`
+		}
+		json.NewDecoder(rr.Body).Decode(&response)
+		return response.QueueID
+	}
+
+	t.Run("banned words", func(t *testing.T) {
+		if rr := testRequest(mux, http.MethodPost, "/api/admin/words", modHeader, `{"word":"casino","action":"block"}`); rr.Code != http.StatusForbidden {
+			t.Errorf("Expected moderators not to manage the word list, got %d", rr.Code)
+		}
+		for _, body := range []string{`{"word":"casino","action":"block"}`, `{"word":"darn","action":"replace"}`} {
+			if rr := testRequest(mux, http.MethodPost, "/api/admin/words", adminHeader, body); rr.Code != http.StatusOK {
+				t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+			}
+		}
+		if rr := testRequest(mux, http.MethodPost, "/api/admin/words", adminHeader, `{"word":"x","action":"shout"}`); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for an unknown action, got %d", rr.Code)
+		}
+
+		if rr := createPost("Casino night", "Come along"); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected a blocked word to be refused, got %d", rr.Code)
+		}
+		rr := createPost("Darn bugs", "This darn bug again")
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("Expected 201, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var post models.Post
+		json.NewDecoder(rr.Body).Decode(&post)
+		if post.Title != "**** bugs" || post.Content != "This **** bug again" {
+			t.Errorf("Expected the word to be masked, got %q / %q", post.Title, post.Content)
+		}
+
+		if rr := testRequest(mux, http.MethodDelete, "/api/admin/words?word=darn", adminHeader, ""); rr.Code != http.StatusOK {
+			t.Errorf("Expected 200, got %d", rr.Code)
+		}
+	})
+
+	t.Run("flagged posts wait for approval", func(t *testing.T) {
+		before := countPosts(t, db)
+		id := queued(createPost("Deals", "https://a.example https://b.example https://c.example"))
+		if countPosts(t, db) != before {
+			t.Fatal("Expected the flagged post not to be published")
+		}
+
+		if rr := testRequest(mux, http.MethodGet, "/api/moderation/queue", aliceHeader, ""); rr.Code != http.StatusForbidden {
+			t.Errorf("Expected 403 for users, got %d", rr.Code)
+		}
+		var page struct {
+			Items []models.QueueItem `json:"items"`
+		}
+		json.NewDecoder(testRequest(mux, http.MethodGet, "/api/moderation/queue", modHeader, "").Body).Decode(&page)
+		if len(page.Items) != 1 || page.Items[0].ID != id || len(page.Items[0].Reasons) != 1 {
+			t.Fatalf("Unexpected queue: %+v", page.Items)
+		}
+
+		path := "/api/moderation/queue/" + strconv.Itoa(id) + "/approve"
+		rr := testRequest(mux, http.MethodPost, path, modHeader, "")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var response struct {
+			Published models.Post `json:"published"`
+		}
+		json.NewDecoder(rr.Body).Decode(&response)
+		if response.Published.Title != "Deals" || len(response.Published.Tags) != 1 {
+			t.Errorf("Expected the post to be published with its tags, got %+v", response.Published)
+		}
+		if countPosts(t, db) != before+1 {
+			t.Error("Expected the approved post to be published")
+		}
+		if rr := testRequest(mux, http.MethodPost, path, modHeader, ""); rr.Code != http.StatusConflict {
+			t.Errorf("Expected 409 when approving twice, got %d", rr.Code)
+		}
+	})
+
+	t.Run("rejected comments are not published", func(t *testing.T) {
+		post, err := sqlite.CreatePost(db, adminID, nil, "Thread", "Talk here", "")
+		if err != nil {
+			t.Fatalf("CreatePost failed: %v", err)
+		}
+		comment := `{"post_id":` + strconv.Itoa(post.ID) + `,"content":"Visit my shop for the very best widgets around"}`
+		if rr := testRequest(mux, http.MethodPost, "/api/comments/create", aliceHeader, comment); rr.Code != http.StatusCreated {
+			t.Fatalf("Expected 201, got %d: %s", rr.Code, rr.Body.String())
+		}
+		id := queued(testRequest(mux, http.MethodPost, "/api/comments/create", aliceHeader, comment))
+
+		rr := testRequest(mux, http.MethodPost, "/api/moderation/queue/"+strconv.Itoa(id)+"/reject", modHeader, "")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var n int
+		db.QueryRow(`SELECT COUNT(*) FROM comments WHERE post_id = ?`, post.ID).Scan(&n)
+		if n != 1 {
+			t.Errorf("Expected only the first comment to be published, got %d", n)
+		}
+	})
+
+	t.Run("approval repeats the checks", func(t *testing.T) {
+		alice, _ := sqlite.GetUserByUsername(db, "alice")
+		post, err := sqlite.CreatePost(db, adminID, nil, "Another thread", "Talk here", "")
+		if err != nil {
+			t.Fatalf("CreatePost failed: %v", err)
+		}
+		comment := `{"post_id":` + strconv.Itoa(post.ID) + `,"content":"Come and see the finest gadgets in town today"}`
+		if rr := testRequest(mux, http.MethodPost, "/api/comments/create", aliceHeader, comment); rr.Code != http.StatusCreated {
+			t.Fatalf("Expected 201, got %d: %s", rr.Code, rr.Body.String())
+		}
+		path := "/api/moderation/queue/" + strconv.Itoa(queued(testRequest(mux, http.MethodPost, "/api/comments/create", aliceHeader, comment))) + "/approve"
+
+		for _, c := range []struct {
+			name        string
+			apply, undo func() error
+		}{
+			{"locked thread",
+				func() error { return sqlite.SetPostLocked(db, post.ID, true) },
+				func() error { return sqlite.SetPostLocked(db, post.ID, false) }},
+			{"blocked author",
+				func() error { return sqlite.BlockUser(db, adminID, alice.ID) },
+				func() error { return sqlite.UnblockUser(db, adminID, alice.ID) }},
+			{"banned author",
+				func() error { return sqlite.BanUser(db, adminID, alice.ID, "Spam", nil) },
+				func() error { return sqlite.LiftBan(db, adminID, alice.ID, "") }},
+		} {
+			if err := c.apply(); err != nil {
+				t.Fatalf("%s: %v", c.name, err)
+			}
+			if rr := testRequest(mux, http.MethodPost, path, modHeader, ""); rr.Code != http.StatusConflict {
+				t.Errorf("Expected 409 for a %s, got %d", c.name, rr.Code)
+			}
+			if err := c.undo(); err != nil {
+				t.Fatalf("%s: %v", c.name, err)
+			}
+		}
+
+		if rr := testRequest(mux, http.MethodPost, path, modHeader, ""); rr.Code != http.StatusOK {
+			t.Errorf("Expected the reopened item to be approved, got %d: %s", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("edits are screened", func(t *testing.T) {
+		daveID, daveHeader := createTestUser(t, db, "dave")
+		text := "A long enough post about the garden and its flowers"
+		post, err := sqlite.CreatePost(db, daveID, nil, "Garden", text, "")
+		if err != nil {
+			t.Fatalf("CreatePost failed: %v", err)
+		}
+		edit := func(title, content string) *httptest.ResponseRecorder {
+			body, _ := json.Marshal(map[string]any{"id": post.ID, "title": title, "content": content, "tags": []string{"garden"}})
+			return testRequest(mux, http.MethodPut, "/api/posts/update", daveHeader, string(body))
+		}
+		current := func() models.Post {
+			t.Helper()
+			post, err := sqlite.GetPost(db, post.ID)
+			if err != nil {
+				t.Fatalf("GetPost failed: %v", err)
+			}
+			return post
+		}
+
+		if rr := edit("Casino garden", text); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected a blocked word to be refused, got %d", rr.Code)
+		}
+		if rr := edit("My garden", text); rr.Code != http.StatusOK {
+			t.Errorf("Expected keeping the content not to count as a duplicate, got %d: %s", rr.Code, rr.Body.String())
+		}
+
+		id := queued(edit("Seeds", "https://a.example https://b.example https://c.example"))
+		if got := current(); got.Title != "My garden" || got.Content != text {
+			t.Fatalf("Expected the held edit not to be applied, got %q / %q", got.Title, got.Content)
+		}
+		rr := testRequest(mux, http.MethodPost, "/api/moderation/queue/"+strconv.Itoa(id)+"/approve", modHeader, "")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var response struct {
+			Published models.Post `json:"published"`
+		}
+		json.NewDecoder(rr.Body).Decode(&response)
+		if response.Published.ID != post.ID || response.Published.Title != "Seeds" || len(response.Published.Tags) != 1 {
+			t.Errorf("Expected the edit to be applied with its tags, got %+v", response.Published)
+		}
+		if got := current(); got.Title != "Seeds" {
+			t.Errorf("Expected the approved edit to be saved, got %q", got.Title)
+		}
+
+		id = queued(edit("Weeds", "https://a.example https://b.example https://c.example https://d.example"))
+		if err := sqlite.DeletePost(db, post.ID); err != nil {
+			t.Fatalf("DeletePost failed: %v", err)
+		}
+		if rr := testRequest(mux, http.MethodPost, "/api/moderation/queue/"+strconv.Itoa(id)+"/approve", modHeader, ""); rr.Code != http.StatusConflict {
+			t.Errorf("Expected 409 for an edit of a deleted post, got %d", rr.Code)
+		}
+	})
+}
+
+func countPosts(t *testing.T, db *sql.DB) int {
+	t.Helper()
+	var n int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM posts`).Scan(&n); err != nil {
+		t.Fatalf("Failed to count posts: %v", err)
+	}
+	return n
+}
+
+func TestRemoveHeldImage(t *testing.T) {
+	wd, _ := os.Getwd()
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("Chdir failed: %v", err)
+	}
+	defer os.Chdir(wd)
+
+	os.MkdirAll(filepath.Join("static", "pictures"), 0o755)
+	os.WriteFile(filepath.Join("static", "pictures", "post_1.png"), []byte("png"), 0o644)
+	os.WriteFile("keep.png", []byte("png"), 0o644)
+
+	removeHeldImage(models.QueueItem{Post: &models.PostDetails{ImageURL: "/static/pictures/post_1.png"}})
+	if _, err := os.Stat(filepath.Join("static", "pictures", "post_1.png")); !os.IsNotExist(err) {
+		t.Errorf("Expected the image of a rejected post to be removed, got %v", err)
+	}
+
+	removeHeldImage(models.QueueItem{Post: &models.PostDetails{ImageURL: "/static/pictures/../../keep.png"}})
+	if _, err := os.Stat("keep.png"); err != nil {
+		t.Errorf("Expected files outside static/pictures to be left alone, got %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"forum/models"
+	"forum/sqlite"
+	"forum/utils"
+)
+
+const (
+	// defaultStatsDays is the range of /api/user/stats when none is given
+	defaultStatsDays = 30
+	// maxStatsDays is the longest range /api/user/stats accepts
+	maxStatsDays = 366
+	// maxTopPosts caps the top=N parameter of /api/user/stats
+	maxTopPosts = 20
+)
+
+// GetUserStats returns the reactions and comments the current user's content
+// received per day over ?from=YYYY-MM-DD&to=YYYY-MM-DD, with their top posts,
+// as JSON or as CSV with ?format=csv (protected)
+func GetUserStats(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := utils.GetUserIDFromSession(db, r)
+	if err != nil || userID == "" {
+		utils.SendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	to := time.Now().UTC()
+	if raw := query.Get("to"); raw != "" {
+		if to, err = time.Parse("2006-01-02", raw); err != nil {
+			utils.SendJSONError(w, "to must be a date like 2024-01-31", http.StatusBadRequest)
+			return
+		}
+	}
+	from := to.AddDate(0, 0, -(defaultStatsDays - 1))
+	if raw := query.Get("from"); raw != "" {
+		if from, err = time.Parse("2006-01-02", raw); err != nil {
+			utils.SendJSONError(w, "from must be a date like 2024-01-01", http.StatusBadRequest)
+			return
+		}
+	}
+	if from.After(to) {
+		utils.SendJSONError(w, "from must not be after to", http.StatusBadRequest)
+		return
+	}
+	if to.Sub(from) >= maxStatsDays*24*time.Hour {
+		utils.SendJSONError(w, fmt.Sprintf("The range can be at most %d days", maxStatsDays), http.StatusBadRequest)
+		return
+	}
+
+	top := 5
+	if raw := query.Get("top"); raw != "" {
+		if top, err = strconv.Atoi(raw); err != nil || top < 0 {
+			utils.SendJSONError(w, "top must be a non-negative number", http.StatusBadRequest)
+			return
+		}
+		if top > maxTopPosts {
+			top = maxTopPosts
+		}
+	}
+
+	stats, err := sqlite.GetAuthorStats(db, userID, from, to, top)
+	if err != nil {
+		utils.SendJSONError(w, "Failed to fetch stats", http.StatusInternalServerError)
+		return
+	}
+
+	switch strings.ToLower(query.Get("format")) {
+	case "", "json":
+		utils.SendJSONResponse(w, stats, http.StatusOK)
+	case "csv":
+		writeStatsCSV(db, w, stats)
+	default:
+		utils.SendJSONError(w, "format must be json or csv", http.StatusBadRequest)
+	}
+}
+
+// writeStatsCSV writes one row per day: the date, comments, total reactions
+// and a column for each reaction type
+func writeStatsCSV(db *sql.DB, w http.ResponseWriter, stats models.AuthorStats) {
+	types, err := sqlite.GetReactionTypes(db, true)
+	if err != nil {
+		utils.SendJSONError(w, "Failed to fetch reaction types", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="stats-%s-%s.csv"`, stats.From, stats.To))
+	w.WriteHeader(http.StatusOK)
+
+	out := csv.NewWriter(w)
+	header := []string{"date", "comments", "reactions"}
+	for _, rt := range types {
+		header = append(header, rt.Name)
+	}
+	out.Write(header)
+	for _, day := range stats.Days {
+		row := []string{day.Date, strconv.Itoa(day.Comments), strconv.Itoa(day.ReactionTotal)}
+		for _, rt := range types {
+			row = append(row, strconv.Itoa(day.Reactions[rt.Name]))
+		}
+		out.Write(row)
+	}
+	out.Flush()
+	if err := out.Error(); err != nil {
+		log.Printf("Warning: Failed to write stats CSV: %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"forum/models"
+	"forum/sqlite"
+)
+
+func TestGetUserStats(t *testing.T) {
+	db := setupSchemaTestDB(t)
+	defer db.Close()
+
+	authorID, authorHeader := createTestUser(t, db, "author")
+	fanID, _ := createTestUser(t, db, "fan")
+	post, err := sqlite.CreatePost(db, authorID, nil, "Hello", "World", "")
+	if err != nil {
+		t.Fatalf("CreatePost failed: %v", err)
+	}
+	if _, err := sqlite.ToggleLike(db, fanID, &post.ID, nil, "celebrate"); err != nil {
+		t.Fatalf("ToggleLike failed: %v", err)
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { GetUserStats(db, w, r) })
+
+	t.Run("json", func(t *testing.T) {
+		rr := testRequest(handler, http.MethodGet, "/api/user/stats", authorHeader, "")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var stats models.AuthorStats
+		json.NewDecoder(rr.Body).Decode(&stats)
+		if len(stats.Days) != 30 || stats.Reactions["celebrate"] != 1 || len(stats.TopPosts) != 1 {
+			t.Errorf("Unexpected stats: %+v", stats)
+		}
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		rr := testRequest(handler, http.MethodGet, "/api/user/stats?format=csv&from=2024-01-01&to=2024-01-07", authorHeader, "")
+		if rr.Code != http.StatusOK || !strings.HasPrefix(rr.Header().Get("Content-Type"), "text/csv") {
+			t.Fatalf("Expected a CSV response, got %d %s", rr.Code, rr.Header().Get("Content-Type"))
+		}
+		records, err := csv.NewReader(rr.Body).ReadAll()
+		if err != nil {
+			t.Fatalf("Invalid CSV: %v", err)
+		}
+		if len(records) != 8 || strings.Join(records[0][:4], ",") != "date,comments,reactions,like" || records[1][0] != "2024-01-01" {
+			t.Errorf("Unexpected CSV: %v", records)
+		}
+	})
+
+	t.Run("bad ranges", func(t *testing.T) {
+		for _, query := range []string{"?from=yesterday", "?from=2024-02-01&to=2024-01-01", "?from=2020-01-01&to=2024-01-01", "?format=xml"} {
+			if rr := testRequest(handler, http.MethodGet, "/api/user/stats"+query, authorHeader, ""); rr.Code != http.StatusBadRequest {
+				t.Errorf("Expected 400 for %s, got %d", query, rr.Code)
+			}
+		}
+	})
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"forum/realtime"
+	"forum/utils"
+)
+
+// heartbeatInterval keeps proxies from closing idle streams
+const heartbeatInterval = 25 * time.Second
+
+// Stream serves live forum activity as Server-Sent Events.
+//
+// Everyone receives new posts and post reaction counts. Pass ?posts=1,2 to
+// also receive comments, replies and comment reaction counts on those posts.
+// Logged-in clients (session cookie) additionally receive their own
+// notifications. Reconnecting clients send Last-Event-ID and get the events
+// they missed; if that is no longer possible a "reset" event tells them to
+// refetch.
+func Stream(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var postIDs []int
+	if raw := r.URL.Query().Get("posts"); raw != "" {
+		for _, part := range strings.Split(raw, ",") {
+			id, err := utils.ValidateID(strings.TrimSpace(part), "post id")
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			postIDs = append(postIDs, id)
+		}
+	}
+
+	// Anonymous streams are allowed; a valid session adds private events
+	userID, err := utils.GetUserIDFromSession(db, r)
+	if err != nil {
+		userID = ""
+	}
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // disable nginx buffering
+	w.WriteHeader(http.StatusOK)
+
+	// Subscribe before replaying so nothing published in between is lost
+	sub := realtime.Default.Subscribe(userID, postIDs)
+	defer realtime.Default.Unsubscribe(sub)
+	fmt.Fprint(w, "retry: 3000\n\n")
+
+	var lastSent uint64
+	if lastEventID := r.Header.Get("Last-Event-ID"); lastEventID != "" {
+		lastID, err := strconv.ParseUint(lastEventID, 10, 64)
+		missed, ok := realtime.Default.Since(lastID, sub)
+		if err != nil || !ok {
+			fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+		}
+		for _, e := range missed {
+			if writeEvent(w, e) != nil {
+				return
+			}
+			lastSent = e.ID
+		}
+	}
+	if rc.Flush() != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case e, open := <-sub.Events():
+			if !open {
+				// Dropped as too slow, or the server is shutting down
+				return
+			}
+			if e.ID <= lastSent {
+				continue // already sent during replay
+			}
+			if writeEvent(w, e) != nil || rc.Flush() != nil {
+				return
+			}
+			lastSent = e.ID
+
+		case <-heartbeat.C:
+			// End the stream once the session behind it is gone (logout)
+			if userID != "" {
+				if current, err := utils.GetUserIDFromSession(db, r); err != nil || current != userID {
+					return
+				}
+			}
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil || rc.Flush() != nil {
+				return
+			}
+		}
+	}
+}
+
+// writeEvent writes e in text/event-stream format
+func writeEvent(w http.ResponseWriter, e realtime.Event) error {
+	data, err := json.Marshal(e.Data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+	return err
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"forum/realtime"
+	"forum/sqlite"
+)
+
+// sseEvent is one parsed text/event-stream frame
+type sseEvent struct {
+	id, event, data string
+}
+
+// readEvent reads the next frame that carries an event
+func readEvent(t *testing.T, r *bufio.Reader) sseEvent {
+	t.Helper()
+	var e sseEvent
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read stream: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "":
+			if e.event != "" {
+				return e
+			}
+		case strings.HasPrefix(line, "id: "):
+			e.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			e.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			e.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+// openStream connects to the stream and waits until it is subscribed
+func openStream(t *testing.T, url string, header http.Header) (*bufio.Reader, func()) {
+	t.Helper()
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header = header
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to open stream: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Expected text/event-stream, got %q", ct)
+	}
+
+	// The retry line is written once the subscription exists
+	r := bufio.NewReader(resp.Body)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read stream: %v", err)
+		}
+		if strings.HasPrefix(line, "retry:") {
+			break
+		}
+	}
+	return r, func() { resp.Body.Close() }
+}
+
+func TestStream(t *testing.T) {
+	db := setupPostTestDB(t)
+	db.SetMaxOpenConns(1) // keep every query on the same in-memory database
+	defer db.Close()
+
+	if err := sqlite.CreateUser(db, "streamer", "streamer@example.com", "password", ""); err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+	user, err := sqlite.GetUserByUsername(db, "streamer")
+	if err != nil {
+		t.Fatalf("Failed to get created user: %v", err)
+	}
+	sessionID, err := sqlite.CreateSession(db, user.ID)
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Stream(db, w, r)
+	}))
+	defer server.Close()
+
+	header := http.Header{}
+	header.Set("Cookie", "session_id="+sessionID)
+
+	t.Run("delivers the events the client may see", func(t *testing.T) {
+		r, closeStream := openStream(t, server.URL+"?posts=5", header)
+		defer closeStream()
+
+		realtime.Publish(realtime.Event{Type: realtime.EventCommentCreated, PostID: 6, Data: "other post"})
+		realtime.Publish(realtime.Event{Type: realtime.EventNotification, UserID: "someone-else", Data: "private"})
+		realtime.Publish(realtime.Event{Type: realtime.EventPostCreated, Data: "public"})
+		realtime.Publish(realtime.Event{Type: realtime.EventCommentCreated, PostID: 5, Data: "watched post"})
+		realtime.Publish(realtime.Event{Type: realtime.EventNotification, UserID: user.ID, Data: "mine"})
+
+		want := []sseEvent{
+			{event: realtime.EventPostCreated, data: `"public"`},
+			{event: realtime.EventCommentCreated, data: `"watched post"`},
+			{event: realtime.EventNotification, data: `"mine"`},
+		}
+		for _, w := range want {
+			got := readEvent(t, r)
+			if got.event != w.event || got.data != w.data {
+				t.Errorf("Expected %s %s, got %s %s", w.event, w.data, got.event, got.data)
+			}
+		}
+	})
+
+	t.Run("resumes from Last-Event-ID", func(t *testing.T) {
+		first := realtime.Publish(realtime.Event{Type: realtime.EventPostCreated, Data: "seen"})
+		missed := realtime.Publish(realtime.Event{Type: realtime.EventPostCreated, Data: "missed"})
+
+		resume := http.Header{}
+		resume.Set("Last-Event-ID", strconv.FormatUint(first.ID, 10))
+		r, closeStream := openStream(t, server.URL, resume)
+		defer closeStream()
+
+		realtime.Publish(realtime.Event{Type: realtime.EventPostCreated, Data: "live"})
+
+		got := readEvent(t, r)
+		if got.id != strconv.FormatUint(missed.ID, 10) || got.data != `"missed"` {
+			t.Errorf("Expected the missed event first, got %+v", got)
+		}
+		if got := readEvent(t, r); got.data != `"live"` {
+			t.Errorf("Expected the live event next, got %+v", got)
+		}
+	})
+
+	t.Run("asks the client to refetch when it cannot resume", func(t *testing.T) {
+		resume := http.Header{}
+		resume.Set("Last-Event-ID", "1")
+		req, _ := http.NewRequest("GET", server.URL, nil)
+		req.Header = resume
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to open stream: %v", err)
+		}
+		defer resp.Body.Close()
+
+		done := make(chan sseEvent, 1)
+		go func() { done <- readEvent(t, bufio.NewReader(resp.Body)) }()
+		select {
+		case got := <-done:
+			if got.event != "reset" {
+				t.Errorf("Expected a reset event, got %+v", got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for the reset event")
+		}
+	})
+
+	t.Run("invalid post list", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/stream?posts=abc", nil)
+		rr := httptest.NewRecorder()
+		Stream(db, rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400, got %d", rr.Code)
+		}
+	})
+
+	t.Run("method not allowed", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/api/stream", nil)
+		rr := httptest.NewRecorder()
+		Stream(db, rr, req)
+		if rr.Code != http.StatusMethodNotAllowed {
+			t.Errorf("Expected status 405, got %d", rr.Code)
+		}
+	})
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"forum/sqlite"
+	"forum/utils"
+)
+
+// SuggestTags returns the most used tags starting with ?q=, for
+// autocompletion (public)
+func SuggestTags(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	prefix, err := sqlite.NormalizeTag(r.URL.Query().Get("q"))
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if prefix == "" {
+		utils.SendJSONError(w, "q is required", http.StatusBadRequest)
+		return
+	}
+
+	_, limit := utils.GetPaginationParams(r)
+	if limit > 100 {
+		limit = 100
+	}
+	tags, err := sqlite.SearchTags(db, prefix, limit)
+	if err != nil {
+		utils.SendJSONError(w, "Failed to fetch tags", http.StatusInternalServerError)
+		return
+	}
+	utils.SendJSONResponse(w, tags, http.StatusOK)
+}
+
+// GetTagCloud returns the most used tags with their usage counts (public)
+func GetTagCloud(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	_, limit := utils.GetPaginationParams(r)
+	if limit > 100 {
+		limit = 100
+	}
+	tags, err := sqlite.GetTagCloud(db, limit)
+	if err != nil {
+		utils.SendJSONError(w, "Failed to fetch tags", http.StatusInternalServerError)
+		return
+	}
+	utils.SendJSONResponse(w, tags, http.StatusOK)
+}
+
+// MergeTag makes {"source": ...} a synonym of {"target": ...}, retagging its
+// posts (admin only)
+func MergeTag(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request struct {
+		Source string `json:"source"`
+		Target string `json:"target"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request data", http.StatusBadRequest)
+		return
+	}
+	source, err := sqlite.NormalizeTag(request.Source)
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	target, err := sqlite.NormalizeTag(request.Target)
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if source == "" || target == "" || source == target {
+		utils.SendJSONError(w, "source and target must be two different tags", http.StatusBadRequest)
+		return
+	}
+
+	tag, err := sqlite.MergeTags(db, source, target)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.SendJSONError(w, "Tag not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.SendJSONError(w, "Failed to merge tags", http.StatusInternalServerError)
+		return
+	}
+	utils.SendJSONResponse(w, tag, http.StatusOK)
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"forum/models"
+)
+
+func TestPostTags(t *testing.T) {
+	db := setupSchemaTestDB(t)
+	defer db.Close()
+	_, header := createTestUser(t, db, "alice")
+
+	createPost := func(tags ...string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		form.WriteField("title", "Tagged")
+		form.WriteField("content", "A post with tags")
+		for _, tag := range tags {
+			form.WriteField("tags[]", tag)
+		}
+		form.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/api/posts/create", &body)
+		req.Header = header.Clone()
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		rr := httptest.NewRecorder()
+		CreatePost(db, rr, req)
+		return rr
+	}
+
+	t.Run("tags are normalized on create", func(t *testing.T) {
+		rr := createPost("#Go", "web  dev", "GO")
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("Expected 201, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var post models.Post
+		json.NewDecoder(rr.Body).Decode(&post)
+		if strings.Join(post.Tags, ",") != "go,web-dev" {
+			t.Errorf("Unexpected tags: %v", post.Tags)
+		}
+
+		if rr := createPost("not/valid"); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for an invalid tag, got %d", rr.Code)
+		}
+	})
+
+	t.Run("listing filters by tag", func(t *testing.T) {
+		createPost("rust")
+
+		rr := httptest.NewRecorder()
+		GetPosts(db, rr, httptest.NewRequest(http.MethodGet, "/api/posts?tag=Web+Dev", nil))
+		var posts []models.Post
+		json.NewDecoder(rr.Body).Decode(&posts)
+		if rr.Code != http.StatusOK || len(posts) != 1 || posts[0].Tags[0] != "go" {
+			t.Errorf("Expected the go post, got %d %+v", rr.Code, posts)
+		}
+	})
+
+	t.Run("autocomplete needs a prefix", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		SuggestTags(db, rr, httptest.NewRequest(http.MethodGet, "/api/tags/autocomplete?q=", nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400, got %d", rr.Code)
+		}
+
+		rr = httptest.NewRecorder()
+		SuggestTags(db, rr, httptest.NewRequest(http.MethodGet, "/api/tags/autocomplete?q=%23W", nil))
+		var tags []models.Tag
+		json.NewDecoder(rr.Body).Decode(&tags)
+		if len(tags) != 1 || tags[0].Name != "web-dev" || tags[0].PostCount != 1 {
+			t.Errorf("Unexpected suggestions: %+v", tags)
+		}
+	})
+
+	t.Run("merge validates names", func(t *testing.T) {
+		for body, want := range map[string]int{
+			`{"source":"go","target":"Go"}`:      http.StatusBadRequest,
+			`{"source":"missing","target":"go"}`: http.StatusNotFound,
+			`{"source":"rust","target":"go"}`:    http.StatusOK,
+		} {
+			rr := httptest.NewRecorder()
+			MergeTag(db, rr, httptest.NewRequest(http.MethodPost, "/api/admin/tags/merge", strings.NewReader(body)))
+			if rr.Code != want {
+				t.Errorf("%s: expected %d, got %d", body, want, rr.Code)
+			}
+		}
+	})
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"regexp"
+	"time"
+
+	"forum/models"
+	"forum/realtime"
+	"forum/sqlite"
+	"forum/utils"
+)
+
+// autocompletePrefix matches the start of a username as typed after "@"
+var autocompletePrefix = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,30}$`)
+
+// AutocompleteUsers suggests usernames for @mentions in the composer
+func AutocompleteUsers(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	prefix := r.URL.Query().Get("prefix")
+	if !autocompletePrefix.MatchString(prefix) {
+		utils.SendJSONError(w, "prefix must be 1-30 letters, numbers, underscores or hyphens", http.StatusBadRequest)
+		return
+	}
+
+	users, err := sqlite.SearchUsersByPrefix(db, prefix, 10)
+	if err != nil {
+		utils.SendJSONError(w, "Failed to search users", http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendJSONResponse(w, users, http.StatusOK)
+}
+
+// GetOnlineUsers lists the users who are online now, leaving out those who
+// hide their status
+func GetOnlineUsers(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	users, err := sqlite.GetVisibleUsers(db, realtime.Online.OnlineUserIDs())
+	if err != nil {
+		utils.SendJSONError(w, "Failed to fetch online users", http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendJSONResponse(w, map[string]any{
+		"users": users,
+		"count": len(users),
+	}, http.StatusOK)
+}
+
+// PrivacySettings gets (GET) or updates (PUT) the current user's privacy
+// settings. Fields left out of a PUT keep their value.
+func PrivacySettings(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := utils.GetUserIDFromSession(db, r)
+	if err != nil || userID == "" {
+		utils.SendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	settings, err := sqlite.GetPrivacySettings(db, userID)
+	if err != nil {
+		utils.SendJSONError(w, "Failed to fetch privacy settings", http.StatusInternalServerError)
+		return
+	}
+
+	if r.Method == http.MethodPut {
+		var request struct {
+			ShowOnlineStatus *bool `json:"show_online_status"`
+			ShowPosts        *bool `json:"show_posts"`
+			ShowComments     *bool `json:"show_comments"`
+			ShowLikedPosts   *bool `json:"show_liked_posts"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request data", http.StatusBadRequest)
+			return
+		}
+		if request.ShowOnlineStatus != nil {
+			settings.ShowOnlineStatus = *request.ShowOnlineStatus
+		}
+		if request.ShowPosts != nil {
+			settings.ShowPosts = *request.ShowPosts
+		}
+		if request.ShowComments != nil {
+			settings.ShowComments = *request.ShowComments
+		}
+		if request.ShowLikedPosts != nil {
+			settings.ShowLikedPosts = *request.ShowLikedPosts
+		}
+		if err := sqlite.UpdatePrivacySettings(db, userID, settings); err != nil {
+			utils.SendJSONError(w, "Failed to update privacy settings", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	utils.SendJSONResponse(w, settings, http.StatusOK)
+}
+
+// applyPresence fills in whether user is online and when they were last
+// seen. Others only see it if the user shows their status; self is the
+// user looking at their own account.
+func applyPresence(db *sql.DB, user *models.User, self bool) {
+	user.Online, user.LastSeenAt = presenceOf(db, user.ID, self)
+}
+
+// presenceOf returns whether a user is online and when they were last seen,
+// or nothing if they hide their status from others
+func presenceOf(db *sql.DB, userID string, self bool) (bool, *time.Time) {
+	lastSeen, visible, err := sqlite.GetLastSeen(db, userID)
+	if err != nil {
+		log.Printf("Warning: Failed to load presence for %s: %v", userID, err)
+		return false, nil
+	}
+	if !visible && !self {
+		return false, nil
+	}
+
+	// Memory is fresher than the periodically flushed column
+	if seen, ok := realtime.Online.LastSeen(userID); ok && (lastSeen == nil || seen.After(*lastSeen)) {
+		lastSeen = &seen
+	}
+	return realtime.Online.IsOnline(userID), lastSeen
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"forum/models"
+	"forum/realtime"
+	"forum/sqlite"
+	"forum/utils"
+)
+
+// markUnread sets when the signed-in viewer last read each post and how many
+// comments they have not seen
+func markUnread(db *sql.DB, r *http.Request, posts []models.Post) {
+	viewerID, _ := utils.GetUserIDFromSession(db, r)
+	if err := sqlite.MarkUnread(db, viewerID, posts); err != nil {
+		log.Printf("Warning: Failed to load read state: %v", err)
+	}
+}
+
+// viewerKey identifies who is viewing a post, so repeat views are counted
+// once: the user ID when signed in, otherwise a hash of the visitor's
+// address and browser
+func viewerKey(r *http.Request, viewerID string) string {
+	if viewerID != "" {
+		return viewerID
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	sum := sha256.Sum256([]byte(host + "\n" + r.UserAgent()))
+	return "visitor:" + hex.EncodeToString(sum[:16])
+}
+
+// readPost counts a view of a published post by anyone but its author and,
+// for signed-in viewers, fills in what they had not read yet before marking
+// the post read
+func readPost(db *sql.DB, r *http.Request, viewerID string, post *models.Post) {
+	if post.Status != sqlite.PostPublished {
+		return
+	}
+	if viewerID != post.UserID {
+		realtime.PostViews.Record(post.ID, viewerKey(r, viewerID))
+	}
+	// Views not flushed yet still count for the reader
+	post.ViewCount += realtime.PostViews.Pending(post.ID)
+	if viewerID == "" {
+		return
+	}
+
+	posts := []models.Post{*post}
+	if err := sqlite.MarkUnread(db, viewerID, posts); err != nil {
+		log.Printf("Warning: Failed to load read state: %v", err)
+		return
+	}
+	post.LastReadAt, post.UnreadComments = posts[0].LastReadAt, posts[0].UnreadComments
+	if err := sqlite.MarkPostRead(db, viewerID, post.ID, time.Now()); err != nil {
+		log.Printf("Warning: Failed to mark post %d read: %v", post.ID, err)
+	}
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"forum/models"
+	"forum/realtime"
+	"forum/sqlite"
+)
+
+func TestPostViewsAndReads(t *testing.T) {
+	db := setupSchemaTestDB(t)
+	defer db.Close()
+
+	aliceID, aliceHeader := createTestUser(t, db, "alice")
+	_, bobHeader := createTestUser(t, db, "bob")
+	post, err := sqlite.CreatePost(db, aliceID, nil, "Thread", "Let's talk", "")
+	if err != nil {
+		t.Fatalf("CreatePost failed: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/posts", func(w http.ResponseWriter, r *http.Request) { GetPosts(db, w, r) })
+	mux.HandleFunc("/api/posts/{id}", func(w http.ResponseWriter, r *http.Request) { GetPost(db, w, r) })
+
+	getPost := func(header http.Header, remoteAddr string) models.Post {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/posts/"+strconv.Itoa(post.ID), nil)
+		req.Header = header.Clone()
+		req.RemoteAddr = remoteAddr
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var got models.Post
+		json.NewDecoder(rr.Body).Decode(&got)
+		return got
+	}
+
+	t.Run("views are counted once per viewer", func(t *testing.T) {
+		before := realtime.PostViews.Pending(post.ID)
+		getPost(bobHeader, "10.0.0.1:1000")
+		getPost(bobHeader, "10.0.0.2:1000")
+		getPost(aliceHeader, "10.0.0.3:1000")
+		getPost(http.Header{}, "10.0.0.4:1000")
+		got := getPost(http.Header{}, "10.0.0.4:2000")
+		if n := realtime.PostViews.Pending(post.ID) - before; n != 2 {
+			t.Errorf("Expected bob and one visitor to count, got %d views", n)
+		}
+		if got.ViewCount < 2 {
+			t.Errorf("Expected pending views in view_count, got %d", got.ViewCount)
+		}
+	})
+
+	t.Run("reading marks the post read", func(t *testing.T) {
+		got := getPost(bobHeader, "10.0.0.1:1000")
+		if got.LastReadAt == nil {
+			t.Error("Expected last_read_at from the earlier visit")
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/api/posts", nil)
+		req.Header = bobHeader.Clone()
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		var posts []models.Post
+		json.NewDecoder(rr.Body).Decode(&posts)
+		if len(posts) != 1 || posts[0].LastReadAt == nil {
+			t.Errorf("Expected the listing to show when bob read the post, got %+v", posts)
+		}
+	})
+}
@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+
+	"forum/models"
+	"forum/sqlite"
+	"forum/utils"
+	"forum/webhooks"
+)
+
+// decodeWebhook reads {"url", "events", "active"} from the request body.
+// active defaults to true.
+func decodeWebhook(r *http.Request) (models.Webhook, error) {
+	var request struct {
+		URL    string   `json:"url"`
+		Events []string `json:"events"`
+		Active *bool    `json:"active"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return models.Webhook{}, errors.New("Invalid request data")
+	}
+
+	target, err := url.Parse(request.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return models.Webhook{}, errors.New("url must be an absolute http or https URL")
+	}
+	if len(request.URL) > 2000 {
+		return models.Webhook{}, errors.New("url exceeds maximum length of 2000 characters")
+	}
+	if len(request.Events) == 0 {
+		return models.Webhook{}, errors.New("at least one event is required")
+	}
+	seen := make(map[string]bool)
+	events := []string{}
+	for _, event := range request.Events {
+		if !webhooks.ValidEvent(event) {
+			return models.Webhook{}, fmt.Errorf("unknown event %q", event)
+		}
+		if !seen[event] {
+			seen[event] = true
+			events = append(events, event)
+		}
+	}
+
+	hook := models.Webhook{URL: request.URL, Events: events, Active: true}
+	if request.Active != nil {
+		hook.Active = *request.Active
+	}
+	return hook, nil
+}
+
+// AdminWebhooks lists the webhooks (GET) or registers one (POST {"url",
+// "events", "active"}) (admin only). The signing secret is generated here
+// and only returned in the response to the POST.
+func AdminWebhooks(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		hooks, err := sqlite.GetWebhooks(db)
+		if err != nil {
+			utils.SendJSONError(w, "Failed to fetch webhooks", http.StatusInternalServerError)
+			return
+		}
+		utils.SendJSONResponse(w, map[string]any{"webhooks": hooks, "events": webhooks.Events}, http.StatusOK)
+
+	case http.MethodPost:
+		userID, err := utils.GetUserIDFromSession(db, r)
+		if err != nil {
+			utils.SendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		hook, err := decodeWebhook(r)
+		if err != nil {
+			utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			utils.SendJSONError(w, "Failed to create webhook", http.StatusInternalServerError)
+			return
+		}
+		hook.Secret = hex.EncodeToString(secret)
+
+		created, err := sqlite.CreateWebhook(db, userID, hook)
+		if err != nil {
+			utils.SendJSONError(w, "Failed to create webhook", http.StatusInternalServerError)
+			return
+		}
+		utils.SendJSONResponse(w, created, http.StatusCreated)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// AdminWebhook changes (PUT {"url", "events", "active"}) or deletes (DELETE)
+// the webhook in the path (admin only)
+func AdminWebhook(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut && r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	webhookID, err := utils.ValidateID(r.PathValue("id"), "webhook ID")
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		err := sqlite.DeleteWebhook(db, webhookID)
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.SendJSONError(w, "Webhook not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			utils.SendJSONError(w, "Failed to delete webhook", http.StatusInternalServerError)
+			return
+		}
+		utils.SuccessResponse(w, "Webhook deleted")
+		return
+	}
+
+	hook, err := decodeWebhook(r)
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	hook.ID = webhookID
+
+	updated, err := sqlite.UpdateWebhook(db, hook)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.SendJSONError(w, "Webhook not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.SendJSONError(w, "Failed to update webhook", http.StatusInternalServerError)
+		return
+	}
+	utils.SendJSONResponse(w, updated, http.StatusOK)
+}
+
+// GetWebhookDeliveries returns a page of the delivery log of the webhook in
+// the path, optionally only ?status=pending, delivered or failed (admin only)
+func GetWebhookDeliveries(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	webhookID, err := utils.ValidateID(r.PathValue("id"), "webhook ID")
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", sqlite.DeliveryPending, sqlite.DeliveryDelivered, sqlite.DeliveryFailed:
+	default:
+		utils.SendJSONError(w, "status must be pending, delivered or failed", http.StatusBadRequest)
+		return
+	}
+
+	page, limit := utils.GetPaginationParams(r)
+	if limit > 100 {
+		limit = 100
+	}
+	deliveries, err := sqlite.GetWebhookDeliveries(db, webhookID, status, page, limit)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.SendJSONError(w, "Webhook not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.SendJSONError(w, "Failed to fetch deliveries", http.StatusInternalServerError)
+		return
+	}
+	utils.SendJSONResponse(w, deliveries, http.StatusOK)
+}
+
+// RetryWebhookDelivery queues the failed delivery in the path to be sent
+// again (admin only)
+func RetryWebhookDelivery(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	deliveryID, err := utils.ValidateID(r.PathValue("id"), "delivery ID")
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = sqlite.RetryDelivery(db, deliveryID)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.SendJSONError(w, "No failed delivery with that ID", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.SendJSONError(w, "Failed to retry delivery", http.StatusInternalServerError)
+		return
+	}
+	utils.SuccessResponse(w, "Delivery queued")
+}
+
+// enqueueWebhook queues event for the webhooks subscribed to it. Failures
+// are logged rather than failing the action that triggered the event.
+func enqueueWebhook(db *sql.DB, event string, data any) {
+	if err := webhooks.Enqueue(db, event, data); err != nil {
+		log.Printf("Warning: failed to queue %s webhooks: %v", event, err)
+	}
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"forum/middleware"
+	"forum/models"
+	"forum/sqlite"
+	"forum/webhooks"
+)
+
+func TestWebhooks(t *testing.T) {
+	db := setupSchemaTestDB(t)
+	defer db.Close()
+
+	_, adminHeader := createTestUser(t, db, "admin")
+	_, aliceHeader := createTestUser(t, db, "alice")
+	if err := sqlite.SetUserRole(db, "admin", sqlite.RoleAdmin); err != nil {
+		t.Fatalf("SetUserRole failed: %v", err)
+	}
+
+	var events []string
+	var bodies [][]byte
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		events = append(events, r.Header.Get(webhooks.HeaderEvent))
+		bodies = append(bodies, body)
+	}))
+	defer receiver.Close()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/posts/create", func(w http.ResponseWriter, r *http.Request) { CreatePost(db, w, r) })
+	mux.HandleFunc("/api/likes/toggle", func(w http.ResponseWriter, r *http.Request) { ToggleLike(db, w, r) })
+	mux.Handle("/api/admin/webhooks", middleware.AdminMiddleware(db, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { AdminWebhooks(db, w, r) })))
+	mux.Handle("/api/admin/webhooks/{id}", middleware.AdminMiddleware(db, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { AdminWebhook(db, w, r) })))
+	mux.Handle("/api/admin/webhooks/{id}/deliveries", middleware.AdminMiddleware(db, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { GetWebhookDeliveries(db, w, r) })))
+
+	var hook models.Webhook
+	t.Run("register", func(t *testing.T) {
+		body := `{"url":"` + receiver.URL + `","events":["post.created","reaction.toggled","post.created"]}`
+		if rr := testRequest(mux, http.MethodPost, "/api/admin/webhooks", aliceHeader, body); rr.Code != http.StatusForbidden {
+			t.Errorf("Expected 403 for a regular user, got %d", rr.Code)
+		}
+		for _, bad := range []string{
+			`{"url":"ftp://example.com","events":["post.created"]}`,
+			`{"url":"/relative","events":["post.created"]}`,
+			`{"url":"http://example.com","events":[]}`,
+			`{"url":"http://example.com","events":["post.deleted"]}`,
+		} {
+			if rr := testRequest(mux, http.MethodPost, "/api/admin/webhooks", adminHeader, bad); rr.Code != http.StatusBadRequest {
+				t.Errorf("Expected 400 for %s, got %d", bad, rr.Code)
+			}
+		}
+
+		rr := testRequest(mux, http.MethodPost, "/api/admin/webhooks", adminHeader, body)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("Expected 201, got %d: %s", rr.Code, rr.Body.String())
+		}
+		json.NewDecoder(rr.Body).Decode(&hook)
+		if len(hook.Secret) != 64 || !hook.Active || len(hook.Events) != 2 {
+			t.Fatalf("Expected an active webhook with a secret and 2 events, got %+v", hook)
+		}
+
+		rr = testRequest(mux, http.MethodGet, "/api/admin/webhooks", adminHeader, "")
+		if body := rr.Body.String(); rr.Code != http.StatusOK || strings.Contains(body, hook.Secret) || !strings.Contains(body, "user.registered") {
+			t.Errorf("Expected the list and event types without the secret, got %d %s", rr.Code, body)
+		}
+	})
+
+	t.Run("events are delivered", func(t *testing.T) {
+		var form bytes.Buffer
+		writer := multipart.NewWriter(&form)
+		writer.WriteField("title", "Hello hooks")
+		writer.WriteField("content", "Delivered to the receiver")
+		writer.Close()
+		req := httptest.NewRequest(http.MethodPost, "/api/posts/create", &form)
+		req.Header = aliceHeader.Clone()
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK && rr.Code != http.StatusCreated {
+			t.Fatalf("CreatePost failed: %d %s", rr.Code, rr.Body.String())
+		}
+		var post models.Post
+		json.NewDecoder(rr.Body).Decode(&post)
+
+		if rr := testRequest(mux, http.MethodPost, "/api/likes/toggle", adminHeader, `{"post_id":`+strconv.Itoa(post.ID)+`,"type":"like"}`); rr.Code != http.StatusOK {
+			t.Fatalf("ToggleLike failed: %d %s", rr.Code, rr.Body.String())
+		}
+
+		if delivered, err := webhooks.NewWorker(db).RunOnce(); err != nil || delivered != 2 {
+			t.Fatalf("Expected 2 deliveries, got %d, %v", delivered, err)
+		}
+		if len(events) != 2 || events[0] != webhooks.PostCreated || events[1] != webhooks.ReactionToggled {
+			t.Fatalf("Unexpected events %v", events)
+		}
+		var reaction struct {
+			Data struct {
+				PostID int  `json:"post_id"`
+				Active bool `json:"active"`
+				Likes  int  `json:"likes"`
+			} `json:"data"`
+		}
+		json.Unmarshal(bodies[1], &reaction)
+		if reaction.Data.PostID != post.ID || !reaction.Data.Active || reaction.Data.Likes != 1 {
+			t.Errorf("Unexpected reaction payload %s", bodies[1])
+		}
+
+		rr = testRequest(mux, http.MethodGet, "/api/admin/webhooks/"+strconv.Itoa(hook.ID)+"/deliveries?status=delivered", adminHeader, "")
+		var log []models.WebhookDelivery
+		json.NewDecoder(rr.Body).Decode(&log)
+		if rr.Code != http.StatusOK || len(log) != 2 {
+			t.Errorf("Expected 2 delivered entries in the log, got %d %+v", rr.Code, log)
+		}
+		if rr := testRequest(mux, http.MethodGet, "/api/admin/webhooks/"+strconv.Itoa(hook.ID)+"/deliveries?status=lost", adminHeader, ""); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for an unknown status, got %d", rr.Code)
+		}
+		if rr := testRequest(mux, http.MethodGet, "/api/admin/webhooks/9999/deliveries", adminHeader, ""); rr.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 for an unknown webhook, got %d", rr.Code)
+		}
+	})
+
+	t.Run("update and delete", func(t *testing.T) {
+		path := "/api/admin/webhooks/" + strconv.Itoa(hook.ID)
+		rr := testRequest(mux, http.MethodPut, path, adminHeader, `{"url":"`+receiver.URL+`","events":["user.registered"],"active":false}`)
+		var updated models.Webhook
+		json.NewDecoder(rr.Body).Decode(&updated)
+		if rr.Code != http.StatusOK || updated.Active || updated.Secret != "" {
+			t.Errorf("Expected an inactive webhook without its secret, got %d %+v", rr.Code, updated)
+		}
+		if rr := testRequest(mux, http.MethodPut, "/api/admin/webhooks/9999", adminHeader, `{"url":"http://example.com","events":["post.created"]}`); rr.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 for an unknown webhook, got %d", rr.Code)
+		}
+		if rr := testRequest(mux, http.MethodDelete, path, adminHeader, ""); rr.Code != http.StatusOK {
+			t.Errorf("Expected 200 deleting, got %d", rr.Code)
+		}
+		if rr := testRequest(mux, http.MethodDelete, path, adminHeader, ""); rr.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 deleting twice, got %d", rr.Code)
+		}
+	})
+}
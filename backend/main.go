@@ -1,16 +1,24 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	"forum/middleware"
+	"forum/models"
+	"forum/realtime"
 	"forum/routes"
 	"forum/sqlite"
+	"forum/webhooks"
 )
 
 func main() {
@@ -40,6 +48,16 @@ func main() {
 	}
 	defer sqlite.CloseDatabase()
 
+	// Promote the users listed in ADMIN_USERNAMES and MODERATOR_USERNAMES
+	// (comma-separated)
+	promoteUsers(os.Getenv("ADMIN_USERNAMES"), sqlite.RoleAdmin)
+	promoteUsers(os.Getenv("MODERATOR_USERNAMES"), sqlite.RoleModerator)
+
+	// Push new notifications to the recipient's live streams
+	sqlite.NotificationHook = func(userID string, n models.Notification) {
+		realtime.Publish(realtime.Event{Type: realtime.EventNotification, UserID: userID, Data: n})
+	}
+
 	// Set up routes and CORS
 	mux := routes.SetupRoutes(sqlite.DB)
 	handler := middleware.CORS(mux)
@@ -47,9 +65,61 @@ func main() {
 	// Start daily session cleanup in background
 	go scheduleDailyCleanup()
 
-	// Start server
+	// Publish scheduled posts as they come due
+	go schedulePostPublishing()
+
+	// Save last-seen times collected in memory every minute
+	go schedulePresenceFlush()
+
+	// Add up post views counted in memory every minute
+	go scheduleViewFlush()
+
+	// Clear suspensions as they run out
+	go scheduleSuspensionExpiry()
+
+	// Send queued webhook deliveries
+	go scheduleWebhookDeliveries()
+
+	// Start server. Open event streams never go idle, so the hub is closed
+	// on shutdown to let them return.
+	srv := &http.Server{Addr: port, Handler: handler}
+	srv.RegisterOnShutdown(realtime.Default.Close)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+		<-stop
+
+		log.Println("Shutting down server...")
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Printf("Graceful shutdown failed: %v", err)
+		}
+	}()
+
 	fmt.Printf("🚀 [%s] Server is running at http://localhost%s\n", time.Now().Format(time.RFC3339), port)
-	log.Fatal(http.ListenAndServe(port, handler))
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+	<-done
+	flushPresence()
+	flushViews()
+}
+
+// promoteUsers gives a role to each listed username that exists
+func promoteUsers(usernames, role string) {
+	for _, username := range strings.Split(usernames, ",") {
+		username = strings.TrimSpace(username)
+		if username == "" {
+			continue
+		}
+		if err := sqlite.SetUserRole(sqlite.DB, username, role); err != nil {
+			log.Printf("Warning: Could not give %q the %s role: %v", username, role, err)
+		}
+	}
 }
 
 // scheduleDailyCleanup runs session cleanup at midnight every day
@@ -75,3 +145,88 @@ func scheduleDailyCleanup() {
 		}
 	}
 }
+
+// schedulePostPublishing publishes scheduled posts once a minute. The
+// schedule lives in the database, so the first run at startup catches up on
+// posts that came due while the server was down.
+func schedulePostPublishing() {
+	for {
+		posts, err := sqlite.PublishDuePosts(sqlite.DB)
+		if err != nil {
+			log.Printf("Error publishing scheduled posts: %v", err)
+		}
+		for _, post := range posts {
+			realtime.Publish(realtime.Event{Type: realtime.EventPostCreated, Data: post})
+			if err := webhooks.Enqueue(sqlite.DB, webhooks.PostCreated, post); err != nil {
+				log.Printf("Warning: failed to queue webhooks for post %d: %v", post.ID, err)
+			}
+		}
+		if len(posts) > 0 {
+			log.Printf("Published %d scheduled post(s)", len(posts))
+		}
+		time.Sleep(time.Minute)
+	}
+}
+
+// scheduleWebhookDeliveries sends due webhook deliveries every 10 seconds.
+// The outbox is in the database, so deliveries queued before a restart are
+// still sent.
+func scheduleWebhookDeliveries() {
+	worker := webhooks.NewWorker(sqlite.DB)
+	for range time.Tick(10 * time.Second) {
+		if n, err := worker.RunOnce(); err != nil {
+			log.Printf("Error sending webhooks: %v", err)
+		} else if n > 0 {
+			log.Printf("Delivered %d webhook(s)", n)
+		}
+	}
+}
+
+// scheduleSuspensionExpiry lifts expired suspensions once a minute, so they
+// leave the moderation log an "expire" entry. Expired suspensions stop
+// blocking the user as soon as they end either way.
+func scheduleSuspensionExpiry() {
+	for range time.Tick(time.Minute) {
+		if n, err := sqlite.LiftExpiredSuspensions(sqlite.DB); err != nil {
+			log.Printf("Error lifting expired suspensions: %v", err)
+		} else if n > 0 {
+			log.Printf("Lifted %d expired suspension(s)", n)
+		}
+	}
+}
+
+// schedulePresenceFlush writes the last-seen times tracked in memory to the
+// database once a minute, instead of on every request
+func schedulePresenceFlush() {
+	for range time.Tick(time.Minute) {
+		flushPresence()
+	}
+}
+
+// flushPresence saves the last-seen times that changed since the last flush
+func flushPresence() {
+	err := realtime.Online.Flush(func(seen map[string]time.Time) error {
+		return sqlite.SaveLastSeen(sqlite.DB, seen)
+	})
+	if err != nil {
+		log.Printf("Error saving last seen times: %v", err)
+	}
+}
+
+// scheduleViewFlush writes the post views counted in memory to the database
+// once a minute
+func scheduleViewFlush() {
+	for range time.Tick(time.Minute) {
+		flushViews()
+	}
+}
+
+// flushViews adds the post views counted since the last flush
+func flushViews() {
+	err := realtime.PostViews.Flush(func(counts map[int]int) error {
+		return sqlite.SaveViewCounts(sqlite.DB, counts)
+	})
+	if err != nil {
+		log.Printf("Error saving post views: %v", err)
+	}
+}
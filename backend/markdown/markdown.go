@@ -0,0 +1,276 @@
+// Package markdown renders the small Markdown subset accepted in posts and
+// comments: paragraphs, fenced code blocks, inline code, links, bold/italic,
+// bullet and numbered lists, block quotes and @username mentions.
+//
+// All user text is HTML-escaped and only tags from a fixed allowlist are ever
+// emitted, so the output can be injected into the page as-is.
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// maxQuoteDepth bounds how deeply block quotes are rendered recursively
+const maxQuoteDepth = 4
+
+var (
+	unorderedItem = regexp.MustCompile(`^[-*+]\s+(.*)$`)
+	orderedItem   = regexp.MustCompile(`^\d{1,9}[.)]\s+(.*)$`)
+	fenceLanguage = regexp.MustCompile(`^[A-Za-z0-9_+-]{1,20}$`)
+	mentionName   = regexp.MustCompile(`^@([A-Za-z0-9_-]{3,30})`)
+)
+
+// renderer carries per-document state through block and inline rendering
+type renderer struct {
+	// mentions is the set of usernames rendered as profile links
+	mentions map[string]bool
+	// found collects every @username seen outside code, in order
+	found []string
+	seen  map[string]bool
+}
+
+// Render converts Markdown source to sanitized HTML
+func Render(src string) string {
+	return RenderWithMentions(src, nil)
+}
+
+// RenderWithMentions is Render with every @username in mentions turned into a
+// link to that user's profile. Unknown names are left as plain text.
+func RenderWithMentions(src string, mentions map[string]bool) string {
+	r := &renderer{mentions: mentions}
+	return r.render(src)
+}
+
+// Mentions returns the distinct @usernames in src, in order of appearance.
+// Mentions inside inline code, code blocks and link text are ignored.
+func Mentions(src string) []string {
+	r := &renderer{seen: make(map[string]bool)}
+	r.render(src)
+	return r.found
+}
+
+func (r *renderer) render(src string) string {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	return r.renderBlocks(strings.Split(src, "\n"), 0)
+}
+
+// renderBlocks renders a sequence of lines as block-level elements
+func (r *renderer) renderBlocks(lines []string, depth int) string {
+	var blocks []string
+	var para []string
+
+	flush := func() {
+		if len(para) == 0 {
+			return
+		}
+		parts := make([]string, len(para))
+		for i, line := range para {
+			parts[i] = r.renderInline(strings.TrimSpace(line), true)
+		}
+		blocks = append(blocks, "<p>"+strings.Join(parts, "<br>")+"</p>")
+		para = nil
+	}
+
+	for i := 0; i < len(lines); {
+		trimmed := strings.TrimSpace(lines[i])
+
+		switch {
+		case trimmed == "":
+			flush()
+			i++
+
+		case strings.HasPrefix(trimmed, "```"):
+			flush()
+			lang := strings.TrimSpace(trimmed[3:])
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				code = append(code, lines[i])
+			}
+			i++ // skip the closing fence
+			open := "<pre><code>"
+			if fenceLanguage.MatchString(lang) {
+				open = `<pre><code class="language-` + lang + `">`
+			}
+			blocks = append(blocks, open+html.EscapeString(strings.Join(code, "\n"))+"</code></pre>")
+
+		case strings.HasPrefix(trimmed, ">"):
+			flush()
+			var quoted []string
+			for ; i < len(lines); i++ {
+				line := strings.TrimSpace(lines[i])
+				if !strings.HasPrefix(line, ">") {
+					break
+				}
+				quoted = append(quoted, strings.TrimPrefix(line[1:], " "))
+			}
+			var inner string
+			if depth < maxQuoteDepth {
+				inner = r.renderBlocks(quoted, depth+1)
+			} else {
+				inner = "<p>" + r.renderInline(strings.Join(quoted, " "), true) + "</p>"
+			}
+			blocks = append(blocks, "<blockquote>"+inner+"</blockquote>")
+
+		case unorderedItem.MatchString(trimmed):
+			flush()
+			var items []string
+			i = r.collectItems(lines, i, unorderedItem, &items)
+			blocks = append(blocks, "<ul>"+strings.Join(items, "")+"</ul>")
+
+		case orderedItem.MatchString(trimmed):
+			flush()
+			var items []string
+			i = r.collectItems(lines, i, orderedItem, &items)
+			blocks = append(blocks, "<ol>"+strings.Join(items, "")+"</ol>")
+
+		default:
+			para = append(para, trimmed)
+			i++
+		}
+	}
+	flush()
+
+	return strings.Join(blocks, "\n")
+}
+
+// collectItems renders consecutive list items matching pattern starting at
+// line i, and returns the index of the first line after the list
+func (r *renderer) collectItems(lines []string, i int, pattern *regexp.Regexp, items *[]string) int {
+	for ; i < len(lines); i++ {
+		m := pattern.FindStringSubmatch(strings.TrimSpace(lines[i]))
+		if m == nil {
+			break
+		}
+		*items = append(*items, "<li>"+r.renderInline(m[1], true)+"</li>")
+	}
+	return i
+}
+
+// renderInline renders code spans, links, mentions and emphasis within a
+// single line. Link text is rendered with allowLinks=false so anchors never nest.
+func (r *renderer) renderInline(s string, allowLinks bool) string {
+	var b strings.Builder
+
+	for i := 0; i < len(s); {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && strings.IndexByte("\\`*_[]()>#+-.!", s[i+1]) >= 0:
+			b.WriteString(html.EscapeString(s[i+1 : i+2]))
+			i += 2
+			continue
+
+		case s[i] == '`':
+			if end := strings.IndexByte(s[i+1:], '`'); end >= 0 {
+				b.WriteString("<code>" + html.EscapeString(s[i+1:i+1+end]) + "</code>")
+				i += end + 2
+				continue
+			}
+
+		case s[i] == '[' && allowLinks:
+			if text, target, n, ok := parseLink(s[i:]); ok {
+				if href, safe := safeURL(target); safe {
+					b.WriteString(`<a href="` + html.EscapeString(href) + `" rel="nofollow ugc noopener">` + r.renderInline(text, false) + "</a>")
+				} else {
+					b.WriteString(r.renderInline(text, false))
+				}
+				i += n
+				continue
+			}
+
+		case s[i] == '@' && allowLinks && (i == 0 || !isNameByte(s[i-1])):
+			if m := mentionName.FindStringSubmatch(s[i:]); m != nil && (len(m[0]) == len(s)-i || s[i+len(m[0])] == '.' || !isNameByte(s[i+len(m[0])])) {
+				name := m[1]
+				if r.seen != nil && !r.seen[name] {
+					r.seen[name] = true
+					r.found = append(r.found, name)
+				}
+				if r.mentions[name] {
+					b.WriteString(`<a href="/users/` + html.EscapeString(name) + `" class="mention">@` + html.EscapeString(name) + "</a>")
+				} else {
+					b.WriteString("@" + html.EscapeString(name))
+				}
+				i += len(m[0])
+				continue
+			}
+
+		case strings.HasPrefix(s[i:], "**"):
+			if end := strings.Index(s[i+2:], "**"); end > 0 {
+				b.WriteString("<strong>" + r.renderInline(s[i+2:i+2+end], allowLinks) + "</strong>")
+				i += end + 4
+				continue
+			}
+
+		case s[i] == '*':
+			if end := strings.IndexByte(s[i+1:], '*'); end > 0 && s[i+1] != ' ' {
+				b.WriteString("<em>" + r.renderInline(s[i+1:i+1+end], allowLinks) + "</em>")
+				i += end + 2
+				continue
+			}
+		}
+
+		b.WriteString(html.EscapeString(s[i : i+1]))
+		i++
+	}
+
+	return b.String()
+}
+
+// isNameByte reports whether c can appear around a username or in an email
+// address, so "a@b.c" and "@name@x" are not treated as mentions. A trailing
+// '.' is allowed so a mention can end a sentence.
+func isNameByte(c byte) bool {
+	return c == '_' || c == '-' || c == '.' || c == '@' ||
+		('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
+// parseLink parses "[text](url)" at the start of s and reports the number of
+// bytes consumed
+func parseLink(s string) (text, target string, n int, ok bool) {
+	closeText := strings.IndexByte(s, ']')
+	if closeText < 1 || closeText+1 >= len(s) || s[closeText+1] != '(' {
+		return "", "", 0, false
+	}
+	// Allow balanced parentheses inside the URL, e.g. wiki links
+	closeURL, depth := -1, 0
+	for j, c := range s[closeText+2:] {
+		if c == '(' {
+			depth++
+		} else if c == ')' {
+			if depth == 0 {
+				closeURL = j
+				break
+			}
+			depth--
+		}
+	}
+	if closeURL < 1 {
+		return "", "", 0, false
+	}
+	target = s[closeText+2 : closeText+2+closeURL]
+	if strings.ContainsAny(target, " \t") {
+		return "", "", 0, false
+	}
+	return s[1:closeText], target, closeText + 3 + closeURL, true
+}
+
+// safeURL reports whether a link target uses an allowed scheme. Only http(s),
+// mailto and site-relative links are kept; anything else (javascript:, data:,
+// ...) is dropped and the link text is rendered as plain text.
+func safeURL(target string) (string, bool) {
+	for _, r := range target {
+		if r < 0x20 || r == 0x7f {
+			return "", false
+		}
+	}
+	lower := strings.ToLower(target)
+	switch {
+	case strings.HasPrefix(lower, "http://"),
+		strings.HasPrefix(lower, "https://"),
+		strings.HasPrefix(lower, "mailto:"),
+		strings.HasPrefix(lower, "#"),
+		strings.HasPrefix(lower, "/") && !strings.HasPrefix(lower, "//"):
+		return target, true
+	}
+	return "", false
+}
@@ -0,0 +1,98 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"plain paragraph", "hello world", "<p>hello world</p>"},
+		{"line breaks", "line one\nline two", "<p>line one<br>line two</p>"},
+		{"paragraphs", "one\n\ntwo", "<p>one</p>\n<p>two</p>"},
+		{"escapes html", "<script>alert('x')</script>", "<p>&lt;script&gt;alert(&#39;x&#39;)&lt;/script&gt;</p>"},
+		{"ampersand kept raw", "Tom & Jerry", "<p>Tom &amp; Jerry</p>"},
+		{"bold and italic", "**bold** and *italic*", "<p><strong>bold</strong> and <em>italic</em></p>"},
+		{"inline code", "use `<b>` tags", "<p>use <code>&lt;b&gt;</code> tags</p>"},
+		{"safe link", "[docs](https://go.dev)", `<p><a href="https://go.dev" rel="nofollow ugc noopener">docs</a></p>`},
+		{"relative link", "[home](/posts)", `<p><a href="/posts" rel="nofollow ugc noopener">home</a></p>`},
+		{"javascript link dropped", "[click](javascript:alert(1))", "<p>click</p>"},
+		{"link attribute escaping", `[x](https://a.b/"onmouseover=)`, `<p><a href="https://a.b/&#34;onmouseover=" rel="nofollow ugc noopener">x</a></p>`},
+		{"code block", "```go\nfmt.Println(\"<hi>\")\n```", `<pre><code class="language-go">fmt.Println(&#34;&lt;hi&gt;&#34;)</code></pre>`},
+		{"code block bad language", "```\"><x\ncode\n```", "<pre><code>code</code></pre>"},
+		{"unordered list", "- one\n- two", "<ul><li>one</li><li>two</li></ul>"},
+		{"ordered list", "1. one\n2. two", "<ol><li>one</li><li>two</li></ol>"},
+		{"block quote", "> quoted\n> text", "<blockquote><p>quoted<br>text</p></blockquote>"},
+		{"escaped marker", `\*not italic\*`, "<p>*not italic*</p>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Render(tt.input)
+			if result != tt.expected {
+				t.Fatalf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestRenderNeverEmitsRawTags(t *testing.T) {
+	inputs := []string{
+		"<img src=x onerror=alert(1)>",
+		"[<img src=x>](https://example.com)",
+		"> <iframe src=evil>",
+		"- <svg onload=alert(1)>",
+		"**<b>**",
+	}
+
+	for _, input := range inputs {
+		result := Render(input)
+		if strings.Contains(result, "<img") || strings.Contains(result, "<iframe") ||
+			strings.Contains(result, "<svg") || strings.Contains(result, "<b>") {
+			t.Fatalf("Render(%q) produced unsafe output: %q", input, result)
+		}
+	}
+}
+
+func TestRenderWithMentions(t *testing.T) {
+	mentions := map[string]bool{"alice": true}
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"known user linked", "hi @alice.", `<p>hi <a href="/users/alice" class="mention">@alice</a>.</p>`},
+		{"unknown user plain", "hi @bob", "<p>hi @bob</p>"},
+		{"email not a mention", "mail me@alice.com", "<p>mail me@alice.com</p>"},
+		{"inside code", "`@alice`", "<p><code>@alice</code></p>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := RenderWithMentions(tt.input, mentions)
+			if result != tt.expected {
+				t.Fatalf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestMentions(t *testing.T) {
+	input := "@alice and @bob_1, again @alice\n```\n@carol\n```\n> thanks @dave\n[@erin](https://x.y) me@frank.com @al"
+	expected := []string{"alice", "bob_1", "dave"}
+
+	result := Mentions(input)
+	if len(result) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, result)
+	}
+	for i := range expected {
+		if result[i] != expected[i] {
+			t.Fatalf("Expected %v, got %v", expected, result)
+		}
+	}
+}
@@ -3,8 +3,11 @@ package middleware
 import (
 	"context"
 	"database/sql"
+	"log"
 	"net/http"
 
+	"forum/realtime"
+	"forum/sqlite"
 	"forum/utils"
 )
 
@@ -12,7 +15,8 @@ type contextKey string
 
 const userIDKey contextKey = "userID"
 
-// AuthMiddleware checks if a user is logged in
+// AuthMiddleware checks if a user is logged in and not banned. Banning
+// deletes a user's sessions, but any that slip through are ended here.
 func AuthMiddleware(db *sql.DB, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		userID, err := utils.GetUserIDFromSession(db, r)
@@ -20,12 +24,51 @@ func AuthMiddleware(db *sql.DB, next http.Handler) http.Handler {
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
+		ban, err := sqlite.GetActiveBan(db, userID)
+		if err != nil {
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if ban != nil {
+			if err := sqlite.DeleteAllUserSessions(db, userID); err != nil {
+				log.Printf("Warning: Failed to end sessions of banned user %s: %v", userID, err)
+			}
+			utils.SendBanError(w, ban)
+			return
+		}
+		realtime.Online.Touch(userID)
 
 		ctx := context.WithValue(r.Context(), userIDKey, userID)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// AdminMiddleware lets only signed-in admins through
+func AdminMiddleware(db *sql.DB, next http.Handler) http.Handler {
+	return AuthMiddleware(db, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := GetUserID(r)
+		role, err := sqlite.GetUserRole(db, userID)
+		if err != nil || role != sqlite.RoleAdmin {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}))
+}
+
+// ModeratorMiddleware lets only signed-in moderators and admins through
+func ModeratorMiddleware(db *sql.DB, next http.Handler) http.Handler {
+	return AuthMiddleware(db, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := GetUserID(r)
+		role, err := sqlite.GetUserRole(db, userID)
+		if err != nil || !sqlite.CanModerate(role) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}))
+}
+
 // GetUserID extracts userID from request context
 func GetUserID(r *http.Request) (string, bool) {
 	userID, ok := r.Context().Value(userIDKey).(string)
@@ -5,12 +5,39 @@ import (
 	"os"
 )
 
-// CORS Middleware
-func CORS(next http.Handler) http.Handler {
+// FrontendOrigin is the origin the frontend is served from
+func FrontendOrigin() string {
 	allowedOrigin := os.Getenv("FRONTEND_ORIGIN")
 	if allowedOrigin == "" {
 		allowedOrigin = "http://localhost:8000" // fallback default
 	}
+	return allowedOrigin
+}
+
+// BackendOrigin is the origin this server is reached at, for the links it
+// writes to itself
+func BackendOrigin() string {
+	origin := os.Getenv("BACKEND_ORIGIN")
+	if origin == "" {
+		origin = "http://localhost:8080" // fallback default
+	}
+	return origin
+}
+
+// AllowedOrigin reports whether a browser request from origin may use the
+// session cookie. CORS does not apply to WebSockets, so the chat endpoint
+// checks this itself. An empty origin is a same-origin or non-browser request.
+func AllowedOrigin(origin string) bool {
+	switch origin {
+	case "", FrontendOrigin(), "http://localhost:8000", "http://127.0.0.1:8000":
+		return true
+	}
+	return false
+}
+
+// CORS Middleware
+func CORS(next http.Handler) http.Handler {
+	allowedOrigin := FrontendOrigin()
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// For Docker deployment, requests come through nginx proxy
@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// SiteStats is the admin dashboard's view of the whole forum
+type SiteStats struct {
+	Period        string          `json:"period"` // "day" or "week"
+	From          string          `json:"from"`   // YYYY-MM-DD, start of the first bucket
+	To            string          `json:"to"`
+	Totals        SiteTotals      `json:"totals"`
+	Series        []StatsBucket   `json:"series"` // One entry per day or week of the range
+	DatabaseBytes int64           `json:"database_bytes"`
+	UploadBytes   int64           `json:"upload_bytes"`
+	TopCategories []CategoryCount `json:"top_categories"`
+}
+
+// SiteTotals counts everything on the forum, regardless of the range
+type SiteTotals struct {
+	Users       int `json:"users"`
+	BannedUsers int `json:"banned_users"`
+	ActiveNow   int `json:"active_now"` // Users with a live session
+	Posts       int `json:"posts"`
+	Comments    int `json:"comments"` // Comments and replies
+	Reactions   int `json:"reactions"`
+}
+
+// StatsBucket holds the activity of one day or week, starting on Start
+type StatsBucket struct {
+	Start         string `json:"start"`
+	Registrations int    `json:"registrations"`
+	ActiveUsers   int    `json:"active_users"`
+	Posts         int    `json:"posts"`
+	Comments      int    `json:"comments"`
+	Reactions     int    `json:"reactions"`
+}
+
+// CategoryCount is a category with the number of posts in it
+type CategoryCount struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	PostCount int    `json:"post_count"`
+}
+
+// AdminUser is a user as listed to admins, email included
+type AdminUser struct {
+	ID           string     `json:"id"`
+	Username     string     `json:"username"`
+	Email        string     `json:"email"`
+	Role         string     `json:"role"`
+	CreatedAt    time.Time  `json:"created_at"`
+	LastSeenAt   *time.Time `json:"last_seen_at"`
+	PostCount    int        `json:"post_count"`
+	CommentCount int        `json:"comment_count"`
+	Ban          *Ban       `json:"ban"` // nil unless banned or suspended
+}
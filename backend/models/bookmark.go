@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// BookmarkFolder is a user-named folder of bookmarks
+type BookmarkFolder struct {
+	ID            int       `json:"id"`
+	Name          string    `json:"name"`
+	BookmarkCount int       `json:"bookmark_count"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Bookmark is a saved post or comment. Exactly one of Post and Comment is
+// set; FolderID is nil for unfiled bookmarks.
+type Bookmark struct {
+	ID        int          `json:"id"`
+	FolderID  *int         `json:"folder_id"`
+	Note      string       `json:"note"`
+	CreatedAt time.Time    `json:"created_at"`
+	Post      *Post        `json:"post,omitempty"`
+	Comment   *UserComment `json:"comment,omitempty"`
+}
@@ -1,6 +1,27 @@
 package models
 
+import "time"
+
 type Category struct {
-	ID   int    `json:"id" gorm:"primaryKey"`
-	Name string `json:"name" validate:"required" gorm:"unique;not null"`
+	ID          int    `json:"id" gorm:"primaryKey"`
+	Name        string `json:"name" validate:"required" gorm:"unique;not null"`
+	Slug        string `json:"slug" gorm:"unique"`
+	Description string `json:"description"`
+	Color       string `json:"color"`      // "#rrggbb", or empty for the default
+	ParentID    *int   `json:"parent_id"`  // nil for top-level categories
+	SortOrder   int    `json:"sort_order"` // ascending, then by name
+	Archived    bool   `json:"archived"`   // hidden from listings, no new posts
+
+	// Stats is only filled in by the category listing
+	Stats *CategoryStats `json:"stats,omitempty" gorm:"-"`
+}
+
+// CategoryStats summarises the activity in a category
+type CategoryStats struct {
+	PostCount       int        `json:"post_count"`
+	CommentCount    int        `json:"comment_count"` // comments and replies
+	SubscriberCount int        `json:"subscriber_count"`
+	LastActivityAt  *time.Time `json:"last_activity_at"` // newest post, comment or reply
+	LatestPostID    *int       `json:"latest_post_id"`
+	LatestPostTitle string     `json:"latest_post_title"`
 }
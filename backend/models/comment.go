@@ -9,6 +9,10 @@ type Comment struct {
 	ProfileAvatar string         `json:"avatar_url"`
 	PostID        int            `json:"post_id,omitempty"`
 	Content       string         `json:"content" validate:"required" gorm:"not null"`
+	ContentHTML   string         `json:"content_html" gorm:"-"` // Rendered from Content, never stored
+	IsAccepted    bool           `json:"is_accepted" gorm:"-"`  // The accepted answer of a question
+	LikeCount     int            `json:"like_count"`
+	DislikeCount  int            `json:"dislike_count"`
 	CreatedAt     time.Time      `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt     time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
 	Replies       []ReplyComment `json:"replies,omitempty" gorm:"-"`
@@ -20,7 +24,21 @@ type ReplyComment struct {
 	UserName        string    `json:"username"`
 	ProfileAvatar   string    `json:"avatar_url"`
 	ParentCommentID int       `json:"parent_comment_id,omitempty"`
+	PostID          int       `json:"post_id,omitempty" gorm:"-"` // Post of the parent comment
 	Content         string    `json:"content" validate:"required" gorm:"not null"`
+	ContentHTML     string    `json:"content_html" gorm:"-"` // Rendered from Content, never stored
 	CreatedAt       time.Time `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt       time.Time `json:"updated_at" gorm:"autoUpdateTime"`
 }
+
+// UserComment is a comment or reply listed on its author's profile
+type UserComment struct {
+	ID              int       `json:"id"`
+	Kind            string    `json:"kind"` // "comment" or "reply"
+	PostID          int       `json:"post_id"`
+	PostTitle       string    `json:"post_title"`
+	ParentCommentID *int      `json:"parent_comment_id,omitempty"`
+	Content         string    `json:"content"`
+	ContentHTML     string    `json:"content_html"`
+	CreatedAt       time.Time `json:"created_at"`
+}
@@ -4,5 +4,26 @@ type Like struct {
 	UserID    string `json:"user_id" validate:"required"`
 	PostID    *int   `json:"post_id,omitempty"`
 	CommentID *int   `json:"comment_id,omitempty"`
-	Type      string `json:"type" validate:"required,oneof=like dislike"` // must be "like" or "dislike"
+	Type      string `json:"type" validate:"required"` // name of a reaction type, e.g. "like"
+}
+
+// ReactionType is a reaction users can leave on posts and comments. Built-in
+// types (like and dislike) cannot be disabled.
+type ReactionType struct {
+	Name      string `json:"name"`
+	Emoji     string `json:"emoji"`
+	Label     string `json:"label"`
+	SortOrder int    `json:"sort_order"`
+	BuiltIn   bool   `json:"built_in"`
+	Enabled   bool   `json:"enabled"`
+}
+
+// ReactionSummary holds the reaction counts of a post or comment. Likes and
+// Dislikes repeat the built-in counts for older clients; Counts has an entry
+// for every enabled reaction type.
+type ReactionSummary struct {
+	Likes           int            `json:"likes"`
+	Dislikes        int            `json:"dislikes"`
+	Counts          map[string]int `json:"counts"`
+	ViewerReactions []string       `json:"viewer_reactions"`
 }
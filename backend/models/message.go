@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Message is a direct message within a conversation
+type Message struct {
+	ID             int       `json:"id"`
+	ConversationID int       `json:"conversation_id"`
+	SenderID       string    `json:"sender_id"`
+	Content        string    `json:"content"`
+	ContentHTML    string    `json:"content_html"` // Rendered from Content, never stored
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// Conversation is a one-to-one message thread, seen from one participant
+type Conversation struct {
+	ID          int         `json:"id"`
+	OtherUser   UserSummary `json:"other_user"`
+	LastMessage *Message    `json:"last_message,omitempty"`
+	UnreadCount int         `json:"unread_count"`
+	// The other participant has read every message up to this ID
+	OtherLastReadID int       `json:"other_last_read_id"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
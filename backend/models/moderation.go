@@ -0,0 +1,61 @@
+package models
+
+import "time"
+
+// Ban is a ban or, when Until is set, a suspension that lifts at Until
+type Ban struct {
+	Reason   string     `json:"reason"`
+	BannedAt time.Time  `json:"banned_at"`
+	Until    *time.Time `json:"until"` // nil for a permanent ban
+}
+
+// ModerationAction is an entry of the moderation log
+type ModerationAction struct {
+	ID          int        `json:"id"`
+	UserID      string     `json:"user_id"`
+	Username    string     `json:"username"`
+	ModeratorID *string    `json:"moderator_id"` // nil when a suspension expired
+	Moderator   string     `json:"moderator"`
+	Action      string     `json:"action"` // "ban", "suspend", "lift" or "expire"
+	Reason      string     `json:"reason"`
+	Until       *time.Time `json:"until"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// QueueItem is a post, comment or reply held back for a moderator to review
+type QueueItem struct {
+	ID          int          `json:"id"`
+	Kind        string       `json:"kind"` // "post", "comment" or "reply"
+	UserID      string       `json:"user_id"`
+	Username    string       `json:"username"`
+	TargetID    *int         `json:"target_id"` // the post commented on or edited, or the comment replied to
+	Title       string       `json:"title"`
+	Content     string       `json:"content"`
+	Post        *PostDetails `json:"post,omitempty"`
+	Reasons     []string     `json:"reasons"`
+	Status      string       `json:"status"` // "pending", "approved" or "rejected"
+	ReviewerID  *string      `json:"reviewer_id"`
+	ReviewedAt  *time.Time   `json:"reviewed_at"`
+	PublishedID *int         `json:"published_id"`
+	CreatedAt   time.Time    `json:"created_at"`
+}
+
+// PostDetails is what a new post carries besides its title and content
+type PostDetails struct {
+	CategoryIDs []int    `json:"category_ids"`
+	Tags        []string `json:"tags"`
+	PostType    string   `json:"post_type"`
+	ImageURL    string   `json:"image_url"`
+	Poll        *Poll    `json:"poll,omitempty"`
+	// Status is "draft" or "scheduled" for posts not published right away
+	Status    string     `json:"status,omitempty"`
+	PublishAt *time.Time `json:"publish_at,omitempty"`
+}
+
+// BannedWord is a word content screening replaces or blocks
+type BannedWord struct {
+	Word        string    `json:"word"`
+	Action      string    `json:"action"`      // "replace" or "block"
+	Replacement string    `json:"replacement"` // empty masks the word with *
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// Notification tells a user that someone interacted with their content.
+// Repeated events on the same target are collapsed into one notification:
+// EventCount says how many, and the actor fields show the latest one.
+type Notification struct {
+	ID            int       `json:"id"`
+	Type          string    `json:"type"` // comment, reply, reaction or mention
+	ActorID       string    `json:"actor_id"`
+	ActorUsername string    `json:"actor_username"`
+	ActorAvatar   string    `json:"actor_avatar_url"`
+	EventCount    int       `json:"event_count"`
+	PostID        *int      `json:"post_id,omitempty"`
+	CommentID     *int      `json:"comment_id,omitempty"`
+	ReplyID       *int      `json:"reply_id,omitempty"`
+	IsRead        bool      `json:"is_read"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
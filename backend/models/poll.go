@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Poll is a question attached to a post. Vote counts are nil while the
+// results are hidden from the viewer.
+type Poll struct {
+	ID             int          `json:"id"`
+	PostID         int          `json:"post_id"`
+	Question       string       `json:"question"`
+	MultipleChoice bool         `json:"multiple_choice"`
+	HideResults    bool         `json:"hide_results"` // until the viewer votes or the poll closes
+	ClosesAt       *time.Time   `json:"closes_at"`
+	Closed         bool         `json:"closed"`
+	VoterCount     int          `json:"voter_count"`
+	Options        []PollOption `json:"options"`
+	ViewerVote     []int        `json:"viewer_vote"` // option IDs; empty if the viewer has not voted
+	CreatedAt      time.Time    `json:"created_at"`
+}
+
+// PollOption is one answer of a poll
+type PollOption struct {
+	ID        int    `json:"id"`
+	Text      string `json:"text"`
+	VoteCount *int   `json:"vote_count"`
+}
@@ -3,15 +3,29 @@ package models
 import "time"
 
 type Post struct {
-	ID            int       `json:"id" gorm:"primaryKey"`
-	ProfileAvatar string    `json:"avatar_url"`
-	Title         string    `json:"title" validate:"required" gorm:"not null"`
-	Content       string    `json:"content" validate:"required" gorm:"not null"`
-	Username      string    `json:"username" gorm:"-"`
-	UserID        string    `json:"user_id" gorm:"not null"`
-	CategoryIDs   []int     `json:"category_ids" gorm:"-"`   // For multiple categories
-	CategoryNames []string  `json:"category_names" gorm:"-"` // Category names for display
-	ImageURL      *string   `json:"image_url,omitempty"`
-	CreatedAt     time.Time `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt     time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+	ID                int        `json:"id" gorm:"primaryKey"`
+	ProfileAvatar     string     `json:"avatar_url"`
+	Title             string     `json:"title" validate:"required" gorm:"not null"`
+	Content           string     `json:"content" validate:"required" gorm:"not null"`
+	ContentHTML       string     `json:"content_html" gorm:"-"` // Rendered from Content, never stored
+	Username          string     `json:"username" gorm:"-"`
+	UserID            string     `json:"user_id" gorm:"not null"`
+	CategoryIDs       []int      `json:"category_ids" gorm:"-"`   // For multiple categories
+	CategoryNames     []string   `json:"category_names" gorm:"-"` // Category names for display
+	Tags              []string   `json:"tags" gorm:"-"`           // Normalized tag names, sorted
+	ImageURL          *string    `json:"image_url,omitempty"`
+	IsBookmarked      bool       `json:"is_bookmarked" gorm:"-"`  // Whether the current viewer saved it
+	Locked            bool       `json:"locked"`                  // No new comments or poll votes
+	PostType          string     `json:"post_type"`               // "discussion" or "question"
+	AcceptedCommentID *int       `json:"accepted_comment_id"`     // Questions only
+	Poll              *Poll      `json:"poll,omitempty" gorm:"-"` // Only loaded for a single post
+	LikeCount         int        `json:"like_count"`
+	DislikeCount      int        `json:"dislike_count"`
+	ViewCount         int        `json:"view_count"`
+	LastReadAt        *time.Time `json:"last_read_at,omitempty" gorm:"-"`    // When the viewer last opened it; absent if never
+	UnreadComments    int        `json:"unread_comments,omitempty" gorm:"-"` // Comments and replies since LastReadAt
+	Status            string     `json:"status"`                             // "draft", "scheduled" or "published"
+	PublishAt         *time.Time `json:"publish_at"`                         // When a scheduled post goes out
+	CreatedAt         time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt         time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
 }
@@ -0,0 +1,29 @@
+package models
+
+// AuthorStats summarizes how a user's content was received between two
+// dates (inclusive, UTC). Reactions by the author themself are not counted.
+type AuthorStats struct {
+	From          string         `json:"from"` // YYYY-MM-DD
+	To            string         `json:"to"`
+	Reactions     map[string]int `json:"reactions"` // Totals per reaction type
+	ReactionTotal int            `json:"reaction_total"`
+	Comments      int            `json:"comments"` // Comments others left on the author's posts
+	Days          []DayStats     `json:"days"`     // One entry per day of the range
+	TopPosts      []PostStats    `json:"top_posts"`
+}
+
+// DayStats holds the reactions and comments received on one day
+type DayStats struct {
+	Date          string         `json:"date"`
+	Reactions     map[string]int `json:"reactions"`
+	ReactionTotal int            `json:"reaction_total"`
+	Comments      int            `json:"comments"`
+}
+
+// PostStats holds the reactions and comments a post received over a range
+type PostStats struct {
+	ID            int    `json:"id"`
+	Title         string `json:"title"`
+	ReactionCount int    `json:"reaction_count"`
+	CommentCount  int    `json:"comment_count"`
+}
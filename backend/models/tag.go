@@ -0,0 +1,8 @@
+package models
+
+// Tag is a free-form post tag with the number of posts using it
+type Tag struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	PostCount int    `json:"post_count"`
+}
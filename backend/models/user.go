@@ -10,4 +10,44 @@ type User struct {
 	AvatarURL    string    `json:"avatar_url" gorm:"default:'/static/default-avatar.png'"` // ✅ New field
 	CreatedAt    time.Time `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt    time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+
+	// Presence, left empty when the user hides their online status
+	Role       string     `json:"role,omitempty" gorm:"-"` // "user" or "admin"; only sent to the user themselves
+	Online     bool       `json:"online" gorm:"-"`
+	LastSeenAt *time.Time `json:"last_seen_at,omitempty" gorm:"-"`
+}
+
+// UserSummary is the public subset of a user shown next to content
+type UserSummary struct {
+	ID        string `json:"id"`
+	Username  string `json:"username"`
+	AvatarURL string `json:"avatar_url"`
+}
+
+// Profile is the public view of a user; it never includes the email
+type Profile struct {
+	ID             string    `json:"id"`
+	Username       string    `json:"username"`
+	AvatarURL      string    `json:"avatar_url"`
+	Bio            string    `json:"bio"`
+	JoinedAt       time.Time `json:"joined_at"`
+	PostCount      int       `json:"post_count"`
+	CommentCount   int       `json:"comment_count"` // comments and replies
+	Karma          int       `json:"karma"`         // likes minus dislikes received from others
+	FollowerCount  int       `json:"follower_count"`
+	FollowingCount int       `json:"following_count"`
+	IsFollowing    bool      `json:"is_following"` // whether the viewer follows this user
+
+	// Presence, left empty when the user hides their online status
+	Online     bool       `json:"online"`
+	LastSeenAt *time.Time `json:"last_seen_at,omitempty"`
+}
+
+// PrivacySettings controls what other users can see about a user. The
+// user always sees everything on their own profile.
+type PrivacySettings struct {
+	ShowOnlineStatus bool `json:"show_online_status"`
+	ShowPosts        bool `json:"show_posts"`
+	ShowComments     bool `json:"show_comments"`
+	ShowLikedPosts   bool `json:"show_liked_posts"`
 }
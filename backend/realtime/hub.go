@@ -0,0 +1,225 @@
+// Package realtime is the in-process pub/sub hub behind the live event
+// stream. Handlers publish events after a write succeeds; every open stream
+// holds a Subscriber and receives the events it is allowed to see.
+package realtime
+
+import (
+	"sync"
+	"time"
+)
+
+// Event types
+const (
+	EventPostCreated     = "post.created"
+	EventCommentCreated  = "comment.created"
+	EventReplyCreated    = "reply.created"
+	EventReactionUpdated = "reaction.updated"
+	EventNotification    = "notification"
+	EventMessageCreated  = "message.created"
+	EventMessageTyping   = "message.typing"
+	EventMessageRead     = "message.read"
+)
+
+// Event is a single message on the stream. UserID and PostID scope who
+// receives it; an event with neither set goes to everyone.
+type Event struct {
+	ID     uint64 `json:"id"`
+	Type   string `json:"type"`
+	UserID string `json:"-"` // private: only this user's streams
+	PostID int    `json:"-"` // only streams subscribed to this post
+	Data   any    `json:"data"`
+}
+
+// subscriberBuffer is how many events a stream may fall behind before it is
+// dropped. A dropped client reconnects and catches up from the event log.
+const subscriberBuffer = 64
+
+// Subscriber is one open stream
+type Subscriber struct {
+	UserID  string
+	postIDs map[int]bool
+	events  chan Event
+}
+
+// Events delivers the subscriber's events. It is closed when the subscriber
+// is dropped for being too slow or the hub shuts down.
+func (s *Subscriber) Events() <-chan Event {
+	return s.events
+}
+
+// Wants reports whether the subscriber should receive e
+func (s *Subscriber) Wants(e Event) bool {
+	if e.UserID != "" {
+		return e.UserID == s.UserID
+	}
+	if e.PostID != 0 {
+		return s.postIDs[e.PostID]
+	}
+	return true
+}
+
+// Hub fans published events out to subscribers and keeps the most recent
+// ones so reconnecting clients can resume with Last-Event-ID
+type Hub struct {
+	mu      sync.Mutex
+	nextID  uint64
+	log     []Event
+	logSize int
+	subs    map[*Subscriber]struct{}
+	users   map[string]int // open subscriptions per logged-in user
+	closed  bool
+}
+
+// NewHub creates a hub that remembers the last logSize events. IDs start
+// from the current time so they keep increasing across restarts, and a
+// Last-Event-ID from a previous run is recognised as too old to resume.
+func NewHub(logSize int) *Hub {
+	return &Hub{
+		nextID:  uint64(time.Now().UnixMicro()),
+		logSize: logSize,
+		subs:    make(map[*Subscriber]struct{}),
+		users:   make(map[string]int),
+	}
+}
+
+// Default is the hub used by the HTTP handlers
+var Default = NewHub(1000)
+
+// Publish sends an event through the default hub
+func Publish(e Event) Event {
+	return Default.Publish(e)
+}
+
+// Publish assigns e the next ID, records it in the log and delivers it to
+// every interested subscriber without blocking
+func (h *Hub) Publish(e Event) Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return e
+	}
+
+	e.ID = h.nextID
+	h.nextID++
+
+	h.log = append(h.log, e)
+	if len(h.log) > h.logSize {
+		h.log = h.log[len(h.log)-h.logSize:]
+	}
+
+	for s := range h.subs {
+		if !s.Wants(e) {
+			continue
+		}
+		select {
+		case s.events <- e:
+		default:
+			// Too far behind: drop it rather than stall everyone else
+			h.remove(s)
+		}
+	}
+	return e
+}
+
+// Subscribe opens a subscription for userID ("" for anonymous clients) to
+// public events, that user's private events and activity on postIDs
+func (h *Hub) Subscribe(userID string, postIDs []int) *Subscriber {
+	s := &Subscriber{
+		UserID:  userID,
+		postIDs: make(map[int]bool, len(postIDs)),
+		events:  make(chan Event, subscriberBuffer),
+	}
+	for _, id := range postIDs {
+		s.postIDs[id] = true
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		close(s.events)
+		return s
+	}
+	h.subs[s] = struct{}{}
+	if userID != "" {
+		h.users[userID]++
+	}
+	return s
+}
+
+// Unsubscribe removes a subscriber; it is safe to call more than once
+func (h *Hub) Unsubscribe(s *Subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subs[s]; ok {
+		h.remove(s)
+	}
+}
+
+// remove drops a subscriber; h.mu must be held
+func (h *Hub) remove(s *Subscriber) {
+	delete(h.subs, s)
+	close(s.events)
+	if s.UserID != "" {
+		if h.users[s.UserID]--; h.users[s.UserID] == 0 {
+			delete(h.users, s.UserID)
+		}
+	}
+}
+
+// ConnectedUsers returns the IDs of users with at least one open subscription
+func (h *Hub) ConnectedUsers() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ids := make([]string, 0, len(h.users))
+	for id := range h.users {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// IsConnected reports whether userID has an open subscription
+func (h *Hub) IsConnected(userID string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.users[userID] > 0
+}
+
+// Since returns the logged events after lastID that s wants. ok is false
+// when events after lastID have already been evicted from the log (or
+// lastID is not from this hub), in which case the client must refetch.
+func (h *Hub) Since(lastID uint64, s *Subscriber) (events []Event, ok bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	oldest := h.nextID
+	if len(h.log) > 0 {
+		oldest = h.log[0].ID
+	}
+	if lastID+1 < oldest || lastID >= h.nextID {
+		return nil, false
+	}
+
+	for _, e := range h.log {
+		if e.ID > lastID && s.Wants(e) {
+			events = append(events, e)
+		}
+	}
+	return events, true
+}
+
+// Close ends every subscription and stops accepting events. It is called
+// when the server shuts down so open streams return promptly.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return
+	}
+	h.closed = true
+	for s := range h.subs {
+		close(s.events)
+	}
+	h.subs = make(map[*Subscriber]struct{})
+	h.users = make(map[string]int)
+}
@@ -0,0 +1,135 @@
+package realtime
+
+import (
+	"testing"
+)
+
+// drain returns the events currently buffered for s
+func drain(s *Subscriber) []Event {
+	var events []Event
+	for {
+		select {
+		case e, ok := <-s.Events():
+			if !ok {
+				return events
+			}
+			events = append(events, e)
+		default:
+			return events
+		}
+	}
+}
+
+func TestHubPublish(t *testing.T) {
+	h := NewHub(10)
+	anon := h.Subscribe("", nil)
+	alice := h.Subscribe("alice", []int{7})
+
+	h.Publish(Event{Type: EventPostCreated})
+	h.Publish(Event{Type: EventCommentCreated, PostID: 7})
+	h.Publish(Event{Type: EventCommentCreated, PostID: 8})
+	h.Publish(Event{Type: EventNotification, UserID: "alice"})
+	h.Publish(Event{Type: EventNotification, UserID: "bob"})
+
+	t.Run("anonymous only gets public events", func(t *testing.T) {
+		events := drain(anon)
+		if len(events) != 1 || events[0].Type != EventPostCreated {
+			t.Errorf("Expected only the public event, got %+v", events)
+		}
+	})
+
+	t.Run("subscriber gets post and private events", func(t *testing.T) {
+		events := drain(alice)
+		if len(events) != 3 {
+			t.Fatalf("Expected 3 events, got %+v", events)
+		}
+		if events[1].PostID != 7 || events[2].UserID != "alice" {
+			t.Errorf("Unexpected events: %+v", events)
+		}
+		for i := 1; i < len(events); i++ {
+			if events[i].ID <= events[i-1].ID {
+				t.Errorf("Event IDs should increase, got %d after %d", events[i].ID, events[i-1].ID)
+			}
+		}
+	})
+}
+
+func TestHubSince(t *testing.T) {
+	h := NewHub(3)
+	s := h.Subscribe("alice", nil)
+
+	var ids []uint64
+	for i := 0; i < 5; i++ {
+		ids = append(ids, h.Publish(Event{Type: EventPostCreated}).ID)
+	}
+	h.Publish(Event{Type: EventNotification, UserID: "bob"})
+
+	t.Run("resume within the log", func(t *testing.T) {
+		events, ok := h.Since(ids[3], s)
+		if !ok {
+			t.Fatal("Expected resume to be possible")
+		}
+		if len(events) != 1 || events[0].ID != ids[4] {
+			t.Errorf("Expected only event %d, got %+v", ids[4], events)
+		}
+	})
+
+	t.Run("up to date", func(t *testing.T) {
+		latest := ids[4] + 1 // bob's notification
+		events, ok := h.Since(latest, s)
+		if !ok || len(events) != 0 {
+			t.Errorf("Expected nothing to replay, got %+v (ok=%v)", events, ok)
+		}
+	})
+
+	t.Run("evicted events", func(t *testing.T) {
+		if _, ok := h.Since(ids[0], s); ok {
+			t.Error("Expected resume to fail once events were evicted")
+		}
+	})
+
+	t.Run("unknown id", func(t *testing.T) {
+		if _, ok := h.Since(ids[4]+100, s); ok {
+			t.Error("Expected resume to fail for an ID from the future")
+		}
+	})
+}
+
+func TestHubSlowSubscriber(t *testing.T) {
+	h := NewHub(10)
+	slow := h.Subscribe("", nil)
+
+	for i := 0; i <= subscriberBuffer; i++ {
+		h.Publish(Event{Type: EventPostCreated})
+	}
+
+	events := drain(slow)
+	if len(events) != subscriberBuffer {
+		t.Errorf("Expected %d buffered events, got %d", subscriberBuffer, len(events))
+	}
+	if _, ok := <-slow.Events(); ok {
+		t.Error("Expected the slow subscriber's channel to be closed")
+	}
+
+	// Unsubscribing a dropped subscriber must not panic
+	h.Unsubscribe(slow)
+}
+
+func TestHubClose(t *testing.T) {
+	h := NewHub(10)
+	s := h.Subscribe("alice", nil)
+
+	h.Close()
+	if _, ok := <-s.Events(); ok {
+		t.Error("Expected the channel to be closed")
+	}
+
+	h.Unsubscribe(s)
+	h.Close()
+
+	late := h.Subscribe("bob", nil)
+	if _, ok := <-late.Events(); ok {
+		t.Error("Expected subscriptions after Close to be closed")
+	}
+	h.Publish(Event{Type: EventPostCreated})
+}
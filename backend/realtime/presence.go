@@ -0,0 +1,124 @@
+package realtime
+
+import (
+	"sync"
+	"time"
+)
+
+// Presence tracks who is online without a database write per request. A user
+// is online while they have a stream or chat connection open on the hub, or
+// for the online window after their last authenticated request. Last-seen
+// times are kept in memory and written out in batches by Flush.
+type Presence struct {
+	hub    *Hub
+	window time.Duration
+
+	mu       sync.Mutex
+	lastSeen map[string]time.Time
+	dirty    map[string]bool // changed since the last Flush
+}
+
+// NewPresence creates a tracker that counts hub connections as activity and
+// considers users offline once window has passed since they were last seen
+func NewPresence(hub *Hub, window time.Duration) *Presence {
+	return &Presence{
+		hub:      hub,
+		window:   window,
+		lastSeen: make(map[string]time.Time),
+		dirty:    make(map[string]bool),
+	}
+}
+
+// Online is the tracker fed by the auth middleware and the default hub
+var Online = NewPresence(Default, 5*time.Minute)
+
+// Touch records activity by userID
+func (p *Presence) Touch(userID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.lastSeen[userID] = time.Now().UTC()
+	p.dirty[userID] = true
+}
+
+// LastSeen returns when userID was last active according to memory. It is
+// the current time while they are connected and false if they have not been
+// seen since their entry expired (check the database instead).
+func (p *Presence) LastSeen(userID string) (time.Time, bool) {
+	if p.hub.IsConnected(userID) {
+		return time.Now().UTC(), true
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	seen, ok := p.lastSeen[userID]
+	return seen, ok
+}
+
+// IsOnline reports whether userID is connected or was active recently
+func (p *Presence) IsOnline(userID string) bool {
+	seen, ok := p.LastSeen(userID)
+	return ok && time.Since(seen) < p.window
+}
+
+// OnlineUserIDs returns every user who is currently online
+func (p *Presence) OnlineUserIDs() []string {
+	online := make(map[string]bool)
+	for _, id := range p.hub.ConnectedUsers() {
+		online[id] = true
+	}
+
+	p.mu.Lock()
+	cutoff := time.Now().Add(-p.window)
+	for id, seen := range p.lastSeen {
+		if seen.After(cutoff) {
+			online[id] = true
+		}
+	}
+	p.mu.Unlock()
+
+	ids := make([]string, 0, len(online))
+	for id := range online {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Flush hands the last-seen times that changed since the previous call to
+// save, counting open connections as activity now. If save fails the times
+// are kept for the next attempt. Entries older than the online window are
+// then forgotten so memory only holds recently active users.
+func (p *Presence) Flush(save func(map[string]time.Time) error) error {
+	now := time.Now().UTC()
+	connected := p.hub.ConnectedUsers()
+
+	p.mu.Lock()
+	for _, id := range connected {
+		p.lastSeen[id] = now
+		p.dirty[id] = true
+	}
+	changes := make(map[string]time.Time, len(p.dirty))
+	for id := range p.dirty {
+		changes[id] = p.lastSeen[id]
+	}
+	p.dirty = make(map[string]bool)
+	p.mu.Unlock()
+
+	var err error
+	if len(changes) > 0 {
+		err = save(changes)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		for id := range changes {
+			p.dirty[id] = true
+		}
+	}
+	cutoff := now.Add(-p.window)
+	for id, seen := range p.lastSeen {
+		if !p.dirty[id] && seen.Before(cutoff) {
+			delete(p.lastSeen, id)
+		}
+	}
+	return err
+}
@@ -0,0 +1,81 @@
+package realtime
+
+import (
+	"errors"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestPresence(t *testing.T) {
+	h := NewHub(10)
+	p := NewPresence(h, time.Minute)
+
+	p.Touch("alice")
+	sub := h.Subscribe("bob", nil)
+
+	t.Run("requests and connections count as online", func(t *testing.T) {
+		ids := p.OnlineUserIDs()
+		sort.Strings(ids)
+		if len(ids) != 2 || ids[0] != "alice" || ids[1] != "bob" {
+			t.Errorf("Expected alice and bob online, got %v", ids)
+		}
+		if p.IsOnline("carol") {
+			t.Error("Expected carol to be offline")
+		}
+	})
+
+	t.Run("flush saves changes once", func(t *testing.T) {
+		var saved map[string]time.Time
+		if err := p.Flush(func(changes map[string]time.Time) error {
+			saved = changes
+			return nil
+		}); err != nil {
+			t.Fatalf("Flush failed: %v", err)
+		}
+		if len(saved) != 2 {
+			t.Errorf("Expected 2 changes, got %v", saved)
+		}
+
+		// bob is still connected, so only bob is saved again
+		saved = nil
+		p.Flush(func(changes map[string]time.Time) error {
+			saved = changes
+			return nil
+		})
+		if _, ok := saved["bob"]; len(saved) != 1 || !ok {
+			t.Errorf("Expected only bob, got %v", saved)
+		}
+	})
+
+	t.Run("failed flush is retried", func(t *testing.T) {
+		h.Unsubscribe(sub)
+		p.Touch("alice")
+		if err := p.Flush(func(map[string]time.Time) error { return errors.New("db down") }); err == nil {
+			t.Fatal("Expected the save error to be returned")
+		}
+
+		var saved map[string]time.Time
+		p.Flush(func(changes map[string]time.Time) error {
+			saved = changes
+			return nil
+		})
+		if _, ok := saved["alice"]; !ok {
+			t.Errorf("Expected alice to be saved on retry, got %v", saved)
+		}
+	})
+
+	t.Run("inactive users expire", func(t *testing.T) {
+		p.mu.Lock()
+		p.lastSeen["alice"] = time.Now().Add(-2 * time.Minute)
+		p.mu.Unlock()
+
+		if p.IsOnline("alice") {
+			t.Error("Expected alice to be offline after the window")
+		}
+		p.Flush(func(map[string]time.Time) error { return nil })
+		if _, ok := p.LastSeen("alice"); ok {
+			t.Error("Expected the expired entry to be forgotten")
+		}
+	})
+}
@@ -0,0 +1,89 @@
+package realtime
+
+import (
+	"sync"
+	"time"
+)
+
+// viewKey is one viewer of one post
+type viewKey struct {
+	postID int
+	viewer string
+}
+
+// Views counts post views without a database write per request. A viewer is
+// counted once per post within the window; the counts are kept in memory and
+// added to the database in batches by Flush.
+type Views struct {
+	window time.Duration
+
+	mu      sync.Mutex
+	seen    map[viewKey]time.Time // when each viewer was last counted
+	pending map[int]int           // views per post since the last Flush
+}
+
+// NewViews creates a counter that ignores repeat views of a post by the same
+// viewer within window
+func NewViews(window time.Duration) *Views {
+	return &Views{
+		window:  window,
+		seen:    make(map[viewKey]time.Time),
+		pending: make(map[int]int),
+	}
+}
+
+// PostViews is the counter fed by the post handler
+var PostViews = NewViews(6 * time.Hour)
+
+// Record counts a view of postID by viewer (a user ID, or a fingerprint of
+// a signed-out visitor) and reports whether it was counted
+func (v *Views) Record(postID int, viewer string) bool {
+	key := viewKey{postID, viewer}
+	now := time.Now()
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if at, ok := v.seen[key]; ok && now.Sub(at) < v.window {
+		return false
+	}
+	v.seen[key] = now
+	v.pending[postID]++
+	return true
+}
+
+// Pending returns the views of postID not yet flushed
+func (v *Views) Pending(postID int) int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.pending[postID]
+}
+
+// Flush hands the views counted since the previous call to save. If save
+// fails the counts are kept for the next attempt. Viewers counted longer ago
+// than the window are then forgotten.
+func (v *Views) Flush(save func(map[int]int) error) error {
+	v.mu.Lock()
+	counts := v.pending
+	v.pending = make(map[int]int)
+	v.mu.Unlock()
+
+	var err error
+	if len(counts) > 0 {
+		err = save(counts)
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if err != nil {
+		for postID, n := range counts {
+			v.pending[postID] += n
+		}
+	}
+	cutoff := time.Now().Add(-v.window)
+	for key, at := range v.seen {
+		if at.Before(cutoff) {
+			delete(v.seen, key)
+		}
+	}
+	return err
+}
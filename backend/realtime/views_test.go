@@ -0,0 +1,74 @@
+package realtime
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestViews(t *testing.T) {
+	v := NewViews(time.Hour)
+
+	t.Run("repeat views are counted once", func(t *testing.T) {
+		if !v.Record(1, "alice") || v.Record(1, "alice") {
+			t.Error("Expected only alice's first view to count")
+		}
+		v.Record(1, "bob")
+		v.Record(2, "alice")
+		if n := v.Pending(1); n != 2 {
+			t.Errorf("Expected 2 pending views of post 1, got %d", n)
+		}
+	})
+
+	t.Run("flush saves counts once", func(t *testing.T) {
+		var saved map[int]int
+		if err := v.Flush(func(counts map[int]int) error {
+			saved = counts
+			return nil
+		}); err != nil {
+			t.Fatalf("Flush failed: %v", err)
+		}
+		if len(saved) != 2 || saved[1] != 2 || saved[2] != 1 {
+			t.Errorf("Unexpected counts: %v", saved)
+		}
+
+		called := false
+		v.Flush(func(map[int]int) error {
+			called = true
+			return nil
+		})
+		if called {
+			t.Error("Expected nothing to save")
+		}
+		if v.Record(1, "alice") {
+			t.Error("Expected alice to stay counted after a flush")
+		}
+	})
+
+	t.Run("failed flush is retried", func(t *testing.T) {
+		v.Record(3, "carol")
+		if err := v.Flush(func(map[int]int) error { return errors.New("db down") }); err == nil {
+			t.Fatal("Expected the save error to be returned")
+		}
+		v.Record(3, "dave")
+
+		var saved map[int]int
+		v.Flush(func(counts map[int]int) error {
+			saved = counts
+			return nil
+		})
+		if saved[3] != 2 {
+			t.Errorf("Expected both views of post 3 to be saved, got %v", saved)
+		}
+	})
+
+	t.Run("viewers are forgotten after the window", func(t *testing.T) {
+		short := NewViews(time.Millisecond)
+		short.Record(1, "alice")
+		time.Sleep(2 * time.Millisecond)
+		short.Flush(func(map[int]int) error { return nil })
+		if !short.Record(1, "alice") {
+			t.Error("Expected a view after the window to count again")
+		}
+	})
+}
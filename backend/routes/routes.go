@@ -21,6 +21,32 @@ func SetupRoutes(db *sql.DB) http.Handler {
 	// Fetch user data
 	mux.Handle("/api/user", middleware.AuthMiddleware(db, HandlerWrapper(db, handlers.GetUser)))
 
+	// Username suggestions for @mentions (protected)
+	mux.Handle("/api/users/autocomplete", middleware.AuthMiddleware(db, HandlerWrapper(db, handlers.AutocompleteUsers)))
+
+	// Presence: who is online (public) and the privacy setting hiding it (protected)
+	mux.HandleFunc("/api/users/online", HandlerWrapper(db, handlers.GetOnlineUsers))
+	mux.Handle("/api/user/privacy", middleware.AuthMiddleware(db, HandlerWrapper(db, handlers.PrivacySettings)))
+
+	// Public profiles and activity (public), editing your own profile (protected)
+	mux.HandleFunc("/api/users/{username}", HandlerWrapper(db, handlers.GetProfile))
+	mux.HandleFunc("/api/users/{username}/posts", HandlerWrapper(db, handlers.GetProfilePosts))
+	mux.HandleFunc("/api/users/{username}/comments", HandlerWrapper(db, handlers.GetProfileComments))
+	mux.HandleFunc("/api/users/{username}/liked", HandlerWrapper(db, handlers.GetProfileLiked))
+	mux.HandleFunc("/api/users/{username}/followers", HandlerWrapper(db, handlers.GetProfileFollowers))
+	mux.HandleFunc("/api/users/{username}/following", HandlerWrapper(db, handlers.GetProfileFollowing))
+	mux.Handle("/api/users/{username}/follow", middleware.AuthMiddleware(db, HandlerWrapper(db, handlers.FollowUser)))
+
+	// Blocks and mutes (protected)
+	mux.Handle("/api/users/{username}/block", middleware.AuthMiddleware(db, HandlerWrapper(db, handlers.BlockUser)))
+	mux.Handle("/api/users/{username}/mute", middleware.AuthMiddleware(db, HandlerWrapper(db, handlers.MuteUser)))
+	mux.Handle("/api/user/blocks", middleware.AuthMiddleware(db, HandlerWrapper(db, handlers.GetBlockedUsers)))
+	mux.Handle("/api/user/mutes", middleware.AuthMiddleware(db, HandlerWrapper(db, handlers.GetMutedUsers)))
+	mux.Handle("/api/user/profile", middleware.AuthMiddleware(db, HandlerWrapper(db, handlers.UpdateProfile)))
+
+	// How the current user's content was received over time (protected)
+	mux.Handle("/api/user/stats", middleware.AuthMiddleware(db, HandlerWrapper(db, handlers.GetUserStats)))
+
 	// Authentication routes
 	mux.HandleFunc("/api/register", HandlerWrapper(db, handlers.RegisterUser))
 	mux.HandleFunc("/api/login", HandlerWrapper(db, handlers.LoginUser))
@@ -30,8 +56,16 @@ func SetupRoutes(db *sql.DB) http.Handler {
 	mux.Handle("/api/posts/create", middleware.AuthMiddleware(db, HandlerWrapper(db, handlers.CreatePost)))
 	mux.HandleFunc("/api/posts", HandlerWrapper(db, handlers.GetPosts))                                       // Allow public access
 	mux.Handle("/api/posts/liked", middleware.AuthMiddleware(db, HandlerWrapper(db, handlers.GetLikedPosts))) // Protected
+	mux.Handle("/api/posts/drafts", middleware.AuthMiddleware(db, HandlerWrapper(db, handlers.GetDrafts)))    // Protected
 	mux.Handle("/api/posts/update", middleware.AuthMiddleware(db, HandlerWrapper(db, handlers.UpdatePost)))
 	mux.Handle("/api/posts/delete", middleware.AuthMiddleware(db, HandlerWrapper(db, handlers.DeletePost)))
+	mux.HandleFunc("/api/posts/{id}", HandlerWrapper(db, handlers.GetPost)) // Public access
+	mux.Handle("/api/posts/{id}/accept", middleware.AuthMiddleware(db, HandlerWrapper(db, handlers.AcceptAnswer)))
+	mux.Handle("/api/posts/{id}/publish", middleware.AuthMiddleware(db, HandlerWrapper(db, handlers.PublishDraft)))
+	mux.Handle("/api/admin/posts/{id}/lock", middleware.AdminMiddleware(db, HandlerWrapper(db, handlers.LockPost)))
+
+	// Poll routes
+	mux.Handle("/api/polls/{id}/vote", middleware.AuthMiddleware(db, HandlerWrapper(db, handlers.VotePoll)))
 
 	// Comment routes (protected by auth middleware)
 	mux.Handle("/api/comments/delete", middleware.AuthMiddleware(db, HandlerWrapper(db, handlers.DeleteComment)))
@@ -40,11 +74,75 @@ func SetupRoutes(db *sql.DB) http.Handler {
 	mux.HandleFunc("/api/comments/get", HandlerWrapper(db, handlers.GetPostComments)) // Public access
 
 	// Category routes (protected by auth middleware)
-	mux.Handle("/api/categories/create", middleware.AuthMiddleware(db, HandlerWrapper(db, handlers.CreateCategory)))
 	mux.HandleFunc("/api/categories", HandlerWrapper(db, handlers.GetCategories))
+	mux.Handle("/api/categories/follow", middleware.AuthMiddleware(db, HandlerWrapper(db, handlers.FollowCategory)))
+	mux.Handle("/api/categories/followed", middleware.AuthMiddleware(db, HandlerWrapper(db, handlers.GetFollowedCategories)))
+
+	// Admin dashboard and user management (admin only)
+	mux.Handle("/api/admin/stats", middleware.AdminMiddleware(db, HandlerWrapper(db, handlers.GetSiteStats)))
+	mux.Handle("/api/admin/users", middleware.AdminMiddleware(db, HandlerWrapper(db, handlers.ListUsers)))
+	mux.Handle("/api/admin/users/{id}/ban", middleware.AdminMiddleware(db, HandlerWrapper(db, handlers.BanUser)))
+
+	// Bans, suspensions and the moderation log (moderators and admins)
+	mux.Handle("/api/moderation/users/{id}/ban", middleware.ModeratorMiddleware(db, HandlerWrapper(db, handlers.BanUser)))
+	mux.Handle("/api/moderation/log", middleware.ModeratorMiddleware(db, HandlerWrapper(db, handlers.GetModerationLog)))
+
+	// Content held back by screening (moderators and admins) and the banned
+	// word list (admin only)
+	mux.Handle("/api/moderation/queue", middleware.ModeratorMiddleware(db, HandlerWrapper(db, handlers.GetModerationQueue)))
+	mux.Handle("/api/moderation/queue/{id}/approve", middleware.ModeratorMiddleware(db, HandlerWrapper(db, handlers.ApproveQueueItem)))
+	mux.Handle("/api/moderation/queue/{id}/reject", middleware.ModeratorMiddleware(db, HandlerWrapper(db, handlers.RejectQueueItem)))
+	mux.Handle("/api/admin/words", middleware.AdminMiddleware(db, HandlerWrapper(db, handlers.AdminBannedWords)))
+
+	// Outbound webhooks and their delivery log (admin only)
+	mux.Handle("/api/admin/webhooks", middleware.AdminMiddleware(db, HandlerWrapper(db, handlers.AdminWebhooks)))
+	mux.Handle("/api/admin/webhooks/{id}", middleware.AdminMiddleware(db, HandlerWrapper(db, handlers.AdminWebhook)))
+	mux.Handle("/api/admin/webhooks/{id}/deliveries", middleware.AdminMiddleware(db, HandlerWrapper(db, handlers.GetWebhookDeliveries)))
+	mux.Handle("/api/admin/webhooks/deliveries/{id}/retry", middleware.AdminMiddleware(db, HandlerWrapper(db, handlers.RetryWebhookDelivery)))
+
+	// Category management (admin only)
+	mux.Handle("/api/admin/categories", middleware.AdminMiddleware(db, HandlerWrapper(db, handlers.CreateCategory)))
+	mux.Handle("/api/admin/categories/{id}", middleware.AdminMiddleware(db, HandlerWrapper(db, handlers.Category)))
+	mux.Handle("/api/admin/categories/{id}/merge", middleware.AdminMiddleware(db, HandlerWrapper(db, handlers.MergeCategory)))
+
+	// Tag routes
+	mux.HandleFunc("/api/tags", HandlerWrapper(db, handlers.GetTagCloud))
+	mux.HandleFunc("/api/tags/autocomplete", HandlerWrapper(db, handlers.SuggestTags))
+	mux.Handle("/api/admin/tags/merge", middleware.AdminMiddleware(db, HandlerWrapper(db, handlers.MergeTag)))
+
+	// Home feed of followed users and categories (protected)
+	mux.Handle("/api/feed", middleware.AuthMiddleware(db, HandlerWrapper(db, handlers.GetFeed)))
+
+	// Bookmark routes (protected)
+	mux.Handle("/api/bookmarks", middleware.AuthMiddleware(db, HandlerWrapper(db, handlers.Bookmarks)))
+	mux.Handle("/api/bookmarks/{id}", middleware.AuthMiddleware(db, HandlerWrapper(db, handlers.Bookmark)))
+	mux.Handle("/api/bookmarks/folders", middleware.AuthMiddleware(db, HandlerWrapper(db, handlers.BookmarkFolders)))
+	mux.Handle("/api/bookmarks/folders/{id}", middleware.AuthMiddleware(db, HandlerWrapper(db, handlers.BookmarkFolder)))
 	// Like routes
 	mux.Handle("/api/likes/toggle", middleware.AuthMiddleware(db, HandlerWrapper(db, handlers.ToggleLike))) // Protected
 	mux.HandleFunc("/api/likes/reactions", HandlerWrapper(db, handlers.GetReactions))                       // Public
+	mux.HandleFunc("/api/likes/reactors", HandlerWrapper(db, handlers.GetReactors))                         // Public
+	mux.HandleFunc("/api/reactions", HandlerWrapper(db, handlers.GetReactionTypes))                         // Public
+	mux.Handle("/api/admin/reactions", middleware.AdminMiddleware(db, HandlerWrapper(db, handlers.AdminReactionTypes)))
+
+	// Notification routes (protected)
+	mux.Handle("/api/notifications", middleware.AuthMiddleware(db, HandlerWrapper(db, handlers.GetNotifications)))
+	mux.Handle("/api/notifications/read", middleware.AuthMiddleware(db, HandlerWrapper(db, handlers.MarkNotificationsRead)))
+	mux.Handle("/api/notifications/read-all", middleware.AuthMiddleware(db, HandlerWrapper(db, handlers.MarkAllNotificationsRead)))
+	mux.Handle("/api/notifications/preferences", middleware.AuthMiddleware(db, HandlerWrapper(db, handlers.NotificationPreferences)))
+
+	// Direct messages: WebSocket for live chat, REST for history (protected)
+	mux.Handle("/api/chat", middleware.AuthMiddleware(db, HandlerWrapper(db, handlers.Chat)))
+	mux.Handle("/api/conversations", middleware.AuthMiddleware(db, HandlerWrapper(db, handlers.GetConversations)))
+	mux.Handle("/api/conversations/messages", middleware.AuthMiddleware(db, HandlerWrapper(db, handlers.GetMessages)))
+
+	// Live updates over Server-Sent Events (public; the session adds private events)
+	mux.HandleFunc("/api/stream", HandlerWrapper(db, handlers.Stream))
+
+	// Atom and RSS feeds: {file} is posts.atom or posts.rss (public)
+	mux.HandleFunc("/feeds/{file}", HandlerWrapper(db, handlers.GetPostsFeed))
+	mux.HandleFunc("/feeds/categories/{category}/{file}", HandlerWrapper(db, handlers.GetCategoryFeed))
+	mux.HandleFunc("/feeds/users/{username}/{file}", HandlerWrapper(db, handlers.GetUserFeed))
 
 	// comment, post and likes owner
 	mux.Handle("/api/owner", HandlerWrapper(db, handlers.GetOwner))
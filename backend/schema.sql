@@ -6,6 +6,16 @@ CREATE TABLE IF NOT EXISTS users (
     email TEXT UNIQUE NOT NULL,
     password_hash TEXT NOT NULL,
     avatar_url TEXT DEFAULT '',
+    bio TEXT NOT NULL DEFAULT '',
+    last_seen_at DATETIME,
+    show_online_status INTEGER NOT NULL DEFAULT 1,
+    show_posts INTEGER NOT NULL DEFAULT 1,
+    show_comments INTEGER NOT NULL DEFAULT 1,
+    show_liked_posts INTEGER NOT NULL DEFAULT 0,
+    role TEXT NOT NULL DEFAULT 'user',
+    banned_at DATETIME, -- set while the user is banned or suspended
+    banned_until DATETIME, -- end of a suspension, NULL for a permanent ban
+    ban_reason TEXT NOT NULL DEFAULT '',
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
     updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 );
@@ -22,6 +32,97 @@ CREATE TABLE IF NOT EXISTS sessions (
 -- Index for faster session lookup
 CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions(user_id);
 
+-- Days on which each user was signed in, for the admin dashboard. Sessions
+-- only last a day, so this keeps the history they would lose.
+CREATE TABLE IF NOT EXISTS user_activity (
+    user_id TEXT NOT NULL,
+    day DATE NOT NULL, -- UTC
+    PRIMARY KEY (user_id, day),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_activity_day ON user_activity(day);
+
+-- Bans, suspensions and their lifting, newest last. moderator_id is NULL
+-- when a suspension expired on its own.
+CREATE TABLE IF NOT EXISTS moderation_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id TEXT NOT NULL,
+    moderator_id TEXT,
+    action TEXT NOT NULL CHECK (action IN ('ban', 'suspend', 'lift', 'expire')),
+    reason TEXT NOT NULL DEFAULT '',
+    until DATETIME, -- end of a suspension
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (moderator_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_moderation_log_user ON moderation_log(user_id, id);
+CREATE INDEX IF NOT EXISTS idx_users_banned_until ON users(banned_until) WHERE banned_until IS NOT NULL;
+
+-- Posts, comments and replies held back by content screening until a
+-- moderator approves (publishes) or rejects them
+CREATE TABLE IF NOT EXISTS moderation_queue (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id TEXT NOT NULL,
+    kind TEXT NOT NULL CHECK (kind IN ('post', 'comment', 'reply')),
+    target_id INTEGER, -- the post commented on or edited, or the comment replied to
+    title TEXT NOT NULL DEFAULT '',
+    content TEXT NOT NULL,
+    details TEXT NOT NULL DEFAULT '{}', -- JSON categories, tags, type, poll and image of a post
+    reasons TEXT NOT NULL DEFAULT '[]', -- JSON list of why it was held
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+    reviewer_id TEXT,
+    reviewed_at DATETIME,
+    published_id INTEGER, -- the post, comment or reply created on approval
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (reviewer_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_moderation_queue_status ON moderation_queue(status, id);
+CREATE INDEX IF NOT EXISTS idx_moderation_queue_user ON moderation_queue(user_id, created_at);
+
+-- Words screened out of new content, managed by admins
+CREATE TABLE IF NOT EXISTS banned_words (
+    word TEXT PRIMARY KEY, -- lower case
+    action TEXT NOT NULL CHECK (action IN ('replace', 'block')),
+    replacement TEXT NOT NULL DEFAULT '', -- empty masks the word with *
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Webhooks Table (URLs admins registered to receive signed JSON for the
+-- event types they subscribe to)
+CREATE TABLE IF NOT EXISTS webhooks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL, -- HMAC-SHA256 key of the X-Forum-Signature header
+    events TEXT NOT NULL DEFAULT '[]', -- JSON array of event types
+    active INTEGER NOT NULL DEFAULT 1,
+    created_by TEXT REFERENCES users(id) ON DELETE SET NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Webhook Deliveries Table (the outbox the delivery worker sends from, kept
+-- afterwards as the delivery log)
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    webhook_id INTEGER NOT NULL,
+    event TEXT NOT NULL,
+    payload TEXT NOT NULL, -- the JSON body, fixed when the event happened
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    response_code INTEGER, -- of the last attempt, if the receiver answered
+    last_error TEXT NOT NULL DEFAULT '',
+    delivered_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (webhook_id) REFERENCES webhooks(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_id, id);
+
 -- Updated Posts Table (remove category_id)
 CREATE TABLE IF NOT EXISTS posts (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -29,11 +130,21 @@ CREATE TABLE IF NOT EXISTS posts (
     title TEXT NOT NULL,
     content TEXT NOT NULL,
     image_url TEXT,
-    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    locked INTEGER NOT NULL DEFAULT 0, -- no new comments or poll votes
+    post_type TEXT NOT NULL DEFAULT 'discussion' CHECK (post_type IN ('discussion', 'question')),
+    accepted_comment_id INTEGER REFERENCES comments(id) ON DELETE SET NULL, -- questions only
+    like_count INTEGER NOT NULL DEFAULT 0, -- kept up to date by the likes triggers
+    dislike_count INTEGER NOT NULL DEFAULT 0,
+    view_count INTEGER NOT NULL DEFAULT 0, -- counted in memory, added in batches
+    status TEXT NOT NULL DEFAULT 'published' CHECK (status IN ('draft', 'scheduled', 'published')), -- only published posts are shown to others
+    publish_at DATETIME, -- when a scheduled post goes out
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP, -- reset when a draft is published
     updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
     FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
 );
 
+CREATE INDEX IF NOT EXISTS idx_posts_accepted_comment ON posts(accepted_comment_id) WHERE accepted_comment_id IS NOT NULL;
+
 -- Post-Categories Join Table
 CREATE TABLE IF NOT EXISTS post_categories (
     post_id INTEGER NOT NULL,
@@ -43,6 +154,12 @@ CREATE TABLE IF NOT EXISTS post_categories (
     FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE CASCADE
 );
 
+-- Post listing indexes: newest first overall, per author and per category
+CREATE INDEX IF NOT EXISTS idx_posts_created ON posts(created_at, id);
+CREATE INDEX IF NOT EXISTS idx_posts_user_created ON posts(user_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_posts_scheduled ON posts(publish_at) WHERE status = 'scheduled';
+CREATE INDEX IF NOT EXISTS idx_post_categories_category ON post_categories(category_id, post_id);
+
 
 
 -- Comments Table
@@ -51,6 +168,8 @@ CREATE TABLE IF NOT EXISTS comments (
     user_id TEXT NOT NULL,
     post_id INTEGER NOT NULL,
     content TEXT NOT NULL,
+    like_count INTEGER NOT NULL DEFAULT 0, -- kept up to date by the likes triggers
+    dislike_count INTEGER NOT NULL DEFAULT 0,
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
     updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
     FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
@@ -70,19 +189,76 @@ CREATE TABLE IF NOT EXISTS replycomments (
 );
 
 
--- Likes Table (Supports both post and comment likes and allows dislikes)
+-- Reaction Types Table (the reactions users can leave; like and dislike are built in)
+CREATE TABLE IF NOT EXISTS reaction_types (
+    name TEXT PRIMARY KEY,
+    emoji TEXT NOT NULL,
+    label TEXT NOT NULL,
+    sort_order INTEGER NOT NULL DEFAULT 0,
+    built_in INTEGER NOT NULL DEFAULT 0, -- cannot be disabled
+    enabled INTEGER NOT NULL DEFAULT 1
+);
+
+INSERT OR IGNORE INTO reaction_types (name, emoji, label, sort_order, built_in) VALUES
+('like', '👍', 'Like', 0, 1),
+('dislike', '👎', 'Dislike', 1, 1),
+('love', '❤️', 'Love', 2, 0),
+('laugh', '😂', 'Laugh', 3, 0),
+('celebrate', '🎉', 'Celebrate', 4, 0),
+('eyes', '👀', 'Eyes', 5, 0);
+
+-- Likes Table (reactions on posts and comments: a user leaves each type at
+-- most once per target, and like and dislike exclude each other)
 CREATE TABLE IF NOT EXISTS likes (
     user_id TEXT NOT NULL,
     post_id INTEGER,
     comment_id INTEGER,
-    type TEXT NOT NULL CHECK(type IN ('like', 'dislike')),
+    type TEXT NOT NULL,
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-    PRIMARY KEY (user_id, post_id, comment_id),
+    CHECK ((post_id IS NULL) != (comment_id IS NULL)),
     FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
     FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
-    FOREIGN KEY (comment_id) REFERENCES comments(id) ON DELETE CASCADE
+    FOREIGN KEY (comment_id) REFERENCES comments(id) ON DELETE CASCADE,
+    FOREIGN KEY (type) REFERENCES reaction_types(name)
 );
 
+CREATE UNIQUE INDEX IF NOT EXISTS idx_likes_post ON likes(post_id, user_id, type) WHERE post_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_likes_comment ON likes(comment_id, user_id, type) WHERE comment_id IS NOT NULL;
+
+-- Keep the like/dislike counters of posts and comments in step with the likes
+-- table. Other reaction types are counted from likes directly.
+DROP TRIGGER IF EXISTS likes_count_insert;
+CREATE TRIGGER likes_count_insert
+AFTER INSERT ON likes
+FOR EACH ROW
+WHEN NEW.type IN ('like', 'dislike')
+BEGIN
+    UPDATE posts SET
+        like_count = like_count + (NEW.type = 'like'),
+        dislike_count = dislike_count + (NEW.type = 'dislike')
+    WHERE id = NEW.post_id;
+    UPDATE comments SET
+        like_count = like_count + (NEW.type = 'like'),
+        dislike_count = dislike_count + (NEW.type = 'dislike')
+    WHERE id = NEW.comment_id;
+END;
+
+DROP TRIGGER IF EXISTS likes_count_delete;
+CREATE TRIGGER likes_count_delete
+AFTER DELETE ON likes
+FOR EACH ROW
+WHEN OLD.type IN ('like', 'dislike')
+BEGIN
+    UPDATE posts SET
+        like_count = like_count - (OLD.type = 'like'),
+        dislike_count = dislike_count - (OLD.type = 'dislike')
+    WHERE id = OLD.post_id;
+    UPDATE comments SET
+        like_count = like_count - (OLD.type = 'like'),
+        dislike_count = dislike_count - (OLD.type = 'dislike')
+    WHERE id = OLD.comment_id;
+END;
+
 -- Ensure the old trigger is removed before creating a new one
 DROP TRIGGER IF EXISTS update_user_timestamp;
 DROP TRIGGER IF EXISTS update_post_timestamp;
@@ -96,17 +272,18 @@ BEGIN
     UPDATE users SET updated_at = CURRENT_TIMESTAMP WHERE id = OLD.id;
 END;
 
--- Auto-update `updated_at` column in `posts`
+-- Auto-update `updated_at` column in `posts` when the author edits it (not on
+-- counter, lock or accepted answer changes)
 CREATE TRIGGER update_post_timestamp
-AFTER UPDATE ON posts
+AFTER UPDATE OF title, content, image_url ON posts
 FOR EACH ROW
 BEGIN
     UPDATE posts SET updated_at = CURRENT_TIMESTAMP WHERE id = OLD.id;
 END;
 
--- Auto-update `updated_at` column in `comments`
+-- Auto-update `updated_at` column in `comments` when its content is edited
 CREATE TRIGGER update_comment_timestamp
-AFTER UPDATE ON comments
+AFTER UPDATE OF content ON comments
 FOR EACH ROW
 BEGIN
     UPDATE comments SET updated_at = CURRENT_TIMESTAMP WHERE id = OLD.id;
@@ -114,9 +291,282 @@ END;
 
 CREATE TABLE IF NOT EXISTS categories (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
-    name TEXT UNIQUE NOT NULL
+    name TEXT UNIQUE NOT NULL,
+    slug TEXT,
+    description TEXT NOT NULL DEFAULT '',
+    color TEXT NOT NULL DEFAULT '',
+    parent_id INTEGER REFERENCES categories(id) ON DELETE SET NULL,
+    sort_order INTEGER NOT NULL DEFAULT 0,
+    archived INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_categories_slug ON categories(slug);
+CREATE INDEX IF NOT EXISTS idx_categories_parent ON categories(parent_id);
+
+-- Mentions Table (one row per @username resolved in a post, comment or reply)
+CREATE TABLE IF NOT EXISTS mentions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    mentioned_user_id TEXT NOT NULL,
+    author_id TEXT NOT NULL,
+    post_id INTEGER,
+    comment_id INTEGER,
+    reply_id INTEGER,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (mentioned_user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (author_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+    FOREIGN KEY (comment_id) REFERENCES comments(id) ON DELETE CASCADE,
+    FOREIGN KEY (reply_id) REFERENCES replycomments(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_mentions_post ON mentions(post_id, mentioned_user_id) WHERE post_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_mentions_comment ON mentions(comment_id, mentioned_user_id) WHERE comment_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_mentions_reply ON mentions(reply_id, mentioned_user_id) WHERE reply_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_mentions_user ON mentions(mentioned_user_id);
+
+-- Notifications Table (deleting the content a notification points at deletes it too)
+CREATE TABLE IF NOT EXISTS notifications (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id TEXT NOT NULL,
+    actor_id TEXT NOT NULL,
+    type TEXT NOT NULL,
+    post_id INTEGER,
+    comment_id INTEGER,
+    reply_id INTEGER,
+    is_read INTEGER NOT NULL DEFAULT 0,
+    event_count INTEGER NOT NULL DEFAULT 1,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (actor_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+    FOREIGN KEY (comment_id) REFERENCES comments(id) ON DELETE CASCADE,
+    FOREIGN KEY (reply_id) REFERENCES replycomments(id) ON DELETE CASCADE
+);
+
+DROP INDEX IF EXISTS idx_notifications_user;
+CREATE INDEX IF NOT EXISTS idx_notifications_user_updated ON notifications(user_id, updated_at);
+
+-- Notification Preferences (a missing row means the type is enabled)
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id TEXT NOT NULL,
+    type TEXT NOT NULL,
+    enabled INTEGER NOT NULL DEFAULT 1,
+    PRIMARY KEY (user_id, type),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- User Blocks (a block in either direction stops replies, mentions,
+-- reactions, follows and direct messages between the two users)
+CREATE TABLE IF NOT EXISTS user_blocks (
+    blocker_id TEXT NOT NULL,
+    blocked_id TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (blocker_id, blocked_id),
+    FOREIGN KEY (blocker_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (blocked_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_blocks_blocked ON user_blocks(blocked_id);
+
+-- User Mutes (hides the muted user's posts and comments from the muter only)
+CREATE TABLE IF NOT EXISTS user_mutes (
+    muter_id TEXT NOT NULL,
+    muted_id TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (muter_id, muted_id),
+    FOREIGN KEY (muter_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (muted_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Conversations Table (one per pair of users, stored with user_a < user_b)
+CREATE TABLE IF NOT EXISTS conversations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_a TEXT NOT NULL,
+    user_b TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_a, user_b),
+    CHECK (user_a < user_b),
+    FOREIGN KEY (user_a) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_b) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Conversation Members (read receipts: each member's last read message)
+CREATE TABLE IF NOT EXISTS conversation_members (
+    conversation_id INTEGER NOT NULL,
+    user_id TEXT NOT NULL,
+    last_read_message_id INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (conversation_id, user_id),
+    FOREIGN KEY (conversation_id) REFERENCES conversations(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_conversation_members_user ON conversation_members(user_id);
+
+-- Messages Table
+CREATE TABLE IF NOT EXISTS messages (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    conversation_id INTEGER NOT NULL,
+    sender_id TEXT NOT NULL,
+    content TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (conversation_id) REFERENCES conversations(id) ON DELETE CASCADE,
+    FOREIGN KEY (sender_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_messages_conversation ON messages(conversation_id, id);
+
+-- Follows Table (users following users)
+CREATE TABLE IF NOT EXISTS follows (
+    follower_id TEXT NOT NULL,
+    followee_id TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (follower_id, followee_id),
+    CHECK (follower_id != followee_id),
+    FOREIGN KEY (follower_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (followee_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_follows_followee ON follows(followee_id, created_at);
+
+-- Category Follows Table (users following categories)
+CREATE TABLE IF NOT EXISTS category_follows (
+    user_id TEXT NOT NULL,
+    category_id INTEGER NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, category_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_category_follows_category ON category_follows(category_id);
+
+-- Bookmark Folders Table (user-named folders for saved posts and comments)
+CREATE TABLE IF NOT EXISTS bookmark_folders (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, name),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Bookmarks Table (a saved post or comment; deleting a folder unfiles its bookmarks)
+CREATE TABLE IF NOT EXISTS bookmarks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id TEXT NOT NULL,
+    post_id INTEGER,
+    comment_id INTEGER,
+    folder_id INTEGER,
+    note TEXT NOT NULL DEFAULT '',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((post_id IS NULL) != (comment_id IS NULL)),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+    FOREIGN KEY (comment_id) REFERENCES comments(id) ON DELETE CASCADE,
+    FOREIGN KEY (folder_id) REFERENCES bookmark_folders(id) ON DELETE SET NULL
 );
 
+CREATE UNIQUE INDEX IF NOT EXISTS idx_bookmarks_post ON bookmarks(user_id, post_id) WHERE post_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_bookmarks_comment ON bookmarks(user_id, comment_id) WHERE comment_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_bookmarks_folder ON bookmarks(user_id, folder_id, created_at);
+
+-- Post Reads Table (when each user last opened each post, to count the
+-- comments they have not seen)
+CREATE TABLE IF NOT EXISTS post_reads (
+    user_id TEXT NOT NULL,
+    post_id INTEGER NOT NULL,
+    last_read_at DATETIME NOT NULL,
+    PRIMARY KEY (user_id, post_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE
+);
+
+-- Unread comment counts look up a post's comments and replies by time
+CREATE INDEX IF NOT EXISTS idx_comments_post_created ON comments(post_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_replycomments_parent_created ON replycomments(parent_comment_id, created_at);
+
+-- Tags Table (free-form post tags, stored normalized: lower case, no spaces)
+CREATE TABLE IF NOT EXISTS tags (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Post Tags Table (many-to-many between posts and tags)
+CREATE TABLE IF NOT EXISTS post_tags (
+    post_id INTEGER NOT NULL,
+    tag_id INTEGER NOT NULL,
+    PRIMARY KEY (post_id, tag_id),
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+    FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_tags_tag ON post_tags(tag_id, post_id);
+
+-- Tag Synonyms Table (names of merged tags, resolved to the tag they were merged into)
+CREATE TABLE IF NOT EXISTS tag_synonyms (
+    name TEXT PRIMARY KEY,
+    tag_id INTEGER NOT NULL,
+    FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE
+);
+
+-- Polls Table (at most one poll per post)
+CREATE TABLE IF NOT EXISTS polls (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    post_id INTEGER NOT NULL UNIQUE,
+    question TEXT NOT NULL,
+    multiple_choice INTEGER NOT NULL DEFAULT 0,
+    hide_results INTEGER NOT NULL DEFAULT 0, -- until the viewer votes or the poll closes
+    closes_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE
+);
+
+-- Poll Options Table
+CREATE TABLE IF NOT EXISTS poll_options (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    poll_id INTEGER NOT NULL,
+    text TEXT NOT NULL,
+    position INTEGER NOT NULL,
+    UNIQUE (poll_id, id),
+    FOREIGN KEY (poll_id) REFERENCES polls(id) ON DELETE CASCADE
+);
+
+-- Poll Ballots Table (one per user and poll; the primary key enforces it)
+CREATE TABLE IF NOT EXISTS poll_ballots (
+    poll_id INTEGER NOT NULL,
+    user_id TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (poll_id, user_id),
+    FOREIGN KEY (poll_id) REFERENCES polls(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Poll Votes Table (the options chosen on a ballot, which must belong to its poll)
+CREATE TABLE IF NOT EXISTS poll_votes (
+    poll_id INTEGER NOT NULL,
+    user_id TEXT NOT NULL,
+    option_id INTEGER NOT NULL,
+    PRIMARY KEY (poll_id, user_id, option_id),
+    FOREIGN KEY (poll_id, user_id) REFERENCES poll_ballots(poll_id, user_id) ON DELETE CASCADE,
+    FOREIGN KEY (poll_id, option_id) REFERENCES poll_options(poll_id, id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_poll_votes_option ON poll_votes(option_id);
+
+-- Single-choice ballots hold exactly one option
+DROP TRIGGER IF EXISTS poll_votes_single_choice;
+CREATE TRIGGER poll_votes_single_choice
+BEFORE INSERT ON poll_votes
+FOR EACH ROW
+WHEN (SELECT multiple_choice FROM polls WHERE id = NEW.poll_id) = 0
+    AND EXISTS (SELECT 1 FROM poll_votes WHERE poll_id = NEW.poll_id AND user_id = NEW.user_id)
+BEGIN
+    SELECT RAISE(ABORT, 'single-choice poll allows one option');
+END;
+
 
 BEGIN TRANSACTION;
 
@@ -200,17 +650,22 @@ BEGIN TRANSACTION;
 
 -- Insert sample categories
 
-INSERT OR IGNORE INTO categories (name) VALUES
-('Web Development'),
-('Mobile Development'),
-('Data Science'),
-('DevOps'),
-('Cybersecurity'),
-('Artificial Intelligence'),
-('Software Architecture'),
-('Game Development'),
-('Cloud Computing'),
-('Blockchain');
+-- Only into an empty table, so categories merged or deleted by an admin
+-- do not come back on the next start
+INSERT INTO categories (name, slug, sort_order)
+SELECT name, slug, sort_order FROM (
+    SELECT 'Web Development' AS name, 'web-development' AS slug, 1 AS sort_order
+    UNION ALL SELECT 'Mobile Development', 'mobile-development', 2
+    UNION ALL SELECT 'Data Science', 'data-science', 3
+    UNION ALL SELECT 'DevOps', 'devops', 4
+    UNION ALL SELECT 'Cybersecurity', 'cybersecurity', 5
+    UNION ALL SELECT 'Artificial Intelligence', 'artificial-intelligence', 6
+    UNION ALL SELECT 'Software Architecture', 'software-architecture', 7
+    UNION ALL SELECT 'Game Development', 'game-development', 8
+    UNION ALL SELECT 'Cloud Computing', 'cloud-computing', 9
+    UNION ALL SELECT 'Blockchain', 'blockchain', 10
+)
+WHERE NOT EXISTS (SELECT 1 FROM categories);
 
 -- -- Insert post-category associations
 -- INSERT INTO post_categories (post_id, category_id) VALUES
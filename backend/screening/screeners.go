@@ -0,0 +1,195 @@
+package screening
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"forum/models"
+	"forum/sqlite"
+)
+
+// WordFilter applies the admin-managed banned word list: "replace" words are
+// masked or swapped for their replacement, "block" words refuse the content.
+// Words match whole words, ignoring case. Tags cannot hold a mask, so tags
+// with a "replace" word are dropped instead.
+type WordFilter struct{}
+
+// bannedWord is a banned word with its compiled pattern
+type bannedWord struct {
+	models.BannedWord
+	pattern *regexp.Regexp
+}
+
+// wordCache holds the compiled banned word list of db, until the list
+// changes
+var wordCache struct {
+	sync.Mutex
+	db      *sql.DB
+	version uint64
+	words   []bannedWord
+}
+
+// bannedWords returns the compiled banned word list, loading it again if it
+// changed since it was last compiled
+func bannedWords(db *sql.DB) ([]bannedWord, error) {
+	wordCache.Lock()
+	defer wordCache.Unlock()
+
+	version := sqlite.BannedWordsVersion()
+	if wordCache.db == db && wordCache.version == version && wordCache.words != nil {
+		return wordCache.words, nil
+	}
+	list, err := sqlite.GetBannedWords(db)
+	if err != nil {
+		return nil, err
+	}
+	words := make([]bannedWord, 0, len(list))
+	for _, w := range list {
+		pattern, err := regexp.Compile(`(?i)\b` + regexp.QuoteMeta(w.Word) + `\b`)
+		if err != nil {
+			return nil, err
+		}
+		words = append(words, bannedWord{BannedWord: w, pattern: pattern})
+	}
+	wordCache.db, wordCache.version, wordCache.words = db, version, words
+	return words, nil
+}
+
+func (WordFilter) Screen(db *sql.DB, c *Content) (Finding, error) {
+	words, err := bannedWords(db)
+	if err != nil {
+		return Finding{}, err
+	}
+
+	texts := []*string{&c.Title, &c.Content}
+	if c.Poll != nil {
+		texts = append(texts, &c.Poll.Question)
+		for i := range c.Poll.Options {
+			texts = append(texts, &c.Poll.Options[i].Text)
+		}
+	}
+
+	for _, w := range words {
+		if w.Action == sqlite.WordBlock {
+			for _, text := range texts {
+				if w.pattern.MatchString(*text) {
+					return Finding{Action: Block, Reason: fmt.Sprintf("contains the blocked word %q", w.Word)}, nil
+				}
+			}
+			for _, tag := range c.Tags {
+				if w.pattern.MatchString(tag) {
+					return Finding{Action: Block, Reason: fmt.Sprintf("contains the blocked word %q", w.Word)}, nil
+				}
+			}
+			continue
+		}
+		replacement := w.Replacement
+		if replacement == "" {
+			replacement = strings.Repeat("*", len([]rune(w.Word)))
+		}
+		for _, text := range texts {
+			*text = w.pattern.ReplaceAllLiteralString(*text, replacement)
+		}
+		c.Tags = slices.DeleteFunc(c.Tags, w.pattern.MatchString)
+	}
+	return Finding{}, nil
+}
+
+// linkPattern finds links in titles and Markdown content
+var linkPattern = regexp.MustCompile(`(?i)\bhttps?://`)
+
+// LinkLimit flags content with more than MaxLinks links from accounts
+// younger than NewAccountAge, a common sign of spam
+type LinkLimit struct {
+	MaxLinks      int
+	NewAccountAge time.Duration
+}
+
+func (l LinkLimit) Screen(db *sql.DB, c *Content) (Finding, error) {
+	links := len(linkPattern.FindAllString(c.Title, -1)) + len(linkPattern.FindAllString(c.Content, -1))
+	if links <= l.MaxLinks {
+		return Finding{}, nil
+	}
+	createdAt, err := sqlite.GetUserCreatedAt(db, c.UserID)
+	if err != nil {
+		return Finding{}, err
+	}
+	if time.Since(createdAt) >= l.NewAccountAge {
+		return Finding{}, nil
+	}
+	return Finding{Action: Flag, Reason: fmt.Sprintf("%d links from an account less than %s old", links, formatAge(l.NewAccountAge))}, nil
+}
+
+// DuplicateContent flags content the author already wrote within Window,
+// ignoring case and spacing, other than the post being edited. Content
+// shorter than MinLength is left alone, since short replies like "Thanks!"
+// are often repeated.
+type DuplicateContent struct {
+	Window    time.Duration
+	MinLength int
+}
+
+func (d DuplicateContent) Screen(db *sql.DB, c *Content) (Finding, error) {
+	normalized := normalize(c.Content)
+	if len(normalized) < d.MinLength {
+		return Finding{}, nil
+	}
+	recent, err := sqlite.GetRecentContent(db, c.UserID, time.Now().Add(-d.Window), c.PostID)
+	if err != nil {
+		return Finding{}, err
+	}
+	for _, content := range recent {
+		if normalize(content) == normalized {
+			return Finding{Action: Flag, Reason: fmt.Sprintf("same content posted in the last %s", formatAge(d.Window))}, nil
+		}
+	}
+	return Finding{}, nil
+}
+
+// normalize lowers the case of s and collapses its whitespace
+func normalize(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
+
+// Velocity flags content from accounts that have already written MaxItems
+// posts, comments and replies within Window
+type Velocity struct {
+	MaxItems int
+	Window   time.Duration
+}
+
+func (v Velocity) Screen(db *sql.DB, c *Content) (Finding, error) {
+	count, err := sqlite.CountRecentContent(db, c.UserID, time.Now().Add(-v.Window), c.PostID)
+	if err != nil {
+		return Finding{}, err
+	}
+	if count < v.MaxItems {
+		return Finding{}, nil
+	}
+	return Finding{Action: Flag, Reason: fmt.Sprintf("more than %d posts and comments in %s", v.MaxItems, formatAge(v.Window))}, nil
+}
+
+// formatAge writes a duration in days, hours or minutes
+func formatAge(d time.Duration) string {
+	switch {
+	case d >= 48*time.Hour && d%(24*time.Hour) == 0:
+		return plural(int(d/(24*time.Hour)), "day")
+	case d >= time.Hour && d%time.Hour == 0:
+		return plural(int(d/time.Hour), "hour")
+	default:
+		return plural(int(d/time.Minute), "minute")
+	}
+}
+
+// plural writes n and unit, adding an s unless n is 1
+func plural(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
@@ -0,0 +1,89 @@
+// Package screening checks new posts, comments and replies, and edits of
+// posts, before they are published. Each check is a Screener; a Pipeline runs
+// them in order and decides whether the content is published, held for a
+// moderator or refused.
+package screening
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"forum/models"
+)
+
+// Action is what a screener wants done with a piece of content
+type Action int
+
+const (
+	// Allow publishes the content
+	Allow Action = iota
+	// Flag holds the content in the moderation queue
+	Flag
+	// Block refuses the content
+	Block
+)
+
+// Content is a post, comment or reply about to be published, or an edit of
+// a post. Screeners may rewrite Title, Content, Tags and Poll, for instance
+// to mask banned words.
+type Content struct {
+	Kind    string // sqlite.ContentPost, ContentComment or ContentReply
+	UserID  string
+	PostID  int    // the post being edited; 0 for new content
+	Title   string // posts only
+	Content string
+	Tags    []string     // posts only
+	Poll    *models.Poll // posts only; nil without a poll
+}
+
+// Finding is a screener's verdict on a piece of content. Reason says why it
+// was flagged or blocked; it is shown to moderators, and to the author for
+// blocked content.
+type Finding struct {
+	Action Action
+	Reason string
+}
+
+// Screener checks one thing about new content
+type Screener interface {
+	Screen(db *sql.DB, c *Content) (Finding, error)
+}
+
+// Verdict is the outcome of a Pipeline: the strongest action any screener
+// asked for, with the reasons of those that flagged or blocked the content
+type Verdict struct {
+	Action  Action
+	Reasons []string
+}
+
+// Pipeline runs screeners in order. The first one to block the content stops
+// the rest; flags are collected so moderators see every reason.
+type Pipeline []Screener
+
+// Screen runs the pipeline over c
+func (p Pipeline) Screen(db *sql.DB, c *Content) (Verdict, error) {
+	var verdict Verdict
+	for _, screener := range p {
+		finding, err := screener.Screen(db, c)
+		if err != nil {
+			return verdict, fmt.Errorf("%T: %w", screener, err)
+		}
+		switch finding.Action {
+		case Block:
+			return Verdict{Action: Block, Reasons: []string{finding.Reason}}, nil
+		case Flag:
+			verdict.Action = Flag
+			verdict.Reasons = append(verdict.Reasons, finding.Reason)
+		}
+	}
+	return verdict, nil
+}
+
+// Default is the pipeline new content goes through
+var Default = Pipeline{
+	WordFilter{},
+	LinkLimit{MaxLinks: 2, NewAccountAge: 7 * 24 * time.Hour},
+	DuplicateContent{Window: 24 * time.Hour, MinLength: 30},
+	Velocity{MaxItems: 10, Window: 10 * time.Minute},
+}
@@ -0,0 +1,196 @@
+package screening
+
+import (
+	"database/sql"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"forum/models"
+	"forum/sqlite"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func setupTestDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	db.SetMaxOpenConns(1) // each :memory: connection is its own database
+	t.Cleanup(func() { db.Close() })
+
+	schemaSQL, err := os.ReadFile("../schema.sql")
+	if err != nil {
+		t.Fatalf("Failed to read schema.sql: %v", err)
+	}
+	if _, err := db.Exec(string(schemaSQL)); err != nil {
+		t.Fatalf("Failed to apply schema.sql: %v", err)
+	}
+	return db
+}
+
+func createUser(t *testing.T, db *sql.DB, username string) string {
+	if err := sqlite.CreateUser(db, username, username+"@example.com", "hash", ""); err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	user, err := sqlite.GetUserByUsername(db, username)
+	if err != nil {
+		t.Fatalf("GetUserByUsername failed: %v", err)
+	}
+	return user.ID
+}
+
+// fixed is a screener that always returns the same finding
+type fixed Finding
+
+func (f fixed) Screen(*sql.DB, *Content) (Finding, error) { return Finding(f), nil }
+
+func TestPipeline(t *testing.T) {
+	flag := func(reason string) Screener { return fixed{Action: Flag, Reason: reason} }
+
+	verdict, err := Pipeline{fixed{}, flag("links"), flag("too fast")}.Screen(nil, &Content{})
+	if err != nil {
+		t.Fatalf("Screen failed: %v", err)
+	}
+	if verdict.Action != Flag || !reflect.DeepEqual(verdict.Reasons, []string{"links", "too fast"}) {
+		t.Errorf("Expected both flags, got %+v", verdict)
+	}
+
+	verdict, _ = Pipeline{flag("links"), fixed{Action: Block, Reason: "rude"}, flag("too fast")}.Screen(nil, &Content{})
+	if verdict.Action != Block || !reflect.DeepEqual(verdict.Reasons, []string{"rude"}) {
+		t.Errorf("Expected the block to win on its own, got %+v", verdict)
+	}
+
+	if verdict, _ := (Pipeline{}).Screen(nil, &Content{}); verdict.Action != Allow {
+		t.Errorf("Expected an empty pipeline to allow content, got %+v", verdict)
+	}
+}
+
+func TestWordFilter(t *testing.T) {
+	db := setupTestDB(t)
+	for _, w := range []models.BannedWord{
+		{Word: "darn", Action: sqlite.WordReplace},
+		{Word: "heck", Action: sqlite.WordReplace, Replacement: "h*ck"},
+		{Word: "casino", Action: sqlite.WordBlock},
+	} {
+		if _, err := sqlite.SaveBannedWord(db, w); err != nil {
+			t.Fatalf("SaveBannedWord failed: %v", err)
+		}
+	}
+
+	c := &Content{Title: "Darn it", Content: "What the heck, darned darn"}
+	finding, err := WordFilter{}.Screen(db, c)
+	if err != nil {
+		t.Fatalf("Screen failed: %v", err)
+	}
+	if finding.Action != Allow || c.Title != "**** it" || c.Content != "What the h*ck, darned ****" {
+		t.Errorf("Unexpected replacement: %+v %+v", finding, c)
+	}
+
+	finding, _ = WordFilter{}.Screen(db, &Content{Content: "Best CASINO bonus"})
+	if finding.Action != Block {
+		t.Errorf("Expected a blocked word to block, got %+v", finding)
+	}
+
+	poll := &models.Poll{Question: "Darn or heck?", Options: []models.PollOption{{Text: "darn"}, {Text: "Neither"}}}
+	c = &Content{Tags: []string{"darn-it", "go", "heck"}, Poll: poll}
+	if finding, _ := (WordFilter{}).Screen(db, c); finding.Action != Allow {
+		t.Fatalf("Expected replaced words to be allowed, got %+v", finding)
+	}
+	if !reflect.DeepEqual(c.Tags, []string{"go"}) || poll.Question != "**** or h*ck?" || poll.Options[0].Text != "****" {
+		t.Errorf("Expected tags with banned words dropped and the poll masked, got %v %+v", c.Tags, poll)
+	}
+	for _, c := range []*Content{
+		{Tags: []string{"casino-night"}},
+		{Poll: &models.Poll{Question: "Where?", Options: []models.PollOption{{Text: "The casino"}, {Text: "Home"}}}},
+	} {
+		if finding, _ := (WordFilter{}).Screen(db, c); finding.Action != Block {
+			t.Errorf("Expected a blocked word in tags or polls to block, got %+v", finding)
+		}
+	}
+
+	// The compiled list is reused until the list changes
+	db.Exec(`INSERT INTO banned_words (word, action) VALUES ('gosh', 'block')`)
+	if finding, _ := (WordFilter{}).Screen(db, &Content{Content: "gosh"}); finding.Action != Allow {
+		t.Errorf("Expected the cached list to be used, got %+v", finding)
+	}
+	if err := sqlite.DeleteBannedWord(db, "darn"); err != nil {
+		t.Fatalf("DeleteBannedWord failed: %v", err)
+	}
+	c = &Content{Content: "gosh darn"}
+	if finding, _ := (WordFilter{}).Screen(db, c); finding.Action != Block {
+		t.Errorf("Expected the list to be reloaded after a change, got %+v %+v", finding, c)
+	}
+}
+
+func TestLinkLimit(t *testing.T) {
+	db := setupTestDB(t)
+	newbie := createUser(t, db, "newbie")
+	veteran := createUser(t, db, "veteran")
+	if _, err := db.Exec(`UPDATE users SET created_at = datetime('now', '-30 days') WHERE id = ?`, veteran); err != nil {
+		t.Fatalf("Failed to age account: %v", err)
+	}
+
+	links := LinkLimit{MaxLinks: 2, NewAccountAge: 7 * 24 * time.Hour}
+	spam := "http://a.example https://b.example HTTPS://c.example"
+	if finding, err := links.Screen(db, &Content{UserID: newbie, Content: spam}); err != nil || finding.Action != Flag {
+		t.Errorf("Expected 3 links from a new account to be flagged, got %+v, %v", finding, err)
+	}
+	if finding, _ := links.Screen(db, &Content{UserID: newbie, Content: "http://a.example http://b.example"}); finding.Action != Allow {
+		t.Errorf("Expected 2 links to be allowed, got %+v", finding)
+	}
+	if finding, _ := links.Screen(db, &Content{UserID: veteran, Content: spam}); finding.Action != Allow {
+		t.Errorf("Expected older accounts to be trusted with links, got %+v", finding)
+	}
+}
+
+func TestDuplicateAndVelocity(t *testing.T) {
+	db := setupTestDB(t)
+	author := createUser(t, db, "author")
+	text := "Buy my amazing product now, it is the best product"
+	post, err := sqlite.CreatePost(db, author, nil, "Hello", text, "")
+	if err != nil {
+		t.Fatalf("CreatePost failed: %v", err)
+	}
+	if _, err := sqlite.CreateComment(db, author, post.ID, "Thanks!"); err != nil {
+		t.Fatalf("CreateComment failed: %v", err)
+	}
+	draft := "A draft nobody has seen yet, however long it gets"
+	if _, err := sqlite.CreatePostWithDetails(db, author, "Draft", draft, models.PostDetails{Status: sqlite.PostDraft}); err != nil {
+		t.Fatalf("CreatePostWithDetails failed: %v", err)
+	}
+
+	duplicates := DuplicateContent{Window: time.Hour, MinLength: 30}
+	if finding, err := duplicates.Screen(db, &Content{UserID: author, Content: "  BUY my amazing product now,\nit is the best product"}); err != nil || finding.Action != Flag {
+		t.Errorf("Expected the repeated text to be flagged, got %+v, %v", finding, err)
+	}
+	if finding, _ := duplicates.Screen(db, &Content{UserID: author, Content: "Thanks!"}); finding.Action != Allow {
+		t.Errorf("Expected short repeats to be allowed, got %+v", finding)
+	}
+	if finding, _ := duplicates.Screen(db, &Content{UserID: author, Content: draft}); finding.Action != Allow {
+		t.Errorf("Expected the text of a draft to be allowed, got %+v", finding)
+	}
+	if finding, _ := duplicates.Screen(db, &Content{UserID: author, PostID: post.ID, Content: text}); finding.Action != Allow {
+		t.Errorf("Expected an edit not to repeat the post it edits, got %+v", finding)
+	}
+
+	if finding, _ := (Velocity{MaxItems: 3, Window: time.Minute}).Screen(db, &Content{UserID: author}); finding.Action != Allow {
+		t.Errorf("Expected 2 recent items to be allowed, got %+v", finding)
+	}
+	if _, err := sqlite.QueueContent(db, models.QueueItem{Kind: sqlite.ContentComment, UserID: author, TargetID: &post.ID, Content: "Held"}); err != nil {
+		t.Fatalf("QueueContent failed: %v", err)
+	}
+	if finding, _ := (Velocity{MaxItems: 3, Window: time.Minute}).Screen(db, &Content{UserID: author}); finding.Action != Flag {
+		t.Errorf("Expected held content to count towards the limit, got %+v", finding)
+	}
+}
+
+func TestScreenerErrors(t *testing.T) {
+	db := setupTestDB(t)
+	db.Exec(`DROP TABLE banned_words`)
+	if _, err := (Pipeline{WordFilter{}}).Screen(db, &Content{}); err == nil {
+		t.Error("Expected a failing screener to fail the pipeline")
+	}
+}
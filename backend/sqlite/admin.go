@@ -0,0 +1,222 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"forum/models"
+)
+
+// ErrUnknownSort is returned by ListUsers for a sort it does not know
+var ErrUnknownSort = errors.New("unknown sort order")
+
+// Stats periods accepted by GetSiteStats
+const (
+	PeriodDay  = "day"
+	PeriodWeek = "week"
+)
+
+// bucketExpressions turn a timestamp column into the start of its day or
+// week (weeks start on Monday), as YYYY-MM-DD
+var bucketExpressions = map[string]string{
+	PeriodDay:  `date(%s)`,
+	PeriodWeek: `date(%s, '-6 days', 'weekday 1')`,
+}
+
+// siteSeries are the activity counts of the dashboard, each read from the
+// rows of one table matching where and bucketed by one of its columns
+var siteSeries = []struct {
+	table, column, count, where string
+	add                         func(b *models.StatsBucket, n int)
+}{
+	{"users", "created_at", "COUNT(*)", "1", func(b *models.StatsBucket, n int) { b.Registrations += n }},
+	{"user_activity", "day", "COUNT(DISTINCT user_id)", "1", func(b *models.StatsBucket, n int) { b.ActiveUsers += n }},
+	{"posts", "created_at", "COUNT(*)", "status = 'published'", func(b *models.StatsBucket, n int) { b.Posts += n }},
+	{"comments", "created_at", "COUNT(*)", "1", func(b *models.StatsBucket, n int) { b.Comments += n }},
+	{"replycomments", "created_at", "COUNT(*)", "1", func(b *models.StatsBucket, n int) { b.Comments += n }},
+	{"likes", "created_at", "COUNT(*)", "1", func(b *models.StatsBucket, n int) { b.Reactions += n }},
+}
+
+// GetSiteStats returns the forum's activity per day or week from from to to
+// (UTC dates, inclusive), its totals, the database size and the categories
+// with the most posts. The upload size is left for the caller to fill in.
+func GetSiteStats(db *sql.DB, period string, from, to time.Time, topCategories int) (models.SiteStats, error) {
+	bucket, ok := bucketExpressions[period]
+	if !ok {
+		return models.SiteStats{}, fmt.Errorf("unknown stats period %q", period)
+	}
+	step := 1
+	from = from.UTC().Truncate(24 * time.Hour)
+	to = to.UTC().Truncate(24 * time.Hour)
+	if period == PeriodWeek {
+		step = 7
+		from = from.AddDate(0, 0, -((int(from.Weekday()) + 6) % 7)) // back to Monday
+	}
+
+	stats := models.SiteStats{
+		Period: period,
+		From:   from.Format(statsDateFormat),
+		To:     to.Format(statsDateFormat),
+		Series: []models.StatsBucket{},
+	}
+	buckets := make(map[string]*models.StatsBucket)
+	for start := from; !start.After(to); start = start.AddDate(0, 0, step) {
+		stats.Series = append(stats.Series, models.StatsBucket{Start: start.Format(statsDateFormat)})
+	}
+	for i := range stats.Series {
+		buckets[stats.Series[i].Start] = &stats.Series[i]
+	}
+
+	for _, series := range siteSeries {
+		expr := fmt.Sprintf(bucket, series.column)
+		rows, err := db.Query(fmt.Sprintf(`
+			SELECT %s AS bucket, %s
+			FROM %s
+			WHERE %s AND date(%s) BETWEEN ? AND ?
+			GROUP BY bucket
+		`, expr, series.count, series.table, series.where, series.column), stats.From, stats.To)
+		if err != nil {
+			return stats, err
+		}
+		for rows.Next() {
+			var start string
+			var n int
+			if err := rows.Scan(&start, &n); err != nil {
+				rows.Close()
+				return stats, err
+			}
+			if b, ok := buckets[start]; ok {
+				series.add(b, n)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return stats, err
+		}
+	}
+
+	err := db.QueryRow(`
+		SELECT
+			(SELECT COUNT(*) FROM users),
+			(SELECT COUNT(*) FROM users WHERE `+activeBan+`),
+			(SELECT COUNT(DISTINCT user_id) FROM sessions WHERE datetime(created_at) > datetime('now', '-24 hours')),
+			(SELECT COUNT(*) FROM posts WHERE status = 'published'),
+			(SELECT COUNT(*) FROM comments) + (SELECT COUNT(*) FROM replycomments),
+			(SELECT COUNT(*) FROM likes)
+	`).Scan(&stats.Totals.Users, &stats.Totals.BannedUsers, &stats.Totals.ActiveNow,
+		&stats.Totals.Posts, &stats.Totals.Comments, &stats.Totals.Reactions)
+	if err != nil {
+		return stats, err
+	}
+
+	var pageCount, pageSize int64
+	if err := db.QueryRow(`PRAGMA page_count`).Scan(&pageCount); err != nil {
+		return stats, err
+	}
+	if err := db.QueryRow(`PRAGMA page_size`).Scan(&pageSize); err != nil {
+		return stats, err
+	}
+	stats.DatabaseBytes = pageCount * pageSize
+
+	stats.TopCategories, err = getTopCategories(db, topCategories)
+	return stats, err
+}
+
+// getTopCategories returns the categories with the most posts
+func getTopCategories(db *sql.DB, limit int) ([]models.CategoryCount, error) {
+	rows, err := db.Query(`
+		SELECT c.id, c.name, COUNT(*) AS post_count
+		FROM categories c
+		JOIN post_categories pc ON pc.category_id = c.id
+		JOIN posts p ON p.id = pc.post_id AND p.status = 'published'
+		GROUP BY c.id
+		ORDER BY post_count DESC, c.name
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []models.CategoryCount{}
+	for rows.Next() {
+		var c models.CategoryCount
+		if err := rows.Scan(&c.ID, &c.Name, &c.PostCount); err != nil {
+			return nil, err
+		}
+		categories = append(categories, c)
+	}
+	return categories, rows.Err()
+}
+
+// userSortColumns are the orders ListUsers accepts, by name
+var userSortColumns = map[string]string{
+	"username":   "u.username COLLATE NOCASE",
+	"created_at": "u.created_at",
+	"last_seen":  "u.last_seen_at",
+	"posts":      "post_count",
+	"comments":   "comment_count",
+}
+
+// ListUsers returns a page of users whose username or email contains search
+// (all users if it is empty), ordered by one of the userSortColumns. Ties are
+// broken by username.
+func ListUsers(db *sql.DB, search, sort string, desc bool, page, limit int) ([]models.AdminUser, error) {
+	column, ok := userSortColumns[sort]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownSort, sort)
+	}
+	direction := "ASC"
+	if desc {
+		direction = "DESC"
+	}
+	pattern := "%" + strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(search) + "%"
+
+	rows, err := db.Query(fmt.Sprintf(`
+		SELECT u.id, u.username, u.email, u.role, u.created_at, u.last_seen_at,
+			`+activeBan+`, u.banned_at, u.banned_until, u.ban_reason,
+			(SELECT COUNT(*) FROM posts WHERE user_id = u.id AND status = 'published') AS post_count,
+			(SELECT COUNT(*) FROM comments WHERE user_id = u.id)
+				+ (SELECT COUNT(*) FROM replycomments WHERE user_id = u.id) AS comment_count
+		FROM users u
+		WHERE u.username LIKE ? ESCAPE '\' OR u.email LIKE ? ESCAPE '\'
+		ORDER BY %s %s, u.username
+		LIMIT ? OFFSET ?
+	`, column, direction), pattern, pattern, limit, (page-1)*limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []models.AdminUser{}
+	for rows.Next() {
+		var u models.AdminUser
+		var lastSeen, bannedAt, bannedUntil sql.NullTime
+		var banned bool
+		var reason string
+		if err := rows.Scan(&u.ID, &u.Username, &u.Email, &u.Role, &u.CreatedAt, &lastSeen,
+			&banned, &bannedAt, &bannedUntil, &reason, &u.PostCount, &u.CommentCount); err != nil {
+			return nil, err
+		}
+		if lastSeen.Valid {
+			u.LastSeenAt = &lastSeen.Time
+		}
+		// Expired suspensions linger until they are tidied up
+		if banned {
+			u.Ban = newBan(reason, bannedAt.Time, bannedUntil)
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// recordActivity notes that a user was signed in on the day of at
+func recordActivity(q interface {
+	Exec(string, ...any) (sql.Result, error)
+}, userID string, at time.Time) error {
+	_, err := q.Exec(`INSERT OR IGNORE INTO user_activity (user_id, day) VALUES (?, ?)`, userID, at.UTC().Format(statsDateFormat))
+	return err
+}
@@ -0,0 +1,152 @@
+package sqlite
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"forum/models"
+)
+
+func TestGetSiteStats(t *testing.T) {
+	db := setupSchemaTestDB(t)
+	alice := createTestUser(t, db, "alice")
+	bob := createTestUser(t, db, "bob")
+
+	category, err := CreateCategory(db, models.Category{Name: "Go", Slug: "go"})
+	if err != nil {
+		t.Fatalf("CreateCategory failed: %v", err)
+	}
+	post, err := CreatePost(db, alice, []int{category.ID}, "Hello", "World", "")
+	if err != nil {
+		t.Fatalf("CreatePost failed: %v", err)
+	}
+	if _, err := CreateDraft(db, alice, []int{category.ID}, "Later", "Not yet", "", nil); err != nil {
+		t.Fatalf("CreateDraft failed: %v", err)
+	}
+	if _, err := CreateComment(db, bob, post.ID, "Hi"); err != nil {
+		t.Fatalf("CreateComment failed: %v", err)
+	}
+	if _, err := ToggleLike(db, bob, &post.ID, nil, "like"); err != nil {
+		t.Fatalf("ToggleLike failed: %v", err)
+	}
+
+	// Wednesday 6 and Monday 11 March 2024
+	_, err = db.Exec(`
+		UPDATE users SET created_at = '2024-03-06 10:00:00';
+		UPDATE posts SET created_at = '2024-03-06 11:00:00';
+		UPDATE comments SET created_at = '2024-03-11 09:00:00';
+		UPDATE likes SET created_at = '2024-03-11 09:30:00';
+		INSERT INTO user_activity (user_id, day) VALUES (?, '2024-03-06'), (?, '2024-03-07'), (?, '2024-03-11');
+	`, alice, alice, bob)
+	if err != nil {
+		t.Fatalf("Failed to back-date activity: %v", err)
+	}
+
+	from := time.Date(2024, 3, 6, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 3, 12, 0, 0, 0, 0, time.UTC)
+
+	t.Run("per day", func(t *testing.T) {
+		stats, err := GetSiteStats(db, PeriodDay, from, to, 5)
+		if err != nil {
+			t.Fatalf("GetSiteStats failed: %v", err)
+		}
+		if len(stats.Series) != 7 {
+			t.Fatalf("Expected 7 days, got %d", len(stats.Series))
+		}
+		if day := stats.Series[0]; day.Registrations != 2 || day.Posts != 1 || day.ActiveUsers != 1 {
+			t.Errorf("Unexpected first day: %+v", day)
+		}
+		if day := stats.Series[5]; day.Comments != 1 || day.Reactions != 1 || day.ActiveUsers != 1 {
+			t.Errorf("Unexpected 11 March: %+v", day)
+		}
+		if stats.Totals.Users != 2 || stats.Totals.Posts != 1 || stats.Totals.Reactions != 1 {
+			t.Errorf("Unexpected totals: %+v", stats.Totals)
+		}
+		if stats.DatabaseBytes <= 0 {
+			t.Error("Expected the database size")
+		}
+		if len(stats.TopCategories) != 1 || stats.TopCategories[0].Name != "Go" || stats.TopCategories[0].PostCount != 1 {
+			t.Errorf("Unexpected top categories: %+v", stats.TopCategories)
+		}
+	})
+
+	t.Run("per week", func(t *testing.T) {
+		stats, err := GetSiteStats(db, PeriodWeek, from, to, 5)
+		if err != nil {
+			t.Fatalf("GetSiteStats failed: %v", err)
+		}
+		if stats.From != "2024-03-04" || len(stats.Series) != 2 {
+			t.Fatalf("Expected two weeks from Monday 4 March, got %s with %d", stats.From, len(stats.Series))
+		}
+		// Alice was active twice in the first week but counts once
+		if week := stats.Series[0]; week.Registrations != 2 || week.ActiveUsers != 1 || week.Comments != 0 {
+			t.Errorf("Unexpected first week: %+v", week)
+		}
+		if week := stats.Series[1]; week.Start != "2024-03-11" || week.Comments != 1 || week.Reactions != 1 {
+			t.Errorf("Unexpected second week: %+v", week)
+		}
+	})
+}
+
+func TestListUsers(t *testing.T) {
+	db := setupSchemaTestDB(t)
+	alice := createTestUser(t, db, "alice")
+	createTestUser(t, db, "bob")
+	createTestUser(t, db, "alicia")
+	if _, err := CreatePost(db, alice, nil, "Hello", "World", ""); err != nil {
+		t.Fatalf("CreatePost failed: %v", err)
+	}
+
+	t.Run("search and sort", func(t *testing.T) {
+		users, err := ListUsers(db, "ali", "username", true, 1, 10)
+		if err != nil {
+			t.Fatalf("ListUsers failed: %v", err)
+		}
+		if len(users) != 2 || users[0].Username != "alicia" || users[1].Username != "alice" {
+			t.Errorf("Unexpected users: %+v", users)
+		}
+
+		users, err = ListUsers(db, "", "posts", true, 1, 1)
+		if err != nil {
+			t.Fatalf("ListUsers failed: %v", err)
+		}
+		if len(users) != 1 || users[0].ID != alice || users[0].PostCount != 1 {
+			t.Errorf("Expected alice first by posts, got %+v", users)
+		}
+
+		if _, err := ListUsers(db, "", "password_hash", false, 1, 10); !errors.Is(err, ErrUnknownSort) {
+			t.Errorf("Expected ErrUnknownSort, got %v", err)
+		}
+	})
+
+	t.Run("bans and suspensions", func(t *testing.T) {
+		admin := createTestUser(t, db, "admin")
+		if err := SetUserRole(db, "admin", RoleAdmin); err != nil {
+			t.Fatalf("SetUserRole failed: %v", err)
+		}
+		bob, _ := GetUserByUsername(db, "bob")
+		if err := BanUser(db, admin, alice, "Spam", nil); err != nil {
+			t.Fatalf("BanUser failed: %v", err)
+		}
+		ended := time.Now().Add(-time.Hour)
+		if err := BanUser(db, admin, bob.ID, "Flaming", &ended); err != nil {
+			t.Fatalf("BanUser failed: %v", err)
+		}
+
+		users, err := ListUsers(db, "", "username", false, 1, 10)
+		if err != nil {
+			t.Fatalf("ListUsers failed: %v", err)
+		}
+		bans := make(map[string]*models.Ban)
+		for _, u := range users {
+			bans[u.Username] = u.Ban
+		}
+		if bans["alice"] == nil || bans["alice"].Reason != "Spam" || bans["alice"].Until != nil {
+			t.Errorf("Expected alice's permanent ban, got %+v", bans["alice"])
+		}
+		if bans["bob"] != nil {
+			t.Errorf("Expected bob's ended suspension to be left out, got %+v", bans["bob"])
+		}
+	})
+}
@@ -0,0 +1,100 @@
+package sqlite
+
+import (
+	"database/sql"
+
+	"forum/models"
+)
+
+// hiddenAuthors selects the users whose content a viewer does not want to
+// see: those they muted or blocked. It takes the viewer ID twice.
+const hiddenAuthors = `
+	SELECT muted_id FROM user_mutes WHERE muter_id = ?
+	UNION
+	SELECT blocked_id FROM user_blocks WHERE blocker_id = ?`
+
+// IsBlocked reports whether either user has blocked the other
+func IsBlocked(db *sql.DB, userID, otherID string) (bool, error) {
+	var count int
+	err := db.QueryRow(`
+		SELECT COUNT(*) FROM user_blocks
+		WHERE (blocker_id = ? AND blocked_id = ?) OR (blocker_id = ? AND blocked_id = ?)
+	`, userID, otherID, otherID, userID).Scan(&count)
+	return count > 0, err
+}
+
+// BlockUser blocks a user and removes any follows between the two
+func BlockUser(db *sql.DB, blockerID, blockedID string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`INSERT OR IGNORE INTO user_blocks (blocker_id, blocked_id) VALUES (?, ?)`, blockerID, blockedID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+		DELETE FROM follows
+		WHERE (follower_id = ? AND followee_id = ?) OR (follower_id = ? AND followee_id = ?)
+	`, blockerID, blockedID, blockedID, blockerID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UnblockUser removes a block if there is one
+func UnblockUser(db *sql.DB, blockerID, blockedID string) error {
+	_, err := db.Exec(`DELETE FROM user_blocks WHERE blocker_id = ? AND blocked_id = ?`, blockerID, blockedID)
+	return err
+}
+
+// GetBlockedUsers returns the users userID blocked, most recent first
+func GetBlockedUsers(db *sql.DB, userID string) ([]models.UserSummary, error) {
+	return queryUserSummaries(db, `
+		SELECT u.id, u.username, u.avatar_url
+		FROM user_blocks b
+		JOIN users u ON u.id = b.blocked_id
+		WHERE b.blocker_id = ?
+		ORDER BY b.created_at DESC, u.username
+	`, userID)
+}
+
+// MuteUser mutes a user; muting twice is a no-op
+func MuteUser(db *sql.DB, muterID, mutedID string) error {
+	_, err := db.Exec(`INSERT OR IGNORE INTO user_mutes (muter_id, muted_id) VALUES (?, ?)`, muterID, mutedID)
+	return err
+}
+
+// UnmuteUser removes a mute if there is one
+func UnmuteUser(db *sql.DB, muterID, mutedID string) error {
+	_, err := db.Exec(`DELETE FROM user_mutes WHERE muter_id = ? AND muted_id = ?`, muterID, mutedID)
+	return err
+}
+
+// GetMutedUsers returns the users userID muted, most recent first
+func GetMutedUsers(db *sql.DB, userID string) ([]models.UserSummary, error) {
+	return queryUserSummaries(db, `
+		SELECT u.id, u.username, u.avatar_url
+		FROM user_mutes m
+		JOIN users u ON u.id = m.muted_id
+		WHERE m.muter_id = ?
+		ORDER BY m.created_at DESC, u.username
+	`, userID)
+}
+
+// GetPostAuthorID returns the author of a published post. Drafts and
+// scheduled posts give sql.ErrNoRows, so nobody can react to or comment on
+// them.
+func GetPostAuthorID(db *sql.DB, postID int) (string, error) {
+	var userID string
+	err := db.QueryRow(`SELECT user_id FROM posts WHERE id = ? AND status = 'published'`, postID).Scan(&userID)
+	return userID, err
+}
+
+// GetCommentAuthorID returns the author of a top-level comment
+func GetCommentAuthorID(db *sql.DB, commentID int) (string, error) {
+	var userID string
+	err := db.QueryRow(`SELECT user_id FROM comments WHERE id = ?`, commentID).Scan(&userID)
+	return userID, err
+}
@@ -0,0 +1,115 @@
+package sqlite
+
+import (
+	"testing"
+)
+
+func TestBlocksAndMutes(t *testing.T) {
+	db := setupSchemaTestDB(t)
+	alice := createTestUser(t, db, "alice")
+	bob := createTestUser(t, db, "bob")
+	carol := createTestUser(t, db, "carol")
+
+	bobPost, err := CreatePost(db, bob, nil, "Bob's post", "Body", "")
+	if err != nil {
+		t.Fatalf("CreatePost failed: %v", err)
+	}
+	if _, err := CreatePost(db, carol, nil, "Carol's post", "Body", ""); err != nil {
+		t.Fatalf("CreatePost failed: %v", err)
+	}
+	carolComment, err := CreateComment(db, carol, bobPost.ID, "carol here")
+	if err != nil {
+		t.Fatalf("CreateComment failed: %v", err)
+	}
+	if _, err := CreateComment(db, bob, bobPost.ID, "bob here"); err != nil {
+		t.Fatalf("CreateComment failed: %v", err)
+	}
+	if _, err := CreateReplyComment(db, bob, carolComment.ID, "bob replies"); err != nil {
+		t.Fatalf("CreateReplyComment failed: %v", err)
+	}
+
+	t.Run("muting hides posts, comments and replies", func(t *testing.T) {
+		if err := MuteUser(db, alice, bob); err != nil {
+			t.Fatalf("MuteUser failed: %v", err)
+		}
+
+		posts, err := GetPosts(db, alice, PostFilter{}, 1, 10)
+		if err != nil || len(posts) != 1 || posts[0].UserID != carol {
+			t.Errorf("Expected only carol's post, got %+v (%v)", posts, err)
+		}
+		comments, err := GetPostComments(db, bobPost.ID, alice)
+		if err != nil || len(comments) != 1 || comments[0].UserID != carol || len(comments[0].Replies) != 0 {
+			t.Errorf("Expected only carol's comment without replies, got %+v (%v)", comments, err)
+		}
+
+		// Only alice is affected
+		if posts, _ := GetPosts(db, carol, PostFilter{}, 1, 10); len(posts) != 2 {
+			t.Errorf("Expected carol to see both posts, got %d", len(posts))
+		}
+		if posts, _ := GetPosts(db, "", PostFilter{}, 1, 10); len(posts) != 2 {
+			t.Errorf("Expected visitors to see both posts, got %d", len(posts))
+		}
+
+		muted, err := GetMutedUsers(db, alice)
+		if err != nil || len(muted) != 1 || muted[0].ID != bob {
+			t.Errorf("Expected bob to be muted, got %+v (%v)", muted, err)
+		}
+		if err := UnmuteUser(db, alice, bob); err != nil {
+			t.Fatalf("UnmuteUser failed: %v", err)
+		}
+		if posts, _ := GetPosts(db, alice, PostFilter{}, 1, 10); len(posts) != 2 {
+			t.Errorf("Expected both posts after unmuting, got %d", len(posts))
+		}
+	})
+
+	t.Run("blocking hides content and removes follows", func(t *testing.T) {
+		if err := FollowUser(db, alice, carol); err != nil {
+			t.Fatalf("FollowUser failed: %v", err)
+		}
+		if err := FollowUser(db, carol, alice); err != nil {
+			t.Fatalf("FollowUser failed: %v", err)
+		}
+		if err := BlockUser(db, alice, carol); err != nil {
+			t.Fatalf("BlockUser failed: %v", err)
+		}
+
+		if blocked, err := IsBlocked(db, carol, alice); err != nil || !blocked {
+			t.Errorf("Expected a block in either direction to count (%v)", err)
+		}
+		if n := countRows(t, db, `SELECT COUNT(*) FROM follows`); n != 0 {
+			t.Errorf("Expected follows to be removed, got %d", n)
+		}
+		if posts, _ := GetPosts(db, alice, PostFilter{}, 1, 10); len(posts) != 1 || posts[0].UserID != bob {
+			t.Errorf("Expected only bob's post, got %+v", posts)
+		}
+		blocked, err := GetBlockedUsers(db, alice)
+		if err != nil || len(blocked) != 1 || blocked[0].ID != carol {
+			t.Errorf("Expected carol to be blocked, got %+v (%v)", blocked, err)
+		}
+	})
+
+	t.Run("blocked users cannot be mentioned", func(t *testing.T) {
+		post, err := CreatePost(db, carol, nil, "Hi", "hello @alice and @bob", "")
+		if err != nil {
+			t.Fatalf("CreatePost failed: %v", err)
+		}
+		if n := countRows(t, db, `SELECT COUNT(*) FROM mentions WHERE post_id = ? AND mentioned_user_id = ?`, post.ID, alice); n != 0 {
+			t.Errorf("Expected no mention of alice, got %d", n)
+		}
+		if n := countRows(t, db, `SELECT COUNT(*) FROM notifications WHERE user_id = ?`, alice); n != 0 {
+			t.Errorf("Expected no notification for alice, got %d", n)
+		}
+		if n := countRows(t, db, `SELECT COUNT(*) FROM mentions WHERE post_id = ? AND mentioned_user_id = ?`, post.ID, bob); n != 1 {
+			t.Errorf("Expected bob to be mentioned, got %d", n)
+		}
+	})
+
+	t.Run("unblock", func(t *testing.T) {
+		if err := UnblockUser(db, alice, carol); err != nil {
+			t.Fatalf("UnblockUser failed: %v", err)
+		}
+		if blocked, _ := IsBlocked(db, alice, carol); blocked {
+			t.Error("Expected the block to be removed")
+		}
+	})
+}
@@ -0,0 +1,349 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"forum/models"
+)
+
+// ErrFolderNotFound is returned when a bookmark folder does not exist or
+// belongs to another user
+var ErrFolderNotFound = errors.New("bookmark folder not found")
+
+// GetBookmarkFolders returns a user's folders by name, with their sizes
+func GetBookmarkFolders(db *sql.DB, userID string) ([]models.BookmarkFolder, error) {
+	rows, err := db.Query(`
+		SELECT f.id, f.name, f.created_at,
+			(SELECT COUNT(*) FROM bookmarks b WHERE b.user_id = f.user_id AND b.folder_id = f.id)
+		FROM bookmark_folders f
+		WHERE f.user_id = ?
+		ORDER BY f.name
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	folders := []models.BookmarkFolder{}
+	for rows.Next() {
+		var f models.BookmarkFolder
+		if err := rows.Scan(&f.ID, &f.Name, &f.CreatedAt, &f.BookmarkCount); err != nil {
+			return nil, err
+		}
+		folders = append(folders, f)
+	}
+	return folders, rows.Err()
+}
+
+// CreateBookmarkFolder adds a folder; names are unique per user
+func CreateBookmarkFolder(db *sql.DB, userID, name string) (models.BookmarkFolder, error) {
+	var f models.BookmarkFolder
+	err := db.QueryRow(`
+		INSERT INTO bookmark_folders (user_id, name) VALUES (?, ?)
+		RETURNING id, name, created_at
+	`, userID, name).Scan(&f.ID, &f.Name, &f.CreatedAt)
+	return f, err
+}
+
+// RenameBookmarkFolder renames one of a user's folders
+func RenameBookmarkFolder(db *sql.DB, userID string, folderID int, name string) error {
+	result, err := db.Exec(`UPDATE bookmark_folders SET name = ? WHERE id = ? AND user_id = ?`, name, folderID, userID)
+	return folderChanged(result, err)
+}
+
+// DeleteBookmarkFolder deletes one of a user's folders. Its bookmarks are
+// kept, unfiled.
+func DeleteBookmarkFolder(db *sql.DB, userID string, folderID int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Unfiled by hand: foreign keys are not enforced on every pooled
+	// connection, so ON DELETE SET NULL cannot be relied on
+	if _, err := tx.Exec(`UPDATE bookmarks SET folder_id = NULL WHERE folder_id = ? AND user_id = ?`, folderID, userID); err != nil {
+		return err
+	}
+	result, err := tx.Exec(`DELETE FROM bookmark_folders WHERE id = ? AND user_id = ?`, folderID, userID)
+	if err := folderChanged(result, err); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func folderChanged(result sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrFolderNotFound
+	}
+	return nil
+}
+
+// checkFolder returns ErrFolderNotFound unless folderID is nil or one of
+// userID's folders
+func checkFolder(db *sql.DB, userID string, folderID *int) error {
+	if folderID == nil {
+		return nil
+	}
+	var exists bool
+	err := db.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM bookmark_folders WHERE id = ? AND user_id = ?)
+	`, *folderID, userID).Scan(&exists)
+	if err == nil && !exists {
+		err = ErrFolderNotFound
+	}
+	return err
+}
+
+// SaveBookmark bookmarks a post or a comment (exactly one of postID and
+// commentID) and returns the bookmark ID. Saving something already
+// bookmarked moves it to folderID and replaces its note.
+func SaveBookmark(db *sql.DB, userID string, postID, commentID, folderID *int, note string) (int, error) {
+	if (postID == nil) == (commentID == nil) {
+		return 0, errors.New("must provide either postID or commentID, but not both")
+	}
+	if err := checkFolder(db, userID, folderID); err != nil {
+		return 0, err
+	}
+
+	target := "post_id"
+	if commentID != nil {
+		target = "comment_id"
+	}
+
+	var id int
+	err := db.QueryRow(fmt.Sprintf(`
+		INSERT INTO bookmarks (user_id, post_id, comment_id, folder_id, note)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (user_id, %[1]s) WHERE %[1]s IS NOT NULL
+		DO UPDATE SET folder_id = excluded.folder_id, note = excluded.note
+		RETURNING id
+	`, target), userID, postID, commentID, folderID, note).Scan(&id)
+	return id, err
+}
+
+// UpdateBookmark moves one of a user's bookmarks to folderID (nil to unfile
+// it) and replaces its note. It returns sql.ErrNoRows if there is no such
+// bookmark.
+func UpdateBookmark(db *sql.DB, userID string, bookmarkID int, folderID *int, note string) error {
+	if err := checkFolder(db, userID, folderID); err != nil {
+		return err
+	}
+	result, err := db.Exec(`
+		UPDATE bookmarks SET folder_id = ?, note = ? WHERE id = ? AND user_id = ?
+	`, folderID, note, bookmarkID, userID)
+	return bookmarkChanged(result, err)
+}
+
+// DeleteBookmark removes one of a user's bookmarks. It returns sql.ErrNoRows
+// if there is no such bookmark.
+func DeleteBookmark(db *sql.DB, userID string, bookmarkID int) error {
+	result, err := db.Exec(`DELETE FROM bookmarks WHERE id = ? AND user_id = ?`, bookmarkID, userID)
+	return bookmarkChanged(result, err)
+}
+
+func bookmarkChanged(result sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// BookmarkFilter selects bookmarks by folder. The zero value selects all of
+// them; Unfiled selects those in no folder.
+type BookmarkFilter struct {
+	FolderID *int
+	Unfiled  bool
+}
+
+// GetBookmarks returns a page of a user's bookmarks, most recently saved
+// first, with the bookmarked post or comment
+func GetBookmarks(db *sql.DB, userID string, filter BookmarkFilter, page, limit int) ([]models.Bookmark, error) {
+	query := `
+		SELECT id, post_id, comment_id, folder_id, note, created_at
+		FROM bookmarks
+		WHERE user_id = ?`
+	args := []any{userID}
+	if filter.FolderID != nil {
+		query += ` AND folder_id = ?`
+		args = append(args, *filter.FolderID)
+	} else if filter.Unfiled {
+		query += ` AND folder_id IS NULL`
+	}
+	query += `
+		ORDER BY created_at DESC, id DESC
+		LIMIT ? OFFSET ?`
+	args = append(args, limit, (page-1)*limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bookmarks := []models.Bookmark{}
+	var postIDs, commentIDs []int
+	for rows.Next() {
+		var b models.Bookmark
+		var postID, commentID, folderID sql.NullInt64
+		if err := rows.Scan(&b.ID, &postID, &commentID, &folderID, &b.Note, &b.CreatedAt); err != nil {
+			return nil, err
+		}
+		b.FolderID = intPtr(folderID)
+		if postID.Valid {
+			postIDs = append(postIDs, int(postID.Int64))
+			b.Post = &models.Post{ID: int(postID.Int64)}
+		} else {
+			commentIDs = append(commentIDs, int(commentID.Int64))
+			b.Comment = &models.UserComment{ID: int(commentID.Int64), Kind: "comment"}
+		}
+		bookmarks = append(bookmarks, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	posts, err := getPostsByIDs(db, postIDs)
+	if err != nil {
+		return nil, err
+	}
+	comments, err := getCommentsByIDs(db, commentIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range bookmarks {
+		if b := &bookmarks[i]; b.Post != nil {
+			post := posts[b.Post.ID]
+			post.IsBookmarked = true
+			b.Post = &post
+		} else {
+			comment := comments[b.Comment.ID]
+			b.Comment = &comment
+		}
+	}
+	return bookmarks, nil
+}
+
+// getPostsByIDs loads posts by ID
+func getPostsByIDs(db *sql.DB, ids []int) (map[int]models.Post, error) {
+	byID := make(map[int]models.Post, len(ids))
+	if len(ids) == 0 {
+		return byID, nil
+	}
+
+	placeholders := make([]string, len(ids))
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		placeholders[i] = "?"
+		args[i] = id
+	}
+
+	posts, err := queryPosts(db, fmt.Sprintf(`
+		SELECT `+postColumns+`
+		FROM posts
+		JOIN users ON posts.user_id = users.id
+		WHERE posts.id IN (%s)
+	`, strings.Join(placeholders, ",")), args...)
+	if err != nil {
+		return nil, err
+	}
+	for _, post := range posts {
+		byID[post.ID] = post
+	}
+	return byID, nil
+}
+
+// getCommentsByIDs loads comments by ID, with the post each belongs to
+func getCommentsByIDs(db *sql.DB, ids []int) (map[int]models.UserComment, error) {
+	byID := make(map[int]models.UserComment, len(ids))
+	if len(ids) == 0 {
+		return byID, nil
+	}
+
+	placeholders := make([]string, len(ids))
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		placeholders[i] = "?"
+		args[i] = id
+	}
+
+	rows, err := db.Query(fmt.Sprintf(`
+		SELECT c.id, c.post_id, p.title, c.content, c.created_at
+		FROM comments c
+		JOIN posts p ON p.id = c.post_id
+		WHERE c.id IN (%s)
+	`, strings.Join(placeholders, ",")), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	contents := make(map[int]string, len(ids))
+	for rows.Next() {
+		c := models.UserComment{Kind: "comment"}
+		if err := rows.Scan(&c.ID, &c.PostID, &c.PostTitle, &c.Content, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		contents[c.ID] = c.Content
+		byID[c.ID] = c
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for id, html := range renderContent(db, "comment_id", contents) {
+		c := byID[id]
+		c.ContentHTML = html
+		byID[id] = c
+	}
+	return byID, nil
+}
+
+// MarkBookmarked sets IsBookmarked on the posts userID has bookmarked
+func MarkBookmarked(db *sql.DB, userID string, posts []models.Post) error {
+	if userID == "" || len(posts) == 0 {
+		return nil
+	}
+
+	placeholders := make([]string, len(posts))
+	args := []any{userID}
+	for i, post := range posts {
+		placeholders[i] = "?"
+		args = append(args, post.ID)
+	}
+
+	rows, err := db.Query(fmt.Sprintf(`
+		SELECT post_id FROM bookmarks WHERE user_id = ? AND post_id IN (%s)
+	`, strings.Join(placeholders, ",")), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	saved := make(map[int]bool)
+	for rows.Next() {
+		var postID int
+		if err := rows.Scan(&postID); err != nil {
+			return err
+		}
+		saved[postID] = true
+	}
+	for i := range posts {
+		posts[i].IsBookmarked = saved[posts[i].ID]
+	}
+	return rows.Err()
+}
@@ -0,0 +1,131 @@
+package sqlite
+
+import (
+	"database/sql"
+	"testing"
+
+	"forum/models"
+)
+
+func TestBookmarks(t *testing.T) {
+	db := setupSchemaTestDB(t)
+	alice := createTestUser(t, db, "alice")
+	bob := createTestUser(t, db, "bob")
+
+	post, err := CreatePost(db, bob, nil, "Saved", "Body", "")
+	if err != nil {
+		t.Fatalf("CreatePost failed: %v", err)
+	}
+	other, err := CreatePost(db, bob, nil, "Other", "Body", "")
+	if err != nil {
+		t.Fatalf("CreatePost failed: %v", err)
+	}
+	comment, err := CreateComment(db, bob, post.ID, "a *good* point")
+	if err != nil {
+		t.Fatalf("CreateComment failed: %v", err)
+	}
+
+	folder, err := CreateBookmarkFolder(db, alice, "Reading")
+	if err != nil {
+		t.Fatalf("CreateBookmarkFolder failed: %v", err)
+	}
+	if _, err := CreateBookmarkFolder(db, alice, "Reading"); !IsUniqueConstraintError(err) {
+		t.Errorf("Expected a duplicate folder name to fail, got %v", err)
+	}
+	bobFolder, err := CreateBookmarkFolder(db, bob, "Reading")
+	if err != nil {
+		t.Fatalf("Expected other users to reuse folder names: %v", err)
+	}
+
+	postBookmark, err := SaveBookmark(db, alice, &post.ID, nil, nil, "later")
+	if err != nil {
+		t.Fatalf("SaveBookmark failed: %v", err)
+	}
+	if _, err := SaveBookmark(db, alice, nil, &comment.ID, &folder.ID, ""); err != nil {
+		t.Fatalf("SaveBookmark failed: %v", err)
+	}
+
+	t.Run("saving again updates the bookmark", func(t *testing.T) {
+		id, err := SaveBookmark(db, alice, &post.ID, nil, &folder.ID, "now")
+		if err != nil || id != postBookmark {
+			t.Fatalf("Expected bookmark %d to be updated, got %d (%v)", postBookmark, id, err)
+		}
+		if n := countRows(t, db, `SELECT COUNT(*) FROM bookmarks WHERE user_id = ?`, alice); n != 2 {
+			t.Errorf("Expected 2 bookmarks, got %d", n)
+		}
+	})
+
+	t.Run("other users' folders are rejected", func(t *testing.T) {
+		if _, err := SaveBookmark(db, alice, &other.ID, nil, &bobFolder.ID, ""); err != ErrFolderNotFound {
+			t.Errorf("Expected ErrFolderNotFound, got %v", err)
+		}
+	})
+
+	t.Run("list and filter", func(t *testing.T) {
+		bookmarks, err := GetBookmarks(db, alice, BookmarkFilter{FolderID: &folder.ID}, 1, 10)
+		if err != nil {
+			t.Fatalf("GetBookmarks failed: %v", err)
+		}
+		if len(bookmarks) != 2 {
+			t.Fatalf("Expected 2 bookmarks, got %+v", bookmarks)
+		}
+		for _, b := range bookmarks {
+			switch {
+			case b.Post != nil:
+				if b.Post.Title != "Saved" || !b.Post.IsBookmarked || b.Note != "now" {
+					t.Errorf("Unexpected post bookmark: %+v %+v", b, b.Post)
+				}
+			case b.Comment != nil:
+				if b.Comment.PostTitle != "Saved" || b.Comment.ContentHTML != "<p>a <em>good</em> point</p>" {
+					t.Errorf("Unexpected comment bookmark: %+v", b.Comment)
+				}
+			}
+		}
+
+		unfiled, err := GetBookmarks(db, alice, BookmarkFilter{Unfiled: true}, 1, 10)
+		if err != nil || len(unfiled) != 0 {
+			t.Errorf("Expected no unfiled bookmarks, got %+v (%v)", unfiled, err)
+		}
+	})
+
+	t.Run("deleting a folder unfiles its bookmarks", func(t *testing.T) {
+		// As on a pooled connection that never turned foreign keys on
+		if _, err := db.Exec(`PRAGMA foreign_keys = OFF`); err != nil {
+			t.Fatalf("Failed to disable foreign keys: %v", err)
+		}
+		defer db.Exec(`PRAGMA foreign_keys = ON`)
+
+		if err := DeleteBookmarkFolder(db, bob, folder.ID); err != ErrFolderNotFound {
+			t.Errorf("Expected bob to be unable to delete alice's folder, got %v", err)
+		}
+		if err := DeleteBookmarkFolder(db, alice, folder.ID); err != nil {
+			t.Fatalf("DeleteBookmarkFolder failed: %v", err)
+		}
+		unfiled, err := GetBookmarks(db, alice, BookmarkFilter{Unfiled: true}, 1, 10)
+		if err != nil || len(unfiled) != 2 {
+			t.Errorf("Expected 2 unfiled bookmarks, got %+v (%v)", unfiled, err)
+		}
+	})
+
+	t.Run("mark bookmarked posts", func(t *testing.T) {
+		posts := []models.Post{{ID: post.ID}, {ID: other.ID}}
+		if err := MarkBookmarked(db, alice, posts); err != nil {
+			t.Fatalf("MarkBookmarked failed: %v", err)
+		}
+		if !posts[0].IsBookmarked || posts[1].IsBookmarked {
+			t.Errorf("Unexpected flags: %+v", posts)
+		}
+	})
+
+	t.Run("update and delete", func(t *testing.T) {
+		if err := UpdateBookmark(db, bob, postBookmark, nil, "mine"); err != sql.ErrNoRows {
+			t.Errorf("Expected bob to be unable to edit alice's bookmark, got %v", err)
+		}
+		if err := DeleteBookmark(db, alice, postBookmark); err != nil {
+			t.Fatalf("DeleteBookmark failed: %v", err)
+		}
+		if err := DeleteBookmark(db, alice, postBookmark); err != sql.ErrNoRows {
+			t.Errorf("Expected sql.ErrNoRows, got %v", err)
+		}
+	})
+}
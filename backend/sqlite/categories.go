@@ -0,0 +1,387 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"forum/models"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+var (
+	// ErrUnknownCategory is returned for a category name that does not exist
+	// when users may not create categories
+	ErrUnknownCategory = errors.New("unknown category")
+	// ErrArchivedCategory is returned when posting to an archived category
+	ErrArchivedCategory = errors.New("category is archived")
+	// ErrCategoryCycle is returned when a category would become its own ancestor
+	ErrCategoryCycle = errors.New("a category cannot be nested under itself")
+)
+
+// categoryColumns is the select list scanCategory expects, in order
+const categoryColumns = `id, name, slug, description, color, parent_id, sort_order, archived`
+
+func scanCategory(row interface{ Scan(...any) error }) (models.Category, error) {
+	var c models.Category
+	var slug sql.NullString
+	var parentID sql.NullInt64
+	err := row.Scan(&c.ID, &c.Name, &slug, &c.Description, &c.Color, &parentID, &c.SortOrder, &c.Archived)
+	c.Slug = slug.String
+	c.ParentID = intPtr(parentID)
+	return c, err
+}
+
+// slugify turns a category name into a URL-friendly slug: lower case
+// letters and digits separated by single hyphens
+func slugify(name string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			hyphen = false
+		} else {
+			hyphen = true
+		}
+	}
+	if b.Len() == 0 {
+		return "category"
+	}
+	return b.String()
+}
+
+// uniqueSlug returns the slug for name, with a numeric suffix if another
+// category than excludeID already uses it
+func uniqueSlug(q interface {
+	QueryRow(string, ...any) *sql.Row
+}, name string, excludeID int) (string, error) {
+	base := slugify(name)
+	for i := 1; ; i++ {
+		slug := base
+		if i > 1 {
+			slug = fmt.Sprintf("%s-%d", base, i)
+		}
+		var taken bool
+		err := q.QueryRow(`SELECT EXISTS (SELECT 1 FROM categories WHERE slug = ? AND id != ?)`, slug, excludeID).Scan(&taken)
+		if err != nil || !taken {
+			return slug, err
+		}
+	}
+}
+
+// categoryStatsQuery lists categories with their activity in one pass. Each
+// CTE aggregates a whole table once instead of running a subquery per
+// category. Callers append the WHERE and ORDER BY clauses.
+const categoryStatsQuery = `
+	WITH category_posts AS (
+		SELECT pc.category_id, p.id, p.title, p.created_at,
+			COUNT(*) OVER (PARTITION BY pc.category_id) AS post_count,
+			ROW_NUMBER() OVER (PARTITION BY pc.category_id ORDER BY p.created_at DESC, p.id DESC) AS rank
+		FROM post_categories pc
+		JOIN posts p ON p.id = pc.post_id
+		WHERE p.status = 'published'
+	),
+	category_comments AS (
+		SELECT pc.category_id, COUNT(*) AS comment_count, MAX(x.created_at) AS last_comment_at
+		FROM post_categories pc
+		JOIN (
+			SELECT post_id, created_at FROM comments
+			UNION ALL
+			SELECT c.post_id, r.created_at FROM replycomments r JOIN comments c ON c.id = r.parent_comment_id
+		) x ON x.post_id = pc.post_id
+		GROUP BY pc.category_id
+	),
+	category_subscribers AS (
+		SELECT category_id, COUNT(*) AS subscriber_count
+		FROM category_follows
+		GROUP BY category_id
+	)
+	SELECT c.id, c.name, c.slug, c.description, c.color, c.parent_id, c.sort_order, c.archived,
+		COALESCE(lp.post_count, 0), COALESCE(cc.comment_count, 0), COALESCE(cs.subscriber_count, 0),
+		lp.created_at, cc.last_comment_at, lp.id, lp.title
+	FROM categories c
+	LEFT JOIN category_posts lp ON lp.category_id = c.id AND lp.rank = 1
+	LEFT JOIN category_comments cc ON cc.category_id = c.id
+	LEFT JOIN category_subscribers cs ON cs.category_id = c.id
+`
+
+// GetCategories returns the categories by sort order, then name, with their
+// stats. Archived categories are left out unless includeArchived is set.
+func GetCategories(db *sql.DB, includeArchived bool) ([]models.Category, error) {
+	rows, err := db.Query(categoryStatsQuery+`
+		WHERE c.archived = 0 OR ?
+		ORDER BY c.sort_order, c.name
+	`, includeArchived)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []models.Category{}
+	for rows.Next() {
+		var c models.Category
+		var stats models.CategoryStats
+		var slug, lastPostAt, lastCommentAt, latestTitle sql.NullString
+		var parentID, latestID sql.NullInt64
+		err := rows.Scan(
+			&c.ID, &c.Name, &slug, &c.Description, &c.Color, &parentID, &c.SortOrder, &c.Archived,
+			&stats.PostCount, &stats.CommentCount, &stats.SubscriberCount,
+			&lastPostAt, &lastCommentAt, &latestID, &latestTitle,
+		)
+		if err != nil {
+			return nil, err
+		}
+		c.Slug = slug.String
+		c.ParentID = intPtr(parentID)
+		stats.LatestPostID = intPtr(latestID)
+		stats.LatestPostTitle = latestTitle.String
+
+		// MAX() loses the DATETIME column type, so the timestamps are
+		// scanned as text and parsed here
+		for _, s := range []sql.NullString{lastPostAt, lastCommentAt} {
+			if !s.Valid {
+				continue
+			}
+			at, err := parseTimestamp(s.String)
+			if err != nil {
+				return nil, err
+			}
+			if stats.LastActivityAt == nil || at.After(*stats.LastActivityAt) {
+				stats.LastActivityAt = &at
+			}
+		}
+
+		c.Stats = &stats
+		categories = append(categories, c)
+	}
+	return categories, rows.Err()
+}
+
+// parseTimestamp parses a timestamp scanned as text: RFC 3339 for typed
+// columns, or any of the formats the driver writes for untyped ones
+func parseTimestamp(s string) (time.Time, error) {
+	for _, layout := range append([]string{time.RFC3339Nano}, sqlite3.SQLiteTimestampFormats...) {
+		if t, err := time.ParseInLocation(layout, s, time.UTC); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognised timestamp %q", s)
+}
+
+// GetCategory returns one category
+func GetCategory(db *sql.DB, categoryID int) (models.Category, error) {
+	return scanCategory(db.QueryRow(`SELECT `+categoryColumns+` FROM categories WHERE id = ?`, categoryID))
+}
+
+// GetCategoryByName returns the category with the given name or slug
+func GetCategoryByName(db *sql.DB, nameOrSlug string) (models.Category, error) {
+	return scanCategory(db.QueryRow(`
+		SELECT `+categoryColumns+` FROM categories WHERE name = ? OR slug = ?
+		ORDER BY name = ? DESC LIMIT 1
+	`, nameOrSlug, nameOrSlug, nameOrSlug))
+}
+
+// GetOrCreateCategoryIDs resolves the category names of a new post to IDs.
+// Names match a category's name or slug. Unknown names create a category if
+// allowCreate is set and fail with ErrUnknownCategory otherwise; archived
+// categories fail with ErrArchivedCategory.
+func GetOrCreateCategoryIDs(db *sql.DB, names []string, allowCreate bool) ([]int, error) {
+	var ids []int
+
+	for _, name := range names {
+		var id int
+		var archived bool
+		err := db.QueryRow(`
+			SELECT id, archived FROM categories WHERE name = ? OR slug = ?
+			ORDER BY name = ? DESC LIMIT 1
+		`, name, slugify(name), name).Scan(&id, &archived)
+		switch {
+		case err == sql.ErrNoRows && allowCreate:
+			category, err := CreateCategory(db, models.Category{Name: name})
+			if err != nil {
+				return nil, fmt.Errorf("could not create category %q: %w", name, err)
+			}
+			id = category.ID
+		case err == sql.ErrNoRows:
+			return nil, fmt.Errorf("%w: %q", ErrUnknownCategory, name)
+		case err != nil:
+			return nil, fmt.Errorf("failed to fetch category %q: %w", name, err)
+		case archived:
+			return nil, fmt.Errorf("%w: %q", ErrArchivedCategory, name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// CreateCategory inserts a category. An empty slug is derived from the name.
+func CreateCategory(db *sql.DB, c models.Category) (models.Category, error) {
+	if err := checkParent(db, 0, c.ParentID); err != nil {
+		return c, err
+	}
+
+	slug := c.Slug
+	if slug == "" {
+		var err error
+		if slug, err = uniqueSlug(db, c.Name, 0); err != nil {
+			return c, err
+		}
+	}
+
+	return scanCategory(db.QueryRow(`
+		INSERT INTO categories (name, slug, description, color, parent_id, sort_order, archived)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		RETURNING `+categoryColumns,
+		c.Name, slug, c.Description, c.Color, c.ParentID, c.SortOrder, c.Archived))
+}
+
+// UpdateCategory replaces the editable fields of a category. An empty slug
+// is derived from the name. It returns sql.ErrNoRows if there is no such
+// category.
+func UpdateCategory(db *sql.DB, c models.Category) (models.Category, error) {
+	if err := checkParent(db, c.ID, c.ParentID); err != nil {
+		return c, err
+	}
+
+	slug := c.Slug
+	if slug == "" {
+		var err error
+		if slug, err = uniqueSlug(db, c.Name, c.ID); err != nil {
+			return c, err
+		}
+	}
+
+	return scanCategory(db.QueryRow(`
+		UPDATE categories
+		SET name = ?, slug = ?, description = ?, color = ?, parent_id = ?, sort_order = ?, archived = ?
+		WHERE id = ?
+		RETURNING `+categoryColumns,
+		c.Name, slug, c.Description, c.Color, c.ParentID, c.SortOrder, c.Archived, c.ID))
+}
+
+// checkParent verifies that parentID exists and is not categoryID or one of
+// its descendants. A nil parent is always valid.
+func checkParent(db *sql.DB, categoryID int, parentID *int) error {
+	if parentID == nil {
+		return nil
+	}
+
+	var cycle, exists bool
+	err := db.QueryRow(`
+		WITH RECURSIVE ancestors(id) AS (
+			SELECT id FROM categories WHERE id = ?
+			UNION
+			SELECT c.parent_id FROM categories c JOIN ancestors a ON c.id = a.id
+			WHERE c.parent_id IS NOT NULL
+		)
+		SELECT
+			EXISTS (SELECT 1 FROM ancestors WHERE id = ?),
+			EXISTS (SELECT 1 FROM categories WHERE id = ?)
+	`, *parentID, categoryID, *parentID).Scan(&cycle, &exists)
+	switch {
+	case err != nil:
+		return err
+	case !exists:
+		return fmt.Errorf("parent category %d: %w", *parentID, sql.ErrNoRows)
+	case cycle:
+		return ErrCategoryCycle
+	}
+	return nil
+}
+
+// MergeCategories moves the posts, followers and subcategories of sourceID
+// into targetID and deletes sourceID. It returns sql.ErrNoRows if either
+// category does not exist.
+func MergeCategories(db *sql.DB, sourceID, targetID int) error {
+	if sourceID == targetID {
+		return errors.New("cannot merge a category into itself")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var found int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM categories WHERE id IN (?, ?)`, sourceID, targetID).Scan(&found); err != nil {
+		return err
+	}
+	if found != 2 {
+		return sql.ErrNoRows
+	}
+
+	statements := []string{
+		`INSERT OR IGNORE INTO post_categories (post_id, category_id)
+		 SELECT post_id, ?2 FROM post_categories WHERE category_id = ?1`,
+		`INSERT OR IGNORE INTO category_follows (user_id, category_id, created_at)
+		 SELECT user_id, ?2, created_at FROM category_follows WHERE category_id = ?1`,
+		// A target nested anywhere under the source first moves up to the
+		// source's parent, so its old ancestors can become its children
+		`WITH RECURSIVE ancestors(id) AS (
+			SELECT parent_id FROM categories WHERE id = ?2
+			UNION
+			SELECT c.parent_id FROM categories c JOIN ancestors a ON c.id = a.id
+			WHERE c.parent_id IS NOT NULL
+		 )
+		 UPDATE categories SET parent_id = (SELECT parent_id FROM categories WHERE id = ?1)
+		 WHERE id = ?2 AND ?1 IN (SELECT id FROM ancestors)`,
+		`UPDATE categories SET parent_id = ?2 WHERE parent_id = ?1`,
+		// Cleared by hand: foreign keys are not enforced on every pooled
+		// connection, so ON DELETE CASCADE cannot be relied on
+		`DELETE FROM post_categories WHERE category_id = ?1`,
+		`DELETE FROM category_follows WHERE category_id = ?1`,
+		`DELETE FROM categories WHERE id = ?1`,
+	}
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt, sourceID, targetID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// DeleteCategory deletes a category. Its posts stay, without it; its
+// subcategories move up to its parent. It returns sql.ErrNoRows if there is
+// no such category.
+func DeleteCategory(db *sql.DB, categoryID int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		UPDATE categories SET parent_id = (SELECT parent_id FROM categories WHERE id = ?1)
+		WHERE parent_id = ?1
+	`, categoryID); err != nil {
+		return err
+	}
+	// Cleared by hand, as in MergeCategories
+	for _, stmt := range []string{
+		`DELETE FROM post_categories WHERE category_id = ?`,
+		`DELETE FROM category_follows WHERE category_id = ?`,
+	} {
+		if _, err := tx.Exec(stmt, categoryID); err != nil {
+			return err
+		}
+	}
+	result, err := tx.Exec(`DELETE FROM categories WHERE id = ?`, categoryID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,260 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"forum/models"
+)
+
+func TestSlugify(t *testing.T) {
+	tests := map[string]string{
+		"Web Development": "web-development",
+		"  C++ & Go!  ":   "c-go",
+		"Ünïcode Straße":  "ünïcode-straße",
+		"---":             "category",
+		"DevOps/CI 2025":  "devops-ci-2025",
+	}
+	for name, want := range tests {
+		if got := slugify(name); got != want {
+			t.Errorf("slugify(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestCategories(t *testing.T) {
+	db := setupSchemaTestDB(t)
+	if _, err := db.Exec(`DELETE FROM categories`); err != nil {
+		t.Fatalf("Failed to clear sample categories: %v", err)
+	}
+	alice := createTestUser(t, db, "alice")
+
+	create := func(c models.Category) models.Category {
+		t.Helper()
+		created, err := CreateCategory(db, c)
+		if err != nil {
+			t.Fatalf("CreateCategory(%q) failed: %v", c.Name, err)
+		}
+		return created
+	}
+
+	programming := create(models.Category{Name: "Programming", SortOrder: 2})
+	golang := create(models.Category{Name: "Go", ParentID: &programming.ID, Color: "#00add8"})
+	gopher := create(models.Category{Name: "go!"})
+	old := create(models.Category{Name: "Old", Archived: true, SortOrder: 1})
+
+	t.Run("slugs are unique", func(t *testing.T) {
+		if golang.Slug != "go" || gopher.Slug != "go-2" {
+			t.Errorf("Expected go and go-2, got %q and %q", golang.Slug, gopher.Slug)
+		}
+	})
+
+	t.Run("listing order and archived", func(t *testing.T) {
+		categories, err := GetCategories(db, false)
+		if err != nil {
+			t.Fatalf("GetCategories failed: %v", err)
+		}
+		if len(categories) != 3 || categories[0].Name != "Go" || categories[2].Name != "Programming" {
+			t.Errorf("Unexpected categories: %+v", categories)
+		}
+		if all, _ := GetCategories(db, true); len(all) != 4 || all[0].Name != "Go" || all[1].Name != "go!" || all[2].ID != old.ID {
+			t.Errorf("Unexpected categories with archived: %+v", all)
+		}
+	})
+
+	t.Run("resolving post categories", func(t *testing.T) {
+		ids, err := GetOrCreateCategoryIDs(db, []string{"Programming", "go-2"}, false)
+		if err != nil || len(ids) != 2 || ids[0] != programming.ID || ids[1] != gopher.ID {
+			t.Errorf("Expected lookups by name and slug, got %v (%v)", ids, err)
+		}
+		if _, err := GetOrCreateCategoryIDs(db, []string{"Progamming"}, false); !errors.Is(err, ErrUnknownCategory) {
+			t.Errorf("Expected ErrUnknownCategory, got %v", err)
+		}
+		if _, err := GetOrCreateCategoryIDs(db, []string{"Old"}, true); !errors.Is(err, ErrArchivedCategory) {
+			t.Errorf("Expected ErrArchivedCategory, got %v", err)
+		}
+		ids, err = GetOrCreateCategoryIDs(db, []string{"Rust"}, true)
+		if err != nil || len(ids) != 1 {
+			t.Fatalf("Expected Rust to be created, got %v (%v)", ids, err)
+		}
+		if rust, _ := GetCategory(db, ids[0]); rust.Slug != "rust" {
+			t.Errorf("Expected a slug for the new category, got %+v", rust)
+		}
+	})
+
+	t.Run("parents cannot form cycles", func(t *testing.T) {
+		programming.ParentID = &golang.ID
+		if _, err := UpdateCategory(db, programming); !errors.Is(err, ErrCategoryCycle) {
+			t.Errorf("Expected ErrCategoryCycle, got %v", err)
+		}
+		programming.ParentID = &programming.ID
+		if _, err := UpdateCategory(db, programming); !errors.Is(err, ErrCategoryCycle) {
+			t.Errorf("Expected ErrCategoryCycle, got %v", err)
+		}
+		missing := 9999
+		programming.ParentID = &missing
+		if _, err := UpdateCategory(db, programming); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("Expected sql.ErrNoRows, got %v", err)
+		}
+		programming.ParentID = nil
+
+		updated, err := UpdateCategory(db, models.Category{ID: golang.ID, Name: "Golang", ParentID: &programming.ID})
+		if err != nil || updated.Slug != "golang" || updated.Color != "" {
+			t.Errorf("Expected a renamed category with a new slug, got %+v (%v)", updated, err)
+		}
+	})
+
+	t.Run("merge moves posts, followers and children", func(t *testing.T) {
+		both, err := CreatePost(db, alice, []int{programming.ID, gopher.ID}, "Both", "Body", "")
+		if err != nil {
+			t.Fatalf("CreatePost failed: %v", err)
+		}
+		if _, err := CreatePost(db, alice, []int{programming.ID}, "One", "Body", ""); err != nil {
+			t.Fatalf("CreatePost failed: %v", err)
+		}
+		if err := FollowCategory(db, alice, programming.ID); err != nil {
+			t.Fatalf("FollowCategory failed: %v", err)
+		}
+
+		// As on a pooled connection that never turned foreign keys on
+		if _, err := db.Exec(`PRAGMA foreign_keys = OFF`); err != nil {
+			t.Fatalf("Failed to disable foreign keys: %v", err)
+		}
+		defer db.Exec(`PRAGMA foreign_keys = ON`)
+
+		if err := MergeCategories(db, programming.ID, gopher.ID); err != nil {
+			t.Fatalf("MergeCategories failed: %v", err)
+		}
+		if n := countRows(t, db, `SELECT COUNT(*) FROM post_categories WHERE category_id = ?`, gopher.ID); n != 2 {
+			t.Errorf("Expected 2 posts in the target, got %d", n)
+		}
+		if n := countRows(t, db, `SELECT COUNT(*) FROM post_categories WHERE post_id = ?`, both.ID); n != 1 {
+			t.Errorf("Expected the post to be listed once, got %d", n)
+		}
+		if n := countRows(t, db, `SELECT COUNT(*) FROM category_follows WHERE category_id = ?`, gopher.ID); n != 1 {
+			t.Errorf("Expected the follower to move, got %d", n)
+		}
+		if child, _ := GetCategory(db, golang.ID); child.ParentID == nil || *child.ParentID != gopher.ID {
+			t.Errorf("Expected the subcategory to move, got %+v", child)
+		}
+		if _, err := GetCategory(db, programming.ID); err != sql.ErrNoRows {
+			t.Errorf("Expected the source to be deleted, got %v", err)
+		}
+		if n := countRows(t, db, `SELECT COUNT(*) FROM post_categories WHERE category_id = ?`, programming.ID); n != 0 {
+			t.Errorf("Expected the source's posts to be cleared, got %d", n)
+		}
+		if n := countRows(t, db, `SELECT COUNT(*) FROM category_follows WHERE category_id = ?`, programming.ID); n != 0 {
+			t.Errorf("Expected the source's followers to be cleared, got %d", n)
+		}
+	})
+
+	t.Run("merging into a descendant does not form a cycle", func(t *testing.T) {
+		top := create(models.Category{Name: "Top"})
+		middle := create(models.Category{Name: "Middle", ParentID: &top.ID})
+		bottom := create(models.Category{Name: "Bottom", ParentID: &middle.ID})
+
+		if err := MergeCategories(db, top.ID, bottom.ID); err != nil {
+			t.Fatalf("MergeCategories failed: %v", err)
+		}
+		if target, _ := GetCategory(db, bottom.ID); target.ParentID != nil {
+			t.Errorf("Expected the target to take the source's place at the top, got %+v", target)
+		}
+		if child, _ := GetCategory(db, middle.ID); child.ParentID == nil || *child.ParentID != bottom.ID {
+			t.Errorf("Expected the source's child to move under the target, got %+v", child)
+		}
+	})
+
+	t.Run("delete moves children up", func(t *testing.T) {
+		if err := FollowCategory(db, alice, gopher.ID); err != nil {
+			t.Fatalf("FollowCategory failed: %v", err)
+		}
+		// As on a pooled connection that never turned foreign keys on
+		if _, err := db.Exec(`PRAGMA foreign_keys = OFF`); err != nil {
+			t.Fatalf("Failed to disable foreign keys: %v", err)
+		}
+		defer db.Exec(`PRAGMA foreign_keys = ON`)
+
+		if err := DeleteCategory(db, gopher.ID); err != nil {
+			t.Fatalf("DeleteCategory failed: %v", err)
+		}
+		if n := countRows(t, db, `SELECT COUNT(*) FROM post_categories WHERE category_id = ?`, gopher.ID); n != 0 {
+			t.Errorf("Expected the category's posts to be cleared, got %d", n)
+		}
+		if n := countRows(t, db, `SELECT COUNT(*) FROM category_follows WHERE category_id = ?`, gopher.ID); n != 0 {
+			t.Errorf("Expected the category's followers to be cleared, got %d", n)
+		}
+		if child, _ := GetCategory(db, golang.ID); child.ParentID != nil {
+			t.Errorf("Expected the subcategory to become top-level, got %+v", child)
+		}
+		if n := countRows(t, db, `SELECT COUNT(*) FROM posts`); n != 2 {
+			t.Errorf("Expected posts to be kept, got %d", n)
+		}
+		if err := DeleteCategory(db, gopher.ID); err != sql.ErrNoRows {
+			t.Errorf("Expected sql.ErrNoRows, got %v", err)
+		}
+	})
+}
+
+func TestCategoryStats(t *testing.T) {
+	db := setupSchemaTestDB(t)
+	alice := createTestUser(t, db, "alice")
+	bob := createTestUser(t, db, "bob")
+
+	golang, err := CreateCategory(db, models.Category{Name: "Golang", SortOrder: -2})
+	if err != nil {
+		t.Fatalf("CreateCategory failed: %v", err)
+	}
+	empty, err := CreateCategory(db, models.Category{Name: "Empty", SortOrder: -1})
+	if err != nil {
+		t.Fatalf("CreateCategory failed: %v", err)
+	}
+
+	first, err := CreatePost(db, alice, []int{golang.ID}, "First", "Hello", "")
+	if err != nil {
+		t.Fatalf("CreatePost failed: %v", err)
+	}
+	if _, err := CreatePost(db, bob, []int{golang.ID}, "Second", "Again", ""); err != nil {
+		t.Fatalf("CreatePost failed: %v", err)
+	}
+	comment, err := CreateComment(db, bob, first.ID, "nice")
+	if err != nil {
+		t.Fatalf("CreateComment failed: %v", err)
+	}
+	if _, err := CreateReplyComment(db, alice, comment.ID, "thanks"); err != nil {
+		t.Fatalf("CreateReplyComment failed: %v", err)
+	}
+	for _, user := range []string{alice, bob} {
+		if err := FollowCategory(db, user, golang.ID); err != nil {
+			t.Fatalf("FollowCategory failed: %v", err)
+		}
+	}
+	// Age the posts so the reply is the latest activity
+	if _, err := db.Exec(`UPDATE posts SET created_at = datetime('now', '-2 hours') WHERE id = ?`, first.ID); err != nil {
+		t.Fatalf("Failed to age post: %v", err)
+	}
+
+	categories, err := GetCategories(db, false)
+	if err != nil {
+		t.Fatalf("GetCategories failed: %v", err)
+	}
+	if len(categories) < 2 || categories[0].ID != golang.ID || categories[1].ID != empty.ID {
+		t.Fatalf("Unexpected categories: %+v", categories)
+	}
+
+	stats := categories[0].Stats
+	if stats == nil || stats.PostCount != 2 || stats.CommentCount != 2 || stats.SubscriberCount != 2 {
+		t.Fatalf("Unexpected stats: %+v", stats)
+	}
+	if stats.LatestPostID == nil || stats.LatestPostTitle != "Second" {
+		t.Errorf("Expected Second as the latest post, got %v %q", stats.LatestPostID, stats.LatestPostTitle)
+	}
+	if stats.LastActivityAt == nil || time.Since(*stats.LastActivityAt) > time.Hour {
+		t.Errorf("Expected recent activity, got %v", stats.LastActivityAt)
+	}
+
+	if stats := categories[1].Stats; stats == nil || stats.PostCount != 0 || stats.LastActivityAt != nil || stats.LatestPostID != nil {
+		t.Errorf("Expected empty stats, got %+v", stats)
+	}
+}
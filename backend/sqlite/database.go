@@ -25,10 +25,25 @@ func InitializeDatabase(dbPath string) error {
 		return fmt.Errorf("failed to enable foreign key constraints: %w", err)
 	}
 	
+	// Add columns introduced since the database was created
+	if err := applyColumnMigrations(DB); err != nil {
+		return fmt.Errorf("failed to apply column migrations: %w", err)
+	}
+
+	// Move aside tables whose definition changed beyond what ALTER TABLE can do
+	if err := retireLegacyTables(DB); err != nil {
+		return fmt.Errorf("failed to retire legacy tables: %w", err)
+	}
+
 	// Apply schema from schema.sql file
 	if err := applySchemaFromFile("schema.sql"); err != nil {
 		return fmt.Errorf("failed to apply schema: %w", err)
 	}
+
+	// Apply one-off data migrations on top of the schema
+	if err := applyMigrations(DB); err != nil {
+		return fmt.Errorf("failed to apply migrations: %w", err)
+	}
 	return nil
 }
 
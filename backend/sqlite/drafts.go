@@ -0,0 +1,144 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"forum/models"
+)
+
+// Post statuses. Only published posts are shown to anyone but their author.
+const (
+	PostDraft     = "draft"
+	PostScheduled = "scheduled"
+	PostPublished = "published"
+)
+
+// ErrPostPublished is returned when scheduling or publishing a post that is
+// already published
+var ErrPostPublished = errors.New("this post has already been published")
+
+// timePtr returns nil for a NULL time, or a pointer to its value
+func timePtr(v sql.NullTime) *time.Time {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Time
+}
+
+// GetDrafts returns a page of userID's drafts and scheduled posts, the ones
+// due first, then the most recent drafts
+func GetDrafts(db *sql.DB, userID string, page, limit int) ([]models.Post, error) {
+	offset := (page - 1) * limit
+
+	return queryPosts(db, `
+		SELECT `+postColumns+`
+		FROM posts
+		JOIN users ON posts.user_id = users.id
+		WHERE posts.user_id = ? AND posts.status != 'published'
+		ORDER BY posts.publish_at IS NULL, posts.publish_at, posts.created_at DESC, posts.id DESC
+		LIMIT ? OFFSET ?
+	`, userID, limit, offset)
+}
+
+// SchedulePost sets an unpublished post to be published at publishAt.
+// Returns sql.ErrNoRows for an unknown post and ErrPostPublished if it is
+// already out.
+func SchedulePost(db *sql.DB, postID int, publishAt time.Time) error {
+	result, err := db.Exec(`
+		UPDATE posts SET status = 'scheduled', publish_at = ?
+		WHERE id = ? AND status != 'published'
+	`, sqliteTime(publishAt), postID)
+	if err != nil {
+		return err
+	}
+	return checkUnpublished(db, result, postID)
+}
+
+// PublishPost publishes a draft or scheduled post now and returns it. Its
+// creation and update times become the publication time so it shows up as
+// new. Only one caller can publish a post; the others get ErrPostPublished.
+func PublishPost(db *sql.DB, postID int) (models.Post, error) {
+	result, err := db.Exec(`
+		UPDATE posts SET status = 'published', publish_at = NULL, created_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND status != 'published'
+	`, postID)
+	if err != nil {
+		return models.Post{}, err
+	}
+	if err := checkUnpublished(db, result, postID); err != nil {
+		return models.Post{}, err
+	}
+
+	post, err := GetPost(db, postID)
+	if err != nil {
+		return post, err
+	}
+	// Mentions in drafts were not recorded, so nobody was notified yet
+	if strings.Contains(post.Content, "@") {
+		if err := syncMentions(db, post.UserID, contentRef{PostID: post.ID}, post.Content); err != nil {
+			log.Printf("Warning: Failed to store mentions for post %d: %v", post.ID, err)
+		}
+	}
+	return post, nil
+}
+
+// checkUnpublished tells why an update of an unpublished post changed
+// nothing: the post does not exist, or it was published already
+func checkUnpublished(db *sql.DB, result sql.Result, postID int) error {
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n > 0 {
+		return nil
+	}
+	var exists bool
+	if err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM posts WHERE id = ?)`, postID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return sql.ErrNoRows
+	}
+	return ErrPostPublished
+}
+
+// PublishDuePosts publishes the scheduled posts whose time has come and
+// returns them. Posts that came due while the server was down are published
+// on the next run, and a post published concurrently is skipped.
+func PublishDuePosts(db *sql.DB) ([]models.Post, error) {
+	rows, err := db.Query(`
+		SELECT id FROM posts
+		WHERE status = 'scheduled' AND publish_at <= ?
+		ORDER BY publish_at, id
+	`, sqliteTime(time.Now()))
+	if err != nil {
+		return nil, err
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	published := []models.Post{}
+	for _, id := range ids {
+		post, err := PublishPost(db, id)
+		if errors.Is(err, ErrPostPublished) || errors.Is(err, sql.ErrNoRows) {
+			continue
+		} else if err != nil {
+			return published, err
+		}
+		published = append(published, post)
+	}
+	return published, nil
+}
@@ -0,0 +1,108 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDraftsAndScheduledPosts(t *testing.T) {
+	db := setupSchemaTestDB(t)
+	alice := createTestUser(t, db, "alice")
+	bob := createTestUser(t, db, "bob")
+
+	published, err := CreatePost(db, alice, nil, "Out", "Already published", "")
+	if err != nil {
+		t.Fatalf("CreatePost failed: %v", err)
+	}
+	draft, err := CreateDraft(db, alice, nil, "Draft", "Not ready yet, @bob", "", nil)
+	if err != nil {
+		t.Fatalf("CreateDraft failed: %v", err)
+	}
+	if draft.Status != PostDraft || draft.PublishAt != nil {
+		t.Fatalf("Unexpected draft: %+v", draft)
+	}
+	at := time.Now().Add(time.Hour)
+	scheduled, err := CreateDraft(db, alice, nil, "Later", "Coming soon", "", &at)
+	if err != nil {
+		t.Fatalf("CreateDraft failed: %v", err)
+	}
+	if scheduled.Status != PostScheduled || scheduled.PublishAt == nil {
+		t.Fatalf("Unexpected scheduled post: %+v", scheduled)
+	}
+
+	t.Run("drafts stay private", func(t *testing.T) {
+		posts, err := GetPosts(db, bob, PostFilter{}, 1, 10)
+		if err != nil {
+			t.Fatalf("GetPosts failed: %v", err)
+		}
+		if len(posts) != 1 || posts[0].ID != published.ID {
+			t.Errorf("Expected only the published post, got %d posts", len(posts))
+		}
+		if posts, _ := GetPostsByUser(db, alice, 1, 10); len(posts) != 1 {
+			t.Errorf("Expected the profile to show 1 post, got %d", len(posts))
+		}
+		if _, err := GetPostAuthorID(db, draft.ID); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("Expected sql.ErrNoRows for a draft, got %v", err)
+		}
+		if n := countRows(t, db, `SELECT COUNT(*) FROM mentions WHERE mentioned_user_id = ?`, bob); n != 0 {
+			t.Errorf("Expected no mentions before publishing, got %d", n)
+		}
+
+		drafts, err := GetDrafts(db, alice, 1, 10)
+		if err != nil {
+			t.Fatalf("GetDrafts failed: %v", err)
+		}
+		if len(drafts) != 2 || drafts[0].ID != scheduled.ID || drafts[1].ID != draft.ID {
+			t.Errorf("Expected the scheduled post then the draft, got %+v", drafts)
+		}
+		if drafts, _ := GetDrafts(db, bob, 1, 10); len(drafts) != 0 {
+			t.Errorf("Expected bob to have no drafts, got %d", len(drafts))
+		}
+	})
+
+	t.Run("publish now", func(t *testing.T) {
+		post, err := PublishPost(db, draft.ID)
+		if err != nil {
+			t.Fatalf("PublishPost failed: %v", err)
+		}
+		if post.Status != PostPublished {
+			t.Errorf("Expected the post to be published, got %q", post.Status)
+		}
+		if n := countRows(t, db, `SELECT COUNT(*) FROM mentions WHERE mentioned_user_id = ?`, bob); n != 1 {
+			t.Errorf("Expected publishing to record the mention, got %d", n)
+		}
+		if _, err := PublishPost(db, draft.ID); !errors.Is(err, ErrPostPublished) {
+			t.Errorf("Expected ErrPostPublished, got %v", err)
+		}
+		if err := SchedulePost(db, draft.ID, at); !errors.Is(err, ErrPostPublished) {
+			t.Errorf("Expected ErrPostPublished, got %v", err)
+		}
+		if _, err := PublishPost(db, 9999); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("Expected sql.ErrNoRows, got %v", err)
+		}
+	})
+
+	t.Run("due posts are published once", func(t *testing.T) {
+		if posts, err := PublishDuePosts(db); err != nil || len(posts) != 0 {
+			t.Fatalf("Expected nothing due yet, got %d posts (%v)", len(posts), err)
+		}
+		if err := SchedulePost(db, scheduled.ID, time.Now().Add(-time.Minute)); err != nil {
+			t.Fatalf("SchedulePost failed: %v", err)
+		}
+		posts, err := PublishDuePosts(db)
+		if err != nil {
+			t.Fatalf("PublishDuePosts failed: %v", err)
+		}
+		if len(posts) != 1 || posts[0].ID != scheduled.ID || posts[0].PublishAt != nil {
+			t.Fatalf("Expected the scheduled post to be published, got %+v", posts)
+		}
+		if posts, _ := PublishDuePosts(db); len(posts) != 0 {
+			t.Errorf("Expected the post to be published only once, got %d", len(posts))
+		}
+		if posts, _ := GetPosts(db, bob, PostFilter{}, 1, 10); len(posts) != 3 {
+			t.Errorf("Expected 3 published posts, got %d", len(posts))
+		}
+	})
+}
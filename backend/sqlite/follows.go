@@ -0,0 +1,178 @@
+package sqlite
+
+import (
+	"database/sql"
+	"time"
+
+	"forum/models"
+)
+
+// FollowUser makes followerID follow followeeID; following twice is a no-op
+func FollowUser(db *sql.DB, followerID, followeeID string) error {
+	_, err := db.Exec(`INSERT OR IGNORE INTO follows (follower_id, followee_id) VALUES (?, ?)`, followerID, followeeID)
+	return err
+}
+
+// UnfollowUser removes a follow if there is one
+func UnfollowUser(db *sql.DB, followerID, followeeID string) error {
+	_, err := db.Exec(`DELETE FROM follows WHERE follower_id = ? AND followee_id = ?`, followerID, followeeID)
+	return err
+}
+
+// IsFollowing reports whether followerID follows followeeID
+func IsFollowing(db *sql.DB, followerID, followeeID string) (bool, error) {
+	var following bool
+	err := db.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM follows WHERE follower_id = ? AND followee_id = ?)
+	`, followerID, followeeID).Scan(&following)
+	return following, err
+}
+
+// GetFollowers returns a page of the users following userID, most recent first
+func GetFollowers(db *sql.DB, userID string, page, limit int) ([]models.UserSummary, error) {
+	return queryUserSummaries(db, `
+		SELECT u.id, u.username, u.avatar_url
+		FROM follows f
+		JOIN users u ON u.id = f.follower_id
+		WHERE f.followee_id = ?
+		ORDER BY f.created_at DESC, u.username
+		LIMIT ? OFFSET ?
+	`, userID, limit, (page-1)*limit)
+}
+
+// GetFollowing returns a page of the users userID follows, most recent first
+func GetFollowing(db *sql.DB, userID string, page, limit int) ([]models.UserSummary, error) {
+	return queryUserSummaries(db, `
+		SELECT u.id, u.username, u.avatar_url
+		FROM follows f
+		JOIN users u ON u.id = f.followee_id
+		WHERE f.follower_id = ?
+		ORDER BY f.created_at DESC, u.username
+		LIMIT ? OFFSET ?
+	`, userID, limit, (page-1)*limit)
+}
+
+func queryUserSummaries(db *sql.DB, query string, args ...any) ([]models.UserSummary, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []models.UserSummary{}
+	for rows.Next() {
+		var user models.UserSummary
+		if err := rows.Scan(&user.ID, &user.Username, &user.AvatarURL); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// FollowCategory makes userID follow a category. It returns sql.ErrNoRows if
+// the category does not exist.
+func FollowCategory(db *sql.DB, userID string, categoryID int) error {
+	var exists bool
+	if err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM categories WHERE id = ?)`, categoryID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return sql.ErrNoRows
+	}
+
+	_, err := db.Exec(`INSERT OR IGNORE INTO category_follows (user_id, category_id) VALUES (?, ?)`, userID, categoryID)
+	return err
+}
+
+// UnfollowCategory removes a category follow if there is one
+func UnfollowCategory(db *sql.DB, userID string, categoryID int) error {
+	_, err := db.Exec(`DELETE FROM category_follows WHERE user_id = ? AND category_id = ?`, userID, categoryID)
+	return err
+}
+
+// GetFollowedCategories returns the categories userID follows, by name
+func GetFollowedCategories(db *sql.DB, userID string) ([]models.Category, error) {
+	rows, err := db.Query(`
+		SELECT c.id, c.name
+		FROM category_follows cf
+		JOIN categories c ON c.id = cf.category_id
+		WHERE cf.user_id = ?
+		ORDER BY c.name
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []models.Category{}
+	for rows.Next() {
+		var category models.Category
+		if err := rows.Scan(&category.ID, &category.Name); err != nil {
+			return nil, err
+		}
+		categories = append(categories, category)
+	}
+	return categories, rows.Err()
+}
+
+// FeedCursor marks the last post of a feed page; the next page starts after
+// it. The zero value starts at the newest post.
+type FeedCursor struct {
+	CreatedAt time.Time
+	ID        int
+}
+
+// sqliteTime formats t the way CURRENT_TIMESTAMP stores it, so it compares
+// correctly with DATETIME columns
+func sqliteTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
+
+// GetFeed returns a page of posts by followed users or in followed
+// categories, newest first, leaving out muted and blocked authors. Each
+// source is read newest first through its own index and cut at limit before
+// they are merged, so a page never scans more than a page per source. Hidden
+// authors are left out before the cut, so pages are only short at the end.
+func GetFeed(db *sql.DB, userID string, after FeedCursor, limit int) ([]models.Post, error) {
+	// With no cursor, start above any real post
+	beforeAt, beforeID := "9999-12-31 23:59:59", 0
+	if after.ID > 0 {
+		beforeAt, beforeID = sqliteTime(after.CreatedAt), after.ID
+	}
+
+	return queryPosts(db, `
+		SELECT `+postColumns+`
+		FROM posts
+		JOIN users ON posts.user_id = users.id
+		WHERE posts.id IN (
+			SELECT id FROM (
+				SELECT p.id
+				FROM follows f
+				JOIN posts p ON p.user_id = f.followee_id
+				WHERE f.follower_id = ? AND p.status = 'published'
+					AND p.user_id NOT IN (`+hiddenAuthors+`)
+					AND (p.created_at < ? OR (p.created_at = ? AND (? = 0 OR p.id < ?)))
+				ORDER BY p.created_at DESC, p.id DESC
+				LIMIT ?
+			)
+			UNION
+			SELECT id FROM (
+				SELECT p.id
+				FROM category_follows cf
+				JOIN post_categories pc ON pc.category_id = cf.category_id
+				JOIN posts p ON p.id = pc.post_id
+				WHERE cf.user_id = ? AND p.status = 'published'
+					AND p.user_id NOT IN (`+hiddenAuthors+`)
+					AND (p.created_at < ? OR (p.created_at = ? AND (? = 0 OR p.id < ?)))
+				ORDER BY p.created_at DESC, p.id DESC
+				LIMIT ?
+			)
+		)
+		ORDER BY posts.created_at DESC, posts.id DESC
+		LIMIT ?
+	`,
+		userID, userID, userID, beforeAt, beforeAt, beforeID, beforeID, limit,
+		userID, userID, userID, beforeAt, beforeAt, beforeID, beforeID, limit,
+		limit)
+}
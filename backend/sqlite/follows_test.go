@@ -0,0 +1,154 @@
+package sqlite
+
+import (
+	"testing"
+
+	"forum/models"
+)
+
+func TestFollows(t *testing.T) {
+	db := setupSchemaTestDB(t)
+	alice := createTestUser(t, db, "alice")
+	bob := createTestUser(t, db, "bob")
+	carol := createTestUser(t, db, "carol")
+
+	category, err := CreateCategory(db, models.Category{Name: "Go"})
+	if err != nil {
+		t.Fatalf("CreateCategory failed: %v", err)
+	}
+	goID := category.ID
+
+	// Posts one minute apart, oldest first; the last two share a timestamp
+	posts := []struct {
+		author     string
+		categories []int
+		minute     int
+	}{
+		{bob, nil, 1},           // followed author
+		{carol, nil, 2},         // not followed
+		{carol, []int{goID}, 3}, // followed category
+		{bob, []int{goID}, 4},   // both, listed once
+		{alice, nil, 5},         // own post
+		{bob, nil, 6},           // same time as the next one
+		{carol, []int{goID}, 6},
+	}
+	ids := make([]int, len(posts))
+	for i, p := range posts {
+		post, err := CreatePost(db, p.author, p.categories, "Post", "Body", "")
+		if err != nil {
+			t.Fatalf("CreatePost failed: %v", err)
+		}
+		ids[i] = post.ID
+		if _, err := db.Exec(`UPDATE posts SET created_at = datetime('2025-01-01 12:00:00', ? || ' minutes') WHERE id = ?`, p.minute, post.ID); err != nil {
+			t.Fatalf("Failed to set created_at: %v", err)
+		}
+	}
+
+	if err := FollowUser(db, alice, bob); err != nil {
+		t.Fatalf("FollowUser failed: %v", err)
+	}
+	if err := FollowUser(db, alice, bob); err != nil {
+		t.Fatalf("Following twice should be a no-op: %v", err)
+	}
+	if err := FollowUser(db, carol, bob); err != nil {
+		t.Fatalf("FollowUser failed: %v", err)
+	}
+	if err := FollowCategory(db, alice, goID); err != nil {
+		t.Fatalf("FollowCategory failed: %v", err)
+	}
+
+	t.Run("follow lists and counts", func(t *testing.T) {
+		if following, err := IsFollowing(db, alice, bob); err != nil || !following {
+			t.Errorf("Expected alice to follow bob (%v)", err)
+		}
+		followers, err := GetFollowers(db, bob, 1, 10)
+		if err != nil || len(followers) != 2 {
+			t.Errorf("Expected 2 followers, got %+v (%v)", followers, err)
+		}
+		following, err := GetFollowing(db, alice, 1, 10)
+		if err != nil || len(following) != 1 || following[0].Username != "bob" {
+			t.Errorf("Expected alice to follow bob, got %+v (%v)", following, err)
+		}
+		profile, err := GetProfileByID(db, bob)
+		if err != nil || profile.FollowerCount != 2 || profile.FollowingCount != 0 {
+			t.Errorf("Unexpected counts: %+v (%v)", profile, err)
+		}
+		categories, err := GetFollowedCategories(db, alice)
+		if err != nil || len(categories) != 1 || categories[0].Name != "Go" {
+			t.Errorf("Expected the Go category, got %+v (%v)", categories, err)
+		}
+		if err := FollowCategory(db, alice, 9999); err == nil {
+			t.Error("Expected following a missing category to fail")
+		}
+	})
+
+	t.Run("feed pages through both sources", func(t *testing.T) {
+		var got []int
+		var cursor FeedCursor
+		for page := 0; page < 5; page++ {
+			feed, err := GetFeed(db, alice, cursor, 2)
+			if err != nil {
+				t.Fatalf("GetFeed failed: %v", err)
+			}
+			if len(feed) == 0 {
+				break
+			}
+			for _, post := range feed {
+				got = append(got, post.ID)
+			}
+			last := feed[len(feed)-1]
+			cursor = FeedCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+		}
+
+		want := []int{ids[6], ids[5], ids[3], ids[2], ids[0]}
+		if len(got) != len(want) {
+			t.Fatalf("Expected %v, got %v", want, got)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("Expected %v, got %v", want, got)
+			}
+		}
+	})
+
+	t.Run("muted authors do not shorten pages", func(t *testing.T) {
+		dave := createTestUser(t, db, "dave")
+		for minute := 10; minute < 13; minute++ {
+			post, err := CreatePost(db, dave, []int{goID}, "Muted", "Body", "")
+			if err != nil {
+				t.Fatalf("CreatePost failed: %v", err)
+			}
+			if _, err := db.Exec(`UPDATE posts SET created_at = datetime('2025-01-01 12:00:00', ? || ' minutes') WHERE id = ?`, minute, post.ID); err != nil {
+				t.Fatalf("Failed to set created_at: %v", err)
+			}
+		}
+		if err := FollowUser(db, alice, dave); err != nil {
+			t.Fatalf("FollowUser failed: %v", err)
+		}
+		if err := MuteUser(db, alice, dave); err != nil {
+			t.Fatalf("MuteUser failed: %v", err)
+		}
+		defer UnfollowUser(db, alice, dave)
+
+		feed, err := GetFeed(db, alice, FeedCursor{}, 2)
+		if err != nil {
+			t.Fatalf("GetFeed failed: %v", err)
+		}
+		if len(feed) != 2 || feed[0].ID != ids[6] || feed[1].ID != ids[5] {
+			t.Errorf("Expected a full page without the muted author, got %+v", feed)
+		}
+	})
+
+	t.Run("unfollow", func(t *testing.T) {
+		if err := UnfollowUser(db, alice, bob); err != nil {
+			t.Fatalf("UnfollowUser failed: %v", err)
+		}
+		if err := UnfollowCategory(db, alice, goID); err != nil {
+			t.Fatalf("UnfollowCategory failed: %v", err)
+		}
+		feed, err := GetFeed(db, alice, FeedCursor{}, 10)
+		if err != nil || len(feed) != 0 {
+			t.Errorf("Expected an empty feed, got %d posts (%v)", len(feed), err)
+		}
+	})
+}
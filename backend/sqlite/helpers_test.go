@@ -0,0 +1,81 @@
+package sqlite
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// setupSchemaTestDB creates an in-memory database from the real schema.sql
+// plus migrations, for features that span several tables
+func setupSchemaTestDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	db.SetMaxOpenConns(1) // each :memory: connection is its own database
+	t.Cleanup(func() { db.Close() })
+	applyTestSchema(t, db)
+	return db
+}
+
+// setupSchemaFileDB is setupSchemaTestDB on a database file, for tests that
+// need several connections at once
+func setupSchemaFileDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "forum.db")+"?_foreign_keys=on")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	applyTestSchema(t, db)
+	return db
+}
+
+// applyTestSchema sets db up the way InitializeDatabase does
+func applyTestSchema(t *testing.T, db *sql.DB) {
+	if _, err := db.Exec("PRAGMA foreign_keys = ON"); err != nil {
+		t.Fatalf("Failed to enable foreign keys: %v", err)
+	}
+
+	if err := applyColumnMigrations(db); err != nil {
+		t.Fatalf("Failed to apply column migrations: %v", err)
+	}
+	if err := retireLegacyTables(db); err != nil {
+		t.Fatalf("Failed to retire legacy tables: %v", err)
+	}
+
+	schemaSQL, err := os.ReadFile("../schema.sql")
+	if err != nil {
+		t.Fatalf("Failed to read schema.sql: %v", err)
+	}
+	if _, err := db.Exec(string(schemaSQL)); err != nil {
+		t.Fatalf("Failed to apply schema.sql: %v", err)
+	}
+	if err := applyMigrations(db); err != nil {
+		t.Fatalf("Failed to apply migrations: %v", err)
+	}
+}
+
+// createTestUser inserts a user and returns its ID
+func createTestUser(t *testing.T, db *sql.DB, username string) string {
+	if err := CreateUser(db, username, username+"@example.com", "hash", "/static/avatar.png"); err != nil {
+		t.Fatalf("Failed to create user %s: %v", username, err)
+	}
+	user, err := GetUserByUsername(db, username)
+	if err != nil {
+		t.Fatalf("Failed to get user %s: %v", username, err)
+	}
+	return user.ID
+}
+
+// countRows runs a COUNT(*) query and returns the result
+func countRows(t *testing.T, db *sql.DB, query string, args ...any) int {
+	var count int
+	if err := db.QueryRow(query, args...).Scan(&count); err != nil {
+		t.Fatalf("Count query failed: %v", err)
+	}
+	return count
+}
@@ -0,0 +1,238 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+
+	"forum/markdown"
+	"forum/models"
+)
+
+// syncMentions resolves the @usernames in content against users.username and
+// stores them for the item ref points at. Mentions that were edited out are
+// removed along with their notifications; newly mentioned users are notified
+// unless they mentioned themselves. Users with a block between them and the
+// author cannot be mentioned.
+func syncMentions(db *sql.DB, authorID string, ref contentRef, content string) error {
+	column, id := ref.target()
+	names := markdown.Mentions(content)
+
+	// Resolve usernames, keeping the order they appear in
+	var resolved []string
+	if len(names) > 0 {
+		placeholders := make([]string, len(names))
+		args := make([]any, len(names))
+		for i, name := range names {
+			placeholders[i] = "?"
+			args[i] = name
+		}
+		args = append(args, authorID, authorID)
+
+		rows, err := db.Query(fmt.Sprintf(`
+			SELECT id, username FROM users
+			WHERE username IN (%s) AND id NOT IN (
+				SELECT blocked_id FROM user_blocks WHERE blocker_id = ?
+				UNION
+				SELECT blocker_id FROM user_blocks WHERE blocked_id = ?
+			)
+		`, strings.Join(placeholders, ",")), args...)
+		if err != nil {
+			return err
+		}
+		byName := make(map[string]string)
+		for rows.Next() {
+			var userID, username string
+			if err := rows.Scan(&userID, &username); err != nil {
+				rows.Close()
+				return err
+			}
+			byName[username] = userID
+		}
+		rows.Close()
+
+		for _, name := range names {
+			if userID, ok := byName[name]; ok {
+				resolved = append(resolved, userID)
+			}
+		}
+	}
+
+	// Load what is already stored for this item
+	existing := make(map[string]bool)
+	rows, err := db.Query(fmt.Sprintf(`SELECT mentioned_user_id FROM mentions WHERE %s = ?`, column), id)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[userID] = true
+	}
+	rows.Close()
+
+	current := make(map[string]bool)
+	for _, userID := range resolved {
+		current[userID] = true
+	}
+
+	for userID := range existing {
+		if current[userID] {
+			continue
+		}
+		if _, err := db.Exec(fmt.Sprintf(`DELETE FROM mentions WHERE %s = ? AND mentioned_user_id = ?`, column), id, userID); err != nil {
+			return err
+		}
+		if _, err := db.Exec(fmt.Sprintf(`DELETE FROM notifications WHERE type = ? AND %s = ? AND user_id = ?`, column), NotificationMention, id, userID); err != nil {
+			return err
+		}
+	}
+
+	for _, userID := range resolved {
+		if existing[userID] {
+			continue
+		}
+		_, err := db.Exec(fmt.Sprintf(`INSERT INTO mentions (mentioned_user_id, author_id, %s) VALUES (?, ?, ?)`, column), userID, authorID, id)
+		if err != nil {
+			return err
+		}
+		if err := notify(db, userID, authorID, NotificationMention, ref); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadMentions returns the mentioned usernames for each ID in column
+func loadMentions(db *sql.DB, column string, ids []int) (map[int]map[string]bool, error) {
+	mentions := make(map[int]map[string]bool)
+	if len(ids) == 0 {
+		return mentions, nil
+	}
+
+	placeholders := make([]string, len(ids))
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		placeholders[i] = "?"
+		args[i] = id
+	}
+
+	rows, err := db.Query(fmt.Sprintf(`
+		SELECT m.%s, u.username
+		FROM mentions m
+		JOIN users u ON u.id = m.mentioned_user_id
+		WHERE m.%s IN (%s)
+	`, column, column, strings.Join(placeholders, ",")), args...)
+	if err != nil {
+		return mentions, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var username string
+		if err := rows.Scan(&id, &username); err != nil {
+			return mentions, err
+		}
+		if mentions[id] == nil {
+			mentions[id] = make(map[string]bool)
+		}
+		mentions[id][username] = true
+	}
+	return mentions, rows.Err()
+}
+
+// renderContent renders each body to HTML, linking the mentions stored for
+// it. Bodies without an '@' skip the lookup, and a failed lookup only drops
+// the links rather than failing the request.
+func renderContent(db *sql.DB, column string, contents map[int]string) map[int]string {
+	var ids []int
+	for id, content := range contents {
+		if strings.Contains(content, "@") {
+			ids = append(ids, id)
+		}
+	}
+
+	mentions, err := loadMentions(db, column, ids)
+	if err != nil {
+		log.Printf("Warning: Failed to load mentions: %v", err)
+	}
+
+	rendered := make(map[int]string, len(contents))
+	for id, content := range contents {
+		rendered[id] = markdown.RenderWithMentions(content, mentions[id])
+	}
+	return rendered
+}
+
+// renderOne is renderContent for a single body
+func renderOne(db *sql.DB, column string, id int, content string) string {
+	return renderContent(db, column, map[int]string{id: content})[id]
+}
+
+// renderPostContent fills ContentHTML for a page of posts
+func renderPostContent(db *sql.DB, posts map[int]*models.Post) {
+	contents := make(map[int]string, len(posts))
+	for id, post := range posts {
+		contents[id] = post.Content
+	}
+	rendered := renderContent(db, "post_id", contents)
+	for id, post := range posts {
+		post.ContentHTML = rendered[id]
+	}
+}
+
+// renderCommentContent fills ContentHTML for comments and their replies
+func renderCommentContent(db *sql.DB, comments []models.Comment) {
+	commentContents := make(map[int]string)
+	replyContents := make(map[int]string)
+	for _, c := range comments {
+		commentContents[c.ID] = c.Content
+		for _, r := range c.Replies {
+			replyContents[r.ID] = r.Content
+		}
+	}
+
+	renderedComments := renderContent(db, "comment_id", commentContents)
+	renderedReplies := renderContent(db, "reply_id", replyContents)
+	for i := range comments {
+		comments[i].ContentHTML = renderedComments[comments[i].ID]
+		for j := range comments[i].Replies {
+			comments[i].Replies[j].ContentHTML = renderedReplies[comments[i].Replies[j].ID]
+		}
+	}
+}
+
+// SearchUsersByPrefix returns up to limit users whose username starts with
+// prefix, for @mention autocomplete
+func SearchUsersByPrefix(db *sql.DB, prefix string, limit int) ([]models.UserSummary, error) {
+	// Escape LIKE wildcards so "_" in a prefix matches literally
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(prefix)
+
+	rows, err := db.Query(`
+		SELECT id, username, avatar_url
+		FROM users
+		WHERE username LIKE ? ESCAPE '\'
+		ORDER BY length(username), username
+		LIMIT ?
+	`, escaped+"%", limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []models.UserSummary{}
+	for rows.Next() {
+		var user models.UserSummary
+		if err := rows.Scan(&user.ID, &user.Username, &user.AvatarURL); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
@@ -0,0 +1,116 @@
+package sqlite
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMentions(t *testing.T) {
+	db := setupSchemaTestDB(t)
+	alice := createTestUser(t, db, "alice")
+	bob := createTestUser(t, db, "bob")
+	createTestUser(t, db, "carol")
+
+	post, err := CreatePost(db, alice, nil, "Hello", "Thanks @bob and @carol, also @alice and @nobody", "")
+	if err != nil {
+		t.Fatalf("CreatePost failed: %v", err)
+	}
+
+	t.Run("mentions resolved and stored", func(t *testing.T) {
+		if n := countRows(t, db, `SELECT COUNT(*) FROM mentions WHERE post_id = ?`, post.ID); n != 3 {
+			t.Fatalf("Expected 3 mentions, got %d", n)
+		}
+	})
+
+	t.Run("self mention not notified", func(t *testing.T) {
+		if n := countRows(t, db, `SELECT COUNT(*) FROM notifications WHERE type = 'mention'`); n != 2 {
+			t.Fatalf("Expected 2 mention notifications, got %d", n)
+		}
+		if n := countRows(t, db, `SELECT COUNT(*) FROM notifications WHERE user_id = ?`, alice); n != 0 {
+			t.Fatalf("Author should not be notified of a self mention")
+		}
+	})
+
+	t.Run("rendered as profile links", func(t *testing.T) {
+		fetched, err := GetPost(db, post.ID)
+		if err != nil {
+			t.Fatalf("GetPost failed: %v", err)
+		}
+		if !strings.Contains(fetched.ContentHTML, `<a href="/users/bob" class="mention">@bob</a>`) {
+			t.Fatalf("Expected mention link, got %q", fetched.ContentHTML)
+		}
+		if strings.Contains(fetched.ContentHTML, `/users/nobody`) {
+			t.Fatalf("Unknown users must not be linked, got %q", fetched.ContentHTML)
+		}
+	})
+
+	t.Run("edit removes stale mentions", func(t *testing.T) {
+		if err := UpdatePost(db, post.ID, "Hello", "Only @bob now"); err != nil {
+			t.Fatalf("UpdatePost failed: %v", err)
+		}
+		if n := countRows(t, db, `SELECT COUNT(*) FROM mentions WHERE post_id = ?`, post.ID); n != 1 {
+			t.Fatalf("Expected 1 mention after edit, got %d", n)
+		}
+		if n := countRows(t, db, `SELECT COUNT(*) FROM notifications WHERE user_id = ?`, bob); n != 1 {
+			t.Fatalf("Bob should keep exactly one notification, got %d", n)
+		}
+	})
+
+	t.Run("deleted content drops notifications", func(t *testing.T) {
+		// As on a pooled connection that never turned foreign keys on
+		if _, err := db.Exec(`PRAGMA foreign_keys = OFF`); err != nil {
+			t.Fatalf("Failed to disable foreign keys: %v", err)
+		}
+		defer db.Exec(`PRAGMA foreign_keys = ON`)
+
+		comment, err := CreateComment(db, bob, post.ID, "cc @carol")
+		if err != nil {
+			t.Fatalf("CreateComment failed: %v", err)
+		}
+		reply, err := CreateReplyComment(db, alice, comment.ID, "@bob agreed")
+		if err != nil {
+			t.Fatalf("CreateReplyComment failed: %v", err)
+		}
+		if err := DeleteComment(db, comment.ID); err != nil {
+			t.Fatalf("DeleteComment failed: %v", err)
+		}
+		if n := countRows(t, db, `SELECT COUNT(*) FROM notifications WHERE comment_id = ?`, comment.ID); n != 0 {
+			t.Fatalf("Expected notifications for deleted comment to be removed, got %d", n)
+		}
+		if n := countRows(t, db, `SELECT COUNT(*) FROM mentions WHERE comment_id = ? OR reply_id = ?`, comment.ID, reply.ID); n != 0 {
+			t.Errorf("Expected the mentions in the comment and its reply to be removed, got %d", n)
+		}
+
+		if err := DeletePost(db, post.ID); err != nil {
+			t.Fatalf("DeletePost failed: %v", err)
+		}
+		if n := countRows(t, db, `SELECT COUNT(*) FROM mentions WHERE post_id = ?`, post.ID); n != 0 {
+			t.Errorf("Expected the mentions in the deleted post to be removed, got %d", n)
+		}
+	})
+}
+
+func TestSearchUsersByPrefix(t *testing.T) {
+	db := setupSchemaTestDB(t)
+	createTestUser(t, db, "sam")
+	createTestUser(t, db, "samantha")
+	createTestUser(t, db, "s_am")
+	createTestUser(t, db, "bob")
+
+	users, err := SearchUsersByPrefix(db, "sam", 10)
+	if err != nil {
+		t.Fatalf("SearchUsersByPrefix failed: %v", err)
+	}
+	if len(users) != 2 || users[0].Username != "sam" || users[1].Username != "samantha" {
+		t.Fatalf("Unexpected results: %+v", users)
+	}
+
+	// "_" must match literally, not as a LIKE wildcard
+	users, err = SearchUsersByPrefix(db, "s_", 10)
+	if err != nil {
+		t.Fatalf("SearchUsersByPrefix failed: %v", err)
+	}
+	if len(users) != 1 || users[0].Username != "s_am" {
+		t.Fatalf("Unexpected results for escaped prefix: %+v", users)
+	}
+}
@@ -0,0 +1,212 @@
+package sqlite
+
+import (
+	"database/sql"
+	"math"
+
+	"forum/markdown"
+	"forum/models"
+)
+
+// GetOrCreateConversation returns the conversation between two users,
+// creating it on first contact
+func GetOrCreateConversation(db *sql.DB, userID, otherID string) (int, error) {
+	userA, userB := userID, otherID
+	if userB < userA {
+		userA, userB = userB, userA
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO conversations (user_a, user_b) VALUES (?, ?)
+		ON CONFLICT(user_a, user_b) DO NOTHING
+	`, userA, userB)
+	if err != nil {
+		return 0, err
+	}
+
+	var conversationID int
+	err = tx.QueryRow(`SELECT id FROM conversations WHERE user_a = ? AND user_b = ?`, userA, userB).Scan(&conversationID)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(`
+		INSERT OR IGNORE INTO conversation_members (conversation_id, user_id) VALUES (?, ?), (?, ?)
+	`, conversationID, userA, conversationID, userB)
+	if err != nil {
+		return 0, err
+	}
+
+	return conversationID, tx.Commit()
+}
+
+// GetConversationPeer returns the other participant of a conversation.
+// It returns sql.ErrNoRows when userID is not part of it.
+func GetConversationPeer(db *sql.DB, conversationID int, userID string) (string, error) {
+	var peerID string
+	err := db.QueryRow(`
+		SELECT CASE WHEN user_a = ? THEN user_b ELSE user_a END
+		FROM conversations
+		WHERE id = ? AND (user_a = ? OR user_b = ?)
+	`, userID, conversationID, userID, userID).Scan(&peerID)
+	return peerID, err
+}
+
+// CreateMessage stores a message, moves the conversation to the top of both
+// participants' lists and marks it as read by its sender
+func CreateMessage(db *sql.DB, conversationID int, senderID, content string) (models.Message, error) {
+	var message models.Message
+
+	tx, err := db.Begin()
+	if err != nil {
+		return message, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+		INSERT INTO messages (conversation_id, sender_id, content)
+		VALUES (?, ?, ?)
+		RETURNING id, conversation_id, sender_id, content, created_at
+	`, conversationID, senderID, content).Scan(
+		&message.ID,
+		&message.ConversationID,
+		&message.SenderID,
+		&message.Content,
+		&message.CreatedAt,
+	)
+	if err != nil {
+		return message, err
+	}
+
+	if _, err := tx.Exec(`UPDATE conversations SET updated_at = CURRENT_TIMESTAMP WHERE id = ?`, conversationID); err != nil {
+		return message, err
+	}
+	_, err = tx.Exec(`
+		UPDATE conversation_members SET last_read_message_id = ?
+		WHERE conversation_id = ? AND user_id = ?
+	`, message.ID, conversationID, senderID)
+	if err != nil {
+		return message, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return message, err
+	}
+
+	message.ContentHTML = markdown.Render(message.Content)
+	return message, nil
+}
+
+// GetMessages returns up to limit messages of a conversation older than
+// beforeID (all messages when beforeID is 0), newest first
+func GetMessages(db *sql.DB, conversationID, beforeID, limit int) ([]models.Message, error) {
+	if beforeID <= 0 {
+		beforeID = math.MaxInt64
+	}
+
+	rows, err := db.Query(`
+		SELECT id, conversation_id, sender_id, content, created_at
+		FROM messages
+		WHERE conversation_id = ? AND id < ?
+		ORDER BY id DESC
+		LIMIT ?
+	`, conversationID, beforeID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []models.Message{}
+	for rows.Next() {
+		var m models.Message
+		if err := rows.Scan(&m.ID, &m.ConversationID, &m.SenderID, &m.Content, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		m.ContentHTML = markdown.Render(m.Content)
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
+
+// GetConversations returns a page of a user's conversations, most recently
+// active first, with the latest message and unread count of each
+func GetConversations(db *sql.DB, userID string, page, limit int) ([]models.Conversation, error) {
+	offset := (page - 1) * limit
+
+	rows, err := db.Query(`
+		SELECT
+			c.id, c.created_at, c.updated_at,
+			u.id, u.username, u.avatar_url,
+			other.last_read_message_id,
+			(SELECT COUNT(*) FROM messages m
+			 WHERE m.conversation_id = c.id AND m.id > me.last_read_message_id AND m.sender_id != me.user_id),
+			lm.id, lm.sender_id, lm.content, lm.created_at
+		FROM conversation_members me
+		JOIN conversations c ON c.id = me.conversation_id
+		JOIN conversation_members other ON other.conversation_id = c.id AND other.user_id != me.user_id
+		JOIN users u ON u.id = other.user_id
+		LEFT JOIN messages lm ON lm.id = (SELECT MAX(id) FROM messages WHERE conversation_id = c.id)
+		WHERE me.user_id = ?
+		ORDER BY lm.id DESC, c.id DESC
+		LIMIT ? OFFSET ?
+	`, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	conversations := []models.Conversation{}
+	for rows.Next() {
+		var c models.Conversation
+		var lastID sql.NullInt64
+		var lastSender, lastContent sql.NullString
+		var lastCreatedAt sql.NullTime
+		err := rows.Scan(
+			&c.ID, &c.CreatedAt, &c.UpdatedAt,
+			&c.OtherUser.ID, &c.OtherUser.Username, &c.OtherUser.AvatarURL,
+			&c.OtherLastReadID,
+			&c.UnreadCount,
+			&lastID, &lastSender, &lastContent, &lastCreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if lastID.Valid {
+			c.LastMessage = &models.Message{
+				ID:             int(lastID.Int64),
+				ConversationID: c.ID,
+				SenderID:       lastSender.String,
+				Content:        lastContent.String,
+				ContentHTML:    markdown.Render(lastContent.String),
+				CreatedAt:      lastCreatedAt.Time,
+			}
+		}
+		conversations = append(conversations, c)
+	}
+	return conversations, rows.Err()
+}
+
+// MarkConversationRead records that userID has read the conversation up to
+// messageID (everything when messageID is 0) and returns the new position.
+// The position never moves backwards or past the latest message.
+func MarkConversationRead(db *sql.DB, conversationID int, userID string, messageID int) (int, error) {
+	if messageID <= 0 {
+		messageID = math.MaxInt64
+	}
+
+	var lastRead int
+	err := db.QueryRow(`
+		UPDATE conversation_members
+		SET last_read_message_id = MAX(last_read_message_id, MIN(?,
+			(SELECT COALESCE(MAX(id), 0) FROM messages WHERE conversation_id = ?)))
+		WHERE conversation_id = ? AND user_id = ?
+		RETURNING last_read_message_id
+	`, messageID, conversationID, conversationID, userID).Scan(&lastRead)
+	return lastRead, err
+}
@@ -0,0 +1,135 @@
+package sqlite
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestMessages(t *testing.T) {
+	db := setupSchemaTestDB(t)
+	alice := createTestUser(t, db, "alice")
+	bob := createTestUser(t, db, "bob")
+	carol := createTestUser(t, db, "carol")
+
+	conversationID, err := GetOrCreateConversation(db, alice, bob)
+	if err != nil {
+		t.Fatalf("GetOrCreateConversation failed: %v", err)
+	}
+
+	t.Run("one conversation per pair", func(t *testing.T) {
+		again, err := GetOrCreateConversation(db, bob, alice)
+		if err != nil {
+			t.Fatalf("GetOrCreateConversation failed: %v", err)
+		}
+		if again != conversationID {
+			t.Errorf("Expected conversation %d, got %d", conversationID, again)
+		}
+		if n := countRows(t, db, `SELECT COUNT(*) FROM conversation_members WHERE conversation_id = ?`, conversationID); n != 2 {
+			t.Errorf("Expected 2 members, got %d", n)
+		}
+	})
+
+	t.Run("peer lookup checks membership", func(t *testing.T) {
+		peer, err := GetConversationPeer(db, conversationID, alice)
+		if err != nil || peer != bob {
+			t.Errorf("Expected bob as peer, got %q (%v)", peer, err)
+		}
+		if _, err := GetConversationPeer(db, conversationID, carol); err != sql.ErrNoRows {
+			t.Errorf("Expected sql.ErrNoRows for a non-member, got %v", err)
+		}
+	})
+
+	var ids []int
+	for i, sender := range []string{alice, alice, bob, alice} {
+		m, err := CreateMessage(db, conversationID, sender, "message **"+string(rune('a'+i))+"**")
+		if err != nil {
+			t.Fatalf("CreateMessage failed: %v", err)
+		}
+		ids = append(ids, m.ID)
+	}
+
+	t.Run("history is paginated newest first", func(t *testing.T) {
+		page, err := GetMessages(db, conversationID, 0, 2)
+		if err != nil {
+			t.Fatalf("GetMessages failed: %v", err)
+		}
+		if len(page) != 2 || page[0].ID != ids[3] || page[1].ID != ids[2] {
+			t.Fatalf("Unexpected first page: %+v", page)
+		}
+		if page[0].ContentHTML != "<p>message <strong>d</strong></p>" {
+			t.Errorf("Unexpected rendered content %q", page[0].ContentHTML)
+		}
+
+		older, err := GetMessages(db, conversationID, page[1].ID, 10)
+		if err != nil {
+			t.Fatalf("GetMessages failed: %v", err)
+		}
+		if len(older) != 2 || older[0].ID != ids[1] || older[1].ID != ids[0] {
+			t.Fatalf("Unexpected second page: %+v", older)
+		}
+	})
+
+	t.Run("conversation list and unread counts", func(t *testing.T) {
+		conversations, err := GetConversations(db, bob, 1, 10)
+		if err != nil {
+			t.Fatalf("GetConversations failed: %v", err)
+		}
+		if len(conversations) != 1 {
+			t.Fatalf("Expected 1 conversation, got %d", len(conversations))
+		}
+		c := conversations[0]
+		if c.OtherUser.ID != alice || c.LastMessage == nil || c.LastMessage.ID != ids[3] {
+			t.Errorf("Unexpected conversation: %+v", c)
+		}
+		// Bob's own message marked everything before it as read
+		if c.UnreadCount != 1 {
+			t.Errorf("Expected 1 unread message for bob, got %d", c.UnreadCount)
+		}
+		if c.OtherLastReadID != ids[3] {
+			t.Errorf("Expected alice to have read up to %d, got %d", ids[3], c.OtherLastReadID)
+		}
+
+		none, err := GetConversations(db, carol, 1, 10)
+		if err != nil || len(none) != 0 {
+			t.Errorf("Expected no conversations for carol, got %d (%v)", len(none), err)
+		}
+	})
+
+	t.Run("read position only moves forward", func(t *testing.T) {
+		lastRead, err := MarkConversationRead(db, conversationID, bob, ids[0])
+		if err != nil {
+			t.Fatalf("MarkConversationRead failed: %v", err)
+		}
+		if lastRead != ids[2] {
+			t.Errorf("Expected position to stay at %d, got %d", ids[2], lastRead)
+		}
+
+		lastRead, err = MarkConversationRead(db, conversationID, bob, 0)
+		if err != nil {
+			t.Fatalf("MarkConversationRead failed: %v", err)
+		}
+		if lastRead != ids[3] {
+			t.Errorf("Expected position %d, got %d", ids[3], lastRead)
+		}
+
+		lastRead, err = MarkConversationRead(db, conversationID, bob, ids[3]+100)
+		if err != nil || lastRead != ids[3] {
+			t.Errorf("Expected position capped at %d, got %d (%v)", ids[3], lastRead, err)
+		}
+	})
+
+	t.Run("blocks work in both directions", func(t *testing.T) {
+		if _, err := db.Exec(`INSERT INTO user_blocks (blocker_id, blocked_id) VALUES (?, ?)`, bob, carol); err != nil {
+			t.Fatalf("Failed to insert block: %v", err)
+		}
+		for _, pair := range [][2]string{{bob, carol}, {carol, bob}} {
+			blocked, err := IsBlocked(db, pair[0], pair[1])
+			if err != nil || !blocked {
+				t.Errorf("Expected a block between %s and %s (%v)", pair[0], pair[1], err)
+			}
+		}
+		if blocked, _ := IsBlocked(db, alice, bob); blocked {
+			t.Error("Expected no block between alice and bob")
+		}
+	})
+}
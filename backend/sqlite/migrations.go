@@ -0,0 +1,332 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"html"
+)
+
+// columnMigration adds a column to a table created by an older schema.sql.
+// Column migrations run before schema.sql so that indexes declared there can
+// reference the new columns; fresh databases get them from CREATE TABLE.
+type columnMigration struct {
+	table      string
+	column     string
+	definition string
+}
+
+// columnMigrations must mirror the column definitions in schema.sql. SQLite
+// cannot ADD COLUMN with a non-constant default such as CURRENT_TIMESTAMP,
+// so those columns are added nullable and filled in by the code writing them.
+var columnMigrations = []columnMigration{
+	{table: "notifications", column: "event_count", definition: "INTEGER NOT NULL DEFAULT 1"},
+	{table: "notifications", column: "updated_at", definition: "DATETIME"},
+	{table: "users", column: "last_seen_at", definition: "DATETIME"},
+	{table: "users", column: "show_online_status", definition: "INTEGER NOT NULL DEFAULT 1"},
+	{table: "users", column: "bio", definition: "TEXT NOT NULL DEFAULT ''"},
+	{table: "users", column: "show_posts", definition: "INTEGER NOT NULL DEFAULT 1"},
+	{table: "users", column: "show_comments", definition: "INTEGER NOT NULL DEFAULT 1"},
+	{table: "users", column: "show_liked_posts", definition: "INTEGER NOT NULL DEFAULT 0"},
+	{table: "users", column: "role", definition: "TEXT NOT NULL DEFAULT 'user'"},
+	{table: "users", column: "banned_at", definition: "DATETIME"},
+	{table: "users", column: "banned_until", definition: "DATETIME"},
+	{table: "users", column: "ban_reason", definition: "TEXT NOT NULL DEFAULT ''"},
+	{table: "categories", column: "slug", definition: "TEXT"},
+	{table: "categories", column: "description", definition: "TEXT NOT NULL DEFAULT ''"},
+	{table: "categories", column: "color", definition: "TEXT NOT NULL DEFAULT ''"},
+	{table: "categories", column: "parent_id", definition: "INTEGER REFERENCES categories(id) ON DELETE SET NULL"},
+	{table: "categories", column: "sort_order", definition: "INTEGER NOT NULL DEFAULT 0"},
+	{table: "categories", column: "archived", definition: "INTEGER NOT NULL DEFAULT 0"},
+	{table: "posts", column: "locked", definition: "INTEGER NOT NULL DEFAULT 0"},
+	{table: "posts", column: "post_type", definition: "TEXT NOT NULL DEFAULT 'discussion' CHECK (post_type IN ('discussion', 'question'))"},
+	{table: "posts", column: "accepted_comment_id", definition: "INTEGER REFERENCES comments(id) ON DELETE SET NULL"},
+	{table: "posts", column: "status", definition: "TEXT NOT NULL DEFAULT 'published' CHECK (status IN ('draft', 'scheduled', 'published'))"},
+	{table: "posts", column: "publish_at", definition: "DATETIME"},
+	{table: "posts", column: "like_count", definition: "INTEGER NOT NULL DEFAULT 0"},
+	{table: "posts", column: "dislike_count", definition: "INTEGER NOT NULL DEFAULT 0"},
+	{table: "posts", column: "view_count", definition: "INTEGER NOT NULL DEFAULT 0"},
+	{table: "comments", column: "like_count", definition: "INTEGER NOT NULL DEFAULT 0"},
+	{table: "comments", column: "dislike_count", definition: "INTEGER NOT NULL DEFAULT 0"},
+}
+
+// applyColumnMigrations adds any missing columns to existing tables
+func applyColumnMigrations(db *sql.DB) error {
+	for _, m := range columnMigrations {
+		rows, err := db.Query(fmt.Sprintf(`PRAGMA table_info(%s)`, m.table))
+		if err != nil {
+			return err
+		}
+		tableFound, columnFound := false, false
+		for rows.Next() {
+			var cid, notNull, pk int
+			var name, colType string
+			var defaultValue sql.NullString
+			if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+				rows.Close()
+				return err
+			}
+			tableFound = true
+			if name == m.column {
+				columnFound = true
+			}
+		}
+		rows.Close()
+
+		// Missing tables are created with every column by schema.sql
+		if !tableFound || columnFound {
+			continue
+		}
+		if _, err := db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, m.table, m.column, m.definition)); err != nil {
+			return fmt.Errorf("failed to add %s.%s: %w", m.table, m.column, err)
+		}
+	}
+	return nil
+}
+
+// legacyTable is a table whose definition in schema.sql changed in a way
+// ALTER TABLE cannot express, such as a dropped CHECK constraint
+type legacyTable struct {
+	table   string
+	marker  string // found only in the old CREATE TABLE statement
+	renamed string
+}
+
+var legacyTables = []legacyTable{
+	{table: "likes", marker: "CHECK(type IN ('like', 'dislike'))", renamed: "likes_legacy"},
+}
+
+// retireLegacyTables renames tables created with an outdated definition out
+// of the way before schema.sql runs, so that it creates them afresh. A data
+// migration then copies the old rows over and drops the renamed table.
+func retireLegacyTables(db *sql.DB) error {
+	for _, l := range legacyTables {
+		var legacy bool
+		err := db.QueryRow(`
+			SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = ? AND instr(sql, ?) > 0)
+		`, l.table, l.marker).Scan(&legacy)
+		if err != nil {
+			return err
+		}
+		if !legacy {
+			continue
+		}
+		if _, err := db.Exec(fmt.Sprintf(`ALTER TABLE %s RENAME TO %s`, l.table, l.renamed)); err != nil {
+			return fmt.Errorf("failed to rename %s: %w", l.table, err)
+		}
+	}
+	return nil
+}
+
+// migration is a one-off data change applied after schema.sql. Each migration
+// runs once, inside a transaction, and is recorded by name in schema_migrations.
+type migration struct {
+	name string
+	run  func(tx *sql.Tx) error
+}
+
+// migrations are applied in order; append new entries, never reorder them
+var migrations = []migration{
+	{name: "unescape_stored_content", run: unescapeStoredContent},
+	{name: "backfill_notification_updated_at", run: backfillNotificationUpdatedAt},
+	{name: "backfill_category_slugs", run: backfillCategorySlugs},
+	{name: "copy_legacy_likes", run: copyLegacyLikes},
+	{name: "backfill_reaction_counters", run: backfillReactionCounters},
+}
+
+// applyMigrations runs every migration that has not been recorded yet
+func applyMigrations(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			name TEXT PRIMARY KEY,
+			applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	for _, m := range migrations {
+		var applied int
+		err := db.QueryRow(`SELECT COUNT(*) FROM schema_migrations WHERE name = ?`, m.name).Scan(&applied)
+		if err != nil {
+			return fmt.Errorf("failed to check migration %q: %w", m.name, err)
+		}
+		if applied > 0 {
+			continue
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if err := m.run(tx); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %q failed: %w", m.name, err)
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_migrations (name) VALUES (?)`, m.name); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to record migration %q: %w", m.name, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// tableExists reports whether a table is present in the database
+func tableExists(tx *sql.Tx, table string) (bool, error) {
+	var count int
+	err := tx.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, table).Scan(&count)
+	return count > 0, err
+}
+
+// unescapeStoredContent reverts the html.EscapeString that used to be applied
+// to post and comment bodies before storage. Bodies are now stored raw and
+// rendered through markdown.Render instead.
+func unescapeStoredContent(tx *sql.Tx) error {
+	for _, table := range []string{"posts", "comments", "replycomments"} {
+		exists, err := tableExists(tx, table)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			continue
+		}
+
+		rows, err := tx.Query(fmt.Sprintf(`SELECT id, content FROM %s`, table))
+		if err != nil {
+			return err
+		}
+
+		updates := make(map[int]string)
+		for rows.Next() {
+			var id int
+			var content string
+			if err := rows.Scan(&id, &content); err != nil {
+				rows.Close()
+				return err
+			}
+			if raw := html.UnescapeString(content); raw != content {
+				updates[id] = raw
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for id, raw := range updates {
+			if _, err := tx.Exec(fmt.Sprintf(`UPDATE %s SET content = ? WHERE id = ?`, table), raw, id); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// backfillNotificationUpdatedAt fills updated_at for notifications stored
+// before the column was added
+func backfillNotificationUpdatedAt(tx *sql.Tx) error {
+	exists, err := tableExists(tx, "notifications")
+	if err != nil || !exists {
+		return err
+	}
+	_, err = tx.Exec(`UPDATE notifications SET updated_at = created_at WHERE updated_at IS NULL`)
+	return err
+}
+
+// backfillCategorySlugs gives categories created before slugs existed a
+// unique slug derived from their name
+func backfillCategorySlugs(tx *sql.Tx) error {
+	// Also false when the table itself is missing
+	var hasSlug bool
+	err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM pragma_table_info('categories') WHERE name = 'slug')`).Scan(&hasSlug)
+	if err != nil || !hasSlug {
+		return err
+	}
+
+	rows, err := tx.Query(`SELECT id, name FROM categories WHERE slug IS NULL ORDER BY id`)
+	if err != nil {
+		return err
+	}
+	names := make(map[int]string)
+	var ids []int
+	for rows.Next() {
+		var id int
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			rows.Close()
+			return err
+		}
+		names[id] = name
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		slug, err := uniqueSlug(tx, names[id], id)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`UPDATE categories SET slug = ? WHERE id = ?`, slug, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyLegacyLikes moves the reactions of a likes table retired by
+// retireLegacyTables into the new one, which no longer limits reaction types
+func copyLegacyLikes(tx *sql.Tx) error {
+	exists, err := tableExists(tx, "likes_legacy")
+	if err != nil || !exists {
+		return err
+	}
+	// The old primary key let rows with a NULL column repeat; the unique
+	// indexes of the new table drop those duplicates
+	_, err = tx.Exec(`
+		INSERT OR IGNORE INTO likes (user_id, post_id, comment_id, type, created_at)
+		SELECT user_id, post_id, comment_id, type, created_at
+		FROM likes_legacy
+		WHERE (post_id IS NULL) != (comment_id IS NULL)
+	`)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`DROP TABLE likes_legacy`)
+	return err
+}
+
+// backfillReactionCounters fills in the like/dislike counters of posts and
+// comments created before the counters existed. The likes triggers keep them
+// up to date from then on.
+func backfillReactionCounters(tx *sql.Tx) error {
+	exists, err := tableExists(tx, "likes")
+	if err != nil || !exists {
+		return err
+	}
+	for table, column := range map[string]string{"posts": "post_id", "comments": "comment_id"} {
+		exists, err := tableExists(tx, table)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			continue
+		}
+		_, err = tx.Exec(fmt.Sprintf(`
+			UPDATE %[1]s SET
+				like_count = (SELECT COUNT(*) FROM likes WHERE %[2]s = %[1]s.id AND type = 'like'),
+				dislike_count = (SELECT COUNT(*) FROM likes WHERE %[2]s = %[1]s.id AND type = 'dislike')
+		`, table, column))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package sqlite
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestApplyMigrations(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1) // each :memory: connection is its own database
+
+	_, err = db.Exec(`
+	CREATE TABLE posts (id INTEGER PRIMARY KEY, content TEXT NOT NULL);
+	CREATE TABLE comments (id INTEGER PRIMARY KEY, content TEXT NOT NULL);
+	INSERT INTO posts (id, content) VALUES (1, 'Tom &amp; Jerry &lt;3'), (2, 'plain');
+	INSERT INTO comments (id, content) VALUES (1, 'it&#39;s &#34;quoted&#34;');
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create test schema: %v", err)
+	}
+
+	t.Run("unescapes stored content", func(t *testing.T) {
+		if err := applyMigrations(db); err != nil {
+			t.Fatalf("applyMigrations failed: %v", err)
+		}
+
+		var post, comment string
+		db.QueryRow(`SELECT content FROM posts WHERE id = 1`).Scan(&post)
+		db.QueryRow(`SELECT content FROM comments WHERE id = 1`).Scan(&comment)
+
+		if post != "Tom & Jerry <3" {
+			t.Errorf("Expected unescaped post content, got %q", post)
+		}
+		if comment != `it's "quoted"` {
+			t.Errorf("Expected unescaped comment content, got %q", comment)
+		}
+	})
+
+	t.Run("runs only once", func(t *testing.T) {
+		// Content that legitimately contains an entity must survive a restart
+		_, err := db.Exec(`UPDATE posts SET content = 'literal &amp; text' WHERE id = 2`)
+		if err != nil {
+			t.Fatalf("Failed to update post: %v", err)
+		}
+
+		if err := applyMigrations(db); err != nil {
+			t.Fatalf("applyMigrations failed: %v", err)
+		}
+
+		var content string
+		db.QueryRow(`SELECT content FROM posts WHERE id = 2`).Scan(&content)
+		if content != "literal &amp; text" {
+			t.Errorf("Migration ran twice, got %q", content)
+		}
+	})
+}
+
+func TestBackfillCategorySlugs(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	// Categories from before slugs existed
+	_, err = db.Exec(`
+	CREATE TABLE categories (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT UNIQUE NOT NULL);
+	INSERT INTO categories (name) VALUES ('Web Development'), ('web development!');
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create test schema: %v", err)
+	}
+	if err := applyColumnMigrations(db); err != nil {
+		t.Fatalf("applyColumnMigrations failed: %v", err)
+	}
+	if err := applyMigrations(db); err != nil {
+		t.Fatalf("applyMigrations failed: %v", err)
+	}
+
+	var first, second string
+	db.QueryRow(`SELECT slug FROM categories WHERE id = 1`).Scan(&first)
+	db.QueryRow(`SELECT slug FROM categories WHERE id = 2`).Scan(&second)
+	if first != "web-development" || second != "web-development-2" {
+		t.Errorf("Expected unique slugs, got %q and %q", first, second)
+	}
+}
+
+func TestCopyLegacyLikes(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	// Reactions from before reaction types were configurable. The NULL
+	// comment_id let the primary key accept the same like twice.
+	_, err = db.Exec(`
+	CREATE TABLE likes (
+		user_id TEXT NOT NULL,
+		post_id INTEGER,
+		comment_id INTEGER,
+		type TEXT NOT NULL CHECK(type IN ('like', 'dislike')),
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (user_id, post_id, comment_id)
+	);
+	INSERT INTO likes (user_id, post_id, type) VALUES ('u1', 1, 'like'), ('u1', 1, 'like'), ('u2', 1, 'dislike');
+	INSERT INTO likes (user_id, comment_id, type) VALUES ('u1', 7, 'like');
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create test schema: %v", err)
+	}
+
+	if err := retireLegacyTables(db); err != nil {
+		t.Fatalf("retireLegacyTables failed: %v", err)
+	}
+	schemaSQL, err := os.ReadFile("../schema.sql")
+	if err != nil {
+		t.Fatalf("Failed to read schema.sql: %v", err)
+	}
+	if _, err := db.Exec(string(schemaSQL)); err != nil {
+		t.Fatalf("Failed to apply schema.sql: %v", err)
+	}
+	if err := applyMigrations(db); err != nil {
+		t.Fatalf("applyMigrations failed: %v", err)
+	}
+
+	if n := countRows(t, db, `SELECT COUNT(*) FROM likes`); n != 3 {
+		t.Errorf("Expected 3 reactions without the duplicate, got %d", n)
+	}
+	if n := countRows(t, db, `SELECT COUNT(*) FROM sqlite_master WHERE name = 'likes_legacy'`); n != 0 {
+		t.Error("Expected likes_legacy to be dropped")
+	}
+	if _, err := db.Exec(`INSERT INTO likes (user_id, post_id, type) VALUES ('u1', 1, 'love')`); err != nil {
+		t.Errorf("Expected the new table to accept other reaction types: %v", err)
+	}
+}
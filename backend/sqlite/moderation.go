@@ -0,0 +1,219 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"forum/models"
+)
+
+var (
+	// ErrBanAdmin is returned when banning an admin
+	ErrBanAdmin = errors.New("admins cannot be banned")
+	// ErrBanModerator is returned when a moderator bans or unbans another
+	// moderator
+	ErrBanModerator = errors.New("only admins can ban or unban moderators")
+	// ErrNotBanned is returned when lifting a ban the user does not have
+	ErrNotBanned = errors.New("user is not banned")
+)
+
+// Moderation log actions
+const (
+	ActionBan     = "ban"
+	ActionSuspend = "suspend"
+	ActionLift    = "lift"
+	ActionExpire  = "expire"
+)
+
+// activeBan is true for users who are banned, or suspended until later than
+// now. Times are stored as sqliteTime text so they compare as strings.
+const activeBan = `(banned_at IS NOT NULL AND (banned_until IS NULL OR banned_until > CURRENT_TIMESTAMP))`
+
+// BanUser bans userID, or suspends them until until when it is not nil, and
+// signs them out everywhere. Banning someone already banned replaces the
+// reason and end time. Returns sql.ErrNoRows if either user does not exist,
+// ErrBanAdmin for admins and ErrBanModerator when a moderator bans another.
+func BanUser(db *sql.DB, moderatorID, userID, reason string, until *time.Time) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := checkBanRoles(tx, moderatorID, userID); err != nil {
+		return err
+	}
+
+	action := ActionBan
+	var end any
+	if until != nil {
+		action = ActionSuspend
+		end = sqliteTime(*until)
+	}
+	if _, err := tx.Exec(`
+		UPDATE users SET banned_at = CURRENT_TIMESTAMP, banned_until = ?, ban_reason = ?
+		WHERE id = ?
+	`, end, reason, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM sessions WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO moderation_log (user_id, moderator_id, action, reason, until)
+		VALUES (?, ?, ?, ?, ?)
+	`, userID, moderatorID, action, reason, end); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// checkBanRoles returns ErrBanAdmin if userID is an admin and
+// ErrBanModerator if they are a moderator and moderatorID is not an admin
+func checkBanRoles(tx *sql.Tx, moderatorID, userID string) error {
+	var moderatorRole, role string
+	if err := tx.QueryRow(`SELECT role FROM users WHERE id = ?`, moderatorID).Scan(&moderatorRole); err != nil {
+		return err
+	}
+	if err := tx.QueryRow(`SELECT role FROM users WHERE id = ?`, userID).Scan(&role); err != nil {
+		return err
+	}
+	switch {
+	case role == RoleAdmin:
+		return ErrBanAdmin
+	case role == RoleModerator && moderatorRole != RoleAdmin:
+		return ErrBanModerator
+	}
+	return nil
+}
+
+// LiftBan lifts userID's ban or suspension early. Returns sql.ErrNoRows if
+// the user does not exist, ErrNotBanned if they are not banned and
+// ErrBanModerator when a moderator lifts another moderator's ban.
+func LiftBan(db *sql.DB, moderatorID, userID, reason string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := checkBanRoles(tx, moderatorID, userID); err != nil && !errors.Is(err, ErrBanAdmin) {
+		return err
+	}
+
+	var banned bool
+	if err := tx.QueryRow(`SELECT `+activeBan+` FROM users WHERE id = ?`, userID).Scan(&banned); err != nil {
+		return err
+	}
+	if !banned {
+		return ErrNotBanned
+	}
+	if _, err := tx.Exec(`UPDATE users SET banned_at = NULL, banned_until = NULL, ban_reason = '' WHERE id = ?`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO moderation_log (user_id, moderator_id, action, reason)
+		VALUES (?, ?, ?, ?)
+	`, userID, moderatorID, ActionLift, reason); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetActiveBan returns the user's ban or running suspension, or nil if they
+// have none
+func GetActiveBan(db *sql.DB, userID string) (*models.Ban, error) {
+	var bannedAt time.Time
+	var until sql.NullTime
+	var reason string
+	err := db.QueryRow(`
+		SELECT banned_at, banned_until, ban_reason FROM users WHERE id = ? AND `+activeBan,
+		userID).Scan(&bannedAt, &until, &reason)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newBan(reason, bannedAt, until), nil
+}
+
+// newBan builds a Ban from the users columns
+func newBan(reason string, bannedAt time.Time, until sql.NullTime) *models.Ban {
+	ban := &models.Ban{Reason: reason, BannedAt: bannedAt}
+	if until.Valid {
+		ban.Until = &until.Time
+	}
+	return ban
+}
+
+// LiftExpiredSuspensions clears the suspensions that have run out and logs
+// them as expired. They stop counting as soon as they end; this only tidies
+// them up. Returns how many were lifted.
+func LiftExpiredSuspensions(db *sql.DB) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		INSERT INTO moderation_log (user_id, action, reason, until)
+		SELECT id, ?, ban_reason, banned_until
+		FROM users
+		WHERE banned_until <= CURRENT_TIMESTAMP
+	`, ActionExpire); err != nil {
+		return 0, err
+	}
+	result, err := tx.Exec(`
+		UPDATE users SET banned_at = NULL, banned_until = NULL, ban_reason = ''
+		WHERE banned_until <= CURRENT_TIMESTAMP
+	`)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(n), tx.Commit()
+}
+
+// GetModerationLog returns a page of the moderation log, newest first, for
+// one user or for everyone when userID is empty
+func GetModerationLog(db *sql.DB, userID string, page, limit int) ([]models.ModerationAction, error) {
+	rows, err := db.Query(`
+		SELECT l.id, l.user_id, u.username, l.moderator_id, COALESCE(m.username, ''),
+			l.action, l.reason, l.until, l.created_at
+		FROM moderation_log l
+		JOIN users u ON u.id = l.user_id
+		LEFT JOIN users m ON m.id = l.moderator_id
+		WHERE ? = '' OR l.user_id = ?
+		ORDER BY l.id DESC
+		LIMIT ? OFFSET ?
+	`, userID, userID, limit, (page-1)*limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	actions := []models.ModerationAction{}
+	for rows.Next() {
+		var a models.ModerationAction
+		var moderatorID sql.NullString
+		var until sql.NullTime
+		if err := rows.Scan(&a.ID, &a.UserID, &a.Username, &moderatorID, &a.Moderator,
+			&a.Action, &a.Reason, &until, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		if moderatorID.Valid {
+			a.ModeratorID = &moderatorID.String
+		}
+		if until.Valid {
+			a.Until = &until.Time
+		}
+		actions = append(actions, a)
+	}
+	return actions, rows.Err()
+}
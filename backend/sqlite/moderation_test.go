@@ -0,0 +1,139 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBansAndSuspensions(t *testing.T) {
+	db := setupSchemaTestDB(t)
+	mod := createTestUser(t, db, "mod")
+	alice := createTestUser(t, db, "alice")
+	bob := createTestUser(t, db, "bob")
+	boss := createTestUser(t, db, "boss")
+	if err := SetUserRole(db, "mod", RoleModerator); err != nil {
+		t.Fatalf("SetUserRole failed: %v", err)
+	}
+	if err := SetUserRole(db, "boss", RoleAdmin); err != nil {
+		t.Fatalf("SetUserRole failed: %v", err)
+	}
+
+	t.Run("suspend and lift", func(t *testing.T) {
+		if _, err := CreateSession(db, alice); err != nil {
+			t.Fatalf("CreateSession failed: %v", err)
+		}
+		until := time.Now().Add(72 * time.Hour)
+		if err := BanUser(db, mod, alice, "Spam", &until); err != nil {
+			t.Fatalf("BanUser failed: %v", err)
+		}
+		ban, err := GetActiveBan(db, alice)
+		if err != nil {
+			t.Fatalf("GetActiveBan failed: %v", err)
+		}
+		if ban == nil || ban.Reason != "Spam" || ban.Until == nil || ban.Until.Sub(until).Abs() > time.Second {
+			t.Fatalf("Unexpected ban: %+v", ban)
+		}
+		if n := countRows(t, db, `SELECT COUNT(*) FROM sessions WHERE user_id = ?`, alice); n != 0 {
+			t.Errorf("Expected the suspension to end %d sessions", n)
+		}
+
+		if err := LiftBan(db, mod, alice, "Appealed"); err != nil {
+			t.Fatalf("LiftBan failed: %v", err)
+		}
+		if ban, _ := GetActiveBan(db, alice); ban != nil {
+			t.Errorf("Expected the suspension to be lifted, got %+v", ban)
+		}
+		if err := LiftBan(db, mod, alice, ""); !errors.Is(err, ErrNotBanned) {
+			t.Errorf("Expected ErrNotBanned, got %v", err)
+		}
+	})
+
+	t.Run("suspensions expire", func(t *testing.T) {
+		until := time.Now().Add(-time.Minute)
+		if err := BanUser(db, mod, bob, "Cooling off", &until); err != nil {
+			t.Fatalf("BanUser failed: %v", err)
+		}
+		if ban, _ := GetActiveBan(db, bob); ban != nil {
+			t.Errorf("Expected an expired suspension not to count, got %+v", ban)
+		}
+		n, err := LiftExpiredSuspensions(db)
+		if err != nil {
+			t.Fatalf("LiftExpiredSuspensions failed: %v", err)
+		}
+		if n != 1 {
+			t.Errorf("Expected 1 suspension to expire, got %d", n)
+		}
+		if n := countRows(t, db, `SELECT COUNT(*) FROM users WHERE id = ? AND banned_at IS NULL`, bob); n != 1 {
+			t.Error("Expected the expired suspension to be cleared")
+		}
+	})
+
+	t.Run("permanent bans", func(t *testing.T) {
+		if err := BanUser(db, boss, alice, "Repeated spam", nil); err != nil {
+			t.Fatalf("BanUser failed: %v", err)
+		}
+		if ban, _ := GetActiveBan(db, alice); ban == nil || ban.Until != nil {
+			t.Errorf("Expected a permanent ban, got %+v", ban)
+		}
+		if n, _ := LiftExpiredSuspensions(db); n != 0 {
+			t.Errorf("Expected permanent bans not to expire, %d did", n)
+		}
+	})
+
+	t.Run("who can be banned", func(t *testing.T) {
+		if err := BanUser(db, mod, boss, "", nil); !errors.Is(err, ErrBanAdmin) {
+			t.Errorf("Expected ErrBanAdmin, got %v", err)
+		}
+		other := createTestUser(t, db, "othermod")
+		if err := SetUserRole(db, "othermod", RoleModerator); err != nil {
+			t.Fatalf("SetUserRole failed: %v", err)
+		}
+		if err := BanUser(db, mod, other, "", nil); !errors.Is(err, ErrBanModerator) {
+			t.Errorf("Expected ErrBanModerator, got %v", err)
+		}
+		if err := BanUser(db, boss, other, "Abuse of powers", nil); err != nil {
+			t.Errorf("Expected admins to be able to ban moderators, got %v", err)
+		}
+		if err := LiftBan(db, mod, other, ""); !errors.Is(err, ErrBanModerator) {
+			t.Errorf("Expected ErrBanModerator lifting a moderator's ban, got %v", err)
+		}
+		if err := LiftBan(db, boss, other, "Forgiven"); err != nil {
+			t.Errorf("Expected admins to be able to lift a moderator's ban, got %v", err)
+		}
+		if err := BanUser(db, mod, "nobody", "", nil); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("Expected sql.ErrNoRows, got %v", err)
+		}
+	})
+
+	t.Run("moderation log", func(t *testing.T) {
+		log, err := GetModerationLog(db, alice, 1, 10)
+		if err != nil {
+			t.Fatalf("GetModerationLog failed: %v", err)
+		}
+		var actions []string
+		for _, entry := range log {
+			actions = append(actions, entry.Action)
+		}
+		if len(log) != 3 || actions[0] != ActionBan || actions[1] != ActionLift || actions[2] != ActionSuspend {
+			t.Fatalf("Unexpected log for alice: %v", actions)
+		}
+		if log[0].Moderator != "boss" || log[1].Reason != "Appealed" || log[2].Until == nil {
+			t.Errorf("Unexpected log entries: %+v", log)
+		}
+
+		log, err = GetModerationLog(db, "", 1, 10)
+		if err != nil {
+			t.Fatalf("GetModerationLog failed: %v", err)
+		}
+		for _, entry := range log {
+			if entry.Action == ActionExpire && (entry.Username != "bob" || entry.ModeratorID != nil) {
+				t.Errorf("Unexpected expiry entry: %+v", entry)
+			}
+		}
+		if len(log) != 7 {
+			t.Errorf("Expected 7 log entries, got %d", len(log))
+		}
+	})
+}
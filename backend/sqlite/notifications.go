@@ -0,0 +1,316 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"forum/models"
+)
+
+// Notification types
+const (
+	NotificationMention  = "mention"
+	NotificationComment  = "comment"
+	NotificationReply    = "reply"
+	NotificationReaction = "reaction"
+)
+
+// NotificationTypes lists every type a user can toggle in their preferences
+var NotificationTypes = []string{NotificationComment, NotificationReply, NotificationReaction, NotificationMention}
+
+// contentRef points at a post, comment or reply. Comments and replies also
+// carry the IDs of the post (and comment) they belong to, so a notification
+// can link straight into the thread.
+type contentRef struct {
+	PostID    int
+	CommentID int
+	ReplyID   int
+}
+
+// target returns the column and ID of the most specific item ref points at
+func (ref contentRef) target() (string, int) {
+	switch {
+	case ref.ReplyID != 0:
+		return "reply_id", ref.ReplyID
+	case ref.CommentID != 0:
+		return "comment_id", ref.CommentID
+	default:
+		return "post_id", ref.PostID
+	}
+}
+
+// nullID maps a zero ID to NULL for optional foreign keys
+func nullID(id int) any {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+// NotificationHook, when set, is called with every notification that is
+// created or bumped, so it can be pushed to the recipient's live streams
+var NotificationHook func(userID string, n models.Notification)
+
+// notify records that actorID did something to content owned by recipientID.
+// Nothing is stored for self-actions or types the recipient switched off.
+// Repeated events on the same target collapse into the recipient's unread
+// notification: its count goes up and the latest actor is shown.
+func notify(db *sql.DB, recipientID, actorID, notificationType string, ref contentRef) error {
+	if recipientID == "" || recipientID == actorID {
+		return nil
+	}
+
+	enabled, err := notificationEnabled(db, recipientID, notificationType)
+	if err != nil || !enabled {
+		return err
+	}
+
+	now := time.Now().UTC()
+
+	// Only collapse into a notification about exactly the same target: a
+	// reaction on a post must not merge with one on a comment under it
+	var existingID int
+	var existingActor string
+	err = db.QueryRow(`
+		SELECT id, actor_id FROM notifications
+		WHERE user_id = ? AND type = ? AND is_read = 0
+			AND post_id IS ? AND comment_id IS ? AND reply_id IS ?
+		ORDER BY id DESC LIMIT 1
+	`, recipientID, notificationType, nullID(ref.PostID), nullID(ref.CommentID), nullID(ref.ReplyID)).Scan(&existingID, &existingActor)
+
+	switch {
+	case err == sql.ErrNoRows:
+		var result sql.Result
+		result, err = db.Exec(`
+			INSERT INTO notifications (user_id, actor_id, type, post_id, comment_id, reply_id, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`, recipientID, actorID, notificationType, nullID(ref.PostID), nullID(ref.CommentID), nullID(ref.ReplyID), now, now)
+		if err == nil {
+			var lastID int64
+			lastID, err = result.LastInsertId()
+			existingID = int(lastID)
+		}
+	case err == nil && existingActor == actorID:
+		// Same person again (e.g. toggling a reaction): just bump it
+		_, err = db.Exec(`UPDATE notifications SET updated_at = ? WHERE id = ?`, now, existingID)
+	case err == nil:
+		_, err = db.Exec(`
+			UPDATE notifications SET actor_id = ?, event_count = event_count + 1, updated_at = ?
+			WHERE id = ?
+		`, actorID, now, existingID)
+	}
+	if err != nil {
+		return err
+	}
+
+	if NotificationHook != nil {
+		n, err := getNotification(db, existingID)
+		if err != nil {
+			return err
+		}
+		NotificationHook(recipientID, n)
+	}
+	return nil
+}
+
+// notifyOwner notifies the author of the post, comment or reply ref points at
+func notifyOwner(db *sql.DB, actorID, notificationType string, ref contentRef) error {
+	table := map[string]string{"post_id": "posts", "comment_id": "comments", "reply_id": "replycomments"}
+	column, id := ref.target()
+
+	var ownerID string
+	err := db.QueryRow(fmt.Sprintf(`SELECT user_id FROM %s WHERE id = ?`, table[column]), id).Scan(&ownerID)
+	if err != nil {
+		return err
+	}
+	return notify(db, ownerID, actorID, notificationType, ref)
+}
+
+// notificationEnabled reports whether userID wants notifications of a type
+func notificationEnabled(db *sql.DB, userID, notificationType string) (bool, error) {
+	var enabled bool
+	err := db.QueryRow(`
+		SELECT enabled FROM notification_preferences WHERE user_id = ? AND type = ?
+	`, userID, notificationType).Scan(&enabled)
+	if err == sql.ErrNoRows {
+		return true, nil
+	}
+	return enabled, err
+}
+
+// GetNotifications returns a page of a user's notifications, most recently
+// updated first, together with their total unread count
+func GetNotifications(db *sql.DB, userID string, unreadOnly bool, page, limit int) ([]models.Notification, int, error) {
+	offset := (page - 1) * limit
+
+	filter := ""
+	if unreadOnly {
+		filter = "AND n.is_read = 0"
+	}
+
+	rows, err := db.Query(fmt.Sprintf(`
+		%s
+		WHERE n.user_id = ? %s
+		ORDER BY n.updated_at DESC, n.id DESC
+		LIMIT ? OFFSET ?
+	`, notificationSelect, filter), userID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	notifications := []models.Notification{}
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		notifications = append(notifications, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	var unread int
+	err = db.QueryRow(`SELECT COUNT(*) FROM notifications WHERE user_id = ? AND is_read = 0`, userID).Scan(&unread)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return notifications, unread, nil
+}
+
+// notificationSelect selects the columns read by scanNotification
+const notificationSelect = `
+	SELECT
+		n.id, n.type, n.actor_id, u.username, u.avatar_url, n.event_count,
+		n.post_id, n.comment_id, n.reply_id, n.is_read,
+		n.created_at, n.updated_at
+	FROM notifications n
+	JOIN users u ON u.id = n.actor_id`
+
+// scanNotification reads one row selected with notificationSelect
+func scanNotification(row interface{ Scan(...any) error }) (models.Notification, error) {
+	var n models.Notification
+	var postID, commentID, replyID sql.NullInt64
+	err := row.Scan(
+		&n.ID, &n.Type, &n.ActorID, &n.ActorUsername, &n.ActorAvatar, &n.EventCount,
+		&postID, &commentID, &replyID, &n.IsRead,
+		&n.CreatedAt, &n.UpdatedAt,
+	)
+	n.PostID = intPtr(postID)
+	n.CommentID = intPtr(commentID)
+	n.ReplyID = intPtr(replyID)
+	return n, err
+}
+
+// getNotification loads a single notification by ID
+func getNotification(db *sql.DB, id int) (models.Notification, error) {
+	return scanNotification(db.QueryRow(notificationSelect+` WHERE n.id = ?`, id))
+}
+
+// intPtr converts a nullable integer column to an optional int
+func intPtr(v sql.NullInt64) *int {
+	if !v.Valid {
+		return nil
+	}
+	i := int(v.Int64)
+	return &i
+}
+
+// MarkNotificationsRead marks the given notifications of userID as read and
+// returns how many were changed. Returns sql.ErrNoRows, changing nothing, if
+// any of them is not one of userID's notifications.
+func MarkNotificationsRead(db *sql.DB, userID string, ids []int) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	placeholders := make([]string, len(ids))
+	args := []any{userID}
+	distinct := make(map[int]bool)
+	for i, id := range ids {
+		placeholders[i] = "?"
+		args = append(args, id)
+		distinct[id] = true
+	}
+	in := strings.Join(placeholders, ",")
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var owned int
+	err = tx.QueryRow(fmt.Sprintf(`SELECT COUNT(*) FROM notifications WHERE user_id = ? AND id IN (%s)`, in), args...).Scan(&owned)
+	if err != nil {
+		return 0, err
+	}
+	if owned != len(distinct) {
+		return 0, sql.ErrNoRows
+	}
+
+	result, err := tx.Exec(fmt.Sprintf(`
+		UPDATE notifications SET is_read = 1
+		WHERE user_id = ? AND is_read = 0 AND id IN (%s)
+	`, in), args...)
+	if err != nil {
+		return 0, err
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return updated, tx.Commit()
+}
+
+// MarkAllNotificationsRead marks every notification of userID as read
+func MarkAllNotificationsRead(db *sql.DB, userID string) (int64, error) {
+	result, err := db.Exec(`UPDATE notifications SET is_read = 1 WHERE user_id = ? AND is_read = 0`, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// GetNotificationPreferences returns whether each notification type is
+// enabled for userID
+func GetNotificationPreferences(db *sql.DB, userID string) (map[string]bool, error) {
+	prefs := make(map[string]bool, len(NotificationTypes))
+	for _, t := range NotificationTypes {
+		prefs[t] = true
+	}
+
+	rows, err := db.Query(`SELECT type, enabled FROM notification_preferences WHERE user_id = ?`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var t string
+		var enabled bool
+		if err := rows.Scan(&t, &enabled); err != nil {
+			return nil, err
+		}
+		prefs[t] = enabled
+	}
+	return prefs, rows.Err()
+}
+
+// SetNotificationPreferences stores the given per-type settings for userID
+func SetNotificationPreferences(db *sql.DB, userID string, prefs map[string]bool) error {
+	for t, enabled := range prefs {
+		_, err := db.Exec(`
+			INSERT INTO notification_preferences (user_id, type, enabled) VALUES (?, ?, ?)
+			ON CONFLICT(user_id, type) DO UPDATE SET enabled = excluded.enabled
+		`, userID, t, enabled)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package sqlite
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestNotifier(t *testing.T) {
+	db := setupSchemaTestDB(t)
+	author := createTestUser(t, db, "author")
+	bob := createTestUser(t, db, "bob")
+	carol := createTestUser(t, db, "carol")
+
+	post, err := CreatePost(db, author, nil, "Post", "Body", "")
+	if err != nil {
+		t.Fatalf("CreatePost failed: %v", err)
+	}
+
+	t.Run("comments collapse per post", func(t *testing.T) {
+		for _, user := range []string{bob, carol, bob} {
+			if _, err := CreateComment(db, user, post.ID, "nice"); err != nil {
+				t.Fatalf("CreateComment failed: %v", err)
+			}
+		}
+		if _, err := CreateComment(db, author, post.ID, "thanks"); err != nil {
+			t.Fatalf("CreateComment failed: %v", err)
+		}
+
+		notifications, unread, err := GetNotifications(db, author, false, 1, 10)
+		if err != nil {
+			t.Fatalf("GetNotifications failed: %v", err)
+		}
+		if len(notifications) != 1 || unread != 1 {
+			t.Fatalf("Expected 1 collapsed notification, got %d (unread %d)", len(notifications), unread)
+		}
+		n := notifications[0]
+		if n.Type != NotificationComment || n.EventCount != 3 || n.ActorID != bob {
+			t.Fatalf("Unexpected notification: %+v", n)
+		}
+	})
+
+	t.Run("reply notifies comment author", func(t *testing.T) {
+		comment, err := CreateComment(db, bob, post.ID, "question")
+		if err != nil {
+			t.Fatalf("CreateComment failed: %v", err)
+		}
+		if _, err := CreateReplyComment(db, carol, comment.ID, "answer"); err != nil {
+			t.Fatalf("CreateReplyComment failed: %v", err)
+		}
+
+		notifications, _, err := GetNotifications(db, bob, true, 1, 10)
+		if err != nil {
+			t.Fatalf("GetNotifications failed: %v", err)
+		}
+		if len(notifications) != 1 || notifications[0].Type != NotificationReply || *notifications[0].CommentID != comment.ID {
+			t.Fatalf("Unexpected reply notifications: %+v", notifications)
+		}
+	})
+
+	t.Run("reactions notify and respect preferences", func(t *testing.T) {
+		if err := SetNotificationPreferences(db, author, map[string]bool{NotificationReaction: false}); err != nil {
+			t.Fatalf("SetNotificationPreferences failed: %v", err)
+		}
+		if _, err := ToggleLike(db, bob, &post.ID, nil, "like"); err != nil {
+			t.Fatalf("ToggleLike failed: %v", err)
+		}
+		if n := countRows(t, db, `SELECT COUNT(*) FROM notifications WHERE user_id = ? AND type = ?`, author, NotificationReaction); n != 0 {
+			t.Fatalf("Disabled reaction notifications were stored")
+		}
+
+		if err := SetNotificationPreferences(db, author, map[string]bool{NotificationReaction: true}); err != nil {
+			t.Fatalf("SetNotificationPreferences failed: %v", err)
+		}
+		if _, err := ToggleLike(db, carol, &post.ID, nil, "dislike"); err != nil {
+			t.Fatalf("ToggleLike failed: %v", err)
+		}
+		if n := countRows(t, db, `SELECT COUNT(*) FROM notifications WHERE user_id = ? AND type = ?`, author, NotificationReaction); n != 1 {
+			t.Fatalf("Expected 1 reaction notification, got %d", n)
+		}
+	})
+
+	t.Run("reactions on a post and its comment stay apart", func(t *testing.T) {
+		comment, err := CreateComment(db, author, post.ID, "my own comment")
+		if err != nil {
+			t.Fatalf("CreateComment failed: %v", err)
+		}
+		if _, err := ToggleLike(db, bob, nil, &comment.ID, "like"); err != nil {
+			t.Fatalf("ToggleLike failed: %v", err)
+		}
+		if _, err := ToggleLike(db, carol, &post.ID, nil, "love"); err != nil {
+			t.Fatalf("ToggleLike failed: %v", err)
+		}
+
+		if n := countRows(t, db, `SELECT COUNT(*) FROM notifications WHERE user_id = ? AND type = ?`, author, NotificationReaction); n != 2 {
+			t.Fatalf("Expected 2 reaction notifications, got %d", n)
+		}
+		if n := countRows(t, db, `SELECT event_count FROM notifications WHERE user_id = ? AND type = ? AND comment_id = ?`, author, NotificationReaction, comment.ID); n != 1 {
+			t.Errorf("Expected the post reaction not to collapse into the comment's, got count %d", n)
+		}
+	})
+
+	t.Run("mark read", func(t *testing.T) {
+		notifications, _, _ := GetNotifications(db, author, true, 1, 10)
+		if len(notifications) == 0 {
+			t.Fatal("Expected unread notifications")
+		}
+
+		// Another user's IDs are refused
+		if updated, err := MarkNotificationsRead(db, bob, []int{notifications[0].ID}); updated != 0 || err != sql.ErrNoRows {
+			t.Fatalf("Expected sql.ErrNoRows for another user's notification, got %d, %v", updated, err)
+		}
+		if updated, _ := MarkNotificationsRead(db, author, []int{notifications[0].ID}); updated != 1 {
+			t.Fatalf("Expected 1 notification marked read, got %d", updated)
+		}
+		if _, err := MarkAllNotificationsRead(db, author); err != nil {
+			t.Fatalf("MarkAllNotificationsRead failed: %v", err)
+		}
+
+		_, unread, _ := GetNotifications(db, author, true, 1, 10)
+		if unread != 0 {
+			t.Fatalf("Expected no unread notifications, got %d", unread)
+		}
+
+		// A new comment after reading starts a fresh notification
+		if _, err := CreateComment(db, carol, post.ID, "again"); err != nil {
+			t.Fatalf("CreateComment failed: %v", err)
+		}
+		_, unread, _ = GetNotifications(db, author, true, 1, 10)
+		if unread != 1 {
+			t.Fatalf("Expected 1 new unread notification, got %d", unread)
+		}
+	})
+
+	t.Run("deleting content deletes its notifications", func(t *testing.T) {
+		// As on a pooled connection that never turned foreign keys on
+		if _, err := db.Exec(`PRAGMA foreign_keys = OFF`); err != nil {
+			t.Fatalf("Failed to disable foreign keys: %v", err)
+		}
+		defer db.Exec(`PRAGMA foreign_keys = ON`)
+
+		before := countRows(t, db, `SELECT COUNT(*) FROM notifications WHERE user_id = ?`, bob)
+		other, err := CreatePost(db, author, nil, "Other", "Body", "")
+		if err != nil {
+			t.Fatalf("CreatePost failed: %v", err)
+		}
+		comment, err := CreateComment(db, bob, other.ID, "question")
+		if err != nil {
+			t.Fatalf("CreateComment failed: %v", err)
+		}
+		if _, err := CreateReplyComment(db, carol, comment.ID, "answer"); err != nil {
+			t.Fatalf("CreateReplyComment failed: %v", err)
+		}
+		if n := countRows(t, db, `SELECT COUNT(*) FROM notifications WHERE user_id = ?`, bob); n != before+1 {
+			t.Fatalf("Expected bob to be notified of the reply, got %d notifications", n)
+		}
+
+		if err := DeleteComment(db, comment.ID); err != nil {
+			t.Fatalf("DeleteComment failed: %v", err)
+		}
+		if n := countRows(t, db, `SELECT COUNT(*) FROM notifications WHERE user_id = ?`, bob); n != before {
+			t.Errorf("Expected the reply notification to go with the comment, got %d notifications", n)
+		}
+
+		if err := DeletePost(db, other.ID); err != nil {
+			t.Fatalf("DeletePost failed: %v", err)
+		}
+		if n := countRows(t, db, `SELECT COUNT(*) FROM notifications WHERE post_id = ?`, other.ID); n != 0 {
+			t.Errorf("Expected the post's notifications to go with it, got %d", n)
+		}
+	})
+}
+
+func TestApplyColumnMigrations(t *testing.T) {
+	db := setupSchemaTestDB(t)
+
+	// Simulate a notifications table created before event_count existed
+	_, err := db.Exec(`
+	DROP TABLE notifications;
+	CREATE TABLE notifications (id INTEGER PRIMARY KEY, user_id TEXT, actor_id TEXT, type TEXT, is_read INTEGER DEFAULT 0);
+	`)
+	if err != nil {
+		t.Fatalf("Failed to recreate table: %v", err)
+	}
+
+	if err := applyColumnMigrations(db); err != nil {
+		t.Fatalf("applyColumnMigrations failed: %v", err)
+	}
+	if n := countRows(t, db, `SELECT COUNT(*) FROM pragma_table_info('notifications') WHERE name IN ('event_count', 'updated_at')`); n != 2 {
+		t.Fatalf("Expected both columns to be added, got %d", n)
+	}
+
+	// Running again is a no-op
+	if err := applyColumnMigrations(db); err != nil {
+		t.Fatalf("Second applyColumnMigrations failed: %v", err)
+	}
+}
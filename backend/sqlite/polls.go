@@ -0,0 +1,245 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"forum/models"
+)
+
+var (
+	// ErrPollClosed is returned when voting after a poll's close time
+	ErrPollClosed = errors.New("this poll is closed")
+	// ErrPostLocked is returned when adding to a locked thread
+	ErrPostLocked = errors.New("this thread is locked")
+	// ErrAlreadyVoted is returned when a user votes twice on a poll
+	ErrAlreadyVoted = errors.New("you already voted on this poll")
+	// ErrInvalidVote is returned for options that are not part of the poll, or
+	// several options on a single-choice poll
+	ErrInvalidVote = errors.New("invalid choice for this poll")
+)
+
+// CreatePoll attaches a poll to a post. Only the question, options (by text),
+// choice mode, close time and hide_results setting of p are used.
+func CreatePoll(db *sql.DB, postID int, p models.Poll) (models.Poll, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return models.Poll{}, err
+	}
+	defer tx.Rollback()
+
+	pollID, err := createPoll(tx, postID, p)
+	if err != nil {
+		return models.Poll{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Poll{}, err
+	}
+
+	return GetPoll(db, pollID, "")
+}
+
+// createPoll inserts a poll and its options within tx and returns its ID
+func createPoll(tx *sql.Tx, postID int, p models.Poll) (int, error) {
+	var closesAt any
+	if p.ClosesAt != nil {
+		closesAt = sqliteTime(*p.ClosesAt)
+	}
+	var pollID int
+	err := tx.QueryRow(`
+		INSERT INTO polls (post_id, question, multiple_choice, hide_results, closes_at)
+		VALUES (?, ?, ?, ?, ?)
+		RETURNING id
+	`, postID, p.Question, p.MultipleChoice, p.HideResults, closesAt).Scan(&pollID)
+	if err != nil {
+		return 0, err
+	}
+	for i, option := range p.Options {
+		if _, err := tx.Exec(`INSERT INTO poll_options (poll_id, text, position) VALUES (?, ?, ?)`, pollID, option.Text, i); err != nil {
+			return 0, err
+		}
+	}
+	return pollID, nil
+}
+
+// GetPoll returns a poll with its tallies as seen by viewerID (empty for
+// signed-out visitors)
+func GetPoll(db *sql.DB, pollID int, viewerID string) (models.Poll, error) {
+	return scanPoll(db, viewerID, `WHERE id = ?`, pollID)
+}
+
+// GetPollByPostID returns the poll of a post, or nil if it has none
+func GetPollByPostID(db *sql.DB, postID int, viewerID string) (*models.Poll, error) {
+	poll, err := scanPoll(db, viewerID, `WHERE post_id = ?`, postID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &poll, nil
+}
+
+// scanPoll loads the poll matching where, with its options, tallies and the
+// viewer's vote. Tallies stay nil while the results are hidden from viewerID.
+func scanPoll(db *sql.DB, viewerID, where string, args ...any) (models.Poll, error) {
+	var p models.Poll
+	var closesAt sql.NullTime
+	err := db.QueryRow(`
+		SELECT id, post_id, question, multiple_choice, hide_results, closes_at,
+			closes_at IS NOT NULL AND closes_at <= CURRENT_TIMESTAMP,
+			(SELECT COUNT(*) FROM poll_ballots WHERE poll_id = polls.id),
+			created_at
+		FROM polls `+where, args...).Scan(
+		&p.ID, &p.PostID, &p.Question, &p.MultipleChoice, &p.HideResults, &closesAt,
+		&p.Closed, &p.VoterCount, &p.CreatedAt,
+	)
+	if err != nil {
+		return p, err
+	}
+	if closesAt.Valid {
+		p.ClosesAt = &closesAt.Time
+	}
+
+	p.ViewerVote = []int{}
+	if viewerID != "" {
+		rows, err := db.Query(`SELECT option_id FROM poll_votes WHERE poll_id = ? AND user_id = ? ORDER BY option_id`, p.ID, viewerID)
+		if err != nil {
+			return p, err
+		}
+		for rows.Next() {
+			var optionID int
+			if err := rows.Scan(&optionID); err != nil {
+				rows.Close()
+				return p, err
+			}
+			p.ViewerVote = append(p.ViewerVote, optionID)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return p, err
+		}
+	}
+	showResults := !p.HideResults || p.Closed || len(p.ViewerVote) > 0
+
+	rows, err := db.Query(`
+		SELECT o.id, o.text, COUNT(v.user_id)
+		FROM poll_options o
+		LEFT JOIN poll_votes v ON v.option_id = o.id
+		WHERE o.poll_id = ?
+		GROUP BY o.id
+		ORDER BY o.position
+	`, p.ID)
+	if err != nil {
+		return p, err
+	}
+	defer rows.Close()
+
+	p.Options = []models.PollOption{}
+	for rows.Next() {
+		var option models.PollOption
+		var votes int
+		if err := rows.Scan(&option.ID, &option.Text, &votes); err != nil {
+			return p, err
+		}
+		if showResults {
+			option.VoteCount = &votes
+		}
+		p.Options = append(p.Options, option)
+	}
+	return p, rows.Err()
+}
+
+// Vote records userID's ballot on a poll. Each user votes once; on a
+// single-choice poll the ballot holds exactly one option. Returns
+// sql.ErrNoRows if the poll does not exist.
+func Vote(db *sql.DB, pollID int, userID string, optionIDs []int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var multipleChoice, closed, locked bool
+	err = tx.QueryRow(`
+		SELECT polls.multiple_choice,
+			polls.closes_at IS NOT NULL AND polls.closes_at <= CURRENT_TIMESTAMP,
+			posts.locked
+		FROM polls
+		JOIN posts ON posts.id = polls.post_id
+		WHERE polls.id = ?
+	`, pollID).Scan(&multipleChoice, &closed, &locked)
+	if err != nil {
+		return err
+	}
+	if locked {
+		return ErrPostLocked
+	}
+	if closed {
+		return ErrPollClosed
+	}
+
+	// Every option must be distinct and belong to this poll
+	seen := make(map[int]bool)
+	placeholders := make([]string, 0, len(optionIDs))
+	args := []any{pollID}
+	for _, id := range optionIDs {
+		if seen[id] {
+			return ErrInvalidVote
+		}
+		seen[id] = true
+		placeholders = append(placeholders, "?")
+		args = append(args, id)
+	}
+	if len(optionIDs) == 0 || (!multipleChoice && len(optionIDs) > 1) {
+		return ErrInvalidVote
+	}
+	var found int
+	err = tx.QueryRow(fmt.Sprintf(`
+		SELECT COUNT(*) FROM poll_options WHERE poll_id = ? AND id IN (%s)
+	`, strings.Join(placeholders, ",")), args...).Scan(&found)
+	if err != nil {
+		return err
+	}
+	if found != len(optionIDs) {
+		return ErrInvalidVote
+	}
+
+	if _, err := tx.Exec(`INSERT INTO poll_ballots (poll_id, user_id) VALUES (?, ?)`, pollID, userID); err != nil {
+		if IsUniqueConstraintError(err) {
+			return ErrAlreadyVoted
+		}
+		return err
+	}
+	for _, optionID := range optionIDs {
+		if _, err := tx.Exec(`INSERT INTO poll_votes (poll_id, user_id, option_id) VALUES (?, ?, ?)`, pollID, userID, optionID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// IsPostLocked reports whether a post is locked. Returns sql.ErrNoRows if the
+// post does not exist.
+func IsPostLocked(db *sql.DB, postID int) (bool, error) {
+	var locked bool
+	err := db.QueryRow(`SELECT locked FROM posts WHERE id = ?`, postID).Scan(&locked)
+	return locked, err
+}
+
+// SetPostLocked locks or unlocks a post. Returns sql.ErrNoRows if the post
+// does not exist.
+func SetPostLocked(db *sql.DB, postID int, locked bool) error {
+	result, err := db.Exec(`UPDATE posts SET locked = ? WHERE id = ?`, locked, postID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
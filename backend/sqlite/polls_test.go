@@ -0,0 +1,161 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"forum/models"
+)
+
+func TestPolls(t *testing.T) {
+	db := setupSchemaTestDB(t)
+	alice := createTestUser(t, db, "alice")
+	bob := createTestUser(t, db, "bob")
+
+	newPoll := func(p models.Poll, options ...string) models.Poll {
+		t.Helper()
+		post, err := CreatePost(db, alice, nil, "Poll", "Vote!", "")
+		if err != nil {
+			t.Fatalf("CreatePost failed: %v", err)
+		}
+		for _, text := range options {
+			p.Options = append(p.Options, models.PollOption{Text: text})
+		}
+		created, err := CreatePoll(db, post.ID, p)
+		if err != nil {
+			t.Fatalf("CreatePoll failed: %v", err)
+		}
+		return created
+	}
+
+	t.Run("single choice", func(t *testing.T) {
+		poll := newPoll(models.Poll{Question: "Tabs or spaces?"}, "Tabs", "Spaces")
+		tabs, spaces := poll.Options[0].ID, poll.Options[1].ID
+
+		if err := Vote(db, poll.ID, bob, []int{tabs, spaces}); !errors.Is(err, ErrInvalidVote) {
+			t.Errorf("Expected ErrInvalidVote for two options, got %v", err)
+		}
+		if err := Vote(db, poll.ID, bob, []int{spaces}); err != nil {
+			t.Fatalf("Vote failed: %v", err)
+		}
+		if err := Vote(db, poll.ID, bob, []int{tabs}); !errors.Is(err, ErrAlreadyVoted) {
+			t.Errorf("Expected ErrAlreadyVoted, got %v", err)
+		}
+
+		got, err := GetPollByPostID(db, poll.PostID, bob)
+		if err != nil || got == nil {
+			t.Fatalf("GetPollByPostID failed: %v", err)
+		}
+		if got.VoterCount != 1 || *got.Options[1].VoteCount != 1 || *got.Options[0].VoteCount != 0 {
+			t.Errorf("Unexpected tallies: %+v", got.Options)
+		}
+		if len(got.ViewerVote) != 1 || got.ViewerVote[0] != spaces {
+			t.Errorf("Expected bob's vote for spaces, got %v", got.ViewerVote)
+		}
+	})
+
+	t.Run("the database allows one option on single-choice ballots", func(t *testing.T) {
+		poll := newPoll(models.Poll{Question: "Pick one"}, "A", "B")
+		db.Exec(`INSERT INTO poll_ballots (poll_id, user_id) VALUES (?, ?)`, poll.ID, alice)
+		if _, err := db.Exec(`INSERT INTO poll_votes (poll_id, user_id, option_id) VALUES (?, ?, ?)`, poll.ID, alice, poll.Options[0].ID); err != nil {
+			t.Fatalf("Failed to insert vote: %v", err)
+		}
+		if _, err := db.Exec(`INSERT INTO poll_votes (poll_id, user_id, option_id) VALUES (?, ?, ?)`, poll.ID, alice, poll.Options[1].ID); err == nil {
+			t.Error("Expected the trigger to reject a second option")
+		}
+	})
+
+	t.Run("multiple choice with hidden results", func(t *testing.T) {
+		poll := newPoll(models.Poll{Question: "Languages?", MultipleChoice: true, HideResults: true}, "Go", "Rust", "Zig")
+		other := newPoll(models.Poll{Question: "Other"}, "X", "Y")
+
+		if err := Vote(db, poll.ID, bob, []int{poll.Options[0].ID, other.Options[0].ID}); !errors.Is(err, ErrInvalidVote) {
+			t.Errorf("Expected ErrInvalidVote for another poll's option, got %v", err)
+		}
+		if err := Vote(db, poll.ID, bob, []int{poll.Options[0].ID, poll.Options[2].ID}); err != nil {
+			t.Fatalf("Vote failed: %v", err)
+		}
+
+		hidden, _ := GetPoll(db, poll.ID, alice)
+		if hidden.Options[0].VoteCount != nil || hidden.VoterCount != 1 {
+			t.Errorf("Expected hidden tallies for alice, got %+v", hidden)
+		}
+		shown, _ := GetPoll(db, poll.ID, bob)
+		if shown.Options[0].VoteCount == nil || *shown.Options[2].VoteCount != 1 || len(shown.ViewerVote) != 2 {
+			t.Errorf("Expected tallies for bob, got %+v", shown)
+		}
+	})
+
+	t.Run("closed polls and locked threads", func(t *testing.T) {
+		past := time.Now().Add(-time.Minute)
+		closed := newPoll(models.Poll{Question: "Closed", ClosesAt: &past, HideResults: true}, "A", "B")
+		if !closed.Closed || closed.Options[0].VoteCount == nil {
+			t.Errorf("Expected a closed poll with visible results, got %+v", closed)
+		}
+		if err := Vote(db, closed.ID, bob, []int{closed.Options[0].ID}); !errors.Is(err, ErrPollClosed) {
+			t.Errorf("Expected ErrPollClosed, got %v", err)
+		}
+
+		future := time.Now().Add(time.Hour)
+		open := newPoll(models.Poll{Question: "Open", ClosesAt: &future}, "A", "B")
+		if open.Closed {
+			t.Error("Expected the poll to be open")
+		}
+		if err := SetPostLocked(db, open.PostID, true); err != nil {
+			t.Fatalf("SetPostLocked failed: %v", err)
+		}
+		if err := Vote(db, open.ID, bob, []int{open.Options[0].ID}); !errors.Is(err, ErrPostLocked) {
+			t.Errorf("Expected ErrPostLocked, got %v", err)
+		}
+		if locked, _ := IsPostLocked(db, open.PostID); !locked {
+			t.Error("Expected the post to be locked")
+		}
+		if err := SetPostLocked(db, 9999, true); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("Expected sql.ErrNoRows, got %v", err)
+		}
+	})
+
+	t.Run("posts without a poll", func(t *testing.T) {
+		post, _ := CreatePost(db, alice, nil, "Plain", "No poll", "")
+		if poll, err := GetPollByPostID(db, post.ID, ""); err != nil || poll != nil {
+			t.Errorf("Expected no poll, got %+v (%v)", poll, err)
+		}
+		if err := Vote(db, 9999, bob, []int{1}); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("Expected sql.ErrNoRows, got %v", err)
+		}
+	})
+}
+
+func TestCreatePostWithDetails(t *testing.T) {
+	db := setupSchemaTestDB(t)
+	alice := createTestUser(t, db, "alice")
+	details := models.PostDetails{
+		Tags:     []string{"go", "sqlite"},
+		PostType: "question",
+		Poll:     &models.Poll{Question: "Which?", Options: []models.PollOption{{Text: "This"}, {Text: "That"}}},
+	}
+
+	post, err := CreatePostWithDetails(db, alice, "Help", "Which one?", details)
+	if err != nil {
+		t.Fatalf("CreatePostWithDetails failed: %v", err)
+	}
+	if post.PostType != "question" || len(post.Tags) != 2 || post.Poll == nil || len(post.Poll.Options) != 2 {
+		t.Fatalf("Expected a question with tags and a poll, got %+v", post)
+	}
+
+	// A failure part way through leaves nothing behind
+	if _, err := db.Exec(`CREATE TRIGGER fail_options BEFORE INSERT ON poll_options BEGIN SELECT RAISE(ABORT, 'no options'); END`); err != nil {
+		t.Fatalf("Failed to create trigger: %v", err)
+	}
+	if _, err := CreatePostWithDetails(db, alice, "Help again", "Which one?", details); err == nil {
+		t.Fatal("Expected the failing poll to fail the post")
+	}
+	if count := countRows(t, db, `SELECT COUNT(*) FROM posts`); count != 1 {
+		t.Errorf("Expected the failed post to be rolled back, got %d posts", count)
+	}
+	if count := countRows(t, db, `SELECT COUNT(*) FROM post_tags`); count != 2 {
+		t.Errorf("Expected the failed post's tags to be rolled back, got %d", count)
+	}
+}
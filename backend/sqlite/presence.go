@@ -0,0 +1,103 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"forum/models"
+)
+
+// SaveLastSeen stores a batch of last-seen times collected in memory, and
+// the days they fall on as user activity
+func SaveLastSeen(db *sql.DB, seen map[string]time.Time) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`UPDATE users SET last_seen_at = ? WHERE id = ?`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for userID, at := range seen {
+		if _, err := stmt.Exec(at, userID); err != nil {
+			return err
+		}
+		if err := recordActivity(tx, userID, at); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetLastSeen returns the stored last-seen time of a user (nil if never seen)
+// and whether they let others see their online status
+func GetLastSeen(db *sql.DB, userID string) (*time.Time, bool, error) {
+	var lastSeen sql.NullTime
+	var visible bool
+	err := db.QueryRow(`SELECT last_seen_at, show_online_status FROM users WHERE id = ?`, userID).Scan(&lastSeen, &visible)
+	if err != nil || !lastSeen.Valid {
+		return nil, visible, err
+	}
+	return &lastSeen.Time, visible, nil
+}
+
+// GetVisibleUsers returns the given users, leaving out those who hide their
+// online status, ordered by username
+func GetVisibleUsers(db *sql.DB, userIDs []string) ([]models.UserSummary, error) {
+	users := []models.UserSummary{}
+	if len(userIDs) == 0 {
+		return users, nil
+	}
+
+	placeholders := make([]string, len(userIDs))
+	args := make([]any, len(userIDs))
+	for i, id := range userIDs {
+		placeholders[i] = "?"
+		args[i] = id
+	}
+
+	rows, err := db.Query(fmt.Sprintf(`
+		SELECT id, username, avatar_url
+		FROM users
+		WHERE id IN (%s) AND show_online_status = 1
+		ORDER BY username
+	`, strings.Join(placeholders, ",")), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var user models.UserSummary
+		if err := rows.Scan(&user.ID, &user.Username, &user.AvatarURL); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// GetPrivacySettings returns a user's privacy settings
+func GetPrivacySettings(db *sql.DB, userID string) (models.PrivacySettings, error) {
+	var settings models.PrivacySettings
+	err := db.QueryRow(`
+		SELECT show_online_status, show_posts, show_comments, show_liked_posts
+		FROM users WHERE id = ?
+	`, userID).Scan(&settings.ShowOnlineStatus, &settings.ShowPosts, &settings.ShowComments, &settings.ShowLikedPosts)
+	return settings, err
+}
+
+// UpdatePrivacySettings stores a user's privacy settings
+func UpdatePrivacySettings(db *sql.DB, userID string, settings models.PrivacySettings) error {
+	_, err := db.Exec(`
+		UPDATE users SET show_online_status = ?, show_posts = ?, show_comments = ?, show_liked_posts = ?
+		WHERE id = ?
+	`, settings.ShowOnlineStatus, settings.ShowPosts, settings.ShowComments, settings.ShowLikedPosts, userID)
+	return err
+}
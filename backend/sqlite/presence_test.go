@@ -0,0 +1,50 @@
+package sqlite
+
+import (
+	"testing"
+	"time"
+
+	"forum/models"
+)
+
+func TestPresenceStorage(t *testing.T) {
+	db := setupSchemaTestDB(t)
+	alice := createTestUser(t, db, "alice")
+	bob := createTestUser(t, db, "bob")
+
+	t.Run("never seen", func(t *testing.T) {
+		lastSeen, visible, err := GetLastSeen(db, alice)
+		if err != nil || lastSeen != nil || !visible {
+			t.Errorf("Expected no last seen time and a visible status, got %v %v (%v)", lastSeen, visible, err)
+		}
+	})
+
+	t.Run("saved in batches", func(t *testing.T) {
+		at := time.Date(2025, 5, 27, 10, 0, 0, 0, time.UTC)
+		if err := SaveLastSeen(db, map[string]time.Time{alice: at, bob: at.Add(time.Minute)}); err != nil {
+			t.Fatalf("SaveLastSeen failed: %v", err)
+		}
+		lastSeen, _, err := GetLastSeen(db, alice)
+		if err != nil || lastSeen == nil || !lastSeen.Equal(at) {
+			t.Errorf("Expected %v, got %v (%v)", at, lastSeen, err)
+		}
+	})
+
+	t.Run("hidden users are left out", func(t *testing.T) {
+		if err := UpdatePrivacySettings(db, bob, models.PrivacySettings{ShowOnlineStatus: false}); err != nil {
+			t.Fatalf("UpdatePrivacySettings failed: %v", err)
+		}
+		settings, err := GetPrivacySettings(db, bob)
+		if err != nil || settings.ShowOnlineStatus {
+			t.Errorf("Expected the status to be hidden, got %+v (%v)", settings, err)
+		}
+
+		users, err := GetVisibleUsers(db, []string{alice, bob})
+		if err != nil {
+			t.Fatalf("GetVisibleUsers failed: %v", err)
+		}
+		if len(users) != 1 || users[0].ID != alice {
+			t.Errorf("Expected only alice, got %+v", users)
+		}
+	})
+}
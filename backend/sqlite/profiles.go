@@ -0,0 +1,123 @@
+package sqlite
+
+import (
+	"database/sql"
+
+	"forum/models"
+)
+
+// profileQuery selects public profiles; callers append the WHERE clause and
+// pass AcceptedAnswerKarma before its arguments. Karma counts reactions from
+// others and answers others accepted.
+const profileQuery = `
+	SELECT
+		u.id, u.username, u.avatar_url, u.bio, u.created_at,
+		(SELECT COUNT(*) FROM posts WHERE user_id = u.id AND status = 'published'),
+		(SELECT COUNT(*) FROM comments WHERE user_id = u.id)
+			+ (SELECT COUNT(*) FROM replycomments WHERE user_id = u.id),
+		(SELECT COALESCE(SUM(CASE l.type WHEN 'like' THEN 1 WHEN 'dislike' THEN -1 ELSE 0 END), 0)
+		 FROM likes l JOIN posts p ON p.id = l.post_id
+		 WHERE p.user_id = u.id AND l.user_id != u.id)
+			+ (SELECT COALESCE(SUM(CASE l.type WHEN 'like' THEN 1 WHEN 'dislike' THEN -1 ELSE 0 END), 0)
+			   FROM likes l JOIN comments c ON c.id = l.comment_id
+			   WHERE c.user_id = u.id AND l.user_id != u.id)
+			+ ? * (SELECT COUNT(*) FROM posts p JOIN comments c ON c.id = p.accepted_comment_id
+			       WHERE c.user_id = u.id AND p.user_id != u.id),
+		(SELECT COUNT(*) FROM follows WHERE followee_id = u.id),
+		(SELECT COUNT(*) FROM follows WHERE follower_id = u.id)
+	FROM users u
+`
+
+// scanProfile reads one row selected with profileQuery
+func scanProfile(row *sql.Row) (models.Profile, error) {
+	var p models.Profile
+	err := row.Scan(&p.ID, &p.Username, &p.AvatarURL, &p.Bio, &p.JoinedAt, &p.PostCount, &p.CommentCount, &p.Karma, &p.FollowerCount, &p.FollowingCount)
+	return p, err
+}
+
+// GetProfileByUsername returns the public profile of a user
+func GetProfileByUsername(db *sql.DB, username string) (models.Profile, error) {
+	return scanProfile(db.QueryRow(profileQuery+` WHERE u.username = ?`, AcceptedAnswerKarma, username))
+}
+
+// GetProfileByID returns the public profile of a user
+func GetProfileByID(db *sql.DB, userID string) (models.Profile, error) {
+	return scanProfile(db.QueryRow(profileQuery+` WHERE u.id = ?`, AcceptedAnswerKarma, userID))
+}
+
+// UpdateBio sets a user's profile bio
+func UpdateBio(db *sql.DB, userID, bio string) error {
+	_, err := db.Exec(`UPDATE users SET bio = ? WHERE id = ?`, bio, userID)
+	return err
+}
+
+// GetPostsByUser returns a page of a user's posts, newest first
+func GetPostsByUser(db *sql.DB, userID string, page, limit int) ([]models.Post, error) {
+	offset := (page - 1) * limit
+
+	return queryPosts(db, `
+		SELECT `+postColumns+`
+		FROM posts
+		JOIN users ON posts.user_id = users.id
+		WHERE posts.user_id = ? AND posts.status = 'published'
+		ORDER BY posts.created_at DESC, posts.id DESC
+		LIMIT ? OFFSET ?
+	`, userID, limit, offset)
+}
+
+// GetCommentsByUser returns a page of a user's comments and replies, newest
+// first, each with the post it belongs to
+func GetCommentsByUser(db *sql.DB, userID string, page, limit int) ([]models.UserComment, error) {
+	offset := (page - 1) * limit
+
+	rows, err := db.Query(`
+		SELECT 'comment', c.id, c.post_id, p.title, NULL, c.content, c.created_at
+		FROM comments c
+		JOIN posts p ON p.id = c.post_id
+		WHERE c.user_id = ?
+		UNION ALL
+		SELECT 'reply', r.id, c.post_id, p.title, r.parent_comment_id, r.content, r.created_at
+		FROM replycomments r
+		JOIN comments c ON c.id = r.parent_comment_id
+		JOIN posts p ON p.id = c.post_id
+		WHERE r.user_id = ?
+		ORDER BY 7 DESC, 2 DESC
+		LIMIT ? OFFSET ?
+	`, userID, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := []models.UserComment{}
+	commentContents := make(map[int]string)
+	replyContents := make(map[int]string)
+	for rows.Next() {
+		var c models.UserComment
+		var parentID sql.NullInt64
+		if err := rows.Scan(&c.Kind, &c.ID, &c.PostID, &c.PostTitle, &parentID, &c.Content, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		c.ParentCommentID = intPtr(parentID)
+		if c.Kind == "reply" {
+			replyContents[c.ID] = c.Content
+		} else {
+			commentContents[c.ID] = c.Content
+		}
+		comments = append(comments, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	renderedComments := renderContent(db, "comment_id", commentContents)
+	renderedReplies := renderContent(db, "reply_id", replyContents)
+	for i := range comments {
+		if comments[i].Kind == "reply" {
+			comments[i].ContentHTML = renderedReplies[comments[i].ID]
+		} else {
+			comments[i].ContentHTML = renderedComments[comments[i].ID]
+		}
+	}
+	return comments, nil
+}
@@ -0,0 +1,101 @@
+package sqlite
+
+import (
+	"testing"
+)
+
+func TestProfiles(t *testing.T) {
+	db := setupSchemaTestDB(t)
+	alice := createTestUser(t, db, "alice")
+	bob := createTestUser(t, db, "bob")
+
+	post, err := CreatePost(db, alice, nil, "First", "Hello", "")
+	if err != nil {
+		t.Fatalf("CreatePost failed: %v", err)
+	}
+	if _, err := CreatePost(db, alice, nil, "Second", "Again", ""); err != nil {
+		t.Fatalf("CreatePost failed: %v", err)
+	}
+	comment, err := CreateComment(db, alice, post.ID, "my comment")
+	if err != nil {
+		t.Fatalf("CreateComment failed: %v", err)
+	}
+	bobComment, err := CreateComment(db, bob, post.ID, "bob's comment")
+	if err != nil {
+		t.Fatalf("CreateComment failed: %v", err)
+	}
+	if _, err := CreateReplyComment(db, alice, bobComment.ID, "my **reply**"); err != nil {
+		t.Fatalf("CreateReplyComment failed: %v", err)
+	}
+	// Everything above is created within the same second; age the comment so
+	// the reply sorts first
+	if _, err := db.Exec(`UPDATE comments SET created_at = datetime('now', '-1 hour') WHERE id = ?`, comment.ID); err != nil {
+		t.Fatalf("Failed to age comment: %v", err)
+	}
+
+	// Karma: bob likes the post and dislikes the comment; alice's like of her
+	// own post does not count
+	for _, reaction := range []struct {
+		user      string
+		postID    *int
+		commentID *int
+		kind      string
+	}{
+		{bob, &post.ID, nil, "like"},
+		{bob, nil, &comment.ID, "dislike"},
+		{alice, &post.ID, nil, "like"},
+	} {
+		if _, err := ToggleLike(db, reaction.user, reaction.postID, reaction.commentID, reaction.kind); err != nil {
+			t.Fatalf("ToggleLike failed: %v", err)
+		}
+	}
+
+	t.Run("profile counts and karma", func(t *testing.T) {
+		if err := UpdateBio(db, alice, "Gopher"); err != nil {
+			t.Fatalf("UpdateBio failed: %v", err)
+		}
+		profile, err := GetProfileByUsername(db, "alice")
+		if err != nil {
+			t.Fatalf("GetProfileByUsername failed: %v", err)
+		}
+		if profile.ID != alice || profile.Bio != "Gopher" || profile.PostCount != 2 || profile.CommentCount != 2 || profile.Karma != 0 {
+			t.Errorf("Unexpected profile: %+v", profile)
+		}
+		if profile.JoinedAt.IsZero() {
+			t.Error("Expected a join date")
+		}
+
+		byID, err := GetProfileByID(db, bob)
+		if err != nil || byID.Username != "bob" || byID.CommentCount != 1 {
+			t.Errorf("Unexpected profile: %+v (%v)", byID, err)
+		}
+	})
+
+	t.Run("posts by user", func(t *testing.T) {
+		posts, err := GetPostsByUser(db, alice, 1, 1)
+		if err != nil {
+			t.Fatalf("GetPostsByUser failed: %v", err)
+		}
+		if len(posts) != 1 || posts[0].Title != "Second" {
+			t.Errorf("Expected the newest post first, got %+v", posts)
+		}
+	})
+
+	t.Run("comments and replies by user", func(t *testing.T) {
+		comments, err := GetCommentsByUser(db, alice, 1, 10)
+		if err != nil {
+			t.Fatalf("GetCommentsByUser failed: %v", err)
+		}
+		if len(comments) != 2 {
+			t.Fatalf("Expected 2 items, got %+v", comments)
+		}
+		reply := comments[0]
+		if reply.Kind != "reply" || reply.ParentCommentID == nil || *reply.ParentCommentID != bobComment.ID ||
+			reply.PostTitle != "First" || reply.ContentHTML != "<p>my <strong>reply</strong></p>" {
+			t.Errorf("Unexpected reply: %+v", reply)
+		}
+		if comments[1].Kind != "comment" || comments[1].ID != comment.ID || comments[1].CreatedAt.IsZero() {
+			t.Errorf("Unexpected comment: %+v", comments[1])
+		}
+	})
+}
@@ -2,8 +2,8 @@ package sqlite
 
 import (
 	"database/sql"
-	"errors"
 	"fmt"
+	"log"
 	"strings"
 	"time"
 
@@ -53,60 +53,142 @@ func CreateUser(db *sql.DB, username, email, passwordHash, avatarURL string) err
 	return err
 }
 
-// CreatePost inserts a new post and its category associations
+// CreatePost inserts a new published post and its category associations
 func CreatePost(db *sql.DB, userID string, categoryIDs []int, title, content, imageURL string) (models.Post, error) {
-	var post models.Post
+	return CreatePostWithDetails(db, userID, title, content, models.PostDetails{CategoryIDs: categoryIDs, ImageURL: imageURL})
+}
+
+// CreateDraft inserts a post only its author can see: a draft, or a post
+// scheduled to be published at publishAt when it is not nil
+func CreateDraft(db *sql.DB, userID string, categoryIDs []int, title, content, imageURL string, publishAt *time.Time) (models.Post, error) {
+	return CreatePostWithDetails(db, userID, title, content, models.PostDetails{
+		CategoryIDs: categoryIDs, ImageURL: imageURL, Status: PostDraft, PublishAt: publishAt,
+	})
+}
+
+// CreatePostWithDetails inserts a post with its categories, tags, type and
+// poll in one transaction, so a failure leaves no half-made post behind.
+// Posts with a Status of "draft" or "scheduled" are drafts, scheduled when
+// PublishAt is set; any other post is published. Mentions are only recorded
+// for published posts; drafts get theirs when they are published.
+func CreatePostWithDetails(db *sql.DB, userID, title, content string, details models.PostDetails) (models.Post, error) {
+	status := PostPublished
+	var at any
+	if details.Status == PostDraft || details.Status == PostScheduled {
+		status = PostDraft
+		if details.PublishAt != nil {
+			status = PostScheduled
+			at = sqliteTime(*details.PublishAt)
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return models.Post{}, err
+	}
+	defer tx.Rollback()
 
 	// Insert into posts table
-	query := `
-		INSERT INTO posts (user_id, title, content, image_url)
-		VALUES (?, ?, ?, ?)
-		RETURNING id, user_id, title, content, image_url, created_at
-	`
-	err := db.QueryRow(query, userID, title, content, imageURL).Scan(
+	var post models.Post
+	var storedAt sql.NullTime
+	err = tx.QueryRow(`
+		INSERT INTO posts (user_id, title, content, image_url, post_type, status, publish_at)
+		VALUES (?, ?, ?, ?, COALESCE(NULLIF(?, ''), 'discussion'), ?, ?)
+		RETURNING id, user_id, title, content, image_url, post_type, status, publish_at, created_at
+	`, userID, title, content, details.ImageURL, details.PostType, status, at).Scan(
 		&post.ID,
 		&post.UserID,
 		&post.Title,
 		&post.Content,
 		&post.ImageURL,
+		&post.PostType,
+		&post.Status,
+		&storedAt,
 		&post.CreatedAt,
 	)
 	if err != nil {
 		return post, err
 	}
+	post.PublishAt = timePtr(storedAt)
 
 	// Insert into post_categories table
-	for _, catID := range categoryIDs {
-		_, err := db.Exec(`INSERT INTO post_categories (post_id, category_id) VALUES (?, ?)`, post.ID, catID)
+	for _, catID := range details.CategoryIDs {
+		_, err := tx.Exec(`INSERT INTO post_categories (post_id, category_id) VALUES (?, ?)`, post.ID, catID)
 		if err != nil {
 			return post, fmt.Errorf("failed to insert into post_categories: %w", err)
 		}
 	}
+	if len(details.Tags) > 0 {
+		if err := setPostTags(tx, post.ID, details.Tags); err != nil {
+			return post, err
+		}
+	}
+	var pollID int
+	if details.Poll != nil {
+		if pollID, err = createPoll(tx, post.ID, *details.Poll); err != nil {
+			return post, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return post, err
+	}
 
-	post.CategoryIDs = categoryIDs
+	// Store @mentions and notify the mentioned users
+	if status == PostPublished && strings.Contains(post.Content, "@") {
+		if err := syncMentions(db, userID, contentRef{PostID: post.ID}, post.Content); err != nil {
+			log.Printf("Warning: Failed to store mentions for post %d: %v", post.ID, err)
+		}
+	}
+	post.ContentHTML = renderOne(db, "post_id", post.ID, post.Content)
+
+	post.CategoryIDs = details.CategoryIDs
+	if details.Tags != nil {
+		post.Tags = details.Tags
+	}
+	if details.Poll != nil {
+		poll, err := GetPoll(db, pollID, "")
+		if err != nil {
+			return post, err
+		}
+		post.Poll = &poll
+	}
 	return post, nil
 }
 
 // GetPost retrieves a single post by ID with its category IDs
 func GetPost(db *sql.DB, postID int) (models.Post, error) {
 	var post models.Post
+	var acceptedID sql.NullInt64
+	var publishAt sql.NullTime
 
 	// Fetch main post data
 	err := db.QueryRow(`
-        SELECT id, user_id, title, content, image_url, created_at, updated_at
-        FROM posts WHERE id = ?
+        SELECT posts.id, posts.user_id, users.username, title, content, image_url, locked, post_type, accepted_comment_id, like_count, dislike_count, view_count, posts.status, posts.publish_at, posts.created_at, posts.updated_at
+        FROM posts JOIN users ON users.id = posts.user_id WHERE posts.id = ?
     `, postID).Scan(
 		&post.ID,
 		&post.UserID,
+		&post.Username,
 		&post.Title,
 		&post.Content,
 		&post.ImageURL,
+		&post.Locked,
+		&post.PostType,
+		&acceptedID,
+		&post.LikeCount,
+		&post.DislikeCount,
+		&post.ViewCount,
+		&post.Status,
+		&publishAt,
 		&post.CreatedAt,
 		&post.UpdatedAt,
 	)
 	if err != nil {
 		return post, err
 	}
+	post.AcceptedCommentID = intPtr(acceptedID)
+	post.PublishAt = timePtr(publishAt)
+	post.ContentHTML = renderOne(db, "post_id", post.ID, post.Content)
 
 	// Fetch category IDs from join table
 	rows, err := db.Query(`SELECT category_id FROM post_categories WHERE post_id = ?`, postID)
@@ -132,28 +214,92 @@ func GetPost(db *sql.DB, postID int) (models.Post, error) {
 	}
 	post.CategoryNames = categoryNames
 
+	postTags, err := getPostTags(db, []any{post.ID})
+	if err != nil {
+		log.Printf("Warning: Failed to get tags for post %d: %v", post.ID, err)
+	}
+	post.Tags = postTags[post.ID]
+	if post.Tags == nil {
+		post.Tags = []string{}
+	}
+
 	return post, nil
 }
 
-func GetPosts(db *sql.DB, page, limit int) ([]models.Post, error) {
+// PostFilter narrows the posts listed by GetPosts
+type PostFilter struct {
+	Tags     []string // posts having every one of these normalized tags
+	PostType string   // "discussion" or "question"; empty for both
+	Answered *bool    // questions with or without an accepted answer
+	Category int      // posts in this category; 0 for all
+	AuthorID string   // posts by this user; empty for all
+}
+
+// GetPosts returns a page of posts, newest first, leaving out authors the
+// viewer muted or blocked (viewerID is empty for signed-out visitors)
+func GetPosts(db *sql.DB, viewerID string, filter PostFilter, page, limit int) ([]models.Post, error) {
 	offset := (page - 1) * limit
 
-	// Query basic post data
-	rows, err := db.Query(`
-		SELECT 
-			posts.id, 
-			posts.user_id, 
-			users.username, 
-			posts.title, 
-			posts.content, 
-			posts.image_url,
-			posts.created_at, 
-			posts.updated_at
+	where := `posts.status = 'published' AND posts.user_id NOT IN (` + hiddenAuthors + `)`
+	args := []any{viewerID, viewerID}
+	if len(filter.Tags) > 0 {
+		condition, tagArgs, err := taggedWith(db, filter.Tags)
+		if err != nil {
+			return nil, err
+		}
+		where += ` AND ` + condition
+		args = append(args, tagArgs...)
+	}
+	if filter.PostType != "" {
+		where += ` AND posts.post_type = ?`
+		args = append(args, filter.PostType)
+	}
+	if filter.Answered != nil {
+		where += ` AND posts.post_type = 'question' AND (posts.accepted_comment_id IS NOT NULL) = ?`
+		args = append(args, *filter.Answered)
+	}
+	if filter.Category != 0 {
+		where += ` AND posts.id IN (SELECT post_id FROM post_categories WHERE category_id = ?)`
+		args = append(args, filter.Category)
+	}
+	if filter.AuthorID != "" {
+		where += ` AND posts.user_id = ?`
+		args = append(args, filter.AuthorID)
+	}
+
+	return queryPosts(db, `
+		SELECT `+postColumns+`
 		FROM posts
 		JOIN users ON posts.user_id = users.id
-		ORDER BY posts.created_at DESC
+		WHERE `+where+`
+		ORDER BY posts.created_at DESC, posts.id DESC
 		LIMIT ? OFFSET ?
-	`, limit, offset)
+	`, append(args, limit, offset)...)
+}
+
+// postColumns is the select list queryPosts expects, in order
+const postColumns = `
+	posts.id,
+	posts.user_id,
+	users.username,
+	posts.title,
+	posts.content,
+	posts.image_url,
+	posts.locked,
+	posts.post_type,
+	posts.accepted_comment_id,
+	posts.like_count,
+	posts.dislike_count,
+	posts.view_count,
+	posts.status,
+	posts.publish_at,
+	posts.created_at,
+	posts.updated_at`
+
+// queryPosts runs a query selecting postColumns and returns the posts in
+// query order with rendered content and their categories
+func queryPosts(db *sql.DB, query string, args ...any) ([]models.Post, error) {
+	rows, err := db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -164,6 +310,8 @@ func GetPosts(db *sql.DB, page, limit int) ([]models.Post, error) {
 
 	for rows.Next() {
 		var post models.Post
+		var acceptedID sql.NullInt64
+		var publishAt sql.NullTime
 		err := rows.Scan(
 			&post.ID,
 			&post.UserID,
@@ -171,21 +319,34 @@ func GetPosts(db *sql.DB, page, limit int) ([]models.Post, error) {
 			&post.Title,
 			&post.Content,
 			&post.ImageURL,
+			&post.Locked,
+			&post.PostType,
+			&acceptedID,
+			&post.LikeCount,
+			&post.DislikeCount,
+			&post.ViewCount,
+			&post.Status,
+			&publishAt,
 			&post.CreatedAt,
 			&post.UpdatedAt,
 		)
 		if err != nil {
-			fmt.Println(err)
 			return nil, err
 		}
+		post.AcceptedCommentID = intPtr(acceptedID)
+		post.PublishAt = timePtr(publishAt)
 		post.CategoryIDs = []int{}
 		postMap[post.ID] = &post
 		postIDs = append(postIDs, post.ID)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
 	if len(postIDs) == 0 {
 		return []models.Post{}, nil
 	}
+	renderPostContent(db, postMap)
 
 	// Build query for categories
 	placeholders := make([]string, len(postIDs))
@@ -193,13 +354,11 @@ func GetPosts(db *sql.DB, page, limit int) ([]models.Post, error) {
 		placeholders[i] = "?"
 	}
 
-	query := fmt.Sprintf(`
+	catRows, err := db.Query(fmt.Sprintf(`
 		SELECT post_id, category_id
 		FROM post_categories
 		WHERE post_id IN (%s)
-	`, strings.Join(placeholders, ","))
-
-	catRows, err := db.Query(query, postIDs...)
+	`, strings.Join(placeholders, ",")), postIDs...)
 	if err != nil {
 		return nil, err
 	}
@@ -215,137 +374,158 @@ func GetPosts(db *sql.DB, page, limit int) ([]models.Post, error) {
 		}
 	}
 
-	// Build final slice from postMap in the original order and fetch category names
-	posts := make([]models.Post, 0, len(postMap))
+	postTags, err := getPostTags(db, postIDs)
+	if err != nil {
+		// Log error but don't fail the entire request
+		log.Printf("Warning: Failed to get tags: %v", err)
+	}
 
-	// Iterate through postIDs to maintain the original order from the SQL query
+	// Build final slice in the original order and fetch category names
+	posts := make([]models.Post, 0, len(postMap))
 	for _, postIDInterface := range postIDs {
-		postID := postIDInterface.(int)
-		if post, ok := postMap[postID]; ok {
-			// Get category names for this post
-			categoryNames, err := GetCategoryNamesByIDs(db, post.CategoryIDs)
-			if err != nil {
-				// Log error but don't fail the entire request
-				fmt.Printf("Warning: Failed to get category names for post %d: %v\n", post.ID, err)
-				categoryNames = []string{}
-			}
-			post.CategoryNames = categoryNames
-			posts = append(posts, *post)
+		post := postMap[postIDInterface.(int)]
+		categoryNames, err := GetCategoryNamesByIDs(db, post.CategoryIDs)
+		if err != nil {
+			// Log error but don't fail the entire request
+			fmt.Printf("Warning: Failed to get category names for post %d: %v\n", post.ID, err)
+			categoryNames = []string{}
+		}
+		post.CategoryNames = categoryNames
+		post.Tags = postTags[post.ID]
+		if post.Tags == nil {
+			post.Tags = []string{}
 		}
+		posts = append(posts, *post)
 	}
 
 	return posts, nil
 }
 
-// DeletePost removes a post by ID
+// DeletePost removes a post by ID, with the notifications and mentions of it
+// and its comments and replies
 func DeletePost(db *sql.DB, postID int) error {
-	_, err := db.Exec(`DELETE FROM posts WHERE id = ?`, postID)
-	return err
-}
-
-// GetOrCreateCategoryIDs resolves category names to IDs, creating new ones if needed.
-func GetOrCreateCategoryIDs(db *sql.DB, names []string) ([]int, error) {
-	var ids []int
-
-	for _, name := range names {
-		var id int
-		err := db.QueryRow(`SELECT id FROM categories WHERE name = ?`, name).Scan(&id)
-		if err != nil {
-			if err == sql.ErrNoRows {
-				// Create new category
-				err = db.QueryRow(`INSERT INTO categories (name) VALUES (?) RETURNING id`, name).Scan(&id)
-				if err != nil {
-					return nil, fmt.Errorf("could not create category %q: %w", name, err)
-				}
-			} else {
-				return nil, fmt.Errorf("failed to fetch category %q: %w", name, err)
-			}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	statements := []string{
+		// Cleared by hand: foreign keys are not enforced on every pooled
+		// connection, so ON DELETE CASCADE cannot be relied on
+		`DELETE FROM notifications WHERE post_id = ?1
+			OR comment_id IN (SELECT id FROM comments WHERE post_id = ?1)
+			OR reply_id IN (SELECT r.id FROM replycomments r JOIN comments c ON c.id = r.parent_comment_id WHERE c.post_id = ?1)`,
+		`DELETE FROM mentions WHERE post_id = ?1
+			OR comment_id IN (SELECT id FROM comments WHERE post_id = ?1)
+			OR reply_id IN (SELECT r.id FROM replycomments r JOIN comments c ON c.id = r.parent_comment_id WHERE c.post_id = ?1)`,
+		`DELETE FROM posts WHERE id = ?1`,
+	}
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt, postID); err != nil {
+			return err
 		}
-		ids = append(ids, id)
 	}
-	return ids, nil
+	return tx.Commit()
 }
 
-// ToggleLike toggles a like for a post or comment
-func ToggleLike(db *sql.DB, userID string, postID *int, commentID *int, reactionType string) error {
-	if reactionType != "like" && reactionType != "dislike" {
-		return errors.New("invalid reaction type")
+// ToggleLike adds a reaction of the given type to a post or comment, or
+// removes it if the user already left one. A user may leave several reaction
+// types on the same target, but like and dislike replace each other. Returns
+// ErrUnknownReaction if the type is not an enabled reaction type. Reports
+// whether the user's reaction is there after the toggle.
+//
+// The toggle is one transaction that starts by writing, so it takes the
+// write lock up front: concurrent toggles by the same user queue up instead
+// of reading the same state and racing each other.
+func ToggleLike(db *sql.DB, userID string, postID *int, commentID *int, reactionType string) (bool, error) {
+	column, targetID, err := reactionTarget(postID, commentID)
+	if err != nil {
+		return false, err
 	}
-	if (postID == nil && commentID == nil) || (postID != nil && commentID != nil) {
-		return errors.New("must provide either postID or commentID, but not both")
+
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
 	}
+	defer tx.Rollback()
 
-	var existingType string
-	var query string
-	var args []any
+	// Add the reaction unless it is already there, or not an enabled type
+	result, err := tx.Exec(`
+		INSERT INTO likes (user_id, `+column+`, type)
+		SELECT ?, ?, name FROM reaction_types WHERE name = ? AND enabled
+		ON CONFLICT DO NOTHING
+	`, userID, targetID, reactionType)
+	if err != nil {
+		return false, err
+	}
+	added, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
 
-	if postID != nil {
-		query = `SELECT type FROM likes WHERE user_id = ? AND post_id = ?`
-		args = []any{userID, *postID}
+	if added > 0 {
+		if opposite, ok := opposingReactions[reactionType]; ok {
+			if _, err := tx.Exec(`DELETE FROM likes WHERE user_id = ? AND `+column+` = ? AND type = ?`, userID, targetID, opposite); err != nil {
+				return false, err
+			}
+		}
 	} else {
-		query = `SELECT type FROM likes WHERE user_id = ? AND comment_id = ?`
-		args = []any{userID, *commentID}
+		// Already there: toggle it off
+		result, err := tx.Exec(`
+			DELETE FROM likes
+			WHERE user_id = ? AND `+column+` = ? AND type = ?
+				AND type IN (SELECT name FROM reaction_types WHERE enabled)
+		`, userID, targetID, reactionType)
+		if err != nil {
+			return false, err
+		}
+		if removed, err := result.RowsAffected(); err != nil {
+			return false, err
+		} else if removed == 0 {
+			return false, fmt.Errorf("%w %q", ErrUnknownReaction, reactionType)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
 	}
 
-	err := db.QueryRow(query, args...).Scan(&existingType)
-
-	switch {
-	case err == sql.ErrNoRows:
-		// No existing reaction — insert
+	// Let the author know someone reacted; removing a reaction is silent
+	if added > 0 {
+		ref := contentRef{}
 		if postID != nil {
-			_, err = db.Exec(`INSERT INTO likes (user_id, post_id, type) VALUES (?, ?, ?)`, userID, *postID, reactionType)
+			ref.PostID = *postID
 		} else {
-			_, err = db.Exec(`INSERT INTO likes (user_id, comment_id, type) VALUES (?, ?, ?)`, userID, *commentID, reactionType)
+			ref.CommentID = *commentID
+			ref.PostID, _ = GetCommentPostID(db, *commentID)
 		}
-	case err == nil && existingType == reactionType:
-		// Same reaction exists — toggle off (delete)
-		if postID != nil {
-			_, err = db.Exec(`DELETE FROM likes WHERE user_id = ? AND post_id = ?`, userID, *postID)
-		} else {
-			_, err = db.Exec(`DELETE FROM likes WHERE user_id = ? AND comment_id = ?`, userID, *commentID)
-		}
-	case err == nil:
-		// Different reaction — update
-		if postID != nil {
-			_, err = db.Exec(`UPDATE likes SET type = ? WHERE user_id = ? AND post_id = ?`, reactionType, userID, *postID)
-		} else {
-			_, err = db.Exec(`UPDATE likes SET type = ? WHERE user_id = ? AND comment_id = ?`, reactionType, userID, *commentID)
+		if err := notifyOwner(db, userID, NotificationReaction, ref); err != nil {
+			log.Printf("Warning: Failed to notify about reaction: %v", err)
 		}
-	default:
-		return err
 	}
 
-	return err
+	return added > 0, nil
 }
 
-func CountLikesAndDislikes(db *sql.DB, postID *int, commentID *int) (likes int, dislikes int, err error) {
-	if (postID == nil && commentID == nil) || (postID != nil && commentID != nil) {
-		return 0, 0, errors.New("must provide either postID or commentID, but not both")
-	}
+// GetCommentPostID returns the ID of the post a comment belongs to
+func GetCommentPostID(db *sql.DB, commentID int) (int, error) {
+	var postID int
+	err := db.QueryRow(`SELECT post_id FROM comments WHERE id = ?`, commentID).Scan(&postID)
+	return postID, err
+}
 
-	var rows *sql.Rows
-	if postID != nil {
-		rows, err = db.Query(`SELECT type, COUNT(*) FROM likes WHERE post_id = ? GROUP BY type`, *postID)
-	} else {
-		rows, err = db.Query(`SELECT type, COUNT(*) FROM likes WHERE comment_id = ? GROUP BY type`, *commentID)
-	}
+// CountLikesAndDislikes returns the like and dislike counters of a post or
+// comment. Returns sql.ErrNoRows if it does not exist.
+func CountLikesAndDislikes(db *sql.DB, postID *int, commentID *int) (likes int, dislikes int, err error) {
+	column, id, err := reactionTarget(postID, commentID)
 	if err != nil {
-		return
+		return 0, 0, err
 	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var typ string
-		var count int
-		if err = rows.Scan(&typ, &count); err != nil {
-			return
-		}
-		if typ == "like" {
-			likes = count
-		} else if typ == "dislike" {
-			dislikes = count
-		}
+	table := "posts"
+	if column == "comment_id" {
+		table = "comments"
 	}
+	err = db.QueryRow(`SELECT like_count, dislike_count FROM `+table+` WHERE id = ?`, id).Scan(&likes, &dislikes)
 	return
 }
 
@@ -353,107 +533,15 @@ func CountLikesAndDislikes(db *sql.DB, postID *int, commentID *int) (likes int,
 func GetPostsLikedByUser(db *sql.DB, userID string, page, limit int) ([]models.Post, error) {
 	offset := (page - 1) * limit
 
-	// Query posts that the user has liked
-	rows, err := db.Query(`
-		SELECT
-			posts.id,
-			posts.user_id,
-			users.username,
-			posts.title,
-			posts.content,
-			posts.image_url,
-			posts.created_at,
-			posts.updated_at
+	return queryPosts(db, `
+		SELECT `+postColumns+`
 		FROM posts
 		JOIN users ON posts.user_id = users.id
 		JOIN likes ON posts.id = likes.post_id
-		WHERE likes.user_id = ? AND likes.type = 'like'
+		WHERE likes.user_id = ? AND likes.type = 'like' AND posts.status = 'published'
 		ORDER BY likes.created_at DESC
 		LIMIT ? OFFSET ?
 	`, userID, limit, offset)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	postMap := make(map[int]*models.Post)
-	var postIDs []any
-
-	for rows.Next() {
-		var post models.Post
-		err := rows.Scan(
-			&post.ID,
-			&post.UserID,
-			&post.Username,
-			&post.Title,
-			&post.Content,
-			&post.ImageURL,
-			&post.CreatedAt,
-			&post.UpdatedAt,
-		)
-		if err != nil {
-			return nil, err
-		}
-		post.CategoryIDs = []int{}
-		postMap[post.ID] = &post
-		postIDs = append(postIDs, post.ID)
-	}
-
-	if len(postIDs) == 0 {
-		return []models.Post{}, nil
-	}
-
-	// Build query for categories
-	placeholders := make([]string, len(postIDs))
-	for i := range placeholders {
-		placeholders[i] = "?"
-	}
-
-	query := fmt.Sprintf(`
-		SELECT post_id, category_id
-		FROM post_categories
-		WHERE post_id IN (%s)
-	`, strings.Join(placeholders, ","))
-
-	categoryRows, err := db.Query(query, postIDs...)
-	if err != nil {
-		return nil, err
-	}
-	defer categoryRows.Close()
-
-	// Map categories to posts
-	for categoryRows.Next() {
-		var postID, categoryID int
-		if err := categoryRows.Scan(&postID, &categoryID); err != nil {
-			return nil, err
-		}
-		if post, exists := postMap[postID]; exists {
-			post.CategoryIDs = append(post.CategoryIDs, categoryID)
-		}
-	}
-
-	// Get category names for all posts
-	for _, post := range postMap {
-		if len(post.CategoryIDs) > 0 {
-			categoryNames, err := GetCategoryNamesByIDs(db, post.CategoryIDs)
-			if err != nil {
-				// Log error but don't fail the entire request
-				fmt.Printf("Warning: Failed to get category names for post %d: %v\n", post.ID, err)
-				categoryNames = []string{}
-			}
-			post.CategoryNames = categoryNames
-		}
-	}
-
-	// Convert map to slice, maintaining order
-	var posts []models.Post
-	for _, postID := range postIDs {
-		if post, exists := postMap[postID.(int)]; exists {
-			posts = append(posts, *post)
-		}
-	}
-
-	return posts, nil
 }
 
 // CleanupSessions removes expired sessions
@@ -510,6 +598,19 @@ func CreateComment(db *sql.DB, userID string, postID int, content string) (model
 		return comment, fmt.Errorf("failed to create comment: %w", err)
 	}
 
+	if strings.Contains(comment.Content, "@") {
+		ref := contentRef{PostID: comment.PostID, CommentID: comment.ID}
+		if err := syncMentions(db, userID, ref, comment.Content); err != nil {
+			log.Printf("Warning: Failed to store mentions for comment %d: %v", comment.ID, err)
+		}
+	}
+	comment.ContentHTML = renderOne(db, "comment_id", comment.ID, comment.Content)
+
+	// Let the post author know about the new comment
+	if err := notifyOwner(db, userID, NotificationComment, contentRef{PostID: postID}); err != nil {
+		log.Printf("Warning: Failed to notify about comment %d: %v", comment.ID, err)
+	}
+
 	return comment, err
 }
 
@@ -530,22 +631,47 @@ func CreateReplyComment(db *sql.DB, userID string, parentCommentID int, content
 		&reply.CreatedAt,
 		&reply.UpdatedAt,
 	)
+	if err != nil {
+		return reply, err
+	}
 
-	return reply, err
+	ref := contentRef{CommentID: parentCommentID, ReplyID: reply.ID}
+	if ref.PostID, err = GetCommentPostID(db, parentCommentID); err != nil {
+		log.Printf("Warning: Failed to find post for reply %d: %v", reply.ID, err)
+	}
+	reply.PostID = ref.PostID
+
+	if strings.Contains(reply.Content, "@") {
+		if err := syncMentions(db, userID, ref, reply.Content); err != nil {
+			log.Printf("Warning: Failed to store mentions for reply %d: %v", reply.ID, err)
+		}
+	}
+	reply.ContentHTML = renderOne(db, "reply_id", reply.ID, reply.Content)
+
+	// Let the parent comment's author know about the reply; repeated replies
+	// to one comment collapse onto the comment
+	replyTarget := contentRef{PostID: ref.PostID, CommentID: parentCommentID}
+	if err := notifyOwner(db, userID, NotificationReply, replyTarget); err != nil {
+		log.Printf("Warning: Failed to notify about reply %d: %v", reply.ID, err)
+	}
+
+	return reply, nil
 }
 
-// GetPostComments retrieves comments for a specific post
-func GetPostComments(db *sql.DB, postID int) ([]models.Comment, error) {
+// GetPostComments returns the comments of a post with their replies, leaving
+// out those by users the viewer muted or blocked
+func GetPostComments(db *sql.DB, postID int, viewerID string) ([]models.Comment, error) {
 	// Step 1: Fetch top-level comments
 	commentRows, err := db.Query(`
 		SELECT
-			c.id, c.user_id, c.post_id, c.content,
-			c.created_at, c.updated_at, u.username, u.avatar_url
+			c.id, c.user_id, c.post_id, c.content, c.like_count, c.dislike_count,
+			c.created_at, c.updated_at, u.username, u.avatar_url,
+			c.id IS (SELECT accepted_comment_id FROM posts WHERE id = c.post_id) AS accepted
 		FROM comments c
 		JOIN users u ON u.id = c.user_id
-		WHERE c.post_id = ?
-		ORDER BY c.created_at ASC
-	`, postID)
+		WHERE c.post_id = ? AND c.user_id NOT IN (`+hiddenAuthors+`)
+		ORDER BY accepted DESC, c.created_at ASC
+	`, postID, viewerID, viewerID)
 	if err != nil {
 		return nil, err
 	}
@@ -561,10 +687,13 @@ func GetPostComments(db *sql.DB, postID int) ([]models.Comment, error) {
 			&c.UserID,
 			&c.PostID,
 			&c.Content,
+			&c.LikeCount,
+			&c.DislikeCount,
 			&c.CreatedAt,
 			&c.UpdatedAt,
 			&c.UserName,
 			&c.ProfileAvatar,
+			&c.IsAccepted,
 		)
 		if err != nil {
 			return nil, err
@@ -582,9 +711,9 @@ func GetPostComments(db *sql.DB, postID int) ([]models.Comment, error) {
 		JOIN users u ON u.id = r.user_id
 		WHERE r.parent_comment_id IN (
 			SELECT id FROM comments WHERE post_id = ?
-		)
+		) AND r.user_id NOT IN (`+hiddenAuthors+`)
 		ORDER BY r.created_at ASC
-	`, postID)
+	`, postID, viewerID, viewerID)
 	if err != nil {
 		return nil, err
 	}
@@ -611,35 +740,9 @@ func GetPostComments(db *sql.DB, postID int) ([]models.Comment, error) {
 		}
 	}
 
-	return comments, nil
-}
+	renderCommentContent(db, comments)
 
-// CreateCategory inserts a new category
-func CreateCategory(db *sql.DB, name string) error {
-	_, err := db.Exec(`
-		INSERT INTO categories (name)
-		VALUES (?)
-	`, name)
-	return err
-}
-
-// GetCategories retrieves all categories
-func GetCategories(db *sql.DB) ([]models.Category, error) {
-	rows, err := db.Query(`SELECT id, name FROM categories`)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var categories []models.Category
-	for rows.Next() {
-		var category models.Category
-		if err := rows.Scan(&category.ID, &category.Name); err != nil {
-			return nil, err
-		}
-		categories = append(categories, category)
-	}
-	return categories, nil
+	return comments, nil
 }
 
 // GetCategoryNamesByIDs retrieves category names for given category IDs
@@ -677,20 +780,51 @@ func GetCategoryNamesByIDs(db *sql.DB, categoryIDs []int) ([]string, error) {
 
 // UpdatePost updates an existing post's title and content
 func UpdatePost(db *sql.DB, postID int, title, content string) error {
-	_, err := db.Exec(`
+	var authorID, status string
+	err := db.QueryRow(`
 		UPDATE posts 
 		SET title = ?, content = ?
 		WHERE id = ?
-	`, title, content, postID)
-	return err
+		RETURNING user_id, status
+	`, title, content, postID).Scan(&authorID, &status)
+	if err != nil {
+		return err
+	}
+
+	// Re-sync mentions so edited-out users lose theirs and new ones are
+	// notified; drafts get theirs when published
+	if status != PostPublished {
+		return nil
+	}
+	if err := syncMentions(db, authorID, contentRef{PostID: postID}, content); err != nil {
+		log.Printf("Warning: Failed to update mentions for post %d: %v", postID, err)
+	}
+	return nil
 }
 
-// DeleteComment removes a comment from the database by its ID
+// DeleteComment removes a comment from the database by its ID, with the
+// notifications and mentions of it and its replies
 func DeleteComment(db *sql.DB, commentID int) error {
-	_, err := db.Exec(`
-		DELETE FROM comments WHERE id = ?
-	`, commentID)
-	return err
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	statements := []string{
+		// Cleared by hand, as in DeletePost
+		`DELETE FROM notifications WHERE comment_id = ?1
+			OR reply_id IN (SELECT id FROM replycomments WHERE parent_comment_id = ?1)`,
+		`DELETE FROM mentions WHERE comment_id = ?1
+			OR reply_id IN (SELECT id FROM replycomments WHERE parent_comment_id = ?1)`,
+		`DELETE FROM comments WHERE id = ?1`,
+	}
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt, commentID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
 }
 
 // GetUserByEmail retrieves a user by email
@@ -718,12 +852,16 @@ func GetUserByEmail(db *sql.DB, email string) (models.User, error) {
 // CreateSession creates a new session for a user and returns the session ID
 func CreateSession(db *sql.DB, userID string) (string, error) {
 	sessionID := uuid.New().String()
+	now := time.Now()
 	_, err := db.Exec(`
 		INSERT INTO sessions (id, user_id, created_at) VALUES (?, ?, ?)
-	`, sessionID, userID, time.Now())
+	`, sessionID, userID, now)
 	if err != nil {
 		return "", err
 	}
+	if err := recordActivity(db, userID, now); err != nil {
+		log.Printf("Warning: Failed to record activity of %s: %v", userID, err)
+	}
 	return sessionID, nil
 }
 
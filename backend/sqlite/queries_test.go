@@ -36,7 +36,12 @@ func setupTestDB(t *testing.T) *sql.DB {
 	CREATE TABLE categories (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		name TEXT UNIQUE NOT NULL,
-		description TEXT,
+		slug TEXT UNIQUE,
+		description TEXT NOT NULL DEFAULT '',
+		color TEXT NOT NULL DEFAULT '',
+		parent_id INTEGER REFERENCES categories(id) ON DELETE SET NULL,
+		sort_order INTEGER NOT NULL DEFAULT 0,
+		archived INTEGER NOT NULL DEFAULT 0,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
 	);
 
@@ -46,6 +51,14 @@ func setupTestDB(t *testing.T) *sql.DB {
 		title TEXT NOT NULL,
 		content TEXT NOT NULL,
 		image_url TEXT,
+		locked INTEGER NOT NULL DEFAULT 0,
+		post_type TEXT NOT NULL DEFAULT 'discussion',
+		accepted_comment_id INTEGER,
+		like_count INTEGER NOT NULL DEFAULT 0,
+		dislike_count INTEGER NOT NULL DEFAULT 0,
+		view_count INTEGER NOT NULL DEFAULT 0,
+		status TEXT NOT NULL DEFAULT 'published',
+		publish_at DATETIME,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		FOREIGN KEY (user_id) REFERENCES users(id)
@@ -64,12 +77,31 @@ func setupTestDB(t *testing.T) *sql.DB {
 		user_id TEXT NOT NULL,
 		post_id INTEGER NOT NULL,
 		content TEXT NOT NULL,
+		like_count INTEGER NOT NULL DEFAULT 0,
+		dislike_count INTEGER NOT NULL DEFAULT 0,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		FOREIGN KEY (user_id) REFERENCES users(id),
 		FOREIGN KEY (post_id) REFERENCES posts(id)
 	);
 
+	CREATE TABLE replycomments (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id TEXT NOT NULL,
+		parent_comment_id INTEGER NOT NULL,
+		content TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (user_id) REFERENCES users(id),
+		FOREIGN KEY (parent_comment_id) REFERENCES comments(id)
+	);
+
+	CREATE TABLE category_follows (
+		user_id TEXT NOT NULL,
+		category_id INTEGER NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (user_id, category_id)
+	);
+
 	CREATE TABLE sessions (
 		id TEXT PRIMARY KEY,
 		user_id TEXT NOT NULL,
@@ -477,7 +509,7 @@ func TestGetCategories(t *testing.T) {
 	}
 
 	t.Run("get all categories", func(t *testing.T) {
-		result, err := GetCategories(db)
+		result, err := GetCategories(db, false)
 		if err != nil {
 			t.Fatalf("GetCategories failed: %v", err)
 		}
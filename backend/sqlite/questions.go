@@ -0,0 +1,53 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// AcceptedAnswerKarma is the karma an answer earns its author when accepted
+const AcceptedAnswerKarma = 5
+
+var (
+	// ErrNotAQuestion is returned when accepting an answer on a discussion
+	ErrNotAQuestion = errors.New("only questions have accepted answers")
+	// ErrNotAnAnswer is returned when accepting a comment from another post
+	ErrNotAnAnswer = errors.New("the comment does not belong to this question")
+)
+
+// SetPostType changes a post to a "discussion" or a "question"
+func SetPostType(db *sql.DB, postID int, postType string) error {
+	_, err := db.Exec(`UPDATE posts SET post_type = ? WHERE id = ?`, postType, postID)
+	return err
+}
+
+// AcceptAnswer marks a top-level comment as the accepted answer of a
+// question, replacing any earlier one. A commentID of 0 clears it. Returns
+// sql.ErrNoRows if the post does not exist.
+func AcceptAnswer(db *sql.DB, postID, commentID int) error {
+	var postType string
+	if err := db.QueryRow(`SELECT post_type FROM posts WHERE id = ?`, postID).Scan(&postType); err != nil {
+		return err
+	}
+	if postType != "question" {
+		return ErrNotAQuestion
+	}
+
+	if commentID == 0 {
+		_, err := db.Exec(`UPDATE posts SET accepted_comment_id = NULL WHERE id = ?`, postID)
+		return err
+	}
+	result, err := db.Exec(`
+		UPDATE posts SET accepted_comment_id = ?
+		WHERE id = ? AND EXISTS (SELECT 1 FROM comments WHERE id = ? AND post_id = posts.id)
+	`, commentID, postID, commentID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNotAnAnswer
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+)
+
+func TestQuestions(t *testing.T) {
+	db := setupSchemaTestDB(t)
+	alice := createTestUser(t, db, "alice")
+	bob := createTestUser(t, db, "bob")
+
+	question, err := CreatePost(db, alice, nil, "How do I?", "Help", "")
+	if err != nil {
+		t.Fatalf("CreatePost failed: %v", err)
+	}
+	discussion, err := CreatePost(db, alice, nil, "Chat", "Hi", "")
+	if err != nil {
+		t.Fatalf("CreatePost failed: %v", err)
+	}
+	if discussion.PostType != "discussion" {
+		t.Errorf("Expected posts to be discussions by default, got %q", discussion.PostType)
+	}
+	if err := SetPostType(db, question.ID, "question"); err != nil {
+		t.Fatalf("SetPostType failed: %v", err)
+	}
+
+	first, _ := CreateComment(db, alice, question.ID, "Bump")
+	answer, _ := CreateComment(db, bob, question.ID, "Like this")
+	elsewhere, _ := CreateComment(db, bob, discussion.ID, "Hello")
+
+	t.Run("accepting an answer", func(t *testing.T) {
+		if err := AcceptAnswer(db, discussion.ID, elsewhere.ID); !errors.Is(err, ErrNotAQuestion) {
+			t.Errorf("Expected ErrNotAQuestion, got %v", err)
+		}
+		if err := AcceptAnswer(db, question.ID, elsewhere.ID); !errors.Is(err, ErrNotAnAnswer) {
+			t.Errorf("Expected ErrNotAnAnswer, got %v", err)
+		}
+		if err := AcceptAnswer(db, 9999, answer.ID); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("Expected sql.ErrNoRows, got %v", err)
+		}
+		if err := AcceptAnswer(db, question.ID, answer.ID); err != nil {
+			t.Fatalf("AcceptAnswer failed: %v", err)
+		}
+
+		comments, err := GetPostComments(db, question.ID, "")
+		if err != nil {
+			t.Fatalf("GetPostComments failed: %v", err)
+		}
+		if len(comments) != 2 || comments[0].ID != answer.ID || !comments[0].IsAccepted || comments[1].ID != first.ID || comments[1].IsAccepted {
+			t.Errorf("Expected the accepted answer first, got %+v", comments)
+		}
+	})
+
+	t.Run("answered filter", func(t *testing.T) {
+		yes, no := true, false
+		answered, err := GetPosts(db, "", PostFilter{Answered: &yes}, 1, 10)
+		if err != nil {
+			t.Fatalf("GetPosts failed: %v", err)
+		}
+		if len(answered) != 1 || answered[0].ID != question.ID || answered[0].AcceptedCommentID == nil || *answered[0].AcceptedCommentID != answer.ID {
+			t.Errorf("Unexpected answered questions: %+v", answered)
+		}
+		if unanswered, _ := GetPosts(db, "", PostFilter{Answered: &no}, 1, 10); len(unanswered) != 0 {
+			t.Errorf("Expected no unanswered questions, got %+v", unanswered)
+		}
+		if discussions, _ := GetPosts(db, "", PostFilter{PostType: "discussion"}, 1, 10); len(discussions) != 1 || discussions[0].ID != discussion.ID {
+			t.Errorf("Unexpected discussions: %+v", discussions)
+		}
+	})
+
+	t.Run("accepted answers earn karma", func(t *testing.T) {
+		profile, err := GetProfileByID(db, bob)
+		if err != nil {
+			t.Fatalf("GetProfileByID failed: %v", err)
+		}
+		if profile.Karma != AcceptedAnswerKarma {
+			t.Errorf("Expected karma %d, got %d", AcceptedAnswerKarma, profile.Karma)
+		}
+
+		// Accepting your own answer earns nothing
+		if err := AcceptAnswer(db, question.ID, first.ID); err != nil {
+			t.Fatalf("AcceptAnswer failed: %v", err)
+		}
+		if profile, _ := GetProfileByID(db, alice); profile.Karma != 0 {
+			t.Errorf("Expected no karma for a self-accepted answer, got %d", profile.Karma)
+		}
+		if profile, _ := GetProfileByID(db, bob); profile.Karma != 0 {
+			t.Errorf("Expected bob to lose the karma, got %d", profile.Karma)
+		}
+
+		if err := AcceptAnswer(db, question.ID, 0); err != nil {
+			t.Fatalf("Clearing the answer failed: %v", err)
+		}
+		if post, _ := GetPost(db, question.ID); post.AcceptedCommentID != nil {
+			t.Errorf("Expected no accepted answer, got %v", *post.AcceptedCommentID)
+		}
+	})
+}
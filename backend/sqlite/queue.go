@@ -0,0 +1,160 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"forum/models"
+)
+
+// Moderation queue statuses
+const (
+	QueuePending  = "pending"
+	QueueApproved = "approved"
+	QueueRejected = "rejected"
+)
+
+// ErrAlreadyReviewed is returned when reviewing a queue item that was
+// approved or rejected already
+var ErrAlreadyReviewed = errors.New("this item has already been reviewed")
+
+// QueueContent holds a post, comment or reply for review and returns the
+// queue item's ID
+func QueueContent(db *sql.DB, item models.QueueItem) (int, error) {
+	details := []byte("{}")
+	if item.Post != nil {
+		var err error
+		if details, err = json.Marshal(item.Post); err != nil {
+			return 0, err
+		}
+	}
+	reasons, err := json.Marshal(item.Reasons)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int
+	err = db.QueryRow(`
+		INSERT INTO moderation_queue (user_id, kind, target_id, title, content, details, reasons)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`, item.UserID, item.Kind, item.TargetID, item.Title, item.Content, string(details), string(reasons)).Scan(&id)
+	return id, err
+}
+
+// queueColumns are the columns scanned by scanQueueItem
+const queueColumns = `
+	q.id, q.kind, q.user_id, u.username, q.target_id, q.title, q.content, q.details, q.reasons,
+	q.status, q.reviewer_id, q.reviewed_at, q.published_id, q.created_at
+`
+
+// scanQueueItem reads a row of queueColumns
+func scanQueueItem(row interface{ Scan(...any) error }) (models.QueueItem, error) {
+	var item models.QueueItem
+	var targetID, publishedID sql.NullInt64
+	var reviewerID sql.NullString
+	var reviewedAt sql.NullTime
+	var details, reasons string
+	err := row.Scan(&item.ID, &item.Kind, &item.UserID, &item.Username, &targetID, &item.Title, &item.Content,
+		&details, &reasons, &item.Status, &reviewerID, &reviewedAt, &publishedID, &item.CreatedAt)
+	if err != nil {
+		return item, err
+	}
+
+	item.TargetID = intPtr(targetID)
+	item.PublishedID = intPtr(publishedID)
+	if reviewerID.Valid {
+		item.ReviewerID = &reviewerID.String
+	}
+	if reviewedAt.Valid {
+		item.ReviewedAt = &reviewedAt.Time
+	}
+	if item.Kind == ContentPost {
+		item.Post = &models.PostDetails{}
+		if err := json.Unmarshal([]byte(details), item.Post); err != nil {
+			return item, err
+		}
+	}
+	if err := json.Unmarshal([]byte(reasons), &item.Reasons); err != nil {
+		return item, err
+	}
+	return item, nil
+}
+
+// GetQueue returns a page of queue items with the given status, oldest first
+// so they are reviewed in order
+func GetQueue(db *sql.DB, status string, page, limit int) ([]models.QueueItem, error) {
+	rows, err := db.Query(`
+		SELECT `+queueColumns+`
+		FROM moderation_queue q
+		JOIN users u ON u.id = q.user_id
+		WHERE q.status = ?
+		ORDER BY q.id
+		LIMIT ? OFFSET ?
+	`, status, limit, (page-1)*limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.QueueItem{}
+	for rows.Next() {
+		item, err := scanQueueItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// GetQueueItem returns a queue item, or sql.ErrNoRows
+func GetQueueItem(db *sql.DB, id int) (models.QueueItem, error) {
+	return scanQueueItem(db.QueryRow(`
+		SELECT `+queueColumns+`
+		FROM moderation_queue q
+		JOIN users u ON u.id = q.user_id
+		WHERE q.id = ?
+	`, id))
+}
+
+// ClaimQueueItem marks a pending item approved or rejected by reviewerID and
+// returns it. Only one reviewer can claim an item; the others get
+// ErrAlreadyReviewed. Returns sql.ErrNoRows for an unknown item.
+func ClaimQueueItem(db *sql.DB, id int, reviewerID, status string) (models.QueueItem, error) {
+	result, err := db.Exec(`
+		UPDATE moderation_queue SET status = ?, reviewer_id = ?, reviewed_at = ?
+		WHERE id = ? AND status = 'pending'
+	`, status, reviewerID, sqliteTime(time.Now()), id)
+	if err != nil {
+		return models.QueueItem{}, err
+	}
+	item, err := GetQueueItem(db, id)
+	if err != nil {
+		return item, err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return item, err
+	} else if n == 0 {
+		return item, ErrAlreadyReviewed
+	}
+	return item, nil
+}
+
+// SetQueuePublished records what an approved item was published as
+func SetQueuePublished(db *sql.DB, id, publishedID int) error {
+	_, err := db.Exec(`UPDATE moderation_queue SET published_id = ? WHERE id = ?`, publishedID, id)
+	return err
+}
+
+// ReopenQueueItem puts a claimed item back in the queue, for when it could
+// not be published after all
+func ReopenQueueItem(db *sql.DB, id int) error {
+	_, err := db.Exec(`
+		UPDATE moderation_queue SET status = 'pending', reviewer_id = NULL, reviewed_at = NULL
+		WHERE id = ?
+	`, id)
+	return err
+}
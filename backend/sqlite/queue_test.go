@@ -0,0 +1,115 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+
+	"forum/models"
+)
+
+func TestModerationQueue(t *testing.T) {
+	db := setupSchemaTestDB(t)
+	alice := createTestUser(t, db, "alice")
+	mod := createTestUser(t, db, "mod")
+
+	details := &models.PostDetails{Tags: []string{"go"}, PostType: "question", ImageURL: "/static/pictures/a.png"}
+	postItem, err := QueueContent(db, models.QueueItem{
+		Kind: ContentPost, UserID: alice, Title: "Hello", Content: "World",
+		Post: details, Reasons: []string{"too many links"},
+	})
+	if err != nil {
+		t.Fatalf("QueueContent failed: %v", err)
+	}
+	postID := 7
+	commentItem, err := QueueContent(db, models.QueueItem{Kind: ContentComment, UserID: alice, TargetID: &postID, Content: "Hi"})
+	if err != nil {
+		t.Fatalf("QueueContent failed: %v", err)
+	}
+
+	items, err := GetQueue(db, QueuePending, 1, 10)
+	if err != nil {
+		t.Fatalf("GetQueue failed: %v", err)
+	}
+	if len(items) != 2 || items[0].ID != postItem || items[0].Username != "alice" {
+		t.Fatalf("Unexpected queue: %+v", items)
+	}
+	if !reflect.DeepEqual(items[0].Post, details) || !reflect.DeepEqual(items[0].Reasons, []string{"too many links"}) {
+		t.Errorf("Expected the post details and reasons back, got %+v", items[0])
+	}
+	if items[1].Post != nil || *items[1].TargetID != postID {
+		t.Errorf("Unexpected comment item: %+v", items[1])
+	}
+
+	t.Run("claiming", func(t *testing.T) {
+		item, err := ClaimQueueItem(db, commentItem, mod, QueueApproved)
+		if err != nil {
+			t.Fatalf("ClaimQueueItem failed: %v", err)
+		}
+		if item.Status != QueueApproved || item.ReviewerID == nil || *item.ReviewerID != mod || item.ReviewedAt == nil {
+			t.Errorf("Unexpected claimed item: %+v", item)
+		}
+		if _, err := ClaimQueueItem(db, commentItem, mod, QueueRejected); !errors.Is(err, ErrAlreadyReviewed) {
+			t.Errorf("Expected ErrAlreadyReviewed, got %v", err)
+		}
+		if _, err := ClaimQueueItem(db, 999, mod, QueueApproved); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("Expected sql.ErrNoRows, got %v", err)
+		}
+
+		if err := SetQueuePublished(db, commentItem, 42); err != nil {
+			t.Fatalf("SetQueuePublished failed: %v", err)
+		}
+		approved, _ := GetQueue(db, QueueApproved, 1, 10)
+		if len(approved) != 1 || approved[0].PublishedID == nil || *approved[0].PublishedID != 42 {
+			t.Errorf("Unexpected approved items: %+v", approved)
+		}
+	})
+
+	t.Run("reopening", func(t *testing.T) {
+		if _, err := ClaimQueueItem(db, postItem, mod, QueueApproved); err != nil {
+			t.Fatalf("ClaimQueueItem failed: %v", err)
+		}
+		if err := ReopenQueueItem(db, postItem); err != nil {
+			t.Fatalf("ReopenQueueItem failed: %v", err)
+		}
+		item, err := GetQueueItem(db, postItem)
+		if err != nil {
+			t.Fatalf("GetQueueItem failed: %v", err)
+		}
+		if item.Status != QueuePending || item.ReviewerID != nil {
+			t.Errorf("Expected the item to be pending again, got %+v", item)
+		}
+	})
+}
+
+func TestBannedWords(t *testing.T) {
+	db := setupSchemaTestDB(t)
+
+	if _, err := SaveBannedWord(db, models.BannedWord{Word: "  Spam ", Action: WordReplace}); err != nil {
+		t.Fatalf("SaveBannedWord failed: %v", err)
+	}
+	if _, err := SaveBannedWord(db, models.BannedWord{Word: "spam", Action: WordBlock}); err != nil {
+		t.Fatalf("SaveBannedWord failed: %v", err)
+	}
+	words, err := GetBannedWords(db)
+	if err != nil {
+		t.Fatalf("GetBannedWords failed: %v", err)
+	}
+	if len(words) != 1 || words[0].Word != "spam" || words[0].Action != WordBlock {
+		t.Errorf("Expected one lower-case word changed to block, got %+v", words)
+	}
+
+	for _, w := range []models.BannedWord{{Word: " ", Action: WordBlock}, {Word: "eggs", Action: "flag"}} {
+		if _, err := SaveBannedWord(db, w); !errors.Is(err, ErrInvalidWord) {
+			t.Errorf("Expected ErrInvalidWord for %+v, got %v", w, err)
+		}
+	}
+
+	if err := DeleteBannedWord(db, "SPAM"); err != nil {
+		t.Fatalf("DeleteBannedWord failed: %v", err)
+	}
+	if err := DeleteBannedWord(db, "spam"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows, got %v", err)
+	}
+}
@@ -0,0 +1,160 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"forum/models"
+)
+
+var (
+	// ErrUnknownReaction is returned when reacting with a type that does not
+	// exist or is disabled
+	ErrUnknownReaction = errors.New("unknown reaction type")
+	// ErrInvalidReaction is returned when saving a reaction type with a bad
+	// name, emoji or label
+	ErrInvalidReaction = errors.New("invalid reaction type")
+	// ErrBuiltInReaction is returned when disabling like or dislike
+	ErrBuiltInReaction = errors.New("built-in reaction types cannot be disabled")
+)
+
+// opposingReactions lists reaction types that replace each other: a user
+// either likes or dislikes something, never both
+var opposingReactions = map[string]string{
+	"like":    "dislike",
+	"dislike": "like",
+}
+
+// reactionNamePattern is what reaction type names look like: short lower-case
+// identifiers, used as keys in the API
+var reactionNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,19}$`)
+
+// GetReactionTypes returns the reaction types in display order. Disabled
+// types are only included when includeDisabled is set.
+func GetReactionTypes(db *sql.DB, includeDisabled bool) ([]models.ReactionType, error) {
+	rows, err := db.Query(`
+		SELECT name, emoji, label, sort_order, built_in, enabled
+		FROM reaction_types
+		WHERE enabled OR ?
+		ORDER BY sort_order, name
+	`, includeDisabled)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	types := []models.ReactionType{}
+	for rows.Next() {
+		var rt models.ReactionType
+		if err := rows.Scan(&rt.Name, &rt.Emoji, &rt.Label, &rt.SortOrder, &rt.BuiltIn, &rt.Enabled); err != nil {
+			return nil, err
+		}
+		types = append(types, rt)
+	}
+	return types, rows.Err()
+}
+
+// SaveReactionType creates a reaction type or updates the one with the same
+// name. Disabling a type hides it and its counts without deleting the
+// reactions, so enabling it again brings them back.
+func SaveReactionType(db *sql.DB, rt models.ReactionType) (models.ReactionType, error) {
+	if !reactionNamePattern.MatchString(rt.Name) {
+		return rt, fmt.Errorf("%w: names are 1 to 20 lower-case letters, digits or underscores", ErrInvalidReaction)
+	}
+	if rt.Emoji == "" || rt.Label == "" {
+		return rt, fmt.Errorf("%w: emoji and label are required", ErrInvalidReaction)
+	}
+
+	var builtIn bool
+	err := db.QueryRow(`SELECT built_in FROM reaction_types WHERE name = ?`, rt.Name).Scan(&builtIn)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return rt, err
+	}
+	if builtIn && !rt.Enabled {
+		return rt, ErrBuiltInReaction
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO reaction_types (name, emoji, label, sort_order, enabled)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (name) DO UPDATE SET
+			emoji = excluded.emoji,
+			label = excluded.label,
+			sort_order = excluded.sort_order,
+			enabled = excluded.enabled
+	`, rt.Name, rt.Emoji, rt.Label, rt.SortOrder, rt.Enabled)
+	if err != nil {
+		return rt, err
+	}
+	rt.BuiltIn = builtIn
+	return rt, nil
+}
+
+// GetReactionSummary counts the reactions of each enabled type on a post or
+// comment, and lists the ones viewerID left (none for signed-out visitors)
+func GetReactionSummary(db *sql.DB, postID, commentID *int, viewerID string) (models.ReactionSummary, error) {
+	summary := models.ReactionSummary{Counts: map[string]int{}, ViewerReactions: []string{}}
+	column, targetID, err := reactionTarget(postID, commentID)
+	if err != nil {
+		return summary, err
+	}
+
+	rows, err := db.Query(`
+		SELECT rt.name, COUNT(l.user_id), COALESCE(MAX(l.user_id = ?), 0)
+		FROM reaction_types rt
+		LEFT JOIN likes l ON l.type = rt.name AND l.`+column+` = ?
+		WHERE rt.enabled
+		GROUP BY rt.name
+		ORDER BY rt.sort_order, rt.name
+	`, viewerID, targetID)
+	if err != nil {
+		return summary, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		var count int
+		var mine bool
+		if err := rows.Scan(&name, &count, &mine); err != nil {
+			return summary, err
+		}
+		summary.Counts[name] = count
+		if mine {
+			summary.ViewerReactions = append(summary.ViewerReactions, name)
+		}
+	}
+	summary.Likes, summary.Dislikes = summary.Counts["like"], summary.Counts["dislike"]
+	return summary, rows.Err()
+}
+
+// GetReactors returns a page of the users who left a reaction of the given
+// type on a post or comment, most recent first
+func GetReactors(db *sql.DB, postID, commentID *int, reactionType string, page, limit int) ([]models.UserSummary, error) {
+	column, targetID, err := reactionTarget(postID, commentID)
+	if err != nil {
+		return nil, err
+	}
+	return queryUserSummaries(db, `
+		SELECT u.id, u.username, u.avatar_url
+		FROM likes l
+		JOIN users u ON u.id = l.user_id
+		WHERE l.`+column+` = ? AND l.type = ?
+		ORDER BY l.created_at DESC, u.username
+		LIMIT ? OFFSET ?
+	`, targetID, reactionType, limit, (page-1)*limit)
+}
+
+// reactionTarget returns the likes column and ID of the post or comment
+// being reacted to
+func reactionTarget(postID, commentID *int) (string, int, error) {
+	switch {
+	case postID != nil && commentID == nil:
+		return "post_id", *postID, nil
+	case commentID != nil && postID == nil:
+		return "comment_id", *commentID, nil
+	}
+	return "", 0, errors.New("must provide either postID or commentID, but not both")
+}
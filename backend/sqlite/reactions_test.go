@@ -0,0 +1,231 @@
+package sqlite
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"forum/models"
+)
+
+func TestReactions(t *testing.T) {
+	db := setupSchemaTestDB(t)
+	alice := createTestUser(t, db, "alice")
+	bob := createTestUser(t, db, "bob")
+	carol := createTestUser(t, db, "carol")
+
+	post, err := CreatePost(db, alice, nil, "Hello", "World", "")
+	if err != nil {
+		t.Fatalf("CreatePost failed: %v", err)
+	}
+	toggle := func(userID, reactionType string) {
+		t.Helper()
+		if _, err := ToggleLike(db, userID, &post.ID, nil, reactionType); err != nil {
+			t.Fatalf("ToggleLike(%s) failed: %v", reactionType, err)
+		}
+	}
+	summary := func(viewerID string) models.ReactionSummary {
+		t.Helper()
+		s, err := GetReactionSummary(db, &post.ID, nil, viewerID)
+		if err != nil {
+			t.Fatalf("GetReactionSummary failed: %v", err)
+		}
+		return s
+	}
+
+	t.Run("several types per user", func(t *testing.T) {
+		toggle(bob, "like")
+		toggle(bob, "love")
+		toggle(carol, "love")
+
+		s := summary(bob)
+		if s.Likes != 1 || s.Counts["like"] != 1 || s.Counts["love"] != 2 || s.Counts["eyes"] != 0 {
+			t.Errorf("Unexpected counts: %+v", s)
+		}
+		if !reflect.DeepEqual(s.ViewerReactions, []string{"like", "love"}) {
+			t.Errorf("Expected bob's reactions to be like and love, got %v", s.ViewerReactions)
+		}
+		if len(summary("").ViewerReactions) != 0 {
+			t.Error("Expected no viewer reactions when signed out")
+		}
+	})
+
+	t.Run("like and dislike replace each other", func(t *testing.T) {
+		toggle(bob, "dislike")
+		s := summary(bob)
+		if s.Likes != 0 || s.Dislikes != 1 || s.Counts["love"] != 2 {
+			t.Errorf("Unexpected counts after switching to dislike: %+v", s)
+		}
+
+		toggle(bob, "dislike")
+		if s := summary(bob); s.Dislikes != 0 || !reflect.DeepEqual(s.ViewerReactions, []string{"love"}) {
+			t.Errorf("Expected the dislike to be toggled off, got %+v", s)
+		}
+	})
+
+	t.Run("reports whether the reaction is there", func(t *testing.T) {
+		if active, err := ToggleLike(db, bob, &post.ID, nil, "dislike"); err != nil || !active {
+			t.Errorf("Expected the dislike to be added, got %v, %v", active, err)
+		}
+		if active, err := ToggleLike(db, bob, &post.ID, nil, "dislike"); err != nil || active {
+			t.Errorf("Expected the dislike to be removed, got %v, %v", active, err)
+		}
+	})
+
+	t.Run("counters follow the likes table", func(t *testing.T) {
+		before, err := GetPost(db, post.ID)
+		if err != nil {
+			t.Fatalf("GetPost failed: %v", err)
+		}
+		toggle(carol, "like")
+		toggle(bob, "dislike")
+
+		likes, dislikes, err := CountLikesAndDislikes(db, &post.ID, nil)
+		if err != nil {
+			t.Fatalf("CountLikesAndDislikes failed: %v", err)
+		}
+		if likes != 1 || dislikes != 1 {
+			t.Errorf("Expected 1 like and 1 dislike, got %d and %d", likes, dislikes)
+		}
+		stored, err := GetPost(db, post.ID)
+		if err != nil {
+			t.Fatalf("GetPost failed: %v", err)
+		}
+		if stored.LikeCount != 1 || stored.DislikeCount != 1 {
+			t.Errorf("Unexpected post counters: %d likes, %d dislikes", stored.LikeCount, stored.DislikeCount)
+		}
+		if !stored.UpdatedAt.Equal(before.UpdatedAt) {
+			t.Error("Expected reactions to leave updated_at alone")
+		}
+
+		toggle(carol, "like")
+		toggle(bob, "dislike")
+	})
+
+	t.Run("who reacted", func(t *testing.T) {
+		users, err := GetReactors(db, &post.ID, nil, "love", 1, 10)
+		if err != nil {
+			t.Fatalf("GetReactors failed: %v", err)
+		}
+		if len(users) != 2 {
+			t.Fatalf("Expected 2 users, got %+v", users)
+		}
+	})
+
+	t.Run("unknown and disabled types", func(t *testing.T) {
+		if _, err := ToggleLike(db, bob, &post.ID, nil, "shrug"); !errors.Is(err, ErrUnknownReaction) {
+			t.Errorf("Expected ErrUnknownReaction, got %v", err)
+		}
+
+		if _, err := SaveReactionType(db, models.ReactionType{Name: "love", Emoji: "❤️", Label: "Love", SortOrder: 2}); err != nil {
+			t.Fatalf("SaveReactionType failed: %v", err)
+		}
+		if _, err := ToggleLike(db, bob, &post.ID, nil, "love"); !errors.Is(err, ErrUnknownReaction) {
+			t.Errorf("Expected disabled type to be rejected, got %v", err)
+		}
+		if _, ok := summary("").Counts["love"]; ok {
+			t.Error("Expected disabled type to be left out of the counts")
+		}
+
+		if _, err := SaveReactionType(db, models.ReactionType{Name: "like", Emoji: "👍", Label: "Like"}); !errors.Is(err, ErrBuiltInReaction) {
+			t.Errorf("Expected ErrBuiltInReaction, got %v", err)
+		}
+		if _, err := SaveReactionType(db, models.ReactionType{Name: "Bad Name", Emoji: "x", Label: "x", Enabled: true}); !errors.Is(err, ErrInvalidReaction) {
+			t.Errorf("Expected ErrInvalidReaction, got %v", err)
+		}
+	})
+
+	t.Run("new types", func(t *testing.T) {
+		rt, err := SaveReactionType(db, models.ReactionType{Name: "rocket", Emoji: "🚀", Label: "Rocket", SortOrder: 9, Enabled: true})
+		if err != nil {
+			t.Fatalf("SaveReactionType failed: %v", err)
+		}
+		if rt.BuiltIn {
+			t.Error("Expected a new type not to be built in")
+		}
+		toggle(carol, "rocket")
+
+		types, err := GetReactionTypes(db, false)
+		if err != nil {
+			t.Fatalf("GetReactionTypes failed: %v", err)
+		}
+		if types[len(types)-1].Name != "rocket" || types[0].Name != "like" {
+			t.Errorf("Unexpected reaction type order: %+v", types)
+		}
+		for _, rt := range types {
+			if rt.Name == "love" {
+				t.Error("Expected disabled types to be left out")
+			}
+		}
+		if summary("").Counts["rocket"] != 1 {
+			t.Error("Expected the new type to be counted")
+		}
+	})
+}
+
+func TestConcurrentToggles(t *testing.T) {
+	db := setupSchemaFileDB(t)
+	author := createTestUser(t, db, "author")
+	post, err := CreatePost(db, author, nil, "Hello", "World", "")
+	if err != nil {
+		t.Fatalf("CreatePost failed: %v", err)
+	}
+	comment, err := CreateComment(db, author, post.ID, "First")
+	if err != nil {
+		t.Fatalf("CreateComment failed: %v", err)
+	}
+
+	const users = 8
+	const clicks = 5 // per user; odd, so each user ends up reacting
+	var userIDs []string
+	for i := 0; i < users; i++ {
+		userIDs = append(userIDs, createTestUser(t, db, fmt.Sprintf("user%d", i)))
+	}
+
+	// Every user double-clicks like on the post and dislike on the comment,
+	// all at once
+	var wg sync.WaitGroup
+	errs := make(chan error, users*clicks*2)
+	for _, userID := range userIDs {
+		for i := 0; i < clicks; i++ {
+			wg.Add(2)
+			go func(userID string) {
+				defer wg.Done()
+				_, err := ToggleLike(db, userID, &post.ID, nil, "like")
+				errs <- err
+			}(userID)
+			go func(userID string) {
+				defer wg.Done()
+				_, err := ToggleLike(db, userID, nil, &comment.ID, "dislike")
+				errs <- err
+			}(userID)
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Concurrent ToggleLike failed: %v", err)
+		}
+	}
+
+	if n := countRows(t, db, `SELECT COUNT(*) FROM likes WHERE post_id = ? AND type = 'like'`, post.ID); n != users {
+		t.Errorf("Expected %d likes, got %d", users, n)
+	}
+	likes, dislikes, err := CountLikesAndDislikes(db, &post.ID, nil)
+	if err != nil {
+		t.Fatalf("CountLikesAndDislikes failed: %v", err)
+	}
+	if likes != users || dislikes != 0 {
+		t.Errorf("Expected post counters %d/0, got %d/%d", users, likes, dislikes)
+	}
+	likes, dislikes, err = CountLikesAndDislikes(db, nil, &comment.ID)
+	if err != nil {
+		t.Fatalf("CountLikesAndDislikes failed: %v", err)
+	}
+	if likes != 0 || dislikes != users {
+		t.Errorf("Expected comment counters 0/%d, got %d/%d", users, likes, dislikes)
+	}
+}
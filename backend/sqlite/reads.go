@@ -0,0 +1,100 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"forum/models"
+)
+
+// SaveViewCounts adds a batch of post views collected in memory. Views of
+// posts deleted in the meantime are dropped.
+func SaveViewCounts(db *sql.DB, counts map[int]int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`UPDATE posts SET view_count = view_count + ? WHERE id = ?`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for postID, n := range counts {
+		if _, err := stmt.Exec(n, postID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// MarkPostRead records that userID opened postID at at
+func MarkPostRead(db *sql.DB, userID string, postID int, at time.Time) error {
+	_, err := db.Exec(`
+		INSERT INTO post_reads (user_id, post_id, last_read_at) VALUES (?, ?, ?)
+		ON CONFLICT (user_id, post_id) DO UPDATE SET last_read_at = excluded.last_read_at
+	`, userID, postID, sqliteTime(at))
+	return err
+}
+
+// MarkUnread sets LastReadAt and UnreadComments on posts for userID: when
+// they last opened each post, and how many comments and replies others
+// wrote since. Every comment is unread on a post they never opened. Like
+// GetPostComments, comments by muted and blocked authors are left out.
+// Timestamps only have whole seconds, so a comment from the second the post
+// was opened still counts as unread; the reader's own never do.
+func MarkUnread(db *sql.DB, userID string, posts []models.Post) error {
+	if userID == "" || len(posts) == 0 {
+		return nil
+	}
+
+	placeholders := make([]string, len(posts))
+	args := []any{userID, userID}
+	for i, post := range posts {
+		placeholders[i] = "?"
+		args = append(args, post.ID)
+	}
+
+	rows, err := db.Query(fmt.Sprintf(`
+		WITH hidden(id) AS (`+hiddenAuthors+`)
+		SELECT p.id, r.last_read_at,
+			(SELECT COUNT(*) FROM comments c
+			 WHERE c.post_id = p.id AND c.user_id != ?1 AND c.user_id NOT IN (SELECT id FROM hidden)
+				AND (r.last_read_at IS NULL OR c.created_at >= r.last_read_at))
+			+ (SELECT COUNT(*) FROM replycomments rc JOIN comments c ON c.id = rc.parent_comment_id
+			   WHERE c.post_id = p.id AND rc.user_id != ?1 AND rc.user_id NOT IN (SELECT id FROM hidden)
+				AND (r.last_read_at IS NULL OR rc.created_at >= r.last_read_at))
+		FROM posts p
+		LEFT JOIN post_reads r ON r.post_id = p.id AND r.user_id = ?1
+		WHERE p.id IN (%s)
+	`, strings.Join(placeholders, ",")), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	type readState struct {
+		lastReadAt *time.Time
+		unread     int
+	}
+	states := make(map[int]readState)
+	for rows.Next() {
+		var postID int
+		var lastReadAt sql.NullTime
+		var state readState
+		if err := rows.Scan(&postID, &lastReadAt, &state.unread); err != nil {
+			return err
+		}
+		state.lastReadAt = timePtr(lastReadAt)
+		states[postID] = state
+	}
+	for i := range posts {
+		state := states[posts[i].ID]
+		posts[i].LastReadAt, posts[i].UnreadComments = state.lastReadAt, state.unread
+	}
+	return rows.Err()
+}
@@ -0,0 +1,155 @@
+package sqlite
+
+import (
+	"testing"
+	"time"
+
+	"forum/models"
+)
+
+func TestViewsAndReads(t *testing.T) {
+	db := setupSchemaTestDB(t)
+	alice := createTestUser(t, db, "alice")
+	bob := createTestUser(t, db, "bob")
+
+	post, err := CreatePost(db, alice, nil, "Thread", "Let's talk", "")
+	if err != nil {
+		t.Fatalf("CreatePost failed: %v", err)
+	}
+	other, err := CreatePost(db, alice, nil, "Quiet", "Nobody answers", "")
+	if err != nil {
+		t.Fatalf("CreatePost failed: %v", err)
+	}
+
+	t.Run("view counts add up", func(t *testing.T) {
+		if err := SaveViewCounts(db, map[int]int{post.ID: 3, 9999: 1}); err != nil {
+			t.Fatalf("SaveViewCounts failed: %v", err)
+		}
+		if err := SaveViewCounts(db, map[int]int{post.ID: 2}); err != nil {
+			t.Fatalf("SaveViewCounts failed: %v", err)
+		}
+		got, err := GetPost(db, post.ID)
+		if err != nil {
+			t.Fatalf("GetPost failed: %v", err)
+		}
+		if got.ViewCount != 5 {
+			t.Errorf("Expected 5 views, got %d", got.ViewCount)
+		}
+	})
+
+	t.Run("unread comments", func(t *testing.T) {
+		comment, err := CreateComment(db, alice, post.ID, "First")
+		if err != nil {
+			t.Fatalf("CreateComment failed: %v", err)
+		}
+		if _, err := CreateReplyComment(db, alice, comment.ID, "Second"); err != nil {
+			t.Fatalf("CreateReplyComment failed: %v", err)
+		}
+		if _, err := CreateComment(db, bob, post.ID, "Bob's own"); err != nil {
+			t.Fatalf("CreateComment failed: %v", err)
+		}
+
+		posts := []models.Post{post, other}
+		if err := MarkUnread(db, bob, posts); err != nil {
+			t.Fatalf("MarkUnread failed: %v", err)
+		}
+		if posts[0].LastReadAt != nil || posts[0].UnreadComments != 2 || posts[1].UnreadComments != 0 {
+			t.Errorf("Expected 2 unread comments on a post never opened, got %+v", posts[0])
+		}
+
+		// Bob read the thread after the first comments were written
+		db.Exec(`UPDATE comments SET created_at = datetime('now', '-2 hours')`)
+		db.Exec(`UPDATE replycomments SET created_at = datetime('now', '-2 hours')`)
+		readAt := time.Now().Add(-time.Hour)
+		if err := MarkPostRead(db, bob, post.ID, readAt); err != nil {
+			t.Fatalf("MarkPostRead failed: %v", err)
+		}
+		if _, err := CreateReplyComment(db, alice, comment.ID, "Third"); err != nil {
+			t.Fatalf("CreateReplyComment failed: %v", err)
+		}
+
+		posts = []models.Post{post}
+		if err := MarkUnread(db, bob, posts); err != nil {
+			t.Fatalf("MarkUnread failed: %v", err)
+		}
+		if posts[0].LastReadAt == nil || posts[0].LastReadAt.Sub(readAt).Abs() > time.Second {
+			t.Errorf("Expected last_read_at %v, got %v", readAt, posts[0].LastReadAt)
+		}
+		if posts[0].UnreadComments != 1 {
+			t.Errorf("Expected 1 unread comment since the last visit, got %d", posts[0].UnreadComments)
+		}
+
+		if err := MarkPostRead(db, bob, post.ID, time.Now().Add(time.Second)); err != nil {
+			t.Fatalf("MarkPostRead failed: %v", err)
+		}
+		MarkUnread(db, bob, posts)
+		if posts[0].UnreadComments != 0 {
+			t.Errorf("Expected no unread comments after reading, got %d", posts[0].UnreadComments)
+		}
+	})
+
+	t.Run("comments in the second the post was opened are unread", func(t *testing.T) {
+		thread, err := CreatePost(db, alice, nil, "Quick", "Fast replies", "")
+		if err != nil {
+			t.Fatalf("CreatePost failed: %v", err)
+		}
+		if err := MarkPostRead(db, bob, thread.ID, time.Now()); err != nil {
+			t.Fatalf("MarkPostRead failed: %v", err)
+		}
+		if _, err := CreateComment(db, alice, thread.ID, "Right after"); err != nil {
+			t.Fatalf("CreateComment failed: %v", err)
+		}
+		if _, err := CreateComment(db, bob, thread.ID, "Bob's own"); err != nil {
+			t.Fatalf("CreateComment failed: %v", err)
+		}
+		// Pin both to the second the post was opened
+		db.Exec(`UPDATE comments SET created_at = (SELECT last_read_at FROM post_reads WHERE user_id = ? AND post_id = ?) WHERE post_id = ?`, bob, thread.ID, thread.ID)
+
+		posts := []models.Post{thread}
+		if err := MarkUnread(db, bob, posts); err != nil {
+			t.Fatalf("MarkUnread failed: %v", err)
+		}
+		if posts[0].UnreadComments != 1 {
+			t.Errorf("Expected alice's comment from the same second to be unread, got %d", posts[0].UnreadComments)
+		}
+	})
+
+	t.Run("muted and blocked authors are not unread", func(t *testing.T) {
+		carol := createTestUser(t, db, "carol")
+		dave := createTestUser(t, db, "dave")
+		thread, err := CreatePost(db, alice, nil, "Busy", "Everyone's here", "")
+		if err != nil {
+			t.Fatalf("CreatePost failed: %v", err)
+		}
+		for _, author := range []string{alice, carol, dave} {
+			comment, err := CreateComment(db, author, thread.ID, "Hello")
+			if err != nil {
+				t.Fatalf("CreateComment failed: %v", err)
+			}
+			if _, err := CreateReplyComment(db, author, comment.ID, "Again"); err != nil {
+				t.Fatalf("CreateReplyComment failed: %v", err)
+			}
+		}
+		if err := MuteUser(db, bob, carol); err != nil {
+			t.Fatalf("MuteUser failed: %v", err)
+		}
+		if err := BlockUser(db, bob, dave); err != nil {
+			t.Fatalf("BlockUser failed: %v", err)
+		}
+
+		posts := []models.Post{thread}
+		if err := MarkUnread(db, bob, posts); err != nil {
+			t.Fatalf("MarkUnread failed: %v", err)
+		}
+		if posts[0].UnreadComments != 2 {
+			t.Errorf("Expected only alice's comment and reply to be unread, got %d", posts[0].UnreadComments)
+		}
+	})
+
+	t.Run("signed-out viewers have no read state", func(t *testing.T) {
+		posts := []models.Post{post}
+		if err := MarkUnread(db, "", posts); err != nil || posts[0].LastReadAt != nil {
+			t.Errorf("Expected no read state, got %+v (%v)", posts[0], err)
+		}
+	})
+}
@@ -0,0 +1,37 @@
+package sqlite
+
+import "database/sql"
+
+// User roles. Moderators look after content; admins also manage the site.
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// CanModerate reports whether a role may moderate content
+func CanModerate(role string) bool {
+	return role == RoleModerator || role == RoleAdmin
+}
+
+// GetUserRole returns a user's role: RoleUser, RoleModerator or RoleAdmin
+func GetUserRole(db *sql.DB, userID string) (string, error) {
+	var role string
+	err := db.QueryRow(`SELECT role FROM users WHERE id = ?`, userID).Scan(&role)
+	return role, err
+}
+
+// SetUserRole changes the role of the user with the given username. It
+// returns sql.ErrNoRows if there is no such user.
+func SetUserRole(db *sql.DB, username, role string) error {
+	result, err := db.Exec(`UPDATE users SET role = ? WHERE username = ?`, role, username)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"forum/models"
+)
+
+// Kinds of content that are screened and can be held in the moderation queue
+const (
+	ContentPost    = "post"
+	ContentComment = "comment"
+	ContentReply   = "reply"
+)
+
+// Banned word actions
+const (
+	WordReplace = "replace"
+	WordBlock   = "block"
+)
+
+// ErrInvalidWord is returned by SaveBannedWord for an empty word or an
+// unknown action
+var ErrInvalidWord = errors.New("invalid banned word")
+
+// bannedWordsVersion counts the changes to the banned word list made by
+// this process
+var bannedWordsVersion atomic.Uint64
+
+// BannedWordsVersion changes whenever SaveBannedWord or DeleteBannedWord
+// changes the banned word list, so callers can cache what they derive from it
+func BannedWordsVersion() uint64 {
+	return bannedWordsVersion.Load()
+}
+
+// GetBannedWords returns the banned words in alphabetical order
+func GetBannedWords(db *sql.DB) ([]models.BannedWord, error) {
+	rows, err := db.Query(`SELECT word, action, replacement, created_at FROM banned_words ORDER BY word`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	words := []models.BannedWord{}
+	for rows.Next() {
+		var w models.BannedWord
+		if err := rows.Scan(&w.Word, &w.Action, &w.Replacement, &w.CreatedAt); err != nil {
+			return nil, err
+		}
+		words = append(words, w)
+	}
+	return words, rows.Err()
+}
+
+// SaveBannedWord adds a banned word or changes its action. Words are matched
+// without regard to case, so they are stored in lower case.
+func SaveBannedWord(db *sql.DB, w models.BannedWord) (models.BannedWord, error) {
+	w.Word = strings.ToLower(strings.TrimSpace(w.Word))
+	if w.Word == "" || len(w.Word) > 50 {
+		return w, fmt.Errorf("%w: words must be 1 to 50 characters", ErrInvalidWord)
+	}
+	if w.Action != WordReplace && w.Action != WordBlock {
+		return w, fmt.Errorf("%w: action must be %q or %q", ErrInvalidWord, WordReplace, WordBlock)
+	}
+
+	err := db.QueryRow(`
+		INSERT INTO banned_words (word, action, replacement) VALUES (?, ?, ?)
+		ON CONFLICT (word) DO UPDATE SET action = excluded.action, replacement = excluded.replacement
+		RETURNING created_at
+	`, w.Word, w.Action, w.Replacement).Scan(&w.CreatedAt)
+	if err == nil {
+		bannedWordsVersion.Add(1)
+	}
+	return w, err
+}
+
+// DeleteBannedWord removes a banned word. Returns sql.ErrNoRows if it was
+// not on the list.
+func DeleteBannedWord(db *sql.DB, word string) error {
+	result, err := db.Exec(`DELETE FROM banned_words WHERE word = ?`, strings.ToLower(strings.TrimSpace(word)))
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+	bannedWordsVersion.Add(1)
+	return nil
+}
+
+// recentContent is the user's published posts, comments, replies and held
+// content, with when they were written. Drafts and scheduled posts are left
+// out until they are published, and so is the post ?3 being edited.
+const recentContent = `
+	SELECT content, created_at FROM posts WHERE user_id = ?1 AND status = 'published' AND id != ?3
+	UNION ALL
+	SELECT content, created_at FROM comments WHERE user_id = ?1
+	UNION ALL
+	SELECT content, created_at FROM replycomments WHERE user_id = ?1
+	UNION ALL
+	SELECT content, created_at FROM moderation_queue WHERE user_id = ?1 AND status = 'pending'
+`
+
+// GetRecentContent returns the text of everything userID wrote since since,
+// including content waiting in the moderation queue. The post exceptPostID,
+// which is being edited, is left out; 0 leaves out nothing.
+func GetRecentContent(db *sql.DB, userID string, since time.Time, exceptPostID int) ([]string, error) {
+	rows, err := db.Query(`SELECT content FROM (`+recentContent+`) WHERE created_at >= ?2`, userID, sqliteTime(since), exceptPostID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var contents []string
+	for rows.Next() {
+		var content string
+		if err := rows.Scan(&content); err != nil {
+			return nil, err
+		}
+		contents = append(contents, content)
+	}
+	return contents, rows.Err()
+}
+
+// CountRecentContent returns how many posts, comments and replies userID
+// wrote since since, including content waiting in the moderation queue but
+// not the post exceptPostID
+func CountRecentContent(db *sql.DB, userID string, since time.Time, exceptPostID int) (int, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM (`+recentContent+`) WHERE created_at >= ?2`, userID, sqliteTime(since), exceptPostID).Scan(&count)
+	return count, err
+}
+
+// GetUserCreatedAt returns when a user signed up
+func GetUserCreatedAt(db *sql.DB, userID string) (time.Time, error) {
+	var createdAt time.Time
+	err := db.QueryRow(`SELECT created_at FROM users WHERE id = ?`, userID).Scan(&createdAt)
+	return createdAt, err
+}
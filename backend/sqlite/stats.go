@@ -0,0 +1,143 @@
+package sqlite
+
+import (
+	"database/sql"
+	"time"
+
+	"forum/models"
+)
+
+// statsDateFormat is how days are written in stats, matching SQLite's date()
+const statsDateFormat = "2006-01-02"
+
+// postComments is every comment and reply with the post it was written on
+const postComments = `
+	SELECT post_id, user_id, created_at FROM comments
+	UNION ALL
+	SELECT c.post_id, r.user_id, r.created_at FROM replycomments r JOIN comments c ON c.id = r.parent_comment_id
+`
+
+// GetAuthorStats returns the reactions and comments userID's content
+// received each day from from to to (inclusive, UTC), and their top posts
+// over that range. Comments include replies. Reactions and comments by the
+// author are left out, and so are drafts and scheduled posts.
+func GetAuthorStats(db *sql.DB, userID string, from, to time.Time, top int) (models.AuthorStats, error) {
+	from = from.UTC().Truncate(24 * time.Hour)
+	to = to.UTC().Truncate(24 * time.Hour)
+	stats := models.AuthorStats{
+		From:      from.Format(statsDateFormat),
+		To:        to.Format(statsDateFormat),
+		Reactions: map[string]int{},
+		Days:      []models.DayStats{},
+	}
+	// Bounds for created_at, which holds UTC "YYYY-MM-DD HH:MM:SS" text
+	start, end := sqliteTime(from), sqliteTime(to.AddDate(0, 0, 1))
+
+	days := make(map[string]*models.DayStats)
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		stats.Days = append(stats.Days, models.DayStats{Date: day.Format(statsDateFormat), Reactions: map[string]int{}})
+	}
+	for i := range stats.Days {
+		days[stats.Days[i].Date] = &stats.Days[i]
+	}
+
+	rows, err := db.Query(`
+		SELECT date(l.created_at), l.type, COUNT(*)
+		FROM likes l
+		LEFT JOIN posts p ON p.id = l.post_id AND p.status = 'published'
+		LEFT JOIN comments c ON c.id = l.comment_id
+		WHERE COALESCE(p.user_id, c.user_id) = ? AND l.user_id != ?
+			AND l.created_at >= ? AND l.created_at < ?
+		GROUP BY 1, 2
+	`, userID, userID, start, end)
+	if err != nil {
+		return stats, err
+	}
+	for rows.Next() {
+		var date, reactionType string
+		var count int
+		if err := rows.Scan(&date, &reactionType, &count); err != nil {
+			rows.Close()
+			return stats, err
+		}
+		if day, ok := days[date]; ok {
+			day.Reactions[reactionType] += count
+			day.ReactionTotal += count
+			stats.Reactions[reactionType] += count
+			stats.ReactionTotal += count
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return stats, err
+	}
+
+	rows, err = db.Query(`
+		SELECT date(c.created_at), COUNT(*)
+		FROM (`+postComments+`) c
+		JOIN posts p ON p.id = c.post_id
+		WHERE p.user_id = ? AND p.status = 'published' AND c.user_id != ?
+			AND c.created_at >= ? AND c.created_at < ?
+		GROUP BY 1
+	`, userID, userID, start, end)
+	if err != nil {
+		return stats, err
+	}
+	for rows.Next() {
+		var date string
+		var count int
+		if err := rows.Scan(&date, &count); err != nil {
+			rows.Close()
+			return stats, err
+		}
+		if day, ok := days[date]; ok {
+			day.Comments += count
+			stats.Comments += count
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return stats, err
+	}
+
+	stats.TopPosts, err = topPosts(db, userID, start, end, top)
+	return stats, err
+}
+
+// topPosts returns userID's published posts that received the most
+// reactions, comments and replies from others between start and end; ties go
+// to the one with more reactions
+func topPosts(db *sql.DB, userID, start, end string, limit int) ([]models.PostStats, error) {
+	rows, err := db.Query(`
+		WITH activity AS (
+			SELECT post_id, 1 AS reactions, 0 AS comments
+			FROM likes
+			WHERE post_id IS NOT NULL AND user_id != ? AND created_at >= ? AND created_at < ?
+			UNION ALL
+			SELECT post_id, 0, 1
+			FROM (`+postComments+`)
+			WHERE user_id != ? AND created_at >= ? AND created_at < ?
+		)
+		SELECT p.id, p.title, SUM(a.reactions), SUM(a.comments)
+		FROM activity a
+		JOIN posts p ON p.id = a.post_id
+		WHERE p.user_id = ? AND p.status = 'published'
+		GROUP BY p.id
+		ORDER BY SUM(a.reactions) + SUM(a.comments) DESC, SUM(a.reactions) DESC, p.id DESC
+		LIMIT ?
+	`, userID, start, end, userID, start, end, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := []models.PostStats{}
+	for rows.Next() {
+		var post models.PostStats
+		if err := rows.Scan(&post.ID, &post.Title, &post.ReactionCount, &post.CommentCount); err != nil {
+			return nil, err
+		}
+		posts = append(posts, post)
+	}
+	return posts, rows.Err()
+}
@@ -0,0 +1,93 @@
+package sqlite
+
+import (
+	"testing"
+	"time"
+
+	"forum/models"
+)
+
+func TestGetAuthorStats(t *testing.T) {
+	db := setupSchemaTestDB(t)
+	alice := createTestUser(t, db, "alice")
+	bob := createTestUser(t, db, "bob")
+
+	first, err := CreatePost(db, alice, nil, "First", "one", "")
+	if err != nil {
+		t.Fatalf("CreatePost failed: %v", err)
+	}
+	second, err := CreatePost(db, alice, nil, "Second", "two", "")
+	if err != nil {
+		t.Fatalf("CreatePost failed: %v", err)
+	}
+	comment, err := CreateComment(db, bob, second.ID, "nice")
+	if err != nil {
+		t.Fatalf("CreateComment failed: %v", err)
+	}
+	aliceComment, err := CreateComment(db, alice, second.ID, "thanks")
+	if err != nil {
+		t.Fatalf("CreateComment failed: %v", err)
+	}
+	reply, err := CreateReplyComment(db, bob, aliceComment.ID, "welcome")
+	if err != nil {
+		t.Fatalf("CreateReplyComment failed: %v", err)
+	}
+	draft, err := CreatePostWithDetails(db, alice, "Draft", "three", models.PostDetails{Status: PostDraft})
+	if err != nil {
+		t.Fatalf("CreatePostWithDetails failed: %v", err)
+	}
+
+	// Back-date activity onto fixed days
+	_, err = db.Exec(`
+		INSERT INTO likes (user_id, post_id, type, created_at) VALUES
+			(?, ?, 'like', '2024-03-01 10:00:00'),
+			(?, ?, 'love', '2024-03-01 23:59:59'),
+			(?, ?, 'like', '2024-03-02 08:00:00'),
+			(?, ?, 'like', '2024-03-01 12:00:00'),
+			(?, ?, 'eyes', '2024-02-28 12:00:00');
+		INSERT INTO likes (user_id, comment_id, type, created_at) VALUES (?, ?, 'laugh', '2024-03-03 09:00:00');
+		UPDATE comments SET created_at = '2024-03-02 15:00:00' WHERE id IN (?, ?);
+		UPDATE replycomments SET created_at = '2024-03-02 16:00:00' WHERE id = ?;
+		INSERT INTO likes (user_id, post_id, type, created_at) VALUES (?, ?, 'like', '2024-03-01 11:00:00');
+		INSERT INTO comments (user_id, post_id, content, created_at) VALUES (?, ?, 'early', '2024-03-02 11:00:00');
+	`, bob, first.ID, bob, first.ID, bob, second.ID, alice, first.ID, bob, second.ID,
+		bob, aliceComment.ID, comment.ID, aliceComment.ID, reply.ID, bob, draft.ID, bob, draft.ID)
+	if err != nil {
+		t.Fatalf("Failed to insert activity: %v", err)
+	}
+
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 3, 3, 0, 0, 0, 0, time.UTC)
+	stats, err := GetAuthorStats(db, alice, from, to, 5)
+	if err != nil {
+		t.Fatalf("GetAuthorStats failed: %v", err)
+	}
+
+	if stats.From != "2024-03-01" || stats.To != "2024-03-03" || len(stats.Days) != 3 {
+		t.Fatalf("Unexpected range: %s to %s with %d days", stats.From, stats.To, len(stats.Days))
+	}
+	// Alice's own like, the like from before the range and the activity on
+	// her draft are left out
+	if stats.ReactionTotal != 4 || stats.Reactions["like"] != 2 || stats.Reactions["love"] != 1 || stats.Reactions["laugh"] != 1 {
+		t.Errorf("Unexpected reaction totals: %d %v", stats.ReactionTotal, stats.Reactions)
+	}
+	if day := stats.Days[0]; day.ReactionTotal != 2 || day.Comments != 0 {
+		t.Errorf("Unexpected first day: %+v", day)
+	}
+	if day := stats.Days[1]; day.ReactionTotal != 1 || day.Comments != 2 {
+		t.Errorf("Unexpected second day: %+v", day)
+	}
+	if stats.Comments != 2 {
+		t.Errorf("Expected a comment and a reply from others, got %d", stats.Comments)
+	}
+
+	if len(stats.TopPosts) != 2 {
+		t.Fatalf("Expected 2 top posts, got %+v", stats.TopPosts)
+	}
+	if top := stats.TopPosts[0]; top.ID != second.ID || top.ReactionCount != 1 || top.CommentCount != 2 {
+		t.Errorf("Unexpected top post: %+v", top)
+	}
+	if next := stats.TopPosts[1]; next.ID != first.ID || next.ReactionCount != 2 || next.CommentCount != 0 {
+		t.Errorf("Unexpected second post: %+v", next)
+	}
+}
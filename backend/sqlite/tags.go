@@ -0,0 +1,272 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"forum/models"
+)
+
+const (
+	// MaxTagLength is the longest tag name allowed, in characters
+	MaxTagLength = 30
+	// MaxTagsPerPost is the most tags a post may have
+	MaxTagsPerPost = 5
+)
+
+// ErrInvalidTag is wrapped by NormalizeTag and NormalizeTags with the reason a tag was rejected
+var ErrInvalidTag = errors.New("invalid tag")
+
+// NormalizeTag cleans up a user-entered tag: a leading '#' is dropped, the
+// rest is lower-cased and runs of spaces become a single hyphen. Tags may
+// only contain letters, digits and "-+._". A blank tag normalizes to "".
+func NormalizeTag(tag string) (string, error) {
+	tag = strings.Join(strings.Fields(strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))), "-")
+	for _, r := range tag {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("-+._", r) {
+			return "", fmt.Errorf("%w %q: only letters, digits and -+._ are allowed", ErrInvalidTag, tag)
+		}
+	}
+	if len([]rune(tag)) > MaxTagLength {
+		return "", fmt.Errorf("%w %q: tags are at most %d characters", ErrInvalidTag, tag, MaxTagLength)
+	}
+	return tag, nil
+}
+
+// NormalizeTags normalizes the tags of a post, dropping blanks and
+// duplicates, and returns them sorted
+func NormalizeTags(raw []string) ([]string, error) {
+	seen := make(map[string]bool)
+	tags := []string{}
+	for _, tag := range raw {
+		tag, err := NormalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	if len(tags) > MaxTagsPerPost {
+		return nil, fmt.Errorf("%w: a post can have at most %d tags", ErrInvalidTag, MaxTagsPerPost)
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
+
+// resolveTagName follows the synonym of a merged tag to the tag it was
+// merged into
+func resolveTagName(q interface {
+	QueryRow(string, ...any) *sql.Row
+}, name string) (string, error) {
+	var target string
+	err := q.QueryRow(`
+		SELECT t.name FROM tag_synonyms s JOIN tags t ON t.id = s.tag_id WHERE s.name = ?
+	`, name).Scan(&target)
+	if errors.Is(err, sql.ErrNoRows) {
+		return name, nil
+	}
+	return target, err
+}
+
+// SetPostTags replaces the tags of a post. Tags must already be normalized;
+// unknown tags are created and merged tags are stored under their target.
+func SetPostTags(db *sql.DB, postID int, tags []string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := setPostTags(tx, postID, tags); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// setPostTags replaces the tags of a post within tx
+func setPostTags(tx *sql.Tx, postID int, tags []string) error {
+	if _, err := tx.Exec(`DELETE FROM post_tags WHERE post_id = ?`, postID); err != nil {
+		return err
+	}
+	for _, name := range tags {
+		name, err := resolveTagName(tx, name)
+		if err != nil {
+			return err
+		}
+		var tagID int
+		err = tx.QueryRow(`
+			INSERT INTO tags (name) VALUES (?)
+			ON CONFLICT (name) DO UPDATE SET name = excluded.name
+			RETURNING id
+		`, name).Scan(&tagID)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO post_tags (post_id, tag_id) VALUES (?, ?)`, postID, tagID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getPostTags returns the tag names of each post, sorted
+func getPostTags(db *sql.DB, postIDs []any) (map[int][]string, error) {
+	tags := make(map[int][]string)
+	if len(postIDs) == 0 {
+		return tags, nil
+	}
+
+	placeholders := make([]string, len(postIDs))
+	for i := range placeholders {
+		placeholders[i] = "?"
+	}
+	rows, err := db.Query(fmt.Sprintf(`
+		SELECT pt.post_id, t.name
+		FROM post_tags pt
+		JOIN tags t ON t.id = pt.tag_id
+		WHERE pt.post_id IN (%s)
+		ORDER BY t.name
+	`, strings.Join(placeholders, ",")), postIDs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var postID int
+		var name string
+		if err := rows.Scan(&postID, &name); err != nil {
+			return nil, err
+		}
+		tags[postID] = append(tags[postID], name)
+	}
+	return tags, rows.Err()
+}
+
+// taggedWith returns a condition matching posts that have every one of the
+// given tags, and its arguments. Merged tag names match their target.
+func taggedWith(db *sql.DB, tags []string) (string, []any, error) {
+	names := make(map[string]bool)
+	for _, tag := range tags {
+		name, err := resolveTagName(db, tag)
+		if err != nil {
+			return "", nil, err
+		}
+		names[name] = true
+	}
+
+	placeholders := make([]string, 0, len(names))
+	args := make([]any, 0, len(names)+1)
+	for name := range names {
+		placeholders = append(placeholders, "?")
+		args = append(args, name)
+	}
+	args = append(args, len(names))
+	return fmt.Sprintf(`posts.id IN (
+		SELECT pt.post_id FROM post_tags pt JOIN tags t ON t.id = pt.tag_id
+		WHERE t.name IN (%s)
+		GROUP BY pt.post_id
+		HAVING COUNT(*) = ?
+	)`, strings.Join(placeholders, ",")), args, nil
+}
+
+// SearchTags returns the most used tags starting with prefix, for
+// autocompletion
+func SearchTags(db *sql.DB, prefix string, limit int) ([]models.Tag, error) {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(prefix)
+	return queryTags(db, `
+		SELECT t.id, t.name, COUNT(pt.post_id) AS post_count
+		FROM tags t
+		LEFT JOIN post_tags pt ON pt.tag_id = t.id
+			AND pt.post_id IN (SELECT id FROM posts WHERE status = 'published')
+		WHERE t.name LIKE ? ESCAPE '\'
+		GROUP BY t.id
+		ORDER BY post_count DESC, t.name
+		LIMIT ?
+	`, escaped+"%", limit)
+}
+
+// GetTagCloud returns the most used tags with their usage counts
+func GetTagCloud(db *sql.DB, limit int) ([]models.Tag, error) {
+	return queryTags(db, `
+		SELECT t.id, t.name, COUNT(*) AS post_count
+		FROM tags t
+		JOIN post_tags pt ON pt.tag_id = t.id
+		JOIN posts p ON p.id = pt.post_id AND p.status = 'published'
+		GROUP BY t.id
+		ORDER BY post_count DESC, t.name
+		LIMIT ?
+	`, limit)
+}
+
+func queryTags(db *sql.DB, query string, args ...any) ([]models.Tag, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []models.Tag{}
+	for rows.Next() {
+		var tag models.Tag
+		if err := rows.Scan(&tag.ID, &tag.Name, &tag.PostCount); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// MergeTags makes source a synonym of target: its posts are retagged with
+// target, the source tag is deleted and later uses of its name resolve to
+// target. Returns sql.ErrNoRows if either tag does not exist.
+func MergeTags(db *sql.DB, source, target string) (models.Tag, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return models.Tag{}, err
+	}
+	defer tx.Rollback()
+
+	var sourceID int
+	var merged models.Tag
+	if err := tx.QueryRow(`SELECT id FROM tags WHERE name = ?`, source).Scan(&sourceID); err != nil {
+		return models.Tag{}, err
+	}
+	if err := tx.QueryRow(`SELECT id, name FROM tags WHERE name = ?`, target).Scan(&merged.ID, &merged.Name); err != nil {
+		return models.Tag{}, err
+	}
+	_, err = tx.Exec(`
+		INSERT OR IGNORE INTO post_tags (post_id, tag_id)
+		SELECT post_id, ? FROM post_tags WHERE tag_id = ?
+	`, merged.ID, sourceID)
+	if err != nil {
+		return models.Tag{}, err
+	}
+	// Earlier synonyms of the source follow it to the target
+	if _, err := tx.Exec(`UPDATE tag_synonyms SET tag_id = ? WHERE tag_id = ?`, merged.ID, sourceID); err != nil {
+		return models.Tag{}, err
+	}
+	// Cleared by hand: foreign keys are not enforced on every pooled
+	// connection, so ON DELETE CASCADE cannot be relied on
+	if _, err := tx.Exec(`DELETE FROM post_tags WHERE tag_id = ?`, sourceID); err != nil {
+		return models.Tag{}, err
+	}
+	if _, err := tx.Exec(`DELETE FROM tags WHERE id = ?`, sourceID); err != nil {
+		return models.Tag{}, err
+	}
+	if _, err := tx.Exec(`INSERT OR REPLACE INTO tag_synonyms (name, tag_id) VALUES (?, ?)`, source, merged.ID); err != nil {
+		return models.Tag{}, err
+	}
+
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM post_tags WHERE tag_id = ?`, merged.ID).Scan(&merged.PostCount); err != nil {
+		return models.Tag{}, err
+	}
+	return merged, tx.Commit()
+}
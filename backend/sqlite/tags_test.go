@@ -0,0 +1,130 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	tags, err := NormalizeTags([]string{" #Go ", "web  dev", "go", "", "C++"})
+	if err != nil {
+		t.Fatalf("NormalizeTags failed: %v", err)
+	}
+	if strings.Join(tags, ",") != "c++,go,web-dev" {
+		t.Errorf("Unexpected tags: %v", tags)
+	}
+
+	for _, raw := range [][]string{
+		{"no/slashes"},
+		{strings.Repeat("a", MaxTagLength+1)},
+		{"a", "b", "c", "d", "e", "f"},
+	} {
+		if _, err := NormalizeTags(raw); !errors.Is(err, ErrInvalidTag) {
+			t.Errorf("Expected ErrInvalidTag for %v, got %v", raw, err)
+		}
+	}
+}
+
+func TestTags(t *testing.T) {
+	db := setupSchemaTestDB(t)
+	alice := createTestUser(t, db, "alice")
+
+	post := func(title string, tags ...string) int {
+		t.Helper()
+		p, err := CreatePost(db, alice, nil, title, "body", "")
+		if err != nil {
+			t.Fatalf("CreatePost failed: %v", err)
+		}
+		if err := SetPostTags(db, p.ID, tags); err != nil {
+			t.Fatalf("SetPostTags failed: %v", err)
+		}
+		return p.ID
+	}
+	first := post("First", "go", "web")
+	post("Second", "go")
+	post("Third", "golang", "web")
+
+	t.Run("posts carry their tags", func(t *testing.T) {
+		p, err := GetPost(db, first)
+		if err != nil || strings.Join(p.Tags, ",") != "go,web" {
+			t.Errorf("Unexpected tags %v (%v)", p.Tags, err)
+		}
+	})
+
+	t.Run("filtering by tags", func(t *testing.T) {
+		posts, err := GetPosts(db, "", PostFilter{Tags: []string{"go", "web"}}, 1, 10)
+		if err != nil {
+			t.Fatalf("GetPosts failed: %v", err)
+		}
+		if len(posts) != 1 || posts[0].ID != first {
+			t.Errorf("Expected only the first post, got %+v", posts)
+		}
+		if posts, _ := GetPosts(db, "", PostFilter{Tags: []string{"web"}}, 1, 10); len(posts) != 2 {
+			t.Errorf("Expected 2 posts tagged web, got %d", len(posts))
+		}
+	})
+
+	t.Run("autocomplete and cloud", func(t *testing.T) {
+		suggestions, err := SearchTags(db, "go", 10)
+		if err != nil {
+			t.Fatalf("SearchTags failed: %v", err)
+		}
+		if len(suggestions) != 2 || suggestions[0].Name != "go" || suggestions[0].PostCount != 2 || suggestions[1].Name != "golang" {
+			t.Errorf("Unexpected suggestions: %+v", suggestions)
+		}
+		if none, _ := SearchTags(db, "%", 10); len(none) != 0 {
+			t.Errorf("Expected LIKE wildcards to be escaped, got %+v", none)
+		}
+
+		cloud, err := GetTagCloud(db, 2)
+		if err != nil {
+			t.Fatalf("GetTagCloud failed: %v", err)
+		}
+		if len(cloud) != 2 || cloud[0].Name != "go" || cloud[1].Name != "web" {
+			t.Errorf("Unexpected cloud: %+v", cloud)
+		}
+	})
+
+	t.Run("merging makes a synonym", func(t *testing.T) {
+		var golangID int
+		if err := db.QueryRow(`SELECT id FROM tags WHERE name = 'golang'`).Scan(&golangID); err != nil {
+			t.Fatalf("Failed to find golang: %v", err)
+		}
+
+		// As on a pooled connection that never turned foreign keys on
+		if _, err := db.Exec(`PRAGMA foreign_keys = OFF`); err != nil {
+			t.Fatalf("Failed to disable foreign keys: %v", err)
+		}
+		merged, err := MergeTags(db, "golang", "go")
+		if err != nil {
+			t.Fatalf("MergeTags failed: %v", err)
+		}
+		if _, err := db.Exec(`PRAGMA foreign_keys = ON`); err != nil {
+			t.Fatalf("Failed to enable foreign keys: %v", err)
+		}
+		if n := countRows(t, db, `SELECT COUNT(*) FROM post_tags WHERE tag_id = ?`, golangID); n != 0 {
+			t.Errorf("Expected the source's post tags to be cleared, got %d", n)
+		}
+		if merged.Name != "go" || merged.PostCount != 3 {
+			t.Errorf("Unexpected merged tag: %+v", merged)
+		}
+		if n := countRows(t, db, `SELECT COUNT(*) FROM tags WHERE name = 'golang'`); n != 0 {
+			t.Errorf("Expected golang to be deleted, found %d", n)
+		}
+
+		// The old name now resolves to the target, for filters and new posts
+		if posts, _ := GetPosts(db, "", PostFilter{Tags: []string{"golang"}}, 1, 10); len(posts) != 3 {
+			t.Errorf("Expected golang to match the 3 go posts, got %d", len(posts))
+		}
+		id := post("Fourth", "golang")
+		if p, _ := GetPost(db, id); strings.Join(p.Tags, ",") != "go" {
+			t.Errorf("Expected the synonym to be stored as go, got %v", p.Tags)
+		}
+
+		if _, err := MergeTags(db, "missing", "go"); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("Expected sql.ErrNoRows, got %v", err)
+		}
+	})
+}
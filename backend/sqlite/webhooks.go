@@ -0,0 +1,279 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"forum/models"
+)
+
+// Webhook delivery statuses
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// CreateWebhook registers a webhook created by createdBy, secret included
+func CreateWebhook(db *sql.DB, createdBy string, hook models.Webhook) (models.Webhook, error) {
+	events, err := json.Marshal(hook.Events)
+	if err != nil {
+		return hook, err
+	}
+	err = db.QueryRow(`
+		INSERT INTO webhooks (url, secret, events, active, created_by) VALUES (?, ?, ?, ?, ?)
+		RETURNING id, created_at
+	`, hook.URL, hook.Secret, string(events), hook.Active, createdBy).Scan(&hook.ID, &hook.CreatedAt)
+	return hook, err
+}
+
+// GetWebhooks returns every webhook, oldest first, without their secrets
+func GetWebhooks(db *sql.DB) ([]models.Webhook, error) {
+	rows, err := db.Query(`SELECT id, url, events, active, created_at FROM webhooks ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hooks := []models.Webhook{}
+	for rows.Next() {
+		var hook models.Webhook
+		var events string
+		if err := rows.Scan(&hook.ID, &hook.URL, &events, &hook.Active, &hook.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(events), &hook.Events); err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks, rows.Err()
+}
+
+// UpdateWebhook changes a webhook's URL, events and whether it is active,
+// keeping its secret. Returns sql.ErrNoRows for an unknown webhook.
+func UpdateWebhook(db *sql.DB, hook models.Webhook) (models.Webhook, error) {
+	events, err := json.Marshal(hook.Events)
+	if err != nil {
+		return hook, err
+	}
+	err = db.QueryRow(`
+		UPDATE webhooks SET url = ?, events = ?, active = ? WHERE id = ?
+		RETURNING created_at
+	`, hook.URL, string(events), hook.Active, hook.ID).Scan(&hook.CreatedAt)
+	return hook, err
+}
+
+// DeleteWebhook removes a webhook with its deliveries. Returns sql.ErrNoRows
+// for an unknown webhook.
+func DeleteWebhook(db *sql.DB, id int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Cleared by hand: foreign keys are not enforced on every pooled
+	// connection, so ON DELETE CASCADE cannot be relied on
+	if _, err := tx.Exec(`DELETE FROM webhook_deliveries WHERE webhook_id = ?`, id); err != nil {
+		return err
+	}
+	result, err := tx.Exec(`DELETE FROM webhooks WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+	return tx.Commit()
+}
+
+// EnqueueWebhookEvent adds payload to the outbox of every active webhook
+// subscribed to event and returns how many deliveries it created
+func EnqueueWebhookEvent(db *sql.DB, event string, payload []byte) (int, error) {
+	result, err := db.Exec(`
+		INSERT INTO webhook_deliveries (webhook_id, event, payload, next_attempt_at)
+		SELECT id, ?1, ?2, ?3 FROM webhooks
+		WHERE active = 1 AND EXISTS (SELECT 1 FROM json_each(webhooks.events) WHERE value = ?1)
+	`, event, string(payload), sqliteTime(time.Now()))
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
+
+// deliveryColumns are the columns scanned by scanDelivery
+const deliveryColumns = `
+	d.id, d.webhook_id, w.url, w.secret, d.event, d.payload, d.status, d.attempts,
+	d.next_attempt_at, d.response_code, d.last_error, d.delivered_at, d.created_at
+`
+
+// scanDelivery reads a row of deliveryColumns
+func scanDelivery(row interface{ Scan(...any) error }) (models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	var payload string
+	var nextAttemptAt, deliveredAt sql.NullTime
+	var responseCode sql.NullInt64
+	err := row.Scan(&d.ID, &d.WebhookID, &d.URL, &d.Secret, &d.Event, &payload, &d.Status, &d.Attempts,
+		&nextAttemptAt, &responseCode, &d.LastError, &deliveredAt, &d.CreatedAt)
+	if err != nil {
+		return d, err
+	}
+	d.Payload = json.RawMessage(payload)
+	if d.Status == DeliveryPending {
+		d.NextAttemptAt = timePtr(nextAttemptAt)
+	}
+	d.ResponseCode = intPtr(responseCode)
+	d.DeliveredAt = timePtr(deliveredAt)
+	return d, nil
+}
+
+// queryDeliveries runs a query selecting deliveryColumns
+func queryDeliveries(db *sql.DB, query string, args ...any) ([]models.WebhookDelivery, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// ClaimDueDeliveries returns up to limit pending deliveries due at now, oldest
+// first, and pushes their next attempt back by lease so no other worker picks
+// them up while they are being sent. Pending deliveries of webhooks that were
+// deactivated or deleted are marked failed instead.
+func ClaimDueDeliveries(db *sql.DB, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error) {
+	_, err := db.Exec(`
+		UPDATE webhook_deliveries
+		SET status = 'failed',
+			last_error = CASE WHEN webhook_id IN (SELECT id FROM webhooks) THEN 'webhook is inactive' ELSE 'webhook was deleted' END
+		WHERE status = 'pending' AND webhook_id NOT IN (SELECT id FROM webhooks WHERE active)
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`
+		UPDATE webhook_deliveries SET next_attempt_at = ?
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= ?
+				AND webhook_id IN (SELECT id FROM webhooks WHERE active)
+			ORDER BY next_attempt_at, id
+			LIMIT ?
+		)
+		RETURNING id
+	`, sqliteTime(now.Add(lease)), sqliteTime(now), limit)
+	if err != nil {
+		return nil, err
+	}
+	var ids []any
+	var placeholders []string
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+		placeholders = append(placeholders, "?")
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return []models.WebhookDelivery{}, nil
+	}
+
+	return queryDeliveries(db, fmt.Sprintf(`
+		SELECT `+deliveryColumns+`
+		FROM webhook_deliveries d
+		JOIN webhooks w ON w.id = d.webhook_id
+		WHERE d.id IN (%s)
+		ORDER BY d.id
+	`, strings.Join(placeholders, ",")), ids...)
+}
+
+// MarkDelivered records a successful attempt at a delivery
+func MarkDelivered(db *sql.DB, id, responseCode int, at time.Time) error {
+	_, err := db.Exec(`
+		UPDATE webhook_deliveries
+		SET status = 'delivered', attempts = attempts + 1, response_code = ?, last_error = '', delivered_at = ?
+		WHERE id = ?
+	`, responseCode, sqliteTime(at), id)
+	return err
+}
+
+// MarkAttemptFailed records a failed attempt at a delivery. It is tried again
+// at retryAt, or given up on if retryAt is nil. responseCode is 0 if the
+// receiver did not answer.
+func MarkAttemptFailed(db *sql.DB, id, responseCode int, reason string, retryAt *time.Time) error {
+	var code any
+	if responseCode != 0 {
+		code = responseCode
+	}
+	status, next := DeliveryFailed, any(nil)
+	if retryAt != nil {
+		status, next = DeliveryPending, sqliteTime(*retryAt)
+	}
+	_, err := db.Exec(`
+		UPDATE webhook_deliveries
+		SET status = ?, attempts = attempts + 1, response_code = ?, last_error = ?,
+			next_attempt_at = COALESCE(?, next_attempt_at)
+		WHERE id = ?
+	`, status, code, reason, next, id)
+	return err
+}
+
+// RetryDelivery sends a failed delivery again as soon as the worker runs,
+// with a fresh count of attempts. Returns sql.ErrNoRows unless the delivery
+// exists and has failed.
+func RetryDelivery(db *sql.DB, id int) error {
+	result, err := db.Exec(`
+		UPDATE webhook_deliveries SET status = 'pending', attempts = 0, next_attempt_at = ?
+		WHERE id = ? AND status = 'failed'
+	`, sqliteTime(time.Now()), id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetWebhookDeliveries returns a page of a webhook's delivery log, newest
+// first, optionally only those with the given status. Returns sql.ErrNoRows
+// for an unknown webhook.
+func GetWebhookDeliveries(db *sql.DB, webhookID int, status string, page, limit int) ([]models.WebhookDelivery, error) {
+	var exists bool
+	if err := db.QueryRow(`SELECT 1 FROM webhooks WHERE id = ?`, webhookID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	return queryDeliveries(db, `
+		SELECT `+deliveryColumns+`
+		FROM webhook_deliveries d
+		JOIN webhooks w ON w.id = d.webhook_id
+		WHERE d.webhook_id = ? AND (? = '' OR d.status = ?)
+		ORDER BY d.id DESC
+		LIMIT ? OFFSET ?
+	`, webhookID, status, status, limit, (page-1)*limit)
+}
@@ -0,0 +1,164 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"forum/models"
+)
+
+func TestWebhooks(t *testing.T) {
+	db := setupSchemaTestDB(t)
+	admin := createTestUser(t, db, "admin")
+
+	posts, err := CreateWebhook(db, admin, models.Webhook{URL: "http://example.com/posts", Secret: "a", Events: []string{"post.created"}, Active: true})
+	if err != nil {
+		t.Fatalf("CreateWebhook failed: %v", err)
+	}
+	both, err := CreateWebhook(db, admin, models.Webhook{URL: "http://example.com/both", Secret: "b", Events: []string{"post.created", "comment.created"}, Active: true})
+	if err != nil {
+		t.Fatalf("CreateWebhook failed: %v", err)
+	}
+	paused, err := CreateWebhook(db, admin, models.Webhook{URL: "http://example.com/paused", Secret: "c", Events: []string{"post.created"}})
+	if err != nil {
+		t.Fatalf("CreateWebhook failed: %v", err)
+	}
+
+	t.Run("list and update", func(t *testing.T) {
+		hooks, err := GetWebhooks(db)
+		if err != nil {
+			t.Fatalf("GetWebhooks failed: %v", err)
+		}
+		if len(hooks) != 3 || hooks[1].URL != both.URL || !reflect.DeepEqual(hooks[1].Events, both.Events) {
+			t.Fatalf("Unexpected webhooks: %+v", hooks)
+		}
+		for _, hook := range hooks {
+			if hook.Secret != "" {
+				t.Errorf("Expected secrets to stay out of the list, got %q", hook.Secret)
+			}
+		}
+
+		updated := posts
+		updated.URL = "http://example.com/new"
+		if _, err := UpdateWebhook(db, updated); err != nil {
+			t.Fatalf("UpdateWebhook failed: %v", err)
+		}
+		if hooks, _ := GetWebhooks(db); hooks[0].URL != "http://example.com/new" {
+			t.Errorf("Expected the URL to change, got %q", hooks[0].URL)
+		}
+		if _, err := UpdateWebhook(db, models.Webhook{ID: 9999, Events: []string{}}); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("Expected sql.ErrNoRows for an unknown webhook, got %v", err)
+		}
+	})
+
+	t.Run("enqueue matches active subscriptions", func(t *testing.T) {
+		n, err := EnqueueWebhookEvent(db, "post.created", []byte(`{"id":1}`))
+		if err != nil || n != 2 {
+			t.Fatalf("Expected 2 deliveries, got %d, %v", n, err)
+		}
+		if n, _ := EnqueueWebhookEvent(db, "comment.created", []byte(`{"id":2}`)); n != 1 {
+			t.Errorf("Expected 1 delivery, got %d", n)
+		}
+		if n, _ := EnqueueWebhookEvent(db, "user.registered", []byte(`{}`)); n != 0 {
+			t.Errorf("Expected no deliveries, got %d", n)
+		}
+		if count := countRows(t, db, `SELECT COUNT(*) FROM webhook_deliveries WHERE webhook_id = ?`, paused.ID); count != 0 {
+			t.Errorf("Expected nothing queued for an inactive webhook, got %d", count)
+		}
+	})
+
+	t.Run("claims are leased", func(t *testing.T) {
+		now := time.Now()
+		claimed, err := ClaimDueDeliveries(db, now, time.Minute, 2)
+		if err != nil {
+			t.Fatalf("ClaimDueDeliveries failed: %v", err)
+		}
+		if len(claimed) != 2 || claimed[0].Secret == "" || string(claimed[0].Payload) != `{"id":1}` {
+			t.Fatalf("Unexpected claim: %+v", claimed)
+		}
+		rest, err := ClaimDueDeliveries(db, now, time.Minute, 10)
+		if err != nil || len(rest) != 1 {
+			t.Fatalf("Expected only the unclaimed delivery, got %d, %v", len(rest), err)
+		}
+		if again, _ := ClaimDueDeliveries(db, now.Add(2*time.Minute), time.Minute, 10); len(again) != 3 {
+			t.Errorf("Expected expired leases to be claimed again, got %d", len(again))
+		}
+
+		if err := MarkDelivered(db, claimed[0].ID, 200, now); err != nil {
+			t.Fatalf("MarkDelivered failed: %v", err)
+		}
+		if err := MarkAttemptFailed(db, claimed[1].ID, 0, "connection refused", nil); err != nil {
+			t.Fatalf("MarkAttemptFailed failed: %v", err)
+		}
+	})
+
+	t.Run("delivery log and retries", func(t *testing.T) {
+		failed, err := GetWebhookDeliveries(db, both.ID, DeliveryFailed, 1, 10)
+		if err != nil {
+			t.Fatalf("GetWebhookDeliveries failed: %v", err)
+		}
+		if len(failed) != 1 || failed[0].LastError != "connection refused" || failed[0].Attempts != 1 || failed[0].NextAttemptAt != nil {
+			t.Fatalf("Unexpected failed deliveries: %+v", failed)
+		}
+		if err := RetryDelivery(db, failed[0].ID); err != nil {
+			t.Fatalf("RetryDelivery failed: %v", err)
+		}
+		if err := RetryDelivery(db, failed[0].ID); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("Expected sql.ErrNoRows retrying a pending delivery, got %v", err)
+		}
+		if all, _ := GetWebhookDeliveries(db, both.ID, "", 1, 10); len(all) != 2 || all[0].Status != DeliveryPending || all[0].Attempts != 0 {
+			t.Errorf("Expected both deliveries, newest first, the retried one without attempts, got %+v", all)
+		}
+	})
+
+	t.Run("deliveries of inactive or deleted webhooks fail", func(t *testing.T) {
+		// As on a pooled connection that never turned foreign keys on
+		if _, err := db.Exec(`PRAGMA foreign_keys = OFF`); err != nil {
+			t.Fatalf("Failed to disable foreign keys: %v", err)
+		}
+		defer db.Exec(`PRAGMA foreign_keys = ON`)
+
+		_, err := db.Exec(`INSERT INTO webhook_deliveries (webhook_id, event, payload) VALUES (?, 'post.created', '{}'), (9999, 'post.created', '{}')`, paused.ID)
+		if err != nil {
+			t.Fatalf("Failed to queue deliveries: %v", err)
+		}
+		claimed, err := ClaimDueDeliveries(db, time.Now().Add(time.Hour), time.Minute, 10)
+		if err != nil {
+			t.Fatalf("ClaimDueDeliveries failed: %v", err)
+		}
+		for _, d := range claimed {
+			if d.WebhookID == paused.ID {
+				t.Errorf("Expected no claims for an inactive webhook, got %+v", d)
+			}
+		}
+		if log, _ := GetWebhookDeliveries(db, paused.ID, DeliveryFailed, 1, 10); len(log) != 1 || log[0].LastError != "webhook is inactive" {
+			t.Errorf("Expected the inactive webhook's delivery to fail, got %+v", log)
+		}
+		if count := countRows(t, db, `SELECT COUNT(*) FROM webhook_deliveries WHERE webhook_id = 9999 AND status = 'failed' AND last_error = 'webhook was deleted'`); count != 1 {
+			t.Errorf("Expected the orphaned delivery to fail, got %d", count)
+		}
+	})
+
+	t.Run("delete removes the log", func(t *testing.T) {
+		if _, err := db.Exec(`PRAGMA foreign_keys = OFF`); err != nil {
+			t.Fatalf("Failed to disable foreign keys: %v", err)
+		}
+		defer db.Exec(`PRAGMA foreign_keys = ON`)
+
+		if err := DeleteWebhook(db, both.ID); err != nil {
+			t.Fatalf("DeleteWebhook failed: %v", err)
+		}
+		if count := countRows(t, db, `SELECT COUNT(*) FROM webhook_deliveries WHERE webhook_id = ?`, both.ID); count != 0 {
+			t.Errorf("Expected the deliveries to be deleted, got %d", count)
+		}
+		if err := DeleteWebhook(db, both.ID); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("Expected sql.ErrNoRows deleting twice, got %v", err)
+		}
+		if _, err := GetWebhookDeliveries(db, both.ID, "", 1, 10); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("Expected sql.ErrNoRows for the log of a deleted webhook, got %v", err)
+		}
+	})
+}
@@ -117,6 +117,21 @@ func GetPaginationParams(r *http.Request) (int, int) {
 
 // ValidateAndSanitizeString validates and sanitizes string input
 func ValidateAndSanitizeString(input string, maxLength int, fieldName string) (string, error) {
+	validated, err := ValidateContent(input, maxLength, fieldName)
+	if err != nil {
+		return "", err
+	}
+
+	// HTML escape to prevent XSS
+	sanitized := html.EscapeString(validated)
+
+	return sanitized, nil
+}
+
+// ValidateContent validates user-authored Markdown (post and comment bodies)
+// without escaping it. The raw text is stored as typed and rendered to safe
+// HTML on the way out, see markdown.Render.
+func ValidateContent(input string, maxLength int, fieldName string) (string, error) {
 	// Check for null bytes (potential for SQL injection bypass)
 	if strings.Contains(input, "\x00") {
 		return "", fmt.Errorf("%s contains invalid characters", fieldName)
@@ -139,10 +154,7 @@ func ValidateAndSanitizeString(input string, maxLength int, fieldName string) (s
 		return "", fmt.Errorf("%s contains invalid UTF-8 characters", fieldName)
 	}
 
-	// HTML escape to prevent XSS
-	sanitized := html.EscapeString(input)
-
-	return sanitized, nil
+	return input, nil
 }
 
 // ValidateEmail validates email format
@@ -200,7 +212,7 @@ func ValidatePostContent(title, content string) error {
 		return err
 	}
 
-	if _, err := ValidateAndSanitizeString(content, 10000, "content"); err != nil {
+	if _, err := ValidateContent(content, 10000, "content"); err != nil {
 		return err
 	}
 
@@ -209,7 +221,7 @@ func ValidatePostContent(title, content string) error {
 
 // ValidateCommentContent validates comment content
 func ValidateCommentContent(content string) error {
-	if _, err := ValidateAndSanitizeString(content, 2000, "comment"); err != nil {
+	if _, err := ValidateContent(content, 2000, "comment"); err != nil {
 		return err
 	}
 
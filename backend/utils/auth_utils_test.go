@@ -71,6 +71,36 @@ func TestValidateAndSanitizeString(t *testing.T) {
 	}
 }
 
+func TestValidateContent(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expectError bool
+		expected    string
+	}{
+		{"markdown kept raw", "*a* & <b>", false, "*a* & <b>"},
+		{"trimmed", "  text  ", false, "text"},
+		{"empty", "   ", true, ""},
+		{"too long", "verylongstring", true, ""},
+		{"null byte", "te\x00st", true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ValidateContent(tt.input, 10, "content")
+			if tt.expectError && err == nil {
+				t.Fatalf("Expected error but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !tt.expectError && result != tt.expected {
+				t.Fatalf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
 func TestValidateEmail(t *testing.T) {
 	tests := []struct {
 		email string
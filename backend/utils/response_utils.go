@@ -3,6 +3,9 @@ package utils
 import (
 	"encoding/json"
 	"net/http"
+	"time"
+
+	"forum/models"
 )
 
 // JSONResponse sends a JSON response with the given status code and data
@@ -31,3 +34,13 @@ func SendJSONError(w http.ResponseWriter, message string, statusCode int) {
 func SendJSONResponse(w http.ResponseWriter, data any, statusCode int) {
 	JSONResponse(w, statusCode, data)
 }
+
+// SendBanError tells a banned or suspended user why they were turned away,
+// and until when for a suspension
+func SendBanError(w http.ResponseWriter, ban *models.Ban) {
+	message := "This account has been banned"
+	if ban.Until != nil {
+		message = "This account is suspended until " + ban.Until.UTC().Format(time.RFC1123)
+	}
+	JSONResponse(w, http.StatusForbidden, map[string]any{"error": message, "reason": ban.Reason, "until": ban.Until})
+}
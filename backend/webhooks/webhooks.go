@@ -0,0 +1,184 @@
+// Package webhooks sends forum events to the URLs admins register. Events
+// are written to an outbox table as they happen; a Worker delivers them in
+// the background as HMAC-signed JSON, retrying failures with exponential
+// backoff. Delivered and failed entries stay in the table as the delivery
+// log.
+package webhooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"forum/sqlite"
+)
+
+// Event types webhooks can subscribe to
+const (
+	PostCreated     = "post.created"
+	CommentCreated  = "comment.created" // comments and replies
+	ReactionToggled = "reaction.toggled"
+	UserRegistered  = "user.registered"
+)
+
+// Events lists every event type
+var Events = []string{PostCreated, CommentCreated, ReactionToggled, UserRegistered}
+
+// ValidEvent reports whether event is a known event type
+func ValidEvent(event string) bool {
+	for _, e := range Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Payload is the JSON body of a delivery
+type Payload struct {
+	Event     string    `json:"event"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// Enqueue adds event with data to the outbox of every webhook subscribed to
+// it. The payload is fixed now, so later edits do not change what is sent.
+func Enqueue(db *sql.DB, event string, data any) error {
+	payload, err := json.Marshal(Payload{Event: event, CreatedAt: time.Now().UTC(), Data: data})
+	if err != nil {
+		return err
+	}
+	_, err = sqlite.EnqueueWebhookEvent(db, event, payload)
+	return err
+}
+
+// Headers sent with every delivery. The signature is "sha256=" followed by
+// the hex HMAC-SHA256 of "<timestamp>.<body>" keyed with the webhook's
+// secret; receivers should recompute it and reject old timestamps.
+const (
+	HeaderEvent     = "X-Forum-Event"
+	HeaderDelivery  = "X-Forum-Delivery"
+	HeaderTimestamp = "X-Forum-Timestamp"
+	HeaderSignature = "X-Forum-Signature"
+)
+
+// Sign returns the signature header of a body sent at timestamp (Unix
+// seconds)
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is a valid signature of body sent at
+// timestamp, for receivers written in Go
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// Worker delivers the outbox. Deliveries that fail are tried again after
+// BaseDelay, doubling each time up to MaxDelay, and given up on after
+// MaxAttempts.
+type Worker struct {
+	DB          *sql.DB
+	Client      *http.Client
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	BatchSize   int              // deliveries claimed per run
+	Now         func() time.Time // the clock, replaceable in tests
+}
+
+// NewWorker creates a worker with the default timeouts and retry policy
+func NewWorker(db *sql.DB) *Worker {
+	return &Worker{
+		DB:          db,
+		Client:      &http.Client{Timeout: 10 * time.Second},
+		MaxAttempts: 8,
+		BaseDelay:   30 * time.Second,
+		MaxDelay:    6 * time.Hour,
+		BatchSize:   20,
+		Now:         time.Now,
+	}
+}
+
+// claimLease is how long a claimed delivery is kept from other workers
+const claimLease = 5 * time.Minute
+
+// Backoff returns how long to wait after the given failed attempt (1 for
+// the first) before trying again
+func (w *Worker) Backoff(attempt int) time.Duration {
+	delay := w.BaseDelay
+	for i := 1; i < attempt && delay < w.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, w.MaxDelay)
+}
+
+// RunOnce sends the deliveries that are due and returns how many succeeded
+func (w *Worker) RunOnce() (int, error) {
+	deliveries, err := sqlite.ClaimDueDeliveries(w.DB, w.Now(), claimLease, w.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, d := range deliveries {
+		code, err := w.send(d.ID, d.URL, d.Secret, d.Event, d.Payload)
+		if err == nil {
+			if err := sqlite.MarkDelivered(w.DB, d.ID, code, w.Now()); err != nil {
+				return delivered, err
+			}
+			delivered++
+			continue
+		}
+
+		attempt := d.Attempts + 1
+		var retryAt *time.Time
+		if attempt < w.MaxAttempts {
+			at := w.Now().Add(w.Backoff(attempt))
+			retryAt = &at
+		}
+		if err := sqlite.MarkAttemptFailed(w.DB, d.ID, code, err.Error(), retryAt); err != nil {
+			return delivered, err
+		}
+	}
+	return delivered, nil
+}
+
+// send posts one signed delivery. Any 2xx answer is a success; code is 0 if
+// the receiver could not be reached.
+func (w *Worker) send(id int, url, secret, event string, body []byte) (code int, err error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := w.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "forum-webhooks/1")
+	req.Header.Set(HeaderEvent, event)
+	req.Header.Set(HeaderDelivery, strconv.Itoa(id))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(secret, timestamp, body))
+
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver answered %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
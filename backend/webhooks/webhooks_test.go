@@ -0,0 +1,229 @@
+package webhooks
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"forum/models"
+	"forum/sqlite"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func setupTestDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	db.SetMaxOpenConns(1) // each :memory: connection is its own database
+	t.Cleanup(func() { db.Close() })
+
+	schemaSQL, err := os.ReadFile("../schema.sql")
+	if err != nil {
+		t.Fatalf("Failed to read schema.sql: %v", err)
+	}
+	if _, err := db.Exec(string(schemaSQL)); err != nil {
+		t.Fatalf("Failed to apply schema.sql: %v", err)
+	}
+	return db
+}
+
+// createWebhook registers url for events with the secret "s3cret"
+func createWebhook(t *testing.T, db *sql.DB, url string, events ...string) models.Webhook {
+	if err := sqlite.CreateUser(db, "admin", "admin@example.com", "hash", ""); err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	admin, err := sqlite.GetUserByUsername(db, "admin")
+	if err != nil {
+		t.Fatalf("GetUserByUsername failed: %v", err)
+	}
+	hook, err := sqlite.CreateWebhook(db, admin.ID, models.Webhook{URL: url, Secret: "s3cret", Events: events, Active: true})
+	if err != nil {
+		t.Fatalf("CreateWebhook failed: %v", err)
+	}
+	return hook
+}
+
+// testWorker returns a worker whose clock only moves when the returned
+// function is called
+func testWorker(db *sql.DB) (*Worker, func(time.Duration)) {
+	now := time.Now()
+	w := NewWorker(db)
+	w.Now = func() time.Time { return now }
+	return w, func(d time.Duration) { now = now.Add(d) }
+}
+
+func deliveries(t *testing.T, db *sql.DB, webhookID int) []models.WebhookDelivery {
+	list, err := sqlite.GetWebhookDeliveries(db, webhookID, "", 1, 50)
+	if err != nil {
+		t.Fatalf("GetWebhookDeliveries failed: %v", err)
+	}
+	return list
+}
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"event":"post.created"}`)
+	signature := Sign("s3cret", 1700000000, body)
+	if len(signature) != len("sha256=")+64 || signature[:7] != "sha256=" {
+		t.Fatalf("Unexpected signature %q", signature)
+	}
+	if !Verify("s3cret", 1700000000, body, signature) {
+		t.Error("Expected the signature to verify")
+	}
+	if Verify("other", 1700000000, body, signature) || Verify("s3cret", 1700000001, body, signature) {
+		t.Error("Expected a different secret or timestamp to fail")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	w := NewWorker(nil)
+	for attempt, want := range map[int]time.Duration{
+		1:  30 * time.Second,
+		2:  time.Minute,
+		5:  8 * time.Minute,
+		20: 6 * time.Hour,
+	} {
+		if got := w.Backoff(attempt); got != want {
+			t.Errorf("Backoff(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+}
+
+func TestWorkerDelivers(t *testing.T) {
+	db := setupTestDB(t)
+
+	var received []*http.Request
+	var body []byte
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		received = append(received, r)
+	}))
+	defer receiver.Close()
+
+	hook := createWebhook(t, db, receiver.URL, PostCreated)
+	if err := Enqueue(db, PostCreated, map[string]int{"id": 7}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	if err := Enqueue(db, UserRegistered, map[string]string{"username": "bob"}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+
+	w, _ := testWorker(db)
+	delivered, err := w.RunOnce()
+	if err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+	if delivered != 1 || len(received) != 1 {
+		t.Fatalf("Expected only the subscribed event to be sent, got %d deliveries and %d requests", delivered, len(received))
+	}
+
+	r := received[0]
+	timestamp, _ := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+	if !Verify("s3cret", timestamp, body, r.Header.Get(HeaderSignature)) {
+		t.Error("Expected a valid signature")
+	}
+	if r.Header.Get(HeaderEvent) != PostCreated || r.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("Unexpected headers: %v", r.Header)
+	}
+	var payload struct {
+		Event string         `json:"event"`
+		Data  map[string]int `json:"data"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil || payload.Event != PostCreated || payload.Data["id"] != 7 {
+		t.Errorf("Unexpected payload %s", body)
+	}
+
+	log := deliveries(t, db, hook.ID)
+	if len(log) != 1 || log[0].Status != sqlite.DeliveryDelivered || log[0].Attempts != 1 || log[0].DeliveredAt == nil {
+		t.Errorf("Expected a delivered entry in the log, got %+v", log)
+	}
+	if delivered, _ := w.RunOnce(); delivered != 0 || len(received) != 1 {
+		t.Error("Expected a delivered event not to be sent again")
+	}
+}
+
+func TestWorkerRetries(t *testing.T) {
+	db := setupTestDB(t)
+
+	failures := 1
+	requests := 0
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests <= failures {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+		}
+	}))
+	defer receiver.Close()
+
+	hook := createWebhook(t, db, receiver.URL, CommentCreated)
+	if err := Enqueue(db, CommentCreated, map[string]int{"id": 1}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+
+	w, advance := testWorker(db)
+	if delivered, err := w.RunOnce(); err != nil || delivered != 0 {
+		t.Fatalf("Expected the first attempt to fail, got %d, %v", delivered, err)
+	}
+	log := deliveries(t, db, hook.ID)
+	if log[0].Status != sqlite.DeliveryPending || log[0].Attempts != 1 || log[0].ResponseCode == nil || *log[0].ResponseCode != 503 {
+		t.Fatalf("Expected a pending retry after a 503, got %+v", log[0])
+	}
+
+	if w.RunOnce(); requests != 1 {
+		t.Error("Expected no retry before the backoff has passed")
+	}
+	advance(w.Backoff(1))
+	if delivered, err := w.RunOnce(); err != nil || delivered != 1 {
+		t.Fatalf("Expected the retry to succeed, got %d, %v", delivered, err)
+	}
+	if log := deliveries(t, db, hook.ID); log[0].Status != sqlite.DeliveryDelivered || log[0].Attempts != 2 {
+		t.Errorf("Expected delivered after 2 attempts, got %+v", log[0])
+	}
+}
+
+func TestWorkerGivesUp(t *testing.T) {
+	db := setupTestDB(t)
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	hook := createWebhook(t, db, receiver.URL, ReactionToggled)
+	if err := Enqueue(db, ReactionToggled, nil); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+
+	w, advance := testWorker(db)
+	w.MaxAttempts = 3
+	for attempt := 1; attempt <= 3; attempt++ {
+		if _, err := w.RunOnce(); err != nil {
+			t.Fatalf("RunOnce failed: %v", err)
+		}
+		advance(w.Backoff(attempt))
+	}
+
+	log := deliveries(t, db, hook.ID)
+	if log[0].Status != sqlite.DeliveryFailed || log[0].Attempts != 3 || log[0].NextAttemptAt != nil || log[0].LastError == "" {
+		t.Fatalf("Expected the delivery to fail after 3 attempts, got %+v", log[0])
+	}
+
+	// Unreachable receivers count as failures too
+	receiver.Close()
+	if err := sqlite.RetryDelivery(db, log[0].ID); err != nil {
+		t.Fatalf("RetryDelivery failed: %v", err)
+	}
+	if _, err := w.RunOnce(); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+	if log := deliveries(t, db, hook.ID); log[0].Status != sqlite.DeliveryPending || log[0].Attempts != 1 || log[0].ResponseCode != nil {
+		t.Errorf("Expected a retried delivery to get its attempts back, got %+v", log[0])
+	}
+}
@@ -0,0 +1,144 @@
+package websocket
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// echoServer echoes every message back until the client closes
+func echoServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.SetReadLimit(1024)
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(messageType, data); err != nil {
+				return
+			}
+		}
+	}))
+}
+
+func TestAcceptKey(t *testing.T) {
+	// Example from RFC 6455 section 1.3
+	if got := acceptKey("dGhlIHNhbXBsZSBub25jZQ=="); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("Unexpected accept key %q", got)
+	}
+}
+
+func TestUpgrade(t *testing.T) {
+	server := echoServer()
+	defer server.Close()
+
+	t.Run("rejects plain requests", func(t *testing.T) {
+		resp, err := http.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status 400, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("echoes messages", func(t *testing.T) {
+		conn, err := Dial(server.URL, nil)
+		if err != nil {
+			t.Fatalf("Dial failed: %v", err)
+		}
+		defer conn.Close()
+
+		tests := []struct {
+			messageType int
+			data        []byte
+		}{
+			{TextMessage, []byte("hello")},
+			{BinaryMessage, []byte{0, 1, 2}},
+			{TextMessage, []byte(strings.Repeat("x", 300))}, // 16-bit length
+			{TextMessage, []byte{}},
+		}
+		for _, tt := range tests {
+			if err := conn.WriteMessage(tt.messageType, tt.data); err != nil {
+				t.Fatalf("Write failed: %v", err)
+			}
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				t.Fatalf("Read failed: %v", err)
+			}
+			if messageType != tt.messageType || !bytes.Equal(data, tt.data) {
+				t.Errorf("Expected %d %q, got %d %q", tt.messageType, tt.data, messageType, data)
+			}
+		}
+	})
+
+	t.Run("reassembles fragments and answers pings", func(t *testing.T) {
+		conn, err := Dial(server.URL, nil)
+		if err != nil {
+			t.Fatalf("Dial failed: %v", err)
+		}
+		defer conn.Close()
+
+		// Build the fragments by hand: "hel" (not final), a ping, then "lo"
+		writeRaw := func(fin bool, op int, payload string) {
+			frame := []byte{byte(op), 0x80 | byte(len(payload)), 0, 0, 0, 0}
+			if fin {
+				frame[0] |= 0x80
+			}
+			frame = append(frame, payload...)
+			if _, err := conn.conn.Write(frame); err != nil {
+				t.Fatal(err)
+			}
+		}
+		writeRaw(false, TextMessage, "hel")
+		writeRaw(true, PingMessage, "p")
+		writeRaw(true, continuationFrame, "lo")
+
+		// The pong is skipped by ReadMessage; the echo is the whole message
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("Read failed: %v", err)
+		}
+		if string(data) != "hello" {
+			t.Errorf("Expected reassembled message, got %q", data)
+		}
+	})
+
+	t.Run("closes on oversized messages", func(t *testing.T) {
+		conn, err := Dial(server.URL, nil)
+		if err != nil {
+			t.Fatalf("Dial failed: %v", err)
+		}
+		defer conn.Close()
+
+		if err := conn.WriteMessage(TextMessage, bytes.Repeat([]byte("x"), 2048)); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+		if _, _, err := conn.ReadMessage(); !errors.Is(err, ErrClosed) {
+			t.Errorf("Expected the server to close the connection, got %v", err)
+		}
+	})
+
+	t.Run("closing handshake", func(t *testing.T) {
+		conn, err := Dial(server.URL, nil)
+		if err != nil {
+			t.Fatalf("Dial failed: %v", err)
+		}
+		if err := conn.Close(); err != nil {
+			t.Errorf("Close failed: %v", err)
+		}
+		if err := conn.WriteMessage(TextMessage, []byte("late")); err == nil {
+			t.Error("Expected writes after Close to fail")
+		}
+	})
+}
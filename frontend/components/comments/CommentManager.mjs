@@ -70,10 +70,10 @@ export class CommentManager {
                 </div>
                 <div class="comment-details">
                     <div>
-                        <p class="comment-content">
+                        <div class="comment-content">
                             <strong><span class="comment-username">${username}</span>:</strong>
-                            <span class="comment-text">${comment.content}</span>
-                        </p>
+                            <div class="comment-text">${comment.content_html ?? ''}</div>
+                        </div>
                     </div>
                     <div class="comment-footer">
                         ${commentActions}
@@ -142,7 +142,7 @@ export class CommentManager {
                         <div class="comment-wrapper">
                             <div class="comment-details">
                                 <p class="comment-content">
-                                    <strong>Error loading reply:</strong> ${childReply.content || 'Content unavailable'}
+                                    <strong>Error loading reply:</strong> ${childReply.content_html || 'Content unavailable'}
                                 </p>
                             </div>
                         </div>
@@ -35,7 +35,7 @@ export class PostCard {
                 <div class="post-image hidden">
                     <img src="http://localhost:8080${post.image_url || ''}" alt="Post image" onerror="this.parentElement.innerHTML='<div class=\\'image-error\\'>Image unavailable</div>'"/>
                 </div>
-                <div class="post-body">${post.content}</div>
+                <div class="post-body">${post.content_html ?? ''}</div>
             </div>
             <div class="post-actions">
                 <button class="reaction-btn like-btn" data-id="${post.id}"><i class="fas fa-thumbs-up"></i></button>
@@ -276,7 +276,7 @@ export class PostCard {
                         <img src="http://localhost:8080${post.image_url}" alt="Post image" class="post-image-full" onerror="this.parentElement.innerHTML='<div class=\\'image-error\\'>Image unavailable</div>'">
                     </div>
                 ` : ''}
-                <div class="post-full-text">${post.content}</div>
+                <div class="post-full-text">${post.content_html ?? ''}</div>
             </div>
 
             <div class="post-stats-detailed">
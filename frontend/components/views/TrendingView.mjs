@@ -3,6 +3,7 @@
  */
 
 import { BaseView } from './BaseView.mjs';
+import { ValidationUtils } from '../../utils/ValidationUtils.mjs';
 
 export class TrendingView extends BaseView {
     constructor(app, params, query) {
@@ -196,7 +197,7 @@ export class TrendingView extends BaseView {
                                 <img src="http://localhost:8080${post.image_url}" alt="Post image" class="trending-post-image" onerror="this.parentElement.innerHTML='<div class=\\'image-error\\'>Image unavailable</div>'">
                             </div>
                         ` : ''}
-                        <div class="post-snippet">${this.truncateContent(post.content, 150)}</div>
+                        <div class="post-snippet">${ValidationUtils.escapeHtml(this.truncateContent(post.content, 150))}</div>
 
                         <!-- Interactive reaction buttons -->
                         <div class="post-actions">